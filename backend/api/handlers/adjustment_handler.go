@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AdjustmentHandler struct {
+	repo        *repository.Repository
+	adjustments *services.AdjustmentService
+}
+
+func NewAdjustmentHandler(repo *repository.Repository) *AdjustmentHandler {
+	return &AdjustmentHandler{
+		repo:        repo,
+		adjustments: services.NewAdjustmentService(repo),
+	}
+}
+
+type CreateAdjustmentRequest struct {
+	Direction  string  `json:"direction" binding:"required"` // CREDIT or DEBIT
+	Amount     float64 `json:"amount" binding:"required"`
+	ReasonCode string  `json:"reasonCode" binding:"required"`
+	Note       string  `json:"note" binding:"required"`
+}
+
+// HandleCreateAdjustment credits or debits a customer's wallet. Adjustments above the
+// merchant's approval threshold are returned as PENDING with 202.
+func (h *AdjustmentHandler) HandleCreateAdjustment(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	user := c.GetString("merchant_user")
+
+	walletID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet ID"})
+		return
+	}
+
+	var req CreateAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wallet, err := h.repo.GetWalletByID(c.Request.Context(), walletID)
+	if err != nil || wallet.MerchantID != merchant.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+		return
+	}
+
+	adjustment, err := h.adjustments.Request(c.Request.Context(), merchant, wallet, &domain.BalanceAdjustment{
+		Direction:   domain.AdjustmentDirection(strings.ToUpper(req.Direction)),
+		Amount:      req.Amount,
+		ReasonCode:  domain.AdjustmentReason(strings.ToUpper(req.ReasonCode)),
+		Note:        req.Note,
+		RequestedBy: user,
+	})
+	if err != nil {
+		respondAdjustmentError(c, err)
+		return
+	}
+
+	if adjustment.Status == domain.AdjustmentStatusPending {
+		c.JSON(http.StatusAccepted, adjustment)
+		return
+	}
+	c.JSON(http.StatusCreated, adjustment)
+}
+
+// HandleListAdjustments lists the merchant's adjustments, optionally filtered by ?status=
+func (h *AdjustmentHandler) HandleListAdjustments(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	status := domain.AdjustmentStatus(strings.ToUpper(c.Query("status")))
+	adjustments, err := h.repo.GetAdjustmentsByMerchant(c.Request.Context(), merchant.ID, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch adjustments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, adjustments)
+}
+
+// HandleApproveAdjustment applies a pending adjustment
+func (h *AdjustmentHandler) HandleApproveAdjustment(c *gin.Context) {
+	h.review(c, h.adjustments.Approve)
+}
+
+// HandleRejectAdjustment discards a pending adjustment
+func (h *AdjustmentHandler) HandleRejectAdjustment(c *gin.Context) {
+	h.review(c, h.adjustments.Reject)
+}
+
+type reviewFunc func(ctx context.Context, adjustment *domain.BalanceAdjustment, reviewer string) (*domain.BalanceAdjustment, error)
+
+func (h *AdjustmentHandler) review(c *gin.Context, action reviewFunc) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	user := c.GetString("merchant_user")
+
+	adjustmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid adjustment ID"})
+		return
+	}
+
+	adjustment, err := h.repo.GetAdjustment(c.Request.Context(), adjustmentID)
+	if err != nil || adjustment.MerchantID != merchant.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Adjustment not found"})
+		return
+	}
+
+	adjustment, err = action(c.Request.Context(), adjustment, user)
+	if err != nil {
+		respondAdjustmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, adjustment)
+}
+
+// MerchantUserMiddleware authenticates the staff member acting on behalf of the merchant by
+// their Supabase access token. It runs after AuthMiddleware and only accepts users linked
+// to the API key's merchant in merchant_users.
+func MerchantUserMiddleware(repo *repository.Repository, verifier services.TokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		merchant := c.MustGet("merchant").(*domain.Merchant)
+
+		user, ok := verifyBearer(c, verifier)
+		if !ok {
+			return
+		}
+
+		member, err := repo.IsMerchantUser(c.Request.Context(), merchant.ID, user.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check merchant user"})
+			c.Abort()
+			return
+		}
+		if !member {
+			c.JSON(http.StatusForbidden, gin.H{"error": "User is not a member of this merchant"})
+			c.Abort()
+			return
+		}
+
+		c.Set("merchant_user", user.UserID.String())
+		c.Next()
+	}
+}
+
+func respondAdjustmentError(c *gin.Context, err error) {
+	var fields domain.ValidationErrors
+	switch {
+	case errors.As(err, &fields):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid adjustment", "fields": fields})
+	case errors.Is(err, services.ErrInsufficientBalance):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Insufficient balance for this debit"})
+	case errors.Is(err, services.ErrAdjustmentNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "Adjustment was already reviewed"})
+	case errors.Is(err, services.ErrSelfApproval):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Adjustments must be reviewed by another user"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process adjustment"})
+	}
+}
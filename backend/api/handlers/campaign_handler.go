@@ -1,71 +1,266 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/Ananiaslitz/fidelio/domain"
 	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/services"
+	"github.com/Ananiaslitz/fidelio/strategies"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type CampaignHandler struct {
-	repo *repository.Repository
+	repo       *repository.Repository
+	strategies domain.StrategyRegistry
+	campaigns  *services.CampaignService
+	budgets    *services.BudgetService
+	lifecycle  *services.CampaignLifecycle
+	templates  *services.TemplateService
 }
 
-func NewCampaignHandler(repo *repository.Repository) *CampaignHandler {
-	return &CampaignHandler{repo: repo}
+func NewCampaignHandler(repo *repository.Repository, strategies domain.StrategyRegistry, notifier services.Notifier) *CampaignHandler {
+	return &CampaignHandler{
+		repo:       repo,
+		strategies: strategies,
+		campaigns:  services.NewCampaignService(repo, strategies),
+		budgets:    services.NewBudgetService(repo, notifier),
+		lifecycle:  services.NewCampaignLifecycle(repo, notifier),
+		templates:  services.NewTemplateService(repo, strategies),
+	}
 }
 
 type CreateCampaignRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	Type        string `json:"type" binding:"required"`
-	Config      string `json:"config" binding:"required"`
-	StartsAt    string `json:"startsAt"`
-	EndsAt      string `json:"endsAt"`
+	Name        string          `json:"name" binding:"required"`
+	Description string          `json:"description"`
+	Type        string          `json:"type" binding:"required"`
+	Config      json.RawMessage `json:"config" binding:"required"` // JSON object, or the same object encoded as a string
+	StartsAt    ScheduleDate    `json:"startsAt"`                  // Date (merchant timezone) or RFC 3339 timestamp
+	EndsAt      ScheduleDate    `json:"endsAt"`                    // Date, inclusive, or RFC 3339 timestamp
+
+	// Budget caps, omit for no limit
+	MaxRewardValue *float64 `json:"maxRewardValue"` // Total value of rewards granted
+	MaxRewards     *int     `json:"maxRewards"`     // Number of rewards granted
+	DailyRewardCap *float64 `json:"dailyRewardCap"` // Value of rewards granted per day
+
+	// SegmentID restricts the campaign to a customer segment, omit for everyone
+	SegmentID string `json:"segmentId"`
+
+	// Status applies to creation only: "DRAFT", or empty to start now or at startsAt
+	Status string `json:"status"`
+
+	// MigrationMode applies to updates only: "new_cards_only" (default) or "migrate_all"
+	MigrationMode string `json:"migrationMode"`
 }
 
-// HandleCreateCampaign creates a new campaign
-func (h *CampaignHandler) HandleCreateCampaign(c *gin.Context) {
-	var req CreateCampaignRequest
+// ScheduleDate is a campaign date in a request. It remembers whether the field was sent, so
+// an update tells an omitted date (kept) from null or "" (cleared).
+type ScheduleDate struct {
+	Value string
+	Set   bool
+}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+func (d *ScheduleDate) UnmarshalJSON(data []byte) error {
+	d.Set = true
+	if string(data) == "null" {
+		d.Value = ""
+		return nil
 	}
+	return json.Unmarshal(data, &d.Value)
+}
 
-	// Get merchant ID from context (set by auth middleware)
-	merchantID, exists := c.Get("merchant_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Merchant ID not found"})
-		return
+type ChangeCampaignStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+type CloneCampaignRequest struct {
+	Name string `json:"name"` // Defaults to the original name with " (cópia)"
+}
+
+// HandleListCampaignTypes lists the available campaign types with their config schema and defaults
+func (h *CampaignHandler) HandleListCampaignTypes(c *gin.Context) {
+	types := make([]domain.CampaignTypeInfo, 0, len(h.strategies))
+	for campaignType, strategy := range h.strategies {
+		types = append(types, domain.CampaignTypeInfo{
+			Type:          campaignType,
+			DisplayName:   strategy.DisplayName(),
+			ConfigSchema:  strategy.ConfigSchema(),
+			DefaultConfig: strategy.DefaultConfig(),
+		})
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Type < types[j].Type })
+
+	c.JSON(http.StatusOK, types)
+}
+
+// validateConfig normalizes the request config and validates it against the campaign type.
+// On failure it writes the error response and returns false.
+func (h *CampaignHandler) validateConfig(c *gin.Context, campaignType domain.CampaignType, raw json.RawMessage) (json.RawMessage, bool) {
+	strategy, ok := h.strategies[campaignType]
+	if !ok {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Invalid campaign config",
+			"fields": domain.ValidationErrors{domain.NewFieldError("type", "is not a supported campaign type: %s", campaignType)},
+		})
+		return nil, false
+	}
+
+	config, err := decodeConfig(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid config"})
+		return nil, false
+	}
+
+	if err := strategies.ValidateConfig(strategy, config); err != nil {
+		var fields domain.ValidationErrors
+		if !errors.As(err, &fields) {
+			fields = domain.ValidationErrors{domain.NewFieldError("config", "%s", err.Error())}
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Invalid campaign config",
+			"fields": fields,
+		})
+		return nil, false
+	}
+
+	return config, true
+}
+
+// validateBudget checks the budget caps of the request, writing the error response on failure
+func validateBudget(c *gin.Context, req *CreateCampaignRequest) bool {
+	var fields domain.ValidationErrors
+	if req.MaxRewardValue != nil && *req.MaxRewardValue <= 0 {
+		fields = append(fields, domain.NewFieldError("maxRewardValue", "must be greater than 0"))
+	}
+	if req.MaxRewards != nil && *req.MaxRewards <= 0 {
+		fields = append(fields, domain.NewFieldError("maxRewards", "must be greater than 0"))
+	}
+	if req.DailyRewardCap != nil && *req.DailyRewardCap <= 0 {
+		fields = append(fields, domain.NewFieldError("dailyRewardCap", "must be greater than 0"))
+	}
+
+	if len(fields) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid campaign budget", "fields": fields})
+		return false
+	}
+	return true
+}
+
+// parseSchedule resolves the request dates in the merchant's timezone. On failure it writes
+// the error response and returns false.
+func parseSchedule(c *gin.Context, req *CreateCampaignRequest, loc *time.Location) (startsAt, endsAt *time.Time, ok bool) {
+	startsAt, endsAt, fields := parseScheduleDates(req.StartsAt.Value, req.EndsAt.Value, loc)
+	if len(fields) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid campaign schedule", "fields": fields})
+		return nil, nil, false
+	}
+	return startsAt, endsAt, true
+}
+
+// applySchedule sets the dates of an update on the campaign. An omitted date keeps its value and
+// null or "" clears it; the resulting end must still come after the start.
+func applySchedule(campaign *domain.Campaign, req *CreateCampaignRequest, startsAt, endsAt *time.Time) domain.ValidationErrors {
+	if req.StartsAt.Set {
+		campaign.StartsAt = startsAt
+	}
+	if req.EndsAt.Set {
+		campaign.EndsAt = endsAt
+	}
+	if campaign.StartsAt != nil && campaign.EndsAt != nil && !campaign.EndsAt.After(*campaign.StartsAt) {
+		return domain.ValidationErrors{domain.NewFieldError("endsAt", "must be after startsAt")}
+	}
+	return nil
+}
+
+// parseScheduleDates reads startsAt and endsAt in the given timezone. A start date begins at
+// local midnight and an end date runs through the end of that local day; RFC 3339 timestamps
+// are taken as they are.
+func parseScheduleDates(rawStartsAt, rawEndsAt string, loc *time.Location) (startsAt, endsAt *time.Time, fields domain.ValidationErrors) {
+	parse := func(field, value string, end bool) *time.Time {
+		if value == "" {
+			return nil
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return &t
+		}
+		day, err := time.ParseInLocation("2006-01-02", value, loc)
+		if err != nil {
+			fields = append(fields, domain.NewFieldError(field, "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"))
+			return nil
+		}
+		if end {
+			day = day.AddDate(0, 0, 1)
+		}
+		return &day
+	}
+
+	startsAt = parse("startsAt", rawStartsAt, false)
+	endsAt = parse("endsAt", rawEndsAt, true)
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		fields = append(fields, domain.NewFieldError("endsAt", "must be after startsAt"))
+	}
+	return startsAt, endsAt, fields
+}
+
+// resolveSegment parses the request's segment and checks it belongs to the merchant.
+// On failure it writes the error response and returns false.
+func resolveSegment(c *gin.Context, repo *repository.Repository, merchantID uuid.UUID, raw string) (*uuid.UUID, bool) {
+	if raw == "" {
+		return nil, true
 	}
 
+	invalid := func() (*uuid.UUID, bool) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Invalid campaign segment",
+			"fields": domain.ValidationErrors{domain.NewFieldError("segmentId", "is not a segment of this merchant")},
+		})
+		return nil, false
+	}
+
+	segmentID, err := uuid.Parse(raw)
+	if err != nil {
+		return invalid()
+	}
+	segment, err := repo.GetSegment(c.Request.Context(), segmentID)
+	if err != nil || segment.MerchantID != merchantID {
+		return invalid()
+	}
+	return &segment.ID, true
+}
+
+// checkCampaignLimit enforces the plan's limit of running campaigns (active or scheduled),
+// writing the error response when it is reached
+func (h *CampaignHandler) checkCampaignLimit(c *gin.Context, merchantID uuid.UUID) bool {
 	// Get merchant subscription to check limits
-	subscription, err := h.repo.GetMerchantSubscription(c.Request.Context(), merchantID.(uuid.UUID))
+	subscription, err := h.repo.GetMerchantSubscription(c.Request.Context(), merchantID)
 	if err != nil {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error":   "No active subscription found",
 			"message": "Please subscribe to a plan to create campaigns",
 		})
-		return
+		return false
 	}
 
 	// Get plan limits
 	limits, err := subscription.Plan.GetLimits()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load plan limits"})
-		return
+		return false
 	}
 
 	// Check campaign limit (if not unlimited)
 	if limits.MaxActiveCampaigns > 0 {
-		count, err := h.repo.CountActiveCampaigns(c.Request.Context(), merchantID.(uuid.UUID))
+		count, err := h.repo.CountActiveCampaigns(c.Request.Context(), merchantID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check campaign limit"})
-			return
+			return false
 		}
 
 		if count >= limits.MaxActiveCampaigns {
@@ -75,36 +270,111 @@ func (h *CampaignHandler) HandleCreateCampaign(c *gin.Context) {
 				"currentPlan": subscription.Plan.Name,
 				"limit":       limits.MaxActiveCampaigns,
 			})
-			return
+			return false
 		}
 	}
 
-	// Parse dates
-	var startsAt, endsAt *time.Time
-	if req.StartsAt != "" {
-		t, err := time.Parse("2006-01-02", req.StartsAt)
-		if err == nil {
-			startsAt = &t
-		}
+	return true
+}
+
+// attachBudgets fills the budget consumption of the campaigns that have caps
+func (h *CampaignHandler) attachBudgets(c *gin.Context, merchantID uuid.UUID, campaigns ...*domain.Campaign) error {
+	merchant, err := h.repo.GetMerchantByID(c.Request.Context(), merchantID)
+	if err != nil {
+		return err
+	}
+
+	usages, err := h.repo.GetBudgetUsageByMerchant(c.Request.Context(), merchantID)
+	if err != nil {
+		return err
 	}
-	if req.EndsAt != "" {
-		t, err := time.Parse("2006-01-02", req.EndsAt)
-		if err == nil {
-			endsAt = &t
+
+	for _, campaign := range campaigns {
+		if campaign.HasBudget() {
+			campaign.Budget = h.budgets.Report(campaign, usages[campaign.ID], merchant.Location())
 		}
 	}
+	return nil
+}
+
+// decodeConfig accepts a config sent either as a JSON object or as a string holding the JSON
+func decodeConfig(raw json.RawMessage) (json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '"' {
+		return raw, nil
+	}
+
+	var encoded string
+	if err := json.Unmarshal(trimmed, &encoded); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(encoded), nil
+}
+
+// HandleCreateCampaign creates a new campaign
+func (h *CampaignHandler) HandleCreateCampaign(c *gin.Context) {
+	var req CreateCampaignRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get merchant from context (set by auth middleware)
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	config, ok := h.validateConfig(c, domain.CampaignType(req.Type), req.Config)
+	if !ok {
+		return
+	}
+
+	if !validateBudget(c, &req) {
+		return
+	}
+
+	if req.Status != "" && domain.CampaignStatus(req.Status) != domain.CampaignStatusDraft {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Invalid campaign status",
+			"fields": domain.ValidationErrors{domain.NewFieldError("status", "must be DRAFT or omitted")},
+		})
+		return
+	}
+
+	startsAt, endsAt, ok := parseSchedule(c, &req, merchant.Location())
+	if !ok {
+		return
+	}
+
+	segmentID, ok := resolveSegment(c, h.repo, merchant.ID, req.SegmentID)
+	if !ok {
+		return
+	}
+
+	// Drafts do not run, so they do not count against the plan
+	if req.Status == "" && !h.checkCampaignLimit(c, merchant.ID) {
+		return
+	}
 
 	campaign := &domain.Campaign{
 		ID:         uuid.New(),
-		MerchantID: merchantID.(uuid.UUID),
+		MerchantID: merchant.ID,
 		Name:       req.Name,
 		Type:       domain.CampaignType(req.Type),
-		Config:     []byte(req.Config),
-		IsActive:   true,
+		Config:     config,
 		StartsAt:   startsAt,
 		EndsAt:     endsAt,
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
+
+		MaxRewardValue: req.MaxRewardValue,
+		MaxRewards:     req.MaxRewards,
+		DailyRewardCap: req.DailyRewardCap,
+		SegmentID:      segmentID,
+	}
+	if req.Status != "" {
+		campaign.SetStatus(domain.CampaignStatusDraft)
+	} else {
+		campaign.SetStatus(campaign.InitialStatus(time.Now()))
 	}
 
 	if err := h.repo.CreateCampaign(c.Request.Context(), campaign); err != nil {
@@ -112,10 +382,19 @@ func (h *CampaignHandler) HandleCreateCampaign(c *gin.Context) {
 		return
 	}
 
+	// Instant-win campaigns commit to their draw seed before the first purchase
+	if campaign.Type == domain.CampaignTypeInstantWin {
+		if _, err := services.NewInstantWinService(h.repo).EnsurePool(c.Request.Context(), campaign); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create prize pool"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusCreated, campaign)
 }
 
-// HandleListCampaigns lists all campaigns for a merchant
+// HandleListCampaigns lists the merchant's campaigns. Archived campaigns are only listed
+// with ?status=ARCHIVED.
 func (h *CampaignHandler) HandleListCampaigns(c *gin.Context) {
 	merchantID, exists := c.Get("merchant_id")
 	if !exists {
@@ -123,12 +402,23 @@ func (h *CampaignHandler) HandleListCampaigns(c *gin.Context) {
 		return
 	}
 
-	campaigns, err := h.repo.GetCampaignsByMerchant(c.Request.Context(), merchantID.(uuid.UUID))
+	status := domain.CampaignStatus(strings.ToUpper(c.Query("status")))
+	if status != "" && !status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign status"})
+		return
+	}
+
+	campaigns, err := h.repo.GetCampaignsByMerchant(c.Request.Context(), merchantID.(uuid.UUID), status)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaigns"})
 		return
 	}
 
+	if err := h.attachBudgets(c, merchantID.(uuid.UUID), campaigns...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign budgets"})
+		return
+	}
+
 	c.JSON(http.StatusOK, campaigns)
 }
 
@@ -146,14 +436,18 @@ func (h *CampaignHandler) HandleGetCampaign(c *gin.Context) {
 		return
 	}
 
+	if err := h.attachBudgets(c, campaign.MerchantID, campaign); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign budget"})
+		return
+	}
+
 	c.JSON(http.StatusOK, campaign)
 }
 
 // HandleUpdateCampaign updates a campaign
 func (h *CampaignHandler) HandleUpdateCampaign(c *gin.Context) {
-	campaignID, err := uuid.Parse(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+	campaign, ok := h.loadCampaign(c)
+	if !ok {
 		return
 	}
 
@@ -163,32 +457,68 @@ func (h *CampaignHandler) HandleUpdateCampaign(c *gin.Context) {
 		return
 	}
 
-	campaign, err := h.repo.GetCampaignByID(c.Request.Context(), campaignID)
+	if campaign.Status == domain.CampaignStatusArchived {
+		c.JSON(http.StatusConflict, gin.H{"error": "Archived campaigns cannot be changed"})
+		return
+	}
+
+	if domain.CampaignType(req.Type) != campaign.Type {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Invalid campaign config",
+			"fields": domain.ValidationErrors{domain.NewFieldError("type", "cannot be changed, create a new campaign instead")},
+		})
+		return
+	}
+
+	mode := domain.MigrationModeNewCardsOnly
+	if req.MigrationMode != "" {
+		mode = domain.MigrationMode(req.MigrationMode)
+	}
+	if !mode.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid migration mode"})
+		return
+	}
+
+	config, ok := h.validateConfig(c, campaign.Type, req.Config)
+	if !ok {
+		return
+	}
+
+	if !validateBudget(c, &req) {
+		return
+	}
+
+	merchant, err := h.repo.GetMerchantByID(c.Request.Context(), campaign.MerchantID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load merchant"})
+		return
+	}
+
+	startsAt, endsAt, ok := parseSchedule(c, &req, merchant.Location())
+	if !ok {
+		return
+	}
+
+	segmentID, ok := resolveSegment(c, h.repo, campaign.MerchantID, req.SegmentID)
+	if !ok {
 		return
 	}
 
 	// Update fields
 	campaign.Name = req.Name
-	campaign.Type = domain.CampaignType(req.Type)
-	campaign.Config = []byte(req.Config)
 	campaign.UpdatedAt = time.Now()
+	campaign.MaxRewardValue = req.MaxRewardValue
+	campaign.MaxRewards = req.MaxRewards
+	campaign.DailyRewardCap = req.DailyRewardCap
+	campaign.SegmentID = segmentID
 
-	if req.StartsAt != "" {
-		t, err := time.Parse("2006-01-02", req.StartsAt)
-		if err == nil {
-			campaign.StartsAt = &t
-		}
-	}
-	if req.EndsAt != "" {
-		t, err := time.Parse("2006-01-02", req.EndsAt)
-		if err == nil {
-			campaign.EndsAt = &t
-		}
+	if fields := applySchedule(campaign, &req, startsAt, endsAt); len(fields) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid campaign schedule", "fields": fields})
+		return
 	}
 
-	if err := h.repo.UpdateCampaign(c.Request.Context(), campaign); err != nil {
+	// Config changes create a new version instead of rewriting the rules of running cards
+	if err := h.campaigns.UpdateCampaign(c.Request.Context(), campaign, config, mode); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update campaign"})
 		return
 	}
@@ -196,43 +526,166 @@ func (h *CampaignHandler) HandleUpdateCampaign(c *gin.Context) {
 	c.JSON(http.StatusOK, campaign)
 }
 
-// HandleDeleteCampaign deletes a campaign
-func (h *CampaignHandler) HandleDeleteCampaign(c *gin.Context) {
-	campaignID, err := uuid.Parse(c.Param("id"))
+// HandleListCampaignVersions lists the config versions of a campaign, newest first
+func (h *CampaignHandler) HandleListCampaignVersions(c *gin.Context) {
+	campaign, ok := h.loadCampaign(c)
+	if !ok {
+		return
+	}
+
+	versions, err := h.repo.GetCampaignVersions(c.Request.Context(), campaign.ID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign versions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// HandleDeleteCampaign archives the campaign. Campaigns are never deleted, so the ledger
+// entries that reference them stay attributable; a running campaign has to end first.
+func (h *CampaignHandler) HandleDeleteCampaign(c *gin.Context) {
+	campaign, ok := h.loadCampaign(c)
+	if !ok {
 		return
 	}
 
-	if err := h.repo.DeleteCampaign(c.Request.Context(), campaignID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete campaign"})
+	if !h.changeStatus(c, campaign, domain.CampaignStatusArchived) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Campaign deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Campaign archived successfully"})
 }
 
-// HandleToggleCampaign toggles campaign active status
+// HandleToggleCampaign pauses an active campaign or resumes a paused one
 func (h *CampaignHandler) HandleToggleCampaign(c *gin.Context) {
-	campaignID, err := uuid.Parse(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+	campaign, ok := h.loadCampaign(c)
+	if !ok {
 		return
 	}
 
-	campaign, err := h.repo.GetCampaignByID(c.Request.Context(), campaignID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+	next := domain.CampaignStatusPaused
+	if campaign.Status == domain.CampaignStatusPaused {
+		next = domain.CampaignStatusActive
+	}
+
+	if !h.changeStatus(c, campaign, next) {
 		return
 	}
 
-	campaign.IsActive = !campaign.IsActive
-	campaign.UpdatedAt = time.Now()
+	c.JSON(http.StatusOK, campaign)
+}
+
+// HandleChangeStatus moves the campaign to another lifecycle status
+func (h *CampaignHandler) HandleChangeStatus(c *gin.Context) {
+	campaign, ok := h.loadCampaign(c)
+	if !ok {
+		return
+	}
+
+	var req ChangeCampaignStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := h.repo.UpdateCampaign(c.Request.Context(), campaign); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to toggle campaign"})
+	status := domain.CampaignStatus(strings.ToUpper(req.Status))
+	if !status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign status"})
+		return
+	}
+
+	// Scheduling needs a start time the scheduler can act on
+	if status == domain.CampaignStatusScheduled && (campaign.StartsAt == nil || !campaign.StartsAt.After(time.Now())) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Invalid campaign status",
+			"fields": domain.ValidationErrors{domain.NewFieldError("startsAt", "must be in the future to schedule the campaign")},
+		})
+		return
+	}
+
+	if !h.changeStatus(c, campaign, status) {
 		return
 	}
 
 	c.JSON(http.StatusOK, campaign)
 }
+
+// HandleCloneCampaign copies the campaign's config, schedule, targeting and budget into a new draft
+func (h *CampaignHandler) HandleCloneCampaign(c *gin.Context) {
+	campaign, ok := h.loadCampaign(c)
+	if !ok {
+		return
+	}
+
+	var req CloneCampaignRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	clone, err := h.templates.Clone(c.Request.Context(), campaign, req.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone campaign"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, clone)
+}
+
+// changeStatus applies a lifecycle transition, checking the plan limit when the campaign
+// starts running. On failure it writes the error response and returns false.
+func (h *CampaignHandler) changeStatus(c *gin.Context, campaign *domain.Campaign, to domain.CampaignStatus) bool {
+	running := func(status domain.CampaignStatus) bool {
+		return status == domain.CampaignStatusActive || status == domain.CampaignStatusScheduled
+	}
+	if running(to) && !running(campaign.Status) && campaign.Status.CanTransitionTo(to) {
+		if !h.checkCampaignLimit(c, campaign.MerchantID) {
+			return false
+		}
+	}
+
+	err := h.lifecycle.Transition(c.Request.Context(), campaign, to)
+	var transition *domain.TransitionError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &transition):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Invalid status transition",
+			"from":    transition.From,
+			"to":      transition.To,
+			"allowed": transition.Allowed,
+		})
+	case errors.Is(err, services.ErrCampaignStatusChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": "Campaign status changed, reload and try again"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change campaign status"})
+	}
+	return false
+}
+
+// loadCampaign fetches the campaign from the path and checks it belongs to the merchant
+func (h *CampaignHandler) loadCampaign(c *gin.Context) (*domain.Campaign, bool) {
+	merchantID, exists := c.Get("merchant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Merchant ID not found"})
+		return nil, false
+	}
+
+	campaignID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return nil, false
+	}
+
+	campaign, err := h.repo.GetCampaignByID(c.Request.Context(), campaignID)
+	if err != nil || campaign.MerchantID != merchantID.(uuid.UUID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return nil, false
+	}
+
+	return campaign, true
+}
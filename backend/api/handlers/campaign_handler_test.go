@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+)
+
+func TestApplySchedule(t *testing.T) {
+	startsAt := time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)
+	endsAt := time.Date(2026, 4, 1, 3, 0, 0, 0, time.UTC)
+	loc := time.FixedZone("BRT", -3*60*60)
+
+	tests := []struct {
+		name         string
+		body         string
+		wantStartsAt *time.Time
+		wantEndsAt   *time.Time
+		wantErr      bool
+	}{
+		{
+			name:         "omitted dates are kept",
+			body:         `{"name":"Cartão"}`,
+			wantStartsAt: &startsAt,
+			wantEndsAt:   &endsAt,
+		},
+		{
+			name:         "null clears the end date",
+			body:         `{"endsAt":null}`,
+			wantStartsAt: &startsAt,
+		},
+		{
+			name:       "empty string clears the start date",
+			body:       `{"startsAt":""}`,
+			wantEndsAt: &endsAt,
+		},
+		{
+			name:         "new end date",
+			body:         `{"endsAt":"2026-05-31"}`,
+			wantStartsAt: &startsAt,
+			wantEndsAt:   timePtr(time.Date(2026, 6, 1, 3, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:    "start moved past the kept end date",
+			body:    `{"startsAt":"2026-04-15"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req CreateCampaignRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("invalid body %s: %v", tt.body, err)
+			}
+			campaign := &domain.Campaign{StartsAt: timePtr(startsAt), EndsAt: timePtr(endsAt)}
+
+			parsedStartsAt, parsedEndsAt, fields := parseScheduleDates(req.StartsAt.Value, req.EndsAt.Value, loc)
+			if len(fields) > 0 {
+				t.Fatalf("parseScheduleDates() fields = %v", fields)
+			}
+
+			fields = applySchedule(campaign, &req, parsedStartsAt, parsedEndsAt)
+			if (len(fields) > 0) != tt.wantErr {
+				t.Fatalf("applySchedule() fields = %v, want error %v", fields, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !sameTime(campaign.StartsAt, tt.wantStartsAt) {
+				t.Errorf("StartsAt = %v, want %v", campaign.StartsAt, tt.wantStartsAt)
+			}
+			if !sameTime(campaign.EndsAt, tt.wantEndsAt) {
+				t.Errorf("EndsAt = %v, want %v", campaign.EndsAt, tt.wantEndsAt)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ClaimHandler serves the web page where customers without the app claim their shadow
+// balances with a code sent by SMS
+type ClaimHandler struct {
+	claims *services.ClaimService
+}
+
+func NewClaimHandler(claims *services.ClaimService) *ClaimHandler {
+	return &ClaimHandler{claims: claims}
+}
+
+type StartClaimRequest struct {
+	Phone string `json:"phone" binding:"required"` // E.164, e.g. +5511999999999
+}
+
+type VerifyClaimRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+	Email string `json:"email"` // Optional contact for the claimed balances
+}
+
+// HandleStartClaim sends a one-time code to the phone. The answer is the same whether the
+// phone has balances or not.
+func (h *ClaimHandler) HandleStartClaim(c *gin.Context) {
+	var req StartClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !domain.ValidPhone(req.Phone) {
+		respondInvalidClaim(c, domain.NewFieldError("phone", "must be in E.164 format, e.g. +5511999999999"))
+		return
+	}
+
+	err := h.claims.StartClaim(c.Request.Context(), req.Phone, c.ClientIP())
+	if err != nil {
+		respondClaimError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Se houver saldos para este telefone, enviamos um código por SMS"})
+}
+
+// HandleVerifyClaim checks the code and extends the phone's shadow balances
+func (h *ClaimHandler) HandleVerifyClaim(c *gin.Context) {
+	var req VerifyClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var fields domain.ValidationErrors
+	if !domain.ValidPhone(req.Phone) {
+		fields = append(fields, domain.NewFieldError("phone", "must be in E.164 format, e.g. +5511999999999"))
+	}
+
+	var email *string
+	if req.Email != "" {
+		address, err := mail.ParseAddress(req.Email)
+		if err != nil || address.Address != req.Email {
+			fields = append(fields, domain.NewFieldError("email", "is not a valid email address"))
+		} else {
+			normalized := strings.ToLower(address.Address)
+			email = &normalized
+		}
+	}
+
+	if len(fields) > 0 {
+		respondInvalidClaim(c, fields...)
+		return
+	}
+
+	result, err := h.claims.VerifyClaim(c.Request.Context(), req.Phone, strings.TrimSpace(req.Code), email)
+	if err != nil {
+		respondClaimError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func respondInvalidClaim(c *gin.Context, fields ...*domain.FieldError) {
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid claim", "fields": domain.ValidationErrors(fields)})
+}
+
+func respondClaimError(c *gin.Context, err error) {
+	var throttled *services.ThrottleError
+	switch {
+	case errors.As(err, &throttled):
+		c.Header("Retry-After", strconv.Itoa(int(throttled.RetryAfter.Seconds()+0.5)))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many codes requested, try again later"})
+	case errors.Is(err, services.ErrInvalidCode):
+		respondInvalidClaim(c, domain.NewFieldError("code", "is invalid"))
+	case errors.Is(err, services.ErrNoPendingCode):
+		c.JSON(http.StatusGone, gin.H{"error": "Code expired or already used, request a new one"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process claim"})
+	}
+}
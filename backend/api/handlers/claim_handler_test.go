@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ipRecordingStore records the codes requested by HandleStartClaim. Phones have no balances,
+// so only the methods used to store and throttle codes are implemented.
+type ipRecordingStore struct {
+	services.ClaimStore
+
+	mu    sync.Mutex
+	codes []*domain.OTPCode
+}
+
+func (s *ipRecordingStore) CreateOTPCode(ctx context.Context, otp *domain.OTPCode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes = append(s.codes, otp)
+	return nil
+}
+
+func (s *ipRecordingStore) GetLatestOTPCode(ctx context.Context, phoneHash string, purpose domain.OTPPurpose) (*domain.OTPCode, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (s *ipRecordingStore) CountOTPCodesSince(ctx context.Context, phoneHash string, since time.Time) (int, error) {
+	return 0, nil
+}
+
+func (s *ipRecordingStore) CountOTPCodesFromIPSince(ctx context.Context, ipAddress string, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, otp := range s.codes {
+		if otp.IPAddress == ipAddress {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *ipRecordingStore) GetActiveShadowBalancesByPhone(ctx context.Context, phoneHash string) ([]*domain.ShadowBalance, error) {
+	return nil, nil
+}
+
+func (s *ipRecordingStore) lastIP() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.codes) == 0 {
+		return ""
+	}
+	return s.codes[len(s.codes)-1].IPAddress
+}
+
+func newClaimRouter(t *testing.T, store services.ClaimStore, trustedProxies []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		t.Fatalf("SetTrustedProxies() error = %v", err)
+	}
+
+	handler := NewClaimHandler(services.NewClaimService(store, services.NewFakeSMSSender(), time.Hour))
+	router.POST("/v1/claims/start", handler.HandleStartClaim)
+	return router
+}
+
+func startClaim(router *gin.Engine, remoteAddr, forwardedFor, phone string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/claims/start", bytes.NewBufferString(`{"phone":"`+phone+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleStartClaimClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		forwardedFor   string
+		wantIP         string
+	}{
+		{
+			name:         "no trusted proxies ignores X-Forwarded-For",
+			remoteAddr:   "203.0.113.7:51234",
+			forwardedFor: "198.51.100.1",
+			wantIP:       "203.0.113.7",
+		},
+		{
+			name:           "trusted proxy forwards the client IP",
+			trustedProxies: []string{"172.28.0.0/16"},
+			remoteAddr:     "172.28.0.5:40000",
+			forwardedFor:   "198.51.100.1",
+			wantIP:         "198.51.100.1",
+		},
+		{
+			name:           "client cannot prepend a forged address",
+			trustedProxies: []string{"172.28.0.0/16"},
+			remoteAddr:     "172.28.0.5:40000",
+			forwardedFor:   "192.0.2.99, 198.51.100.1",
+			wantIP:         "198.51.100.1",
+		},
+		{
+			name:           "untrusted peer cannot claim to be forwarded",
+			trustedProxies: []string{"172.28.0.0/16"},
+			remoteAddr:     "203.0.113.7:51234",
+			forwardedFor:   "198.51.100.1",
+			wantIP:         "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &ipRecordingStore{}
+			router := newClaimRouter(t, store, tt.trustedProxies)
+
+			rec := startClaim(router, tt.remoteAddr, tt.forwardedFor, "+5511999990000")
+			if rec.Code != http.StatusAccepted {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusAccepted, rec.Body.String())
+			}
+			if got := store.lastIP(); got != tt.wantIP {
+				t.Errorf("code requested from %q, want %q", got, tt.wantIP)
+			}
+		})
+	}
+}
+
+func TestHandleStartClaimThrottlesForgedForwardedFor(t *testing.T) {
+	store := &ipRecordingStore{}
+	router := newClaimRouter(t, store, nil)
+
+	// A new X-Forwarded-For per request does not reset the per-IP limit
+	for i := 0; ; i++ {
+		phone := fmt.Sprintf("+551199990%04d", i)
+		rec := startClaim(router, "203.0.113.7:51234", fmt.Sprintf("198.51.100.%d", i), phone)
+		if rec.Code == http.StatusTooManyRequests {
+			if rec.Header().Get("Retry-After") == "" {
+				t.Error("throttled response without Retry-After")
+			}
+			return
+		}
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("request %d status = %d (body %s)", i+1, rec.Code, rec.Body.String())
+		}
+		if i > 100 {
+			t.Fatal("requests from one address were never throttled")
+		}
+	}
+}
+
+func TestHandleStartClaimInvalidPhone(t *testing.T) {
+	router := newClaimRouter(t, &ipRecordingStore{}, nil)
+
+	rec := startClaim(router, "203.0.113.7:51234", "", "11999990000")
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusUnprocessableEntity, rec.Body.String())
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxNearbyRadiusKm bounds the search so the bounding box stays selective
+	maxNearbyRadiusKm = 100
+
+	// maxFeaturedPromotions bounds the promotions returned at once
+	maxFeaturedPromotions = 50
+)
+
+// DiscoveryHandler serves the app's public catalog: nearby merchants, categories, merchant
+// profiles and featured promotions
+type DiscoveryHandler struct {
+	repo       *repository.Repository
+	strategies domain.StrategyRegistry
+}
+
+func NewDiscoveryHandler(repo *repository.Repository, strategies domain.StrategyRegistry) *DiscoveryHandler {
+	return &DiscoveryHandler{repo: repo, strategies: strategies}
+}
+
+// HandleNearbyMerchants lists the merchants within radiusKm (default 10, up to 100) of lat and
+// lng, nearest first, optionally filtered by category or categoryId and, with
+// activeCampaign=true, to merchants running a campaign
+func (h *DiscoveryHandler) HandleNearbyMerchants(c *gin.Context) {
+	query, ok := parseNearbyQuery(c)
+	if !ok {
+		return
+	}
+
+	merchants, err := h.repo.GetNearbyMerchants(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch nearby merchants"})
+		return
+	}
+
+	for _, merchant := range merchants {
+		if merchant.ActiveCampaign != nil {
+			h.fillDisplayName(merchant.ActiveCampaign)
+		}
+	}
+
+	c.JSON(http.StatusOK, merchants)
+}
+
+// HandleListCategories lists the merchant categories in display order
+func (h *DiscoveryHandler) HandleListCategories(c *gin.Context) {
+	categories, err := h.repo.GetCategories(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	respondWithETag(c, categories, catalogMaxAge)
+}
+
+// HandleGetMerchantProfile returns a merchant's public profile with its running campaigns and
+// the promotions on air
+func (h *DiscoveryHandler) HandleGetMerchantProfile(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merchant ID"})
+		return
+	}
+
+	profile, err := h.repo.GetMerchantProfile(c.Request.Context(), merchantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Merchant not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch merchant"})
+		return
+	}
+	for _, campaign := range profile.Campaigns {
+		h.fillDisplayName(campaign)
+	}
+
+	profile.Promotions, err = h.repo.GetFeaturedPromotions(c.Request.Context(), time.Now(), &merchantID, nil, maxFeaturedPromotions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch promotions"})
+		return
+	}
+
+	respondWithETag(c, profile, catalogMaxAge)
+}
+
+// HandleListFeaturedPromotions returns the promotions on air for the app's carousel, highest
+// priority first, optionally for the merchants of a categoryId
+func (h *DiscoveryHandler) HandleListFeaturedPromotions(c *gin.Context) {
+	var (
+		categoryID *uuid.UUID
+		fields     domain.ValidationErrors
+		limit      = 10
+	)
+	if raw := c.Query("categoryId"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			fields = append(fields, domain.NewFieldError("categoryId", "must be a UUID"))
+		}
+		categoryID = &id
+	}
+	if raw := c.Query("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 || value > maxFeaturedPromotions {
+			fields = append(fields, domain.NewFieldError("limit", "must be between 1 and %d", maxFeaturedPromotions))
+		}
+		limit = value
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid filter", "fields": fields})
+		return
+	}
+
+	promotions, err := h.repo.GetFeaturedPromotions(c.Request.Context(), time.Now(), nil, categoryID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch promotions"})
+		return
+	}
+
+	respondWithETag(c, promotions, catalogMaxAge)
+}
+
+// fillDisplayName names the campaign type as merchants see it
+func (h *DiscoveryHandler) fillDisplayName(campaign *domain.CampaignSummary) {
+	if strategy, ok := h.strategies[campaign.Type]; ok {
+		campaign.DisplayName = strategy.DisplayName()
+	}
+}
+
+// parseNearbyQuery reads the search parameters. On failure it writes the error response.
+func parseNearbyQuery(c *gin.Context) (*domain.NearbyQuery, bool) {
+	query := &domain.NearbyQuery{RadiusKm: 10, Limit: 50, Category: c.Query("category")}
+	var fields domain.ValidationErrors
+
+	parseFloat := func(field, raw string, min, max float64) float64 {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < min || value > max {
+			fields = append(fields, domain.NewFieldError(field, "must be a number between %g and %g", min, max))
+		}
+		return value
+	}
+	query.Latitude = parseFloat("lat", c.Query("lat"), -90, 90)
+	query.Longitude = parseFloat("lng", c.Query("lng"), -180, 180)
+	if raw := c.Query("radiusKm"); raw != "" {
+		query.RadiusKm = parseFloat("radiusKm", raw, 0, maxNearbyRadiusKm)
+	}
+
+	if raw := c.Query("categoryId"); raw != "" {
+		categoryID, err := uuid.Parse(raw)
+		if err != nil {
+			fields = append(fields, domain.NewFieldError("categoryId", "must be a UUID"))
+		} else {
+			query.CategoryID = &categoryID
+		}
+	}
+
+	if raw := c.Query("activeCampaign"); raw != "" {
+		active, err := strconv.ParseBool(raw)
+		if err != nil {
+			fields = append(fields, domain.NewFieldError("activeCampaign", "must be true or false"))
+		}
+		query.ActiveCampaign = active
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > 100 {
+			fields = append(fields, domain.NewFieldError("limit", "must be between 1 and 100"))
+		}
+		query.Limit = limit
+	}
+
+	if len(fields) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid search", "fields": fields})
+		return nil, false
+	}
+	return query, true
+}
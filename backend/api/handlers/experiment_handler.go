@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ExperimentHandler struct {
+	repo        *repository.Repository
+	experiments *services.ExperimentService
+}
+
+func NewExperimentHandler(repo *repository.Repository, strategies domain.StrategyRegistry) *ExperimentHandler {
+	return &ExperimentHandler{
+		repo:        repo,
+		experiments: services.NewExperimentService(repo, strategies),
+	}
+}
+
+type CreateExperimentRequest struct {
+	Name     string                     `json:"name" binding:"required"`
+	Variants []ExperimentVariantRequest `json:"variants" binding:"required"`
+}
+
+type ExperimentVariantRequest struct {
+	Name    string          `json:"name"`
+	Weight  int             `json:"weight"`
+	Control bool            `json:"control"` // Holdout group that earns nothing
+	Config  json.RawMessage `json:"config"`  // Overrides the campaign config; omit to run the campaign as is
+}
+
+// HandleCreateExperiment starts an experiment on a campaign
+func (h *ExperimentHandler) HandleCreateExperiment(c *gin.Context) {
+	campaign, ok := h.loadCampaign(c)
+	if !ok {
+		return
+	}
+
+	var req CreateExperimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variants := make([]*domain.ExperimentVariant, 0, len(req.Variants))
+	for _, v := range req.Variants {
+		config, err := decodeConfig(v.Config)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant config"})
+			return
+		}
+		variants = append(variants, &domain.ExperimentVariant{
+			Name:      v.Name,
+			Weight:    v.Weight,
+			IsControl: v.Control,
+			Config:    config,
+		})
+	}
+
+	experiment, err := h.experiments.CreateExperiment(c.Request.Context(), campaign, req.Name, variants)
+	if err != nil {
+		var fields domain.ValidationErrors
+		if errors.As(err, &fields) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid experiment", "fields": fields})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create experiment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, experiment)
+}
+
+// HandleListExperiments lists the experiments of a campaign
+func (h *ExperimentHandler) HandleListExperiments(c *gin.Context) {
+	campaign, ok := h.loadCampaign(c)
+	if !ok {
+		return
+	}
+
+	experiments, err := h.repo.GetExperimentsByCampaign(c.Request.Context(), campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch experiments"})
+		return
+	}
+
+	for _, experiment := range experiments {
+		variants, err := h.repo.GetExperimentVariants(c.Request.Context(), experiment.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch experiments"})
+			return
+		}
+		experiment.Variants = variants
+	}
+
+	c.JSON(http.StatusOK, experiments)
+}
+
+// HandleGetResults returns per-variant metrics with significance tests against the control
+func (h *ExperimentHandler) HandleGetResults(c *gin.Context) {
+	experiment, campaign, ok := h.loadExperiment(c)
+	if !ok {
+		return
+	}
+
+	results, err := h.experiments.Results(c.Request.Context(), experiment, campaign.MerchantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute experiment results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// HandleStopExperiment ends the experiment; every customer goes back to the campaign config
+func (h *ExperimentHandler) HandleStopExperiment(c *gin.Context) {
+	experiment, _, ok := h.loadExperiment(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.StopExperiment(c.Request.Context(), experiment.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop experiment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Experiment stopped"})
+}
+
+// loadCampaign fetches the merchant's campaign from the path, writing the error response on failure
+func (h *ExperimentHandler) loadCampaign(c *gin.Context) (*domain.Campaign, bool) {
+	merchantID, exists := c.Get("merchant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Merchant ID not found"})
+		return nil, false
+	}
+
+	campaignID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return nil, false
+	}
+
+	campaign, err := h.repo.GetCampaignByID(c.Request.Context(), campaignID)
+	if err != nil || campaign.MerchantID != merchantID.(uuid.UUID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return nil, false
+	}
+
+	return campaign, true
+}
+
+// loadExperiment fetches the experiment from the path and checks it belongs to the merchant
+func (h *ExperimentHandler) loadExperiment(c *gin.Context) (*domain.Experiment, *domain.Campaign, bool) {
+	merchantID, exists := c.Get("merchant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Merchant ID not found"})
+		return nil, nil, false
+	}
+
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid experiment ID"})
+		return nil, nil, false
+	}
+
+	experiment, err := h.repo.GetExperiment(c.Request.Context(), experimentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Experiment not found"})
+		return nil, nil, false
+	}
+
+	campaign, err := h.repo.GetCampaignByID(c.Request.Context(), experiment.CampaignID)
+	if err != nil || campaign.MerchantID != merchantID.(uuid.UUID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Experiment not found"})
+		return nil, nil, false
+	}
+
+	return experiment, campaign, true
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// historyTypes are the ledger entry types accepted by the type filter
+var historyTypes = map[domain.TransactionType]bool{
+	domain.TransactionTypeEarn:     true,
+	domain.TransactionTypeRedeem:   true,
+	domain.TransactionTypeExpire:   true,
+	domain.TransactionTypeConvert:  true,
+	domain.TransactionTypeOccasion: true,
+	domain.TransactionTypeWelcome:  true,
+	domain.TransactionTypeAdjust:   true,
+	domain.TransactionTypeReset:    true,
+}
+
+// parseHistoryFilter reads the history query parameters: type (comma-separated), from and
+// to (dates in the merchant's timezone, to inclusive, or RFC 3339 timestamps), campaignId,
+// cursor and limit (1-100, default 50). On failure it writes the error response.
+func parseHistoryFilter(c *gin.Context, loc *time.Location) (*domain.HistoryFilter, bool) {
+	filter := &domain.HistoryFilter{Limit: 50}
+	var fields domain.ValidationErrors
+
+	for _, raw := range c.QueryArray("type") {
+		for _, value := range strings.Split(raw, ",") {
+			t := domain.TransactionType(strings.ToUpper(strings.TrimSpace(value)))
+			if !historyTypes[t] {
+				fields = append(fields, domain.NewFieldError("type", "is not a transaction type: %s", value))
+				continue
+			}
+			filter.Types = append(filter.Types, t)
+		}
+	}
+
+	parseTime := func(field, value string, end bool) *time.Time {
+		if value == "" {
+			return nil
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return &t
+		}
+		day, err := time.ParseInLocation("2006-01-02", value, loc)
+		if err != nil {
+			fields = append(fields, domain.NewFieldError(field, "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"))
+			return nil
+		}
+		if end {
+			day = day.AddDate(0, 0, 1)
+		}
+		return &day
+	}
+	filter.From = parseTime("from", c.Query("from"), false)
+	filter.To = parseTime("to", c.Query("to"), true)
+
+	if raw := c.Query("campaignId"); raw != "" {
+		campaignID, err := uuid.Parse(raw)
+		if err != nil {
+			fields = append(fields, domain.NewFieldError("campaignId", "must be a UUID"))
+		} else {
+			filter.CampaignID = &campaignID
+		}
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := domain.ParseHistoryCursor(raw)
+		if err != nil {
+			fields = append(fields, domain.NewFieldError("cursor", "is not a cursor returned by this endpoint"))
+		}
+		filter.After = cursor
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > 100 {
+			fields = append(fields, domain.NewFieldError("limit", "must be between 1 and 100"))
+		}
+		filter.Limit = limit
+	}
+
+	if len(fields) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid history filter", "fields": fields})
+		return nil, false
+	}
+	return filter, true
+}
+
+// describeHistory fills the entries' descriptions. Customers only see the description, since
+// the metadata holds merchant-internal details.
+func describeHistory(page *domain.HistoryPage, forCustomer bool) {
+	for _, entry := range page.Entries {
+		entry.Description = entry.Describe()
+		if forCustomer {
+			entry.Metadata = nil
+		}
+	}
+}
@@ -63,6 +63,15 @@ func (h *IngestHandler) Handle(c *gin.Context) {
 		return
 	}
 
+	// Customers are identified by the E.164 number; a national number cannot be told apart
+	// from another country's
+	if _, ok := domain.InternationalPhone(req.Phone); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "phone must include the country code, e.g. +5511999999999",
+		})
+		return
+	}
+
 	// Process transaction
 	response, err := h.engine.ProcessTransaction(c.Request.Context(), merchantObj, &req)
 	if err != nil {
@@ -79,7 +88,7 @@ func (h *IngestHandler) Handle(c *gin.Context) {
 }
 
 // AuthMiddleware validates the API key and loads the merchant
-func AuthMiddleware(repo *repository.Repository) gin.HandlerFunc {
+func AuthMiddleware(merchants *services.MerchantCache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiKey := c.GetHeader("X-API-Key")
 		if apiKey == "" {
@@ -91,7 +100,7 @@ func AuthMiddleware(repo *repository.Repository) gin.HandlerFunc {
 		}
 
 		// Get merchant by API key
-		merchant, err := repo.GetMerchantByAPIKey(c.Request.Context(), apiKey)
+		merchant, err := merchants.GetByAPIKey(c.Request.Context(), apiKey)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "invalid API key",
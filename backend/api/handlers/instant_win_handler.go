@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type InstantWinHandler struct {
+	repo    *repository.Repository
+	service *services.InstantWinService
+}
+
+func NewInstantWinHandler(repo *repository.Repository) *InstantWinHandler {
+	return &InstantWinHandler{
+		repo:    repo,
+		service: services.NewInstantWinService(repo),
+	}
+}
+
+// HandleGetReport returns the prize pool status, including the revealed seed once the campaign ended
+func (h *InstantWinHandler) HandleGetReport(c *gin.Context) {
+	campaign, ok := h.loadCampaign(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.Report(c.Request.Context(), campaign)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build instant win report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// HandleVerifyDraw returns a single draw so it can be audited against the seed commitment
+func (h *InstantWinHandler) HandleVerifyDraw(c *gin.Context) {
+	campaign, ok := h.loadCampaign(c)
+	if !ok {
+		return
+	}
+
+	verification, err := h.service.VerifyDraw(c.Request.Context(), campaign, c.Param("transactionId"))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Draw not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify draw"})
+		return
+	}
+
+	c.JSON(http.StatusOK, verification)
+}
+
+// loadCampaign fetches the instant-win campaign from the path, writing the error response on failure
+func (h *InstantWinHandler) loadCampaign(c *gin.Context) (*domain.Campaign, bool) {
+	merchantID, exists := c.Get("merchant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Merchant ID not found"})
+		return nil, false
+	}
+
+	campaignID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return nil, false
+	}
+
+	campaign, err := h.repo.GetCampaignByID(c.Request.Context(), campaignID)
+	if err != nil || campaign.MerchantID != merchantID.(uuid.UUID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return nil, false
+	}
+
+	if campaign.Type != domain.CampaignTypeInstantWin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Campaign is not an instant win campaign"})
+		return nil, false
+	}
+
+	return campaign, true
+}
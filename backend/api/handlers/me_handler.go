@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ConsumerAuthMiddleware authenticates app users by their Supabase access token
+func ConsumerAuthMiddleware(verifier services.TokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		consumer, ok := verifyBearer(c, verifier)
+		if !ok {
+			return
+		}
+
+		c.Set("consumer", consumer)
+		c.Next()
+	}
+}
+
+// verifyBearer verifies the request's Supabase access token, aborting with the error
+// response when it is missing or not valid
+func verifyBearer(c *gin.Context, verifier services.TokenVerifier) (*domain.Consumer, bool) {
+	token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found || token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Bearer token required"})
+		c.Abort()
+		return nil, false
+	}
+
+	consumer, err := verifier.Verify(c.Request.Context(), token)
+	switch {
+	case errors.Is(err, services.ErrTokenExpired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
+		c.Abort()
+		return nil, false
+	case errors.Is(err, services.ErrInvalidToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return nil, false
+	case err != nil:
+		// Signing keys could not be fetched
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Token verification unavailable"})
+		c.Abort()
+		return nil, false
+	}
+	return consumer, true
+}
+
+// MeHandler serves the app user's own balances and progress
+type MeHandler struct {
+	repo      *repository.Repository
+	campaigns *services.CampaignService
+}
+
+func NewMeHandler(repo *repository.Repository, strategies domain.StrategyRegistry) *MeHandler {
+	return &MeHandler{
+		repo:      repo,
+		campaigns: services.NewCampaignService(repo, strategies),
+	}
+}
+
+// HandleGetMe returns the authenticated user
+func (h *MeHandler) HandleGetMe(c *gin.Context) {
+	consumer := c.MustGet("consumer").(*domain.Consumer)
+	c.JSON(http.StatusOK, consumer)
+}
+
+// HandleListWallets lists the user's wallets across all merchants
+func (h *MeHandler) HandleListWallets(c *gin.Context) {
+	consumer := c.MustGet("consumer").(*domain.Consumer)
+
+	wallets, err := h.repo.GetConsumerWallets(c.Request.Context(), consumer.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, wallets)
+}
+
+// HandleListShadowBalances lists balances earned with the user's phone that were not claimed
+// yet. Accounts without a verified phone have none.
+func (h *MeHandler) HandleListShadowBalances(c *gin.Context) {
+	consumer := c.MustGet("consumer").(*domain.Consumer)
+
+	phoneHash := consumer.PhoneHash()
+	if phoneHash == "" {
+		c.JSON(http.StatusOK, []*domain.ConsumerShadowBalance{})
+		return
+	}
+
+	shadows, err := h.repo.GetConsumerShadowBalances(c.Request.Context(), phoneHash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shadow balances"})
+		return
+	}
+
+	c.JSON(http.StatusOK, shadows)
+}
+
+// HandleListProgress returns the user's progress in each merchant's campaign, including how
+// close they are to the next reward
+func (h *MeHandler) HandleListProgress(c *gin.Context) {
+	consumer := c.MustGet("consumer").(*domain.Consumer)
+
+	progress, err := h.repo.GetConsumerProgress(c.Request.Context(), consumer.UserID, consumer.PhoneHash())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch progress"})
+		return
+	}
+
+	h.campaigns.DescribeProgress(progress...)
+	c.JSON(http.StatusOK, progress)
+}
+
+// HandleGetWalletHistory pages through the ledger of one of the user's wallets
+func (h *MeHandler) HandleGetWalletHistory(c *gin.Context) {
+	consumer := c.MustGet("consumer").(*domain.Consumer)
+
+	walletID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet ID"})
+		return
+	}
+
+	wallet, err := h.repo.GetWalletByID(c.Request.Context(), walletID)
+	if err != nil || wallet.UserID != consumer.UserID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+		return
+	}
+
+	filter, ok := h.historyFilter(c, wallet.MerchantID)
+	if !ok {
+		return
+	}
+
+	page, err := h.repo.GetWalletHistory(c.Request.Context(), wallet, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet history"})
+		return
+	}
+
+	describeHistory(page, true)
+	c.JSON(http.StatusOK, page)
+}
+
+// HandleGetShadowBalanceHistory pages through the ledger of a shadow balance earned with the
+// user's phone
+func (h *MeHandler) HandleGetShadowBalanceHistory(c *gin.Context) {
+	consumer := c.MustGet("consumer").(*domain.Consumer)
+
+	shadowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shadow balance ID"})
+		return
+	}
+
+	shadow, err := h.repo.GetShadowBalanceByID(c.Request.Context(), shadowID)
+	if err != nil || consumer.PhoneHash() == "" || shadow.PhoneHash != consumer.PhoneHash() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shadow balance not found"})
+		return
+	}
+
+	filter, ok := h.historyFilter(c, shadow.MerchantID)
+	if !ok {
+		return
+	}
+
+	page, err := h.repo.GetShadowBalanceHistory(c.Request.Context(), shadow, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shadow balance history"})
+		return
+	}
+
+	describeHistory(page, true)
+	c.JSON(http.StatusOK, page)
+}
+
+// historyFilter parses the history filter with dates in the merchant's timezone
+func (h *MeHandler) historyFilter(c *gin.Context, merchantID uuid.UUID) (*domain.HistoryFilter, bool) {
+	merchant, err := h.repo.GetMerchantByID(c.Request.Context(), merchantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch merchant"})
+		return nil, false
+	}
+	return parseHistoryFilter(c, merchant.Location())
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type OccasionHandler struct {
+	repo *repository.Repository
+}
+
+func NewOccasionHandler(repo *repository.Repository) *OccasionHandler {
+	return &OccasionHandler{repo: repo}
+}
+
+type UpsertOccasionRewardRequest struct {
+	Amount     float64 `json:"amount" binding:"required"`
+	RewardType string  `json:"rewardType"`
+	ValidDays  *int    `json:"validDays"`
+	IsActive   *bool   `json:"isActive"`
+}
+
+// HandleListOccasionRewards lists the merchant's birthday and anniversary rewards
+func (h *OccasionHandler) HandleListOccasionRewards(c *gin.Context) {
+	merchantID, exists := c.Get("merchant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Merchant ID not found"})
+		return
+	}
+
+	rewards, err := h.repo.GetOccasionRewardsByMerchant(c.Request.Context(), merchantID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch occasion rewards"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rewards)
+}
+
+// HandleUpsertOccasionReward creates or replaces the reward for an occasion
+func (h *OccasionHandler) HandleUpsertOccasionReward(c *gin.Context) {
+	occasion := domain.OccasionType(strings.ToUpper(c.Param("occasion")))
+	if !occasion.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid occasion"})
+		return
+	}
+
+	var req UpsertOccasionRewardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be greater than 0"})
+		return
+	}
+
+	if req.ValidDays != nil && *req.ValidDays <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validDays must be greater than 0"})
+		return
+	}
+
+	merchantID, exists := c.Get("merchant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Merchant ID not found"})
+		return
+	}
+
+	rewardType := req.RewardType
+	if rewardType == "" {
+		rewardType = "points"
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	reward := &domain.OccasionReward{
+		ID:         uuid.New(),
+		MerchantID: merchantID.(uuid.UUID),
+		Occasion:   occasion,
+		Amount:     req.Amount,
+		RewardType: rewardType,
+		ValidDays:  req.ValidDays,
+		IsActive:   isActive,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	if err := h.repo.UpsertOccasionReward(c.Request.Context(), reward); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save occasion reward"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reward)
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PromotionHandler lets merchants manage their banners in the app's featured carousel
+type PromotionHandler struct {
+	repo *repository.Repository
+}
+
+func NewPromotionHandler(repo *repository.Repository) *PromotionHandler {
+	return &PromotionHandler{repo: repo}
+}
+
+type PromotionRequest struct {
+	Title           string `json:"title" binding:"required"`
+	Subtitle        string `json:"subtitle"`
+	ImageURL        string `json:"imageUrl"`
+	BackgroundColor string `json:"backgroundColor"` // ARGB, e.g. 0xFF6B2FBA
+	CampaignID      string `json:"campaignId"`      // Shown only while the campaign is running
+	Priority        int    `json:"priority"`        // 0-100, higher is shown first
+	IsActive        *bool  `json:"isActive"`        // Defaults to true
+	StartsAt        string `json:"startsAt"`        // Date (merchant timezone) or RFC 3339 timestamp
+	EndsAt          string `json:"endsAt"`          // Date, inclusive, or RFC 3339 timestamp
+}
+
+// HandleListPromotions lists all of the merchant's promotions
+func (h *PromotionHandler) HandleListPromotions(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	promotions, err := h.repo.GetPromotionsByMerchant(c.Request.Context(), merchant.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch promotions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, promotions)
+}
+
+// HandleCreatePromotion creates a promotion for the merchant
+func (h *PromotionHandler) HandleCreatePromotion(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	now := time.Now()
+	promotion := &domain.Promotion{
+		ID:         uuid.New(),
+		MerchantID: &merchant.ID,
+		CreatedAt:  now,
+	}
+	if !h.bindPromotion(c, merchant, promotion) {
+		return
+	}
+
+	if err := h.repo.CreatePromotion(c.Request.Context(), promotion); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create promotion"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, promotion)
+}
+
+// HandleUpdatePromotion replaces a promotion's content and schedule
+func (h *PromotionHandler) HandleUpdatePromotion(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	promotion, ok := h.loadPromotion(c)
+	if !ok {
+		return
+	}
+	if !h.bindPromotion(c, merchant, promotion) {
+		return
+	}
+
+	if err := h.repo.UpdatePromotion(c.Request.Context(), promotion); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update promotion"})
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// HandleDeletePromotion deletes a promotion
+func (h *PromotionHandler) HandleDeletePromotion(c *gin.Context) {
+	promotion, ok := h.loadPromotion(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeletePromotion(c.Request.Context(), promotion.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete promotion"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promotion deleted successfully"})
+}
+
+// bindPromotion applies the request to the promotion and validates it. On failure it writes
+// the error response and returns false.
+func (h *PromotionHandler) bindPromotion(c *gin.Context, merchant *domain.Merchant, promotion *domain.Promotion) bool {
+	var req PromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	startsAt, endsAt, fields := parseScheduleDates(req.StartsAt, req.EndsAt, merchant.Location())
+	if len(fields) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid promotion schedule", "fields": fields})
+		return false
+	}
+
+	campaignID, ok := h.resolveCampaign(c, merchant.ID, req.CampaignID)
+	if !ok {
+		return false
+	}
+
+	optional := func(value string) *string {
+		if value == "" {
+			return nil
+		}
+		return &value
+	}
+
+	promotion.CampaignID = campaignID
+	promotion.Title = req.Title
+	promotion.Subtitle = optional(req.Subtitle)
+	promotion.ImageURL = optional(req.ImageURL)
+	promotion.BackgroundColor = optional(req.BackgroundColor)
+	promotion.Priority = req.Priority
+	promotion.IsActive = req.IsActive == nil || *req.IsActive
+	promotion.StartsAt = startsAt
+	promotion.EndsAt = endsAt
+	promotion.UpdatedAt = time.Now()
+
+	if err := promotion.Validate(); err != nil {
+		var fields domain.ValidationErrors
+		if errors.As(err, &fields) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid promotion", "fields": fields})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process promotion"})
+		return false
+	}
+	return true
+}
+
+// resolveCampaign parses the linked campaign and checks it belongs to the merchant. On
+// failure it writes the error response and returns false.
+func (h *PromotionHandler) resolveCampaign(c *gin.Context, merchantID uuid.UUID, raw string) (*uuid.UUID, bool) {
+	if raw == "" {
+		return nil, true
+	}
+
+	campaignID, err := uuid.Parse(raw)
+	if err == nil {
+		campaign, err := h.repo.GetCampaignByID(c.Request.Context(), campaignID)
+		if err == nil && campaign.MerchantID == merchantID {
+			return &campaign.ID, true
+		}
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":  "Invalid promotion campaign",
+		"fields": domain.ValidationErrors{domain.NewFieldError("campaignId", "is not a campaign of this merchant")},
+	})
+	return nil, false
+}
+
+// loadPromotion fetches the promotion from the path, checking it belongs to the merchant
+func (h *PromotionHandler) loadPromotion(c *gin.Context) (*domain.Promotion, bool) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	promotionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+		return nil, false
+	}
+
+	promotion, err := h.repo.GetPromotion(c.Request.Context(), promotionID)
+	if err != nil || promotion.MerchantID == nil || *promotion.MerchantID != merchant.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		return nil, false
+	}
+
+	return promotion, true
+}
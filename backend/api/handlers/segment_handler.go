@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// phoneHashPattern matches the hex SHA-256 phone hashes stored in wallets
+var phoneHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type SegmentHandler struct {
+	repo     *repository.Repository
+	segments *services.SegmentService
+}
+
+func NewSegmentHandler(repo *repository.Repository) *SegmentHandler {
+	return &SegmentHandler{
+		repo:     repo,
+		segments: services.NewSegmentService(repo),
+	}
+}
+
+type SegmentRequest struct {
+	Name        string          `json:"name" binding:"required"`
+	Description string          `json:"description"`
+	Kind        string          `json:"kind"` // Fixed at creation, defaults to rule
+	Rules       json.RawMessage `json:"rules"`
+}
+
+type SegmentMembersRequest struct {
+	PhoneHashes []string `json:"phoneHashes" binding:"required"`
+	Replace     bool     `json:"replace"` // Replace the whole list instead of adding to it
+}
+
+type PreviewSegmentRequest struct {
+	Rules json.RawMessage `json:"rules" binding:"required"`
+}
+
+// HandleListSegments lists the merchant's segments
+func (h *SegmentHandler) HandleListSegments(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	segments, err := h.repo.GetSegmentsByMerchant(c.Request.Context(), merchant.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch segments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, segments)
+}
+
+// HandleGetSegment returns a segment with its current size
+func (h *SegmentHandler) HandleGetSegment(c *gin.Context) {
+	segment, ok := h.loadSegment(c)
+	if !ok {
+		return
+	}
+
+	if !h.fillSize(c, segment) {
+		return
+	}
+	c.JSON(http.StatusOK, segment)
+}
+
+// HandleCreateSegment creates a rule or static segment
+func (h *SegmentHandler) HandleCreateSegment(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	var req SegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	kind := domain.SegmentKind(req.Kind)
+	if kind == "" {
+		kind = domain.SegmentKindRule
+	}
+
+	now := time.Now()
+	segment := &domain.Segment{
+		ID:          uuid.New(),
+		MerchantID:  merchant.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Kind:        kind,
+		Rules:       normalizeRules(req.Rules),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := h.segments.Validate(segment); err != nil {
+		respondSegmentError(c, err)
+		return
+	}
+
+	if err := h.repo.CreateSegment(c.Request.Context(), segment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create segment"})
+		return
+	}
+
+	if !h.fillSize(c, segment) {
+		return
+	}
+	c.JSON(http.StatusCreated, segment)
+}
+
+// HandleUpdateSegment replaces a segment's name, description and rules. The kind cannot change.
+func (h *SegmentHandler) HandleUpdateSegment(c *gin.Context) {
+	segment, ok := h.loadSegment(c)
+	if !ok {
+		return
+	}
+
+	var req SegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Kind != "" && domain.SegmentKind(req.Kind) != segment.Kind {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Invalid segment",
+			"fields": domain.ValidationErrors{domain.NewFieldError("kind", "cannot be changed")},
+		})
+		return
+	}
+
+	segment.Name = req.Name
+	segment.Description = req.Description
+	segment.Rules = normalizeRules(req.Rules)
+	segment.UpdatedAt = time.Now()
+
+	if err := h.segments.Validate(segment); err != nil {
+		respondSegmentError(c, err)
+		return
+	}
+
+	if err := h.repo.UpdateSegment(c.Request.Context(), segment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update segment"})
+		return
+	}
+
+	if !h.fillSize(c, segment) {
+		return
+	}
+	c.JSON(http.StatusOK, segment)
+}
+
+// HandleDeleteSegment deletes a segment no campaign targets anymore
+func (h *SegmentHandler) HandleDeleteSegment(c *gin.Context) {
+	segment, ok := h.loadSegment(c)
+	if !ok {
+		return
+	}
+
+	inUse, err := h.repo.CountCampaignsUsingSegment(c.Request.Context(), segment.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete segment"})
+		return
+	}
+	if inUse > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Segment is targeted by campaigns", "campaigns": inUse})
+		return
+	}
+
+	if err := h.repo.DeleteSegment(c.Request.Context(), segment.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete segment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Segment deleted successfully"})
+}
+
+// HandleAddMembers uploads phone hashes to a static segment
+func (h *SegmentHandler) HandleAddMembers(c *gin.Context) {
+	segment, req, ok := h.bindMembers(c)
+	if !ok {
+		return
+	}
+
+	var (
+		count int
+		err   error
+	)
+	if req.Replace {
+		count, err = h.repo.ReplaceSegmentMembers(c.Request.Context(), segment.ID, req.PhoneHashes)
+	} else {
+		count, err = h.repo.AddSegmentMembers(c.Request.Context(), segment.ID, req.PhoneHashes)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update segment members"})
+		return
+	}
+
+	if !h.fillSize(c, segment) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"added": count, "size": *segment.Size})
+}
+
+// HandleRemoveMembers removes phone hashes from a static segment
+func (h *SegmentHandler) HandleRemoveMembers(c *gin.Context) {
+	segment, req, ok := h.bindMembers(c)
+	if !ok {
+		return
+	}
+
+	count, err := h.repo.RemoveSegmentMembers(c.Request.Context(), segment.ID, req.PhoneHashes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update segment members"})
+		return
+	}
+
+	if !h.fillSize(c, segment) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"removed": count, "size": *segment.Size})
+}
+
+// HandlePreviewSegment counts the customers matching a rule set without saving it
+func (h *SegmentHandler) HandlePreviewSegment(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	var req PreviewSegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	segment := &domain.Segment{MerchantID: merchant.ID, Kind: domain.SegmentKindRule, Rules: req.Rules}
+	if err := h.segments.Validate(segment); err != nil {
+		respondSegmentError(c, err)
+		return
+	}
+
+	if !h.fillSize(c, segment) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"size": *segment.Size})
+}
+
+// bindMembers loads a static segment and validates the phone hashes of the request
+func (h *SegmentHandler) bindMembers(c *gin.Context) (*domain.Segment, *SegmentMembersRequest, bool) {
+	segment, ok := h.loadSegment(c)
+	if !ok {
+		return nil, nil, false
+	}
+	if segment.Kind != domain.SegmentKindStatic {
+		c.JSON(http.StatusConflict, gin.H{"error": "Members can only be managed on static segments"})
+		return nil, nil, false
+	}
+
+	var req SegmentMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+
+	var errs domain.ValidationErrors
+	for i, hash := range req.PhoneHashes {
+		if !phoneHashPattern.MatchString(hash) {
+			errs = append(errs, domain.NewFieldError("phoneHashes", "item %d is not a hex SHA-256 hash", i))
+		}
+	}
+	if len(errs) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid phone hashes", "fields": errs})
+		return nil, nil, false
+	}
+
+	return segment, &req, true
+}
+
+// loadSegment fetches the segment from the path, checking it belongs to the merchant
+func (h *SegmentHandler) loadSegment(c *gin.Context) (*domain.Segment, bool) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	segmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid segment ID"})
+		return nil, false
+	}
+
+	segment, err := h.repo.GetSegment(c.Request.Context(), segmentID)
+	if err != nil || segment.MerchantID != merchant.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Segment not found"})
+		return nil, false
+	}
+
+	return segment, true
+}
+
+// fillSize sets the segment's current size, writing the error response on failure
+func (h *SegmentHandler) fillSize(c *gin.Context, segment *domain.Segment) bool {
+	size, err := h.segments.Size(c.Request.Context(), segment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute segment size"})
+		return false
+	}
+	segment.Size = &size
+	return true
+}
+
+// normalizeRules stores absent rules as SQL NULL
+func normalizeRules(rules json.RawMessage) json.RawMessage {
+	if len(rules) == 0 || string(rules) == "null" {
+		return nil
+	}
+	return rules
+}
+
+func respondSegmentError(c *gin.Context, err error) {
+	var fields domain.ValidationErrors
+	if errors.As(err, &fields) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid segment", "fields": fields})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process segment"})
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SettingsHandler manages merchant settings
+type SettingsHandler struct {
+	repo   *repository.Repository
+	engine *services.IngestionEngine
+}
+
+func NewSettingsHandler(repo *repository.Repository, engine *services.IngestionEngine) *SettingsHandler {
+	return &SettingsHandler{repo: repo, engine: engine}
+}
+
+// HandleGetShadowExpiration returns the policy applied to the merchant's shadow balances
+func (h *SettingsHandler) HandleGetShadowExpiration(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+	c.JSON(http.StatusOK, h.engine.ShadowExpiration(merchant))
+}
+
+// HandleUpdateShadowExpiration replaces the shadow expiration policy and applies it to the
+// open shadow balances
+func (h *SettingsHandler) HandleUpdateShadowExpiration(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	var policy domain.ShadowExpiration
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := policy.Validate(); err != nil {
+		var fields domain.ValidationErrors
+		if errors.As(err, &fields) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid shadow expiration", "fields": fields})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process shadow expiration"})
+		return
+	}
+
+	updated, err := h.repo.UpdateShadowExpiration(c.Request.Context(), merchant.ID, policy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update shadow expiration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policy": policy, "balances_updated": updated})
+}
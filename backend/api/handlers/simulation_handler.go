@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// defaultSimulationDays is the period replayed when the request does not set one
+const defaultSimulationDays = 90
+
+type SimulationHandler struct {
+	repo      *repository.Repository
+	simulator *services.SimulationService
+}
+
+func NewSimulationHandler(repo *repository.Repository, simulator *services.SimulationService) *SimulationHandler {
+	return &SimulationHandler{repo: repo, simulator: simulator}
+}
+
+type CreateSimulationRequest struct {
+	Type   string          `json:"type" binding:"required"`
+	Config json.RawMessage `json:"config" binding:"required"` // JSON object, or the same object encoded as a string
+	From   string          `json:"from"`                      // YYYY-MM-DD, defaults to 90 days ago
+	To     string          `json:"to"`                        // YYYY-MM-DD (exclusive), defaults to today
+}
+
+// HandleCreateSimulation queues a backtest of a candidate config over the merchant's history
+func (h *SimulationHandler) HandleCreateSimulation(c *gin.Context) {
+	merchantID, exists := c.Get("merchant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Merchant ID not found"})
+		return
+	}
+
+	var req CreateSimulationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	config, err := decodeConfig(req.Config)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid config"})
+		return
+	}
+
+	today := time.Now().Truncate(24 * time.Hour)
+	to := today.AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -defaultSimulationDays)
+
+	if req.From != "" {
+		if from, err = time.Parse("2006-01-02", req.From); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	if req.To != "" {
+		if to, err = time.Parse("2006-01-02", req.To); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+
+	job, err := h.simulator.CreateJob(c.Request.Context(), merchantID.(uuid.UUID), domain.CampaignType(req.Type), config, from, to)
+	if err != nil {
+		var fields domain.ValidationErrors
+		if errors.As(err, &fields) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid simulation", "fields": fields})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create simulation"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// HandleListSimulations lists the merchant's simulations without their results
+func (h *SimulationHandler) HandleListSimulations(c *gin.Context) {
+	merchantID, exists := c.Get("merchant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Merchant ID not found"})
+		return
+	}
+
+	jobs, err := h.repo.GetSimulationJobsByMerchant(c.Request.Context(), merchantID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch simulations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, jobs)
+}
+
+// HandleGetSimulation returns a simulation with its status and result
+func (h *SimulationHandler) HandleGetSimulation(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// HandleDownloadResult downloads the result as JSON, or as per-customer CSV with ?format=csv
+func (h *SimulationHandler) HandleDownloadResult(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+
+	if job.Status != domain.SimulationStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Simulation is not completed", "status": job.Status})
+		return
+	}
+
+	filename := fmt.Sprintf("simulation-%s", job.ID)
+
+	if c.Query("format") != "csv" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		c.Data(http.StatusOK, "application/json", job.Result)
+		return
+	}
+
+	var result domain.SimulationResult
+	if err := json.Unmarshal(job.Result, &result); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read simulation result"})
+		return
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"phone_hash", "transactions", "spend", "rewards_issued", "reward_value", "redeemed_value", "balance", "final_tier"})
+	for _, customer := range result.PerCustomer {
+		finalTier := ""
+		if customer.FinalTier != nil {
+			finalTier = strconv.Itoa(*customer.FinalTier)
+		}
+		writer.Write([]string{
+			customer.PhoneHash,
+			strconv.Itoa(customer.Transactions),
+			strconv.FormatFloat(customer.Spend, 'f', 2, 64),
+			strconv.Itoa(customer.RewardsIssued),
+			strconv.FormatFloat(customer.RewardValue, 'f', 2, 64),
+			strconv.FormatFloat(customer.RedeemedValue, 'f', 2, 64),
+			strconv.FormatFloat(customer.Balance, 'f', 2, 64),
+			finalTier,
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write simulation CSV"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, filename))
+	c.Data(http.StatusOK, "text/csv", buf.Bytes())
+}
+
+// loadJob fetches the merchant's simulation from the path, writing the error response on failure
+func (h *SimulationHandler) loadJob(c *gin.Context) (*domain.SimulationJob, bool) {
+	merchantID, exists := c.Get("merchant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Merchant ID not found"})
+		return nil, false
+	}
+
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulation ID"})
+		return nil, false
+	}
+
+	job, err := h.repo.GetSimulationJob(c.Request.Context(), jobID)
+	if err != nil || job.MerchantID != merchantID.(uuid.UUID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Simulation not found"})
+		return nil, false
+	}
+
+	return job, true
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TemplateHandler struct {
+	repo      *repository.Repository
+	templates *services.TemplateService
+}
+
+func NewTemplateHandler(repo *repository.Repository, strategies domain.StrategyRegistry) *TemplateHandler {
+	return &TemplateHandler{
+		repo:      repo,
+		templates: services.NewTemplateService(repo, strategies),
+	}
+}
+
+type CreateTemplateRequest struct {
+	Name        string                     `json:"name" binding:"required"`
+	Description string                     `json:"description"`
+	Type        string                     `json:"type" binding:"required"`
+	Config      json.RawMessage            `json:"config" binding:"required"` // Campaign config with {{param}} placeholders
+	Parameters  []domain.TemplateParameter `json:"parameters"`
+}
+
+type InstantiateTemplateRequest struct {
+	Name       string                 `json:"name" binding:"required"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Config     json.RawMessage        `json:"config"` // Top-level keys replace the rendered config's
+	StartsAt   ScheduleDate           `json:"startsAt"`
+	EndsAt     ScheduleDate           `json:"endsAt"`
+
+	MaxRewardValue *float64 `json:"maxRewardValue"`
+	MaxRewards     *int     `json:"maxRewards"`
+	DailyRewardCap *float64 `json:"dailyRewardCap"`
+	SegmentID      string   `json:"segmentId"`
+}
+
+// HandleListTemplates lists the platform templates and the merchant's own
+func (h *TemplateHandler) HandleListTemplates(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	templates, err := h.repo.GetCampaignTemplatesForMerchant(c.Request.Context(), merchant.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// HandleGetTemplate returns a single template
+func (h *TemplateHandler) HandleGetTemplate(c *gin.Context) {
+	template, ok := h.loadTemplate(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// HandleCreateTemplate saves a merchant template after validating it through the strategy registry
+func (h *TemplateHandler) HandleCreateTemplate(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	var req CreateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	config, err := decodeConfig(req.Config)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid config"})
+		return
+	}
+
+	params := req.Parameters
+	if params == nil {
+		params = []domain.TemplateParameter{}
+	}
+	parameters, err := json.Marshal(params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parameters"})
+		return
+	}
+
+	now := time.Now()
+	template := &domain.CampaignTemplate{
+		ID:          uuid.New(),
+		MerchantID:  &merchant.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Type:        domain.CampaignType(req.Type),
+		Config:      config,
+		Parameters:  parameters,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := h.templates.Validate(template); err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	if err := h.repo.CreateCampaignTemplate(c.Request.Context(), template); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create template"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// HandleDeleteTemplate deletes one of the merchant's templates. Campaigns created from it are kept.
+func (h *TemplateHandler) HandleDeleteTemplate(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	template, ok := h.loadTemplate(c)
+	if !ok {
+		return
+	}
+	if template.IsPlatform() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Platform templates cannot be deleted"})
+		return
+	}
+
+	deleted, err := h.repo.DeleteCampaignTemplate(c.Request.Context(), template.ID, merchant.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete template"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
+}
+
+// HandleInstantiateTemplate creates a draft campaign from the template with the given
+// parameters and overrides
+func (h *TemplateHandler) HandleInstantiateTemplate(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	template, ok := h.loadTemplate(c)
+	if !ok {
+		return
+	}
+
+	var req InstantiateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Schedule and budget follow the same rules as POST /campaigns
+	campaignReq := &CreateCampaignRequest{
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		MaxRewardValue: req.MaxRewardValue,
+		MaxRewards:     req.MaxRewards,
+		DailyRewardCap: req.DailyRewardCap,
+	}
+	if !validateBudget(c, campaignReq) {
+		return
+	}
+	startsAt, endsAt, ok := parseSchedule(c, campaignReq, merchant.Location())
+	if !ok {
+		return
+	}
+	segmentID, ok := resolveSegment(c, h.repo, merchant.ID, req.SegmentID)
+	if !ok {
+		return
+	}
+
+	var overrideConfig json.RawMessage
+	if len(req.Config) > 0 {
+		config, err := decodeConfig(req.Config)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid config"})
+			return
+		}
+		overrideConfig = config
+	}
+
+	campaign, err := h.templates.Instantiate(c.Request.Context(), template, merchant.ID, services.CampaignOverrides{
+		Name:           req.Name,
+		Parameters:     req.Parameters,
+		Config:         overrideConfig,
+		StartsAt:       startsAt,
+		EndsAt:         endsAt,
+		MaxRewardValue: req.MaxRewardValue,
+		MaxRewards:     req.MaxRewards,
+		DailyRewardCap: req.DailyRewardCap,
+		SegmentID:      segmentID,
+	})
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+// loadTemplate fetches the template from the path, checking it is a platform template or
+// belongs to the merchant
+func (h *TemplateHandler) loadTemplate(c *gin.Context) (*domain.CampaignTemplate, bool) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	templateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return nil, false
+	}
+
+	template, err := h.repo.GetCampaignTemplate(c.Request.Context(), templateID)
+	if err != nil || (!template.IsPlatform() && *template.MerchantID != merchant.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return nil, false
+	}
+
+	return template, true
+}
+
+func respondTemplateError(c *gin.Context, err error) {
+	var fields domain.ValidationErrors
+	if errors.As(err, &fields) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid campaign template", "fields": fields})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process template"})
+}
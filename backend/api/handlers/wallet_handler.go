@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WalletHandler struct {
+	repo      *repository.Repository
+	campaigns *services.CampaignService
+}
+
+func NewWalletHandler(repo *repository.Repository, strategies domain.StrategyRegistry) *WalletHandler {
+	return &WalletHandler{
+		repo:      repo,
+		campaigns: services.NewCampaignService(repo, strategies),
+	}
+}
+
+type UpdateWalletProfileRequest struct {
+	BirthDate *string `json:"birthDate"`
+}
+
+// HandleUpdateProfile updates the customer profile attributes of a wallet
+func (h *WalletHandler) HandleUpdateProfile(c *gin.Context) {
+	walletID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet ID"})
+		return
+	}
+
+	var req UpdateWalletProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	merchantID, exists := c.Get("merchant_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Merchant ID not found"})
+		return
+	}
+
+	wallet, err := h.repo.GetWalletByID(c.Request.Context(), walletID)
+	if err != nil || wallet.MerchantID != merchantID.(uuid.UUID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+		return
+	}
+
+	// An empty string clears the birth date
+	var birthDate *time.Time
+	if req.BirthDate != nil && *req.BirthDate != "" {
+		t, err := time.Parse("2006-01-02", *req.BirthDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "birthDate must use the YYYY-MM-DD format"})
+			return
+		}
+		if t.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "birthDate cannot be in the future"})
+			return
+		}
+		birthDate = &t
+	}
+
+	if err := h.repo.UpdateWalletBirthDate(c.Request.Context(), walletID, birthDate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update wallet profile"})
+		return
+	}
+
+	wallet.BirthDate = birthDate
+	c.JSON(http.StatusOK, wallet)
+}
+
+// HandleGetHistory pages through the customer's ledger entries, including manual adjustments
+// and the ones recorded on their shadow balances before sign-up
+func (h *WalletHandler) HandleGetHistory(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	walletID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet ID"})
+		return
+	}
+
+	wallet, err := h.repo.GetWalletByID(c.Request.Context(), walletID)
+	if err != nil || wallet.MerchantID != merchant.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+		return
+	}
+
+	filter, ok := parseHistoryFilter(c, merchant.Location())
+	if !ok {
+		return
+	}
+
+	page, err := h.repo.GetWalletHistory(c.Request.Context(), wallet, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet history"})
+		return
+	}
+
+	describeHistory(page, false)
+	c.JSON(http.StatusOK, page)
+}
+
+// HandleGetShadowHistory pages through the ledger entries of a not yet claimed shadow balance
+func (h *WalletHandler) HandleGetShadowHistory(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	shadowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shadow balance ID"})
+		return
+	}
+
+	shadow, err := h.repo.GetShadowBalanceByID(c.Request.Context(), shadowID)
+	if err != nil || shadow.MerchantID != merchant.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shadow balance not found"})
+		return
+	}
+
+	filter, ok := parseHistoryFilter(c, merchant.Location())
+	if !ok {
+		return
+	}
+
+	page, err := h.repo.GetShadowBalanceHistory(c.Request.Context(), shadow, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shadow balance history"})
+		return
+	}
+
+	describeHistory(page, false)
+	c.JSON(http.StatusOK, page)
+}
+
+// HandleGetProgress shows the customer's progress in the merchant's campaign, such as the
+// punches left for the next reward
+func (h *WalletHandler) HandleGetProgress(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	walletID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet ID"})
+		return
+	}
+
+	progress, err := h.repo.GetWalletProgress(c.Request.Context(), walletID)
+	if err != nil || progress.MerchantID != merchant.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+		return
+	}
+
+	h.campaigns.DescribeProgress(progress)
+	c.JSON(http.StatusOK, progress)
+}
+
+// HandleGetShadowProgress shows the progress of a not yet claimed shadow balance
+func (h *WalletHandler) HandleGetShadowProgress(c *gin.Context) {
+	merchant := c.MustGet("merchant").(*domain.Merchant)
+
+	shadowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shadow balance ID"})
+		return
+	}
+
+	progress, err := h.repo.GetShadowBalanceProgress(c.Request.Context(), shadowID)
+	if err != nil || progress.MerchantID != merchant.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shadow balance not found"})
+		return
+	}
+
+	h.campaigns.DescribeProgress(progress)
+	c.JSON(http.StatusOK, progress)
+}
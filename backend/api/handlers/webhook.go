@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/Ananiaslitz/fidelio/domain"
 	"github.com/Ananiaslitz/fidelio/services"
 
 	"github.com/gin-gonic/gin"
@@ -92,8 +93,9 @@ func (h *WebhookHandler) HandleUserCreated(c *gin.Context) {
 		return
 	}
 
-	// Hash the phone number (same as ingestion)
-	phoneHash := h.hashPhone(phone)
+	// Hash the phone number (same as ingestion and the conversion queue). HashPhone adds
+	// the plus sign Supabase Auth leaves out.
+	phoneHash := domain.HashPhone(phone)
 
 	// Call conversion service
 	if err := h.conversionService.ConvertShadowToRealWallet(c.Request.Context(), parsedUserID, phoneHash); err != nil {
@@ -135,9 +137,3 @@ func (h *WebhookHandler) verifySignature(c *gin.Context, signature string) bool
 	// Compare signatures
 	return hmac.Equal([]byte(signature), []byte(expectedSignature))
 }
-
-// hashPhone creates a SHA-256 hash of the phone number
-func (h *WebhookHandler) hashPhone(phone string) string {
-	hash := sha256.Sum256([]byte(phone))
-	return hex.EncodeToString(hash[:])
-}
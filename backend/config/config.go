@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -15,20 +16,46 @@ type Config struct {
 	DatabaseURL string
 
 	// Server
-	ServerPort string
+	ServerPort     string
+	Environment    string   // "development" or "production"
+	TrustedProxies []string // Proxies whose X-Forwarded-For is believed, none by default
 
 	// Supabase
 	SupabaseURL        string
 	SupabaseServiceKey string
 
+	// Consumer auth (Supabase access tokens, verified locally)
+	SupabaseJWTSecret   string
+	SupabaseJWKSURL     string
+	SupabaseJWTAudience string
+	SupabaseJWTIssuer   string
+
 	// Webhook
 	WebhookSecret string
 
 	// Shadow Wallet
 	ShadowWalletTTL time.Duration
 
+	// Shadow balance claims by SMS code
+	ShadowClaimExtension time.Duration
+	SMSProvider          string // "fake" or "twilio"
+	TwilioAccountSID     string
+	TwilioAuthToken      string
+	TwilioFromNumber     string
+
 	// Worker
 	ExpirationWorkerInterval time.Duration
+	OccasionWorkerInterval   time.Duration
+	SimulationWorkerInterval time.Duration
+	CampaignWorkerInterval   time.Duration
+
+	ConversionWorkerInterval time.Duration
+
+	// Real-time notifications (LISTEN/NOTIFY)
+	ListenerEnabled     bool
+	ListenerDatabaseURL string        // Needs a direct connection, poolers in transaction mode drop notifications
+	MerchantCacheTTL    time.Duration // Only used while the listener keeps the cache fresh
+	CampaignCacheTTL    time.Duration // Only used while the listener keeps the cache fresh
 
 	// Testing
 	MockSupabase bool
@@ -42,20 +69,71 @@ func Load() (*Config, error) {
 	cfg := &Config{
 		DatabaseURL:        getEnv("DATABASE_URL", ""),
 		ServerPort:         getEnv("SERVER_PORT", "8080"),
+		Environment:        getEnv("APP_ENV", "development"),
 		SupabaseURL:        getEnv("SUPABASE_URL", ""),
 		SupabaseServiceKey: getEnv("SUPABASE_SERVICE_KEY", ""),
+		SupabaseJWTSecret:  getEnv("SUPABASE_JWT_SECRET", ""),
+		SupabaseJWKSURL:    getEnv("SUPABASE_JWKS_URL", ""),
 		WebhookSecret:      getEnv("WEBHOOK_SECRET", ""),
 		MockSupabase:       getEnvAsBool("MOCK_SUPABASE", false),
 	}
 
+	// Client IPs (e.g. for claim code throttling) come from X-Forwarded-For only when the
+	// request arrives through one of these proxies (comma-separated IPs or CIDRs)
+	for _, proxy := range strings.Split(getEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			cfg.TrustedProxies = append(cfg.TrustedProxies, proxy)
+		}
+	}
+
+	// Supabase issues user tokens for the "authenticated" audience from <project>/auth/v1
+	cfg.SupabaseJWTAudience = getEnv("SUPABASE_JWT_AUDIENCE", "authenticated")
+	cfg.SupabaseJWTIssuer = getEnv("SUPABASE_JWT_ISSUER", strings.TrimSuffix(cfg.SupabaseURL, "/")+"/auth/v1")
+
 	// Parse shadow wallet TTL (default: 72 hours)
 	shadowTTLHours := getEnvAsInt("SHADOW_WALLET_TTL_HOURS", 72)
 	cfg.ShadowWalletTTL = time.Duration(shadowTTLHours) * time.Hour
 
+	// Claimed shadow balances are kept for at least this long (default: 30 days)
+	claimExtensionDays := getEnvAsInt("SHADOW_CLAIM_EXTENSION_DAYS", 30)
+	cfg.ShadowClaimExtension = time.Duration(claimExtensionDays) * 24 * time.Hour
+
+	// SMS delivery for one-time codes (default: fake, which only logs messages)
+	cfg.SMSProvider = getEnv("SMS_PROVIDER", "fake")
+	cfg.TwilioAccountSID = getEnv("TWILIO_ACCOUNT_SID", "")
+	cfg.TwilioAuthToken = getEnv("TWILIO_AUTH_TOKEN", "")
+	cfg.TwilioFromNumber = getEnv("TWILIO_FROM_NUMBER", "")
+
 	// Parse worker interval (default: 1 hour)
 	workerIntervalMinutes := getEnvAsInt("EXPIRATION_WORKER_INTERVAL_MINUTES", 60)
 	cfg.ExpirationWorkerInterval = time.Duration(workerIntervalMinutes) * time.Minute
 
+	// Parse occasion reward worker interval (default: 1 hour)
+	occasionIntervalMinutes := getEnvAsInt("OCCASION_WORKER_INTERVAL_MINUTES", 60)
+	cfg.OccasionWorkerInterval = time.Duration(occasionIntervalMinutes) * time.Minute
+
+	// Parse simulation worker interval (default: 10 seconds)
+	simulationIntervalSeconds := getEnvAsInt("SIMULATION_WORKER_INTERVAL_SECONDS", 10)
+	cfg.SimulationWorkerInterval = time.Duration(simulationIntervalSeconds) * time.Second
+
+	// Parse campaign lifecycle worker interval (default: 1 minute)
+	campaignIntervalSeconds := getEnvAsInt("CAMPAIGN_WORKER_INTERVAL_SECONDS", 60)
+	cfg.CampaignWorkerInterval = time.Duration(campaignIntervalSeconds) * time.Second
+
+	// Parse conversion queue worker interval (default: 30 seconds)
+	conversionIntervalSeconds := getEnvAsInt("CONVERSION_WORKER_INTERVAL_SECONDS", 30)
+	cfg.ConversionWorkerInterval = time.Duration(conversionIntervalSeconds) * time.Second
+
+	// Real-time conversions and cache invalidation. DATABASE_URL may go through a pooler that
+	// silently drops notifications, which would leave the caches stale, so the listener is
+	// only enabled by default when LISTENER_DATABASE_URL names a direct connection.
+	cfg.ListenerDatabaseURL = getEnv("LISTENER_DATABASE_URL", "")
+	cfg.ListenerEnabled = getEnvAsBool("LISTENER_ENABLED", cfg.ListenerDatabaseURL != "")
+	merchantCacheSeconds := getEnvAsInt("MERCHANT_CACHE_TTL_SECONDS", 300)
+	cfg.MerchantCacheTTL = time.Duration(merchantCacheSeconds) * time.Second
+	campaignCacheSeconds := getEnvAsInt("CAMPAIGN_CACHE_TTL_SECONDS", 60)
+	cfg.CampaignCacheTTL = time.Duration(campaignCacheSeconds) * time.Second
+
 	// Validate required fields
 	if cfg.DatabaseURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL is required")
@@ -69,6 +147,23 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("SUPABASE_SERVICE_KEY is required")
 	}
 
+	if cfg.ListenerEnabled && cfg.ListenerDatabaseURL == "" {
+		return nil, fmt.Errorf("LISTENER_DATABASE_URL is required with LISTENER_ENABLED=true")
+	}
+
+	switch cfg.SMSProvider {
+	case "fake":
+		if cfg.Environment == "production" {
+			return nil, fmt.Errorf("SMS_PROVIDER=fake only logs one-time codes and is not allowed with APP_ENV=production")
+		}
+	case "twilio":
+		if cfg.TwilioAccountSID == "" || cfg.TwilioAuthToken == "" || cfg.TwilioFromNumber == "" {
+			return nil, fmt.Errorf("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_FROM_NUMBER are required with SMS_PROVIDER=twilio")
+		}
+	default:
+		return nil, fmt.Errorf("SMS_PROVIDER must be fake or twilio, got %q", cfg.SMSProvider)
+	}
+
 	return cfg, nil
 }
 
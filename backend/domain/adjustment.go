@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AdjustmentDirection tells whether an adjustment adds to or takes from the balance
+type AdjustmentDirection string
+
+const (
+	AdjustmentCredit AdjustmentDirection = "CREDIT"
+	AdjustmentDebit  AdjustmentDirection = "DEBIT"
+)
+
+// AdjustmentStatus tracks an adjustment through the approval workflow
+type AdjustmentStatus string
+
+const (
+	AdjustmentStatusPending  AdjustmentStatus = "PENDING"  // Above the approval threshold, waiting for review
+	AdjustmentStatusApplied  AdjustmentStatus = "APPLIED"  // Posted to the ledger
+	AdjustmentStatusRejected AdjustmentStatus = "REJECTED" // Reviewed and discarded
+)
+
+// AdjustmentReason is the mandatory reason code of a manual adjustment
+type AdjustmentReason string
+
+const (
+	AdjustmentReasonComplaint      AdjustmentReason = "COMPLAINT"       // Goodwill after a customer complaint
+	AdjustmentReasonIngestionError AdjustmentReason = "INGESTION_ERROR" // Fixes a wrong or duplicated purchase
+	AdjustmentReasonMissedPurchase AdjustmentReason = "MISSED_PURCHASE" // Purchase that was never ingested
+	AdjustmentReasonFraud          AdjustmentReason = "FRAUD"           // Reverses abusive earnings
+	AdjustmentReasonOther          AdjustmentReason = "OTHER"
+)
+
+// IsValid reports whether the reason code is known
+func (r AdjustmentReason) IsValid() bool {
+	switch r {
+	case AdjustmentReasonComplaint, AdjustmentReasonIngestionError, AdjustmentReasonMissedPurchase,
+		AdjustmentReasonFraud, AdjustmentReasonOther:
+		return true
+	}
+	return false
+}
+
+// DefaultAdjustmentApprovalThreshold is used when a merchant has not configured one
+const DefaultAdjustmentApprovalThreshold = 50.0
+
+// BalanceAdjustment is a manual credit or debit of a customer's wallet by merchant staff
+type BalanceAdjustment struct {
+	ID            uuid.UUID           `json:"id" db:"id"`
+	MerchantID    uuid.UUID           `json:"merchant_id" db:"merchant_id"`
+	WalletID      uuid.UUID           `json:"wallet_id" db:"wallet_id"`
+	Direction     AdjustmentDirection `json:"direction" db:"direction"`
+	Amount        float64             `json:"amount" db:"amount"`
+	ReasonCode    AdjustmentReason    `json:"reason_code" db:"reason_code"`
+	Note          string              `json:"note" db:"note"`
+	Status        AdjustmentStatus    `json:"status" db:"status"`
+	RequestedBy   string              `json:"requested_by" db:"requested_by"`
+	ReviewedBy    *string             `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt    *time.Time          `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	TransactionID *uuid.UUID          `json:"transaction_id,omitempty" db:"transaction_id"` // ADJUST ledger entry once applied
+	CreatedAt     time.Time           `json:"created_at" db:"created_at"`
+}
+
+// SignedAmount returns the amount as posted to the ledger (negative for debits)
+func (a *BalanceAdjustment) SignedAmount() float64 {
+	if a.Direction == AdjustmentDebit {
+		return -a.Amount
+	}
+	return a.Amount
+}
+
+// AdjustmentApprovalThreshold returns the amount above which adjustments need approval
+// (settings.adjustment_approval_threshold)
+func (m *Merchant) AdjustmentApprovalThreshold() float64 {
+	var settings struct {
+		Threshold *float64 `json:"adjustment_approval_threshold"`
+	}
+	if len(m.Settings) > 0 {
+		_ = json.Unmarshal(m.Settings, &settings)
+	}
+
+	if settings.Threshold != nil && *settings.Threshold >= 0 {
+		return *settings.Threshold
+	}
+	return DefaultAdjustmentApprovalThreshold
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BudgetKind identifies one of a campaign's budget caps
+type BudgetKind string
+
+const (
+	BudgetKindRewardValue BudgetKind = "reward_value"       // Total value of rewards granted
+	BudgetKindRewards     BudgetKind = "rewards"            // Number of rewards granted
+	BudgetKindDailyValue  BudgetKind = "daily_reward_value" // Value of rewards granted per merchant day
+)
+
+// BudgetAlertThresholds are the usage percentages merchants are alerted at
+var BudgetAlertThresholds = []int{80, 100}
+
+// BudgetUsage is the running consumption of a campaign's budget
+type BudgetUsage struct {
+	CampaignID       uuid.UUID `json:"campaign_id" db:"campaign_id"`
+	RewardValue      float64   `json:"reward_value" db:"reward_value"`
+	RewardsIssued    int       `json:"rewards_issued" db:"rewards_issued"`
+	Day              time.Time `json:"day" db:"day"`                               // Merchant day the daily value refers to
+	DailyRewardValue float64   `json:"daily_reward_value" db:"daily_reward_value"` // Value granted on Day
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CampaignBudget reports a campaign's caps and how much of them is consumed
+type CampaignBudget struct {
+	MaxRewardValue   *float64 `json:"max_reward_value,omitempty"`
+	MaxRewards       *int     `json:"max_rewards,omitempty"`
+	DailyRewardCap   *float64 `json:"daily_reward_cap,omitempty"`
+	RewardValue      float64  `json:"reward_value"`
+	RewardsIssued    int      `json:"rewards_issued"`
+	TodayRewardValue float64  `json:"today_reward_value"`
+
+	// Share of each cap consumed (1 = 100%), omitted for caps that are not set
+	RewardValueUsed *float64 `json:"reward_value_used,omitempty"`
+	RewardsUsed     *float64 `json:"rewards_used,omitempty"`
+	DailyUsed       *float64 `json:"daily_used,omitempty"`
+
+	Exhausted bool `json:"exhausted"` // A total cap is used up and the campaign was paused
+}
+
+// BudgetAlert is sent once per cap, threshold and period (the day, for the daily cap)
+type BudgetAlert struct {
+	CampaignID   uuid.UUID  `json:"campaign_id" db:"campaign_id"`
+	MerchantID   uuid.UUID  `json:"merchant_id" db:"-"`
+	CampaignName string     `json:"campaign_name" db:"-"`
+	Kind         BudgetKind `json:"kind" db:"kind"`
+	Threshold    int        `json:"threshold" db:"threshold"`
+	Period       string     `json:"period" db:"period"` // "total" or the merchant day (YYYY-MM-DD)
+	Used         float64    `json:"used" db:"-"`
+	Limit        float64    `json:"limit" db:"-"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// HasBudget reports whether any budget cap is configured
+func (c *Campaign) HasBudget() bool {
+	return c.MaxRewardValue != nil || c.MaxRewards != nil || c.DailyRewardCap != nil
+}
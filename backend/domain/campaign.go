@@ -3,6 +3,9 @@ package domain
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 )
 
 // CampaignStrategy defines the interface for all campaign types
@@ -16,6 +19,63 @@ type CampaignStrategy interface {
 
 	// GetType returns the campaign type this strategy handles
 	GetType() CampaignType
+
+	// DisplayName returns the human-readable name shown to merchants
+	DisplayName() string
+
+	// ConfigSchema returns the JSON Schema describing the campaign configuration
+	ConfigSchema() json.RawMessage
+
+	// DefaultConfig returns a valid starting configuration
+	DefaultConfig() json.RawMessage
+}
+
+// StrategyRegistry maps each campaign type to its strategy
+type StrategyRegistry map[CampaignType]CampaignStrategy
+
+// StateMerger is implemented by strategies that can combine the state of a shadow balance
+// with the state of the wallet it converts into, both read under config. When the combined
+// progress completes a reward, the result carries it in NewBalance and RewardEarned.
+// Strategies without it get the numeric fields of both states added.
+type StateMerger interface {
+	MergeState(walletState, shadowState, config json.RawMessage) (*StrategyResult, error)
+}
+
+// CampaignTypeInfo describes a campaign type for configuration forms
+type CampaignTypeInfo struct {
+	Type          CampaignType    `json:"type"`
+	DisplayName   string          `json:"displayName"`
+	ConfigSchema  json.RawMessage `json:"configSchema"`
+	DefaultConfig json.RawMessage `json:"defaultConfig"`
+}
+
+// FieldError reports an invalid field of a campaign configuration
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// NewFieldError creates a field error with a formatted message
+func NewFieldError(field, format string, args ...interface{}) *FieldError {
+	return &FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + " " + e.Message
+}
+
+// ValidationErrors collects every invalid field of a configuration
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+	return strings.Join(messages, "; ")
 }
 
 // StrategyInput contains all data needed to execute a campaign strategy
@@ -25,6 +85,8 @@ type StrategyInput struct {
 	Amount        float64
 	CurrentState  json.RawMessage // Current wallet/shadow state
 	Metadata      json.RawMessage
+	OccurredAt    time.Time // When the purchase happened (defaults to now)
+	Seed          []byte    // Secret per-campaign seed for strategies that draw randomness
 }
 
 // StrategyResult contains the outcome of strategy execution
@@ -33,20 +95,121 @@ type StrategyResult struct {
 	NewState     json.RawMessage
 	RewardEarned *RewardInfo
 	StateChanged bool
+	Draw         *PrizeDraw // Set by strategies that run a random draw
+}
+
+// PrizeDraw is the outcome of a random draw. A winning tier must be reserved
+// from the campaign inventory before it is paid; when the inventory is
+// exhausted the reward is dropped.
+type PrizeDraw struct {
+	Roll      float64 `json:"roll"`
+	TierIndex *int    `json:"tier_index,omitempty"` // nil when the draw did not win
+	TierName  string  `json:"tier_name,omitempty"`
+	Amount    float64 `json:"amount,omitempty"`
+}
+
+// WelcomeBonusConfig is an optional "welcome_bonus" block accepted by any campaign config
+type WelcomeBonusConfig struct {
+	Amount        float64 `json:"amount"`
+	RequireSignup bool    `json:"require_signup,omitempty"` // Only grant once the customer signs up
+}
+
+// ParseWelcomeBonus extracts the welcome bonus settings from a campaign config, if any
+func ParseWelcomeBonus(config json.RawMessage) (*WelcomeBonusConfig, error) {
+	var wrapper struct {
+		WelcomeBonus *WelcomeBonusConfig `json:"welcome_bonus"`
+	}
+	if err := json.Unmarshal(config, &wrapper); err != nil {
+		return nil, err
+	}
+	if wrapper.WelcomeBonus == nil || wrapper.WelcomeBonus.Amount <= 0 {
+		return nil, nil
+	}
+	return wrapper.WelcomeBonus, nil
+}
+
+// CustomerLimits is an optional "customer_limits" block accepted by any campaign config.
+// It caps what a single customer can earn, on top of the strategy's own rules. Zero means no limit.
+type CustomerLimits struct {
+	MaxRewardsPerDay       int     `json:"max_rewards_per_day,omitempty"`        // Rewarded purchases per day
+	MaxRewardValuePerDay   float64 `json:"max_reward_value_per_day,omitempty"`   // Reward value per day
+	MaxRewardValuePerMonth float64 `json:"max_reward_value_per_month,omitempty"` // Reward value per calendar month
+	MaxVisitsPerWindow     int     `json:"max_visits_per_window,omitempty"`      // Purchases per VisitWindowMinutes; later ones earn nothing
+	VisitWindowMinutes     int     `json:"visit_window_minutes,omitempty"`
 }
 
+// Validate checks the settings that depend on each other
+func (l *CustomerLimits) Validate() error {
+	if l.MaxVisitsPerWindow > 0 && l.VisitWindowMinutes <= 0 {
+		return NewFieldError("customer_limits.visit_window_minutes", "is required with max_visits_per_window")
+	}
+	return nil
+}
+
+// ParseCustomerLimits extracts the per-customer limits from a campaign config, if any
+func ParseCustomerLimits(config json.RawMessage) (*CustomerLimits, error) {
+	var wrapper struct {
+		CustomerLimits *CustomerLimits `json:"customer_limits"`
+	}
+	if err := json.Unmarshal(config, &wrapper); err != nil {
+		return nil, err
+	}
+	if wrapper.CustomerLimits == nil || *wrapper.CustomerLimits == (CustomerLimits{}) {
+		return nil, nil
+	}
+	return wrapper.CustomerLimits, nil
+}
+
+// StampMode defines how many punches a purchase earns
+type StampMode string
+
+const (
+	StampModePerPurchase StampMode = "per_purchase" // One punch per qualifying purchase (default)
+	StampModePerAmount   StampMode = "per_amount"   // One punch per AmountPerStamp spent
+	StampModePerItem     StampMode = "per_item"     // One punch per item (optionally of ItemCategory)
+)
+
 // PunchCardConfig defines the configuration for punch card campaigns
 type PunchCardConfig struct {
-	RequiredPunches int     `json:"required_punches"`
-	RewardAmount    float64 `json:"reward_amount"`
-	RewardType      string  `json:"reward_type"` // "points", "discount", "free_item"
-	MinPurchase     float64 `json:"min_purchase,omitempty"`
+	RequiredPunches      int       `json:"required_punches"`
+	RewardAmount         float64   `json:"reward_amount"`
+	RewardType           string    `json:"reward_type"` // "points", "discount", "free_item"
+	MinPurchase          float64   `json:"min_purchase,omitempty"`
+	StampMode            StampMode `json:"stamp_mode,omitempty"`
+	AmountPerStamp       float64   `json:"amount_per_stamp,omitempty"`
+	ItemCategory         string    `json:"item_category,omitempty"`
+	MaxStampsPerPurchase int       `json:"max_stamps_per_purchase,omitempty"`
 }
 
 // PunchCardState tracks the current state of a punch card
 type PunchCardState struct {
 	CurrentPunches int `json:"current_punches"`
 	TotalRedeemed  int `json:"total_redeemed"`
+	TotalPunches   int `json:"total_punches,omitempty"`
+}
+
+// TransactionItem is a line item sent by the POS in the ingestion metadata ("items")
+type TransactionItem struct {
+	SKU       string  `json:"sku,omitempty"`
+	Name      string  `json:"name,omitempty"`
+	Category  string  `json:"category,omitempty"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float64 `json:"unit_price,omitempty"`
+}
+
+// ParseTransactionItems extracts the line items from the transaction metadata.
+// Missing or malformed items are treated as an empty list.
+func ParseTransactionItems(metadata json.RawMessage) []TransactionItem {
+	var wrapper struct {
+		Items []TransactionItem `json:"items"`
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(metadata, &wrapper); err != nil {
+		return nil
+	}
+	return wrapper.Items
 }
 
 // CashbackConfig defines the configuration for cashback campaigns
@@ -82,3 +245,56 @@ type ProgressiveState struct {
 	CurrentTier      int     `json:"current_tier"`
 	TotalPoints      float64 `json:"total_points"`
 }
+
+// StreakMode defines how visits are grouped into a streak
+type StreakMode string
+
+const (
+	// StreakModeFrequency rewards N visits inside a rolling window (e.g. 5 visits in 7 days)
+	StreakModeFrequency StreakMode = "frequency"
+	// StreakModeConsecutive rewards visits in N consecutive periods (e.g. every week for a month)
+	StreakModeConsecutive StreakMode = "consecutive"
+)
+
+// StreakConfig defines the configuration for visit streak campaigns
+type StreakConfig struct {
+	Mode          StreakMode `json:"mode"`
+	Milestone     int        `json:"milestone"`    // Visits (frequency) or periods (consecutive) needed for the reward
+	WindowHours   int        `json:"window_hours"` // Rolling window (frequency) or period length (consecutive)
+	MinGapMinutes int        `json:"min_gap_minutes,omitempty"`
+	RewardAmount  float64    `json:"reward_amount"`
+	RewardType    string     `json:"reward_type"` // "points", "discount", "free_item"
+	MinPurchase   float64    `json:"min_purchase,omitempty"`
+}
+
+// StreakState tracks the visits that make up the current streak
+type StreakState struct {
+	VisitTimestamps []time.Time `json:"visit_timestamps"`
+	CurrentStreak   int         `json:"current_streak"`
+	BestStreak      int         `json:"best_streak"`
+	StreakStartedAt *time.Time  `json:"streak_started_at,omitempty"`
+	LastVisitAt     *time.Time  `json:"last_visit_at,omitempty"`
+	TotalRewards    int         `json:"total_rewards"`
+}
+
+// InstantWinPrize is a prize tier of an instant-win campaign
+type InstantWinPrize struct {
+	Name       string  `json:"name"`
+	Odds       int     `json:"odds"` // 1-in-N chance per qualifying purchase
+	Amount     float64 `json:"amount"`
+	RewardType string  `json:"reward_type"`
+	Inventory  int     `json:"inventory"` // Total units available for the whole campaign
+}
+
+// InstantWinConfig defines the configuration for instant-win (sweepstakes) campaigns
+type InstantWinConfig struct {
+	MinPurchase float64           `json:"min_purchase,omitempty"`
+	Prizes      []InstantWinPrize `json:"prizes"`
+}
+
+// InstantWinState tracks the draws a customer took part in
+type InstantWinState struct {
+	TotalDraws int        `json:"total_draws"`
+	LastDrawAt *time.Time `json:"last_draw_at,omitempty"`
+	LastRoll   float64    `json:"last_roll"`
+}
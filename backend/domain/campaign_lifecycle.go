@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// CampaignStatus is the lifecycle state of a campaign
+type CampaignStatus string
+
+const (
+	CampaignStatusDraft     CampaignStatus = "DRAFT"     // Being set up, never ran
+	CampaignStatusScheduled CampaignStatus = "SCHEDULED" // Starts automatically at starts_at
+	CampaignStatusActive    CampaignStatus = "ACTIVE"    // Granting rewards
+	CampaignStatusPaused    CampaignStatus = "PAUSED"    // Temporarily stopped by the merchant or the budget
+	CampaignStatusEnded     CampaignStatus = "ENDED"     // Finished, balances stay redeemable
+	CampaignStatusArchived  CampaignStatus = "ARCHIVED"  // Hidden from listings, kept for the ledger
+)
+
+// campaignTransitions lists the statuses each status can move to
+var campaignTransitions = map[CampaignStatus][]CampaignStatus{
+	CampaignStatusDraft:     {CampaignStatusScheduled, CampaignStatusActive, CampaignStatusArchived},
+	CampaignStatusScheduled: {CampaignStatusDraft, CampaignStatusActive, CampaignStatusArchived},
+	CampaignStatusActive:    {CampaignStatusPaused, CampaignStatusEnded},
+	CampaignStatusPaused:    {CampaignStatusActive, CampaignStatusEnded},
+	CampaignStatusEnded:     {CampaignStatusArchived},
+	CampaignStatusArchived:  {},
+}
+
+// IsValid reports whether the status is known
+func (s CampaignStatus) IsValid() bool {
+	_, ok := campaignTransitions[s]
+	return ok
+}
+
+// Transitions returns the statuses the campaign can move to from s
+func (s CampaignStatus) Transitions() []CampaignStatus {
+	return campaignTransitions[s]
+}
+
+// CanTransitionTo reports whether moving from s to next is allowed
+func (s CampaignStatus) CanTransitionTo(next CampaignStatus) bool {
+	for _, allowed := range campaignTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal reports whether the campaign is over for good in status s
+func (s CampaignStatus) IsFinal() bool {
+	return s == CampaignStatusEnded || s == CampaignStatusArchived
+}
+
+// TransitionError is returned for a status change the lifecycle does not allow
+type TransitionError struct {
+	From    CampaignStatus   `json:"from"`
+	To      CampaignStatus   `json:"to"`
+	Allowed []CampaignStatus `json:"allowed"`
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("campaign cannot move from %s to %s", e.From, e.To)
+}
+
+// SetStatus changes the status, keeping is_active in sync for the queries and
+// RLS policies that still filter on it
+func (c *Campaign) SetStatus(status CampaignStatus) {
+	c.Status = status
+	c.IsActive = status == CampaignStatusActive
+}
+
+// InitialStatus returns the status a new campaign starts in: scheduled when it starts
+// in the future, active otherwise
+func (c *Campaign) InitialStatus(now time.Time) CampaignStatus {
+	if c.StartsAt != nil && c.StartsAt.After(now) {
+		return CampaignStatusScheduled
+	}
+	return CampaignStatusActive
+}
+
+// HasEnded reports whether the campaign reached a final status. A past ends_at alone does
+// not count: the date can still be moved until the scheduler ends the campaign.
+func (c *Campaign) HasEnded() bool {
+	return c.Status.IsFinal()
+}
+
+// IsRunning reports whether the campaign is active and inside its start and end dates, as
+// selected by the active campaigns query
+func (c *Campaign) IsRunning(now time.Time) bool {
+	if c.Status != CampaignStatusActive {
+		return false
+	}
+	if c.StartsAt != nil && c.StartsAt.After(now) {
+		return false
+	}
+	return c.EndsAt == nil || !now.After(*c.EndsAt)
+}
@@ -0,0 +1,211 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TemplateParameterType is the JSON type a template parameter accepts
+type TemplateParameterType string
+
+const (
+	TemplateParameterNumber  TemplateParameterType = "number"
+	TemplateParameterInteger TemplateParameterType = "integer"
+	TemplateParameterString  TemplateParameterType = "string"
+	TemplateParameterBoolean TemplateParameterType = "boolean"
+)
+
+// templatePlaceholder matches {{name}} inside template config strings
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([a-z_][a-z0-9_]*)\s*\}\}`)
+
+// CampaignTemplate is a reusable campaign setup. Platform templates have no merchant and
+// are visible to everyone; merchant templates are private to their owner.
+type CampaignTemplate struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	MerchantID  *uuid.UUID      `json:"merchant_id,omitempty" db:"merchant_id"` // Nil for platform templates
+	Name        string          `json:"name" db:"name"`
+	Description string          `json:"description" db:"description"`
+	Type        CampaignType    `json:"type" db:"type"`
+	Config      json.RawMessage `json:"config" db:"config"`         // Campaign config with {{param}} placeholders
+	Parameters  json.RawMessage `json:"parameters" db:"parameters"` // []TemplateParameter
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// TemplateParameter is a value filled in when a campaign is created from a template
+type TemplateParameter struct {
+	Name        string                `json:"name"`
+	Type        TemplateParameterType `json:"type"`
+	Title       string                `json:"title,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Default     interface{}           `json:"default"` // Used when the parameter is not given
+}
+
+// IsPlatform reports whether the template is provided by the platform
+func (t *CampaignTemplate) IsPlatform() bool {
+	return t.MerchantID == nil
+}
+
+// ParseParameters decodes the template parameters
+func (t *CampaignTemplate) ParseParameters() ([]TemplateParameter, error) {
+	var params []TemplateParameter
+	if len(t.Parameters) == 0 {
+		return params, nil
+	}
+	if err := json.Unmarshal(t.Parameters, &params); err != nil {
+		return nil, fmt.Errorf("invalid template parameters: %w", err)
+	}
+	return params, nil
+}
+
+// ValidateParameters checks the parameter declarations and that the config only uses
+// declared placeholders
+func (t *CampaignTemplate) ValidateParameters() error {
+	params, err := t.ParseParameters()
+	if err != nil {
+		return ValidationErrors{NewFieldError("parameters", "must be a list of parameters")}
+	}
+
+	var errs ValidationErrors
+	declared := make(map[string]bool, len(params))
+	for i, param := range params {
+		field := fmt.Sprintf("parameters[%d]", i)
+		if !templatePlaceholder.MatchString("{{" + param.Name + "}}") {
+			errs = append(errs, NewFieldError(field+".name", "must be lowercase letters, digits and underscores"))
+			continue
+		}
+		if declared[param.Name] {
+			errs = append(errs, NewFieldError(field+".name", "is declared more than once: %s", param.Name))
+		}
+		declared[param.Name] = true
+
+		if _, err := param.coerce(param.Default); err != nil {
+			errs = append(errs, NewFieldError(field+".default", "%s", err.Error()))
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(string(t.Config), -1) {
+		used[match[1]] = true
+		if !declared[match[1]] {
+			errs = append(errs, NewFieldError("config", "uses undeclared parameter %s", match[1]))
+			declared[match[1]] = true // Report once
+		}
+	}
+	for i, param := range params {
+		if param.Name != "" && !used[param.Name] {
+			errs = append(errs, NewFieldError(fmt.Sprintf("parameters[%d].name", i), "is not used in config: %s", param.Name))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Render fills the placeholders of the config with values, using each parameter's default
+// for the values not given. A string that is exactly one placeholder takes the parameter's
+// JSON type ("{{stamps}}" becomes 10); placeholders inside longer strings are substituted as text.
+func (t *CampaignTemplate) Render(values map[string]interface{}) (json.RawMessage, error) {
+	params, err := t.ParseParameters()
+	if err != nil {
+		return nil, err
+	}
+
+	var errs ValidationErrors
+	resolved := make(map[string]interface{}, len(params))
+	for _, param := range params {
+		value, ok := values[param.Name]
+		if !ok {
+			value = param.Default
+		}
+		coerced, err := param.coerce(value)
+		if err != nil {
+			errs = append(errs, NewFieldError("parameters."+param.Name, "%s", err.Error()))
+			continue
+		}
+		resolved[param.Name] = coerced
+	}
+	for name := range values {
+		if _, ok := resolved[name]; !ok && !hasParameter(params, name) {
+			errs = append(errs, NewFieldError("parameters."+name, "is not a parameter of this template"))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var config interface{}
+	if err := json.Unmarshal(t.Config, &config); err != nil {
+		return nil, fmt.Errorf("invalid template config: %w", err)
+	}
+
+	return json.Marshal(renderValue(config, resolved))
+}
+
+// coerce checks a value against the parameter type, accepting whole numbers for integers
+func (p TemplateParameter) coerce(value interface{}) (interface{}, error) {
+	switch p.Type {
+	case TemplateParameterNumber:
+		if n, ok := value.(float64); ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("must be a number")
+	case TemplateParameterInteger:
+		if n, ok := value.(float64); ok && n == math.Trunc(n) {
+			return int64(n), nil
+		}
+		return nil, fmt.Errorf("must be an integer")
+	case TemplateParameterString:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("must be a string")
+	case TemplateParameterBoolean:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("must be a boolean")
+	}
+	return nil, fmt.Errorf("has unknown type %q", p.Type)
+}
+
+func hasParameter(params []TemplateParameter, name string) bool {
+	for _, param := range params {
+		if param.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// renderValue walks decoded JSON replacing placeholders in strings
+func renderValue(value interface{}, values map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = renderValue(item, values)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = renderValue(item, values)
+		}
+		return v
+	case string:
+		if match := templatePlaceholder.FindStringSubmatch(v); match != nil && match[0] == v {
+			return values[match[1]]
+		}
+		return templatePlaceholder.ReplaceAllStringFunc(v, func(placeholder string) string {
+			name := templatePlaceholder.FindStringSubmatch(placeholder)[1]
+			return fmt.Sprint(values[name])
+		})
+	}
+	return value
+}
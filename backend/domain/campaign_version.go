@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MigrationMode defines what happens to existing wallet state when a campaign config changes
+type MigrationMode string
+
+const (
+	// MigrationModeNewCardsOnly keeps existing customers on the previous rules until their next reward
+	MigrationModeNewCardsOnly MigrationMode = "new_cards_only"
+	// MigrationModeMigrateAll converts every existing wallet state to the new rules right away
+	MigrationModeMigrateAll MigrationMode = "migrate_all"
+)
+
+// IsValid reports whether the migration mode is supported
+func (m MigrationMode) IsValid() bool {
+	return m == MigrationModeNewCardsOnly || m == MigrationModeMigrateAll
+}
+
+// CampaignVersion is an immutable revision of a campaign config
+type CampaignVersion struct {
+	ID            uuid.UUID       `json:"id" db:"id"`
+	CampaignID    uuid.UUID       `json:"campaign_id" db:"campaign_id"`
+	Version       int             `json:"version" db:"version"`
+	Config        json.RawMessage `json:"config" db:"config"`
+	MigrationMode MigrationMode   `json:"migration_mode" db:"migration_mode"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
+
+// StateMigrator is implemented by strategies that can convert wallet state built under
+// one config version so it keeps its meaning under another. Strategies without it keep
+// the state unchanged.
+type StateMigrator interface {
+	MigrateState(oldConfig, newConfig, state json.RawMessage) (json.RawMessage, error)
+}
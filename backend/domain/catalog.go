@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Category groups merchants in the app
+type Category struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Slug      string    `json:"slug" db:"slug"`
+	IconKey   string    `json:"icon_key" db:"icon_key"`
+	ColorHex  string    `json:"color_hex" db:"color_hex"`
+	SortOrder int       `json:"sort_order" db:"sort_order"`
+}
+
+// MerchantProfile is the public page of a merchant in the app, without private settings
+type MerchantProfile struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	Address    *string    `json:"address,omitempty" db:"address"`
+	LogoURL    *string    `json:"logo_url,omitempty" db:"logo_url"`
+	BannerURL  *string    `json:"banner_url,omitempty" db:"banner_url"`
+	Category   *string    `json:"category,omitempty" db:"category"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty" db:"category_id"`
+	Latitude   *float64   `json:"latitude,omitempty" db:"latitude"`
+	Longitude  *float64   `json:"longitude,omitempty" db:"longitude"`
+
+	Campaigns  []*CampaignSummary `json:"campaigns" db:"-"`  // Running campaigns, newest first
+	Promotions []*Promotion       `json:"promotions" db:"-"` // Promotions on air
+}
+
+// promotionColorPattern matches the ARGB colors read by the app, e.g. 0xFF6B2FBA
+var promotionColorPattern = regexp.MustCompile(`^0x[0-9A-Fa-f]{8}$`)
+
+// Promotion is a banner shown in the app's featured carousel. It is on air while active and
+// within its schedule, and, when it links a campaign, while that campaign is running.
+type Promotion struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	MerchantID      *uuid.UUID `json:"merchant_id,omitempty" db:"merchant_id"` // Nil for platform promotions
+	CampaignID      *uuid.UUID `json:"campaign_id,omitempty" db:"campaign_id"`
+	Title           string     `json:"title" db:"title"`
+	Subtitle        *string    `json:"subtitle,omitempty" db:"subtitle"`
+	ImageURL        *string    `json:"image_url,omitempty" db:"image_url"`
+	BackgroundColor *string    `json:"background_color,omitempty" db:"background_color"`
+	Priority        int        `json:"priority" db:"priority"` // Higher is shown first
+	IsActive        bool       `json:"is_active" db:"is_active"`
+	StartsAt        *time.Time `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt          *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// MaxPromotionPriority bounds the priority merchants can give their own promotions
+const MaxPromotionPriority = 100
+
+// Validate checks the promotion's fields
+func (p *Promotion) Validate() error {
+	var errs ValidationErrors
+
+	if p.Title == "" {
+		errs = append(errs, NewFieldError("title", "is required"))
+	}
+	if p.BackgroundColor != nil && !promotionColorPattern.MatchString(*p.BackgroundColor) {
+		errs = append(errs, NewFieldError("backgroundColor", "must be an ARGB color such as 0xFF6B2FBA"))
+	}
+	if p.Priority < 0 || p.Priority > MaxPromotionPriority {
+		errs = append(errs, NewFieldError("priority", "must be between 0 and %d", MaxPromotionPriority))
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		errs = append(errs, NewFieldError("endsAt", "must be after startsAt"))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
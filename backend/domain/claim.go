@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OTPPurpose tells what a one-time code confirms
+type OTPPurpose string
+
+const (
+	OTPPurposeClaim OTPPurpose = "claim" // Claim of the shadow balances earned with a phone
+)
+
+// phonePattern matches E.164 phone numbers, the format SMS providers deliver to
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// ValidPhone reports whether the phone is in E.164 format, e.g. +5511999999999
+func ValidPhone(phone string) bool {
+	return phonePattern.MatchString(phone)
+}
+
+// phoneNoise matches everything but digits and the leading plus sign
+var phoneNoise = regexp.MustCompile(`[^0-9+]`)
+
+// NormalizePhone brings a phone to E.164 by dropping spaces and punctuation and adding the
+// plus sign Supabase Auth leaves out (5511999999999 becomes +5511999999999)
+func NormalizePhone(phone string) string {
+	phone = phoneNoise.ReplaceAllString(phone, "")
+	if phone != "" && !strings.HasPrefix(phone, "+") {
+		phone = "+" + phone
+	}
+	return phone
+}
+
+// InternationalPhone returns the E.164 form of a phone written with its country code, e.g.
+// "+55 (11) 99999-0000". A number without the leading plus sign is refused: a national number
+// such as "11 99999-9999" would otherwise read as another country's (+11999999999).
+func InternationalPhone(phone string) (string, bool) {
+	if !strings.HasPrefix(strings.TrimSpace(phone), "+") {
+		return "", false
+	}
+	normalized := NormalizePhone(phone)
+	return normalized, ValidPhone(normalized)
+}
+
+// OTPCode is a one-time code sent by SMS. Only a hash of the code is stored.
+type OTPCode struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	PhoneHash  string     `json:"phone_hash" db:"phone_hash"`
+	Purpose    OTPPurpose `json:"purpose" db:"purpose"`
+	CodeHash   string     `json:"-" db:"code_hash"`
+	Attempts   int        `json:"attempts" db:"attempts"` // Verifications tried, right or wrong
+	IPAddress  string     `json:"ip_address" db:"ip_address"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty" db:"consumed_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// HashOTP hashes a code with the ID of its OTPCode, so equal codes hash differently
+func HashOTP(id uuid.UUID, code string) string {
+	hash := sha256.Sum256([]byte(id.String() + ":" + code))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "testing"
+
+func TestInternationalPhone(t *testing.T) {
+	tests := []struct {
+		name   string
+		phone  string
+		want   string
+		wantOK bool
+	}{
+		{name: "E.164", phone: "+5511999999999", want: "+5511999999999", wantOK: true},
+		{name: "formatted with country code", phone: "+55 (11) 99999-9999", want: "+5511999999999", wantOK: true},
+		{name: "surrounding spaces", phone: " +5511999999999 ", want: "+5511999999999", wantOK: true},
+		{name: "national format", phone: "11 99999-9999"},
+		{name: "national digits", phone: "11999999999"},
+		{name: "country code without plus sign", phone: "5511999999999"},
+		{name: "too short", phone: "+55119"},
+		{name: "empty", phone: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := InternationalPhone(tt.phone)
+			if ok != tt.wantOK {
+				t.Fatalf("InternationalPhone(%q) ok = %v, want %v", tt.phone, ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Errorf("InternationalPhone(%q) = %q, want %q", tt.phone, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HashPhone creates the SHA-256 hash that identifies a phone number in wallets, shadow
+// balances and segments. The number is normalized first, so every way of writing it
+// hashes the same.
+func HashPhone(phone string) string {
+	hash := sha256.Sum256([]byte(NormalizePhone(phone)))
+	return hex.EncodeToString(hash[:])
+}
+
+// Consumer is an app user authenticated by a Supabase access token
+type Consumer struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Phone     string    `json:"phone,omitempty"` // Verified phone, empty when the account has none
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PhoneHash returns the hash matching the consumer's shadow balances, empty without a phone
+func (c *Consumer) PhoneHash() string {
+	if c.Phone == "" {
+		return ""
+	}
+	return HashPhone(c.Phone)
+}
+
+// ConsumerWallet is a wallet as shown to its owner in the app
+type ConsumerWallet struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	MerchantID      uuid.UUID `json:"merchant_id" db:"merchant_id"`
+	MerchantName    string    `json:"merchant_name" db:"merchant_name"`
+	MerchantLogoURL *string   `json:"merchant_logo_url,omitempty" db:"merchant_logo_url"`
+	Balance         float64   `json:"balance" db:"balance"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ConsumerShadowBalance is a not yet claimed balance earned with the consumer's phone
+type ConsumerShadowBalance struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	MerchantID      uuid.UUID `json:"merchant_id" db:"merchant_id"`
+	MerchantName    string    `json:"merchant_name" db:"merchant_name"`
+	MerchantLogoURL *string   `json:"merchant_logo_url,omitempty" db:"merchant_logo_url"`
+	Amount          float64   `json:"amount" db:"amount"`
+	ExpiresAt       time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// CampaignProgress is the consumer's standing in a merchant's campaign, from a wallet or
+// an unclaimed shadow balance
+type CampaignProgress struct {
+	MerchantID     uuid.UUID       `json:"merchant_id" db:"merchant_id"`
+	MerchantName   string          `json:"merchant_name" db:"merchant_name"`
+	CampaignID     *uuid.UUID      `json:"campaign_id,omitempty" db:"campaign_id"` // Nil when the merchant has no running campaign
+	CampaignName   *string         `json:"campaign_name,omitempty" db:"campaign_name"`
+	CampaignType   *CampaignType   `json:"campaign_type,omitempty" db:"campaign_type"`
+	CampaignStatus *CampaignStatus `json:"campaign_status,omitempty" db:"campaign_status"`
+	Balance        float64         `json:"balance" db:"balance"`
+	State          json.RawMessage `json:"state" db:"state"`
+	Registered     bool            `json:"registered" db:"registered"`           // False for shadow balances, claimed on signup
+	ExpiresAt      *time.Time      `json:"expires_at,omitempty" db:"expires_at"` // Shadow balance expiry
+
+	Config   json.RawMessage `json:"-" db:"config"`             // Config the state was built with
+	Progress *RewardProgress `json:"progress,omitempty" db:"-"` // State read through the campaign's strategy
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "testing"
+
+func TestHashPhone(t *testing.T) {
+	normalized := HashPhone("+5511999990000")
+
+	tests := []struct {
+		name  string
+		phone string
+	}{
+		{name: "E.164", phone: "+5511999990000"},
+		{name: "Supabase Auth format", phone: "5511999990000"},
+		{name: "formatted by a POS", phone: "+55 (11) 99999-0000"},
+		{name: "surrounding spaces", phone: " +5511999990000 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HashPhone(tt.phone); got != normalized {
+				t.Errorf("HashPhone(%q) = %s, want %s", tt.phone, got, normalized)
+			}
+		})
+	}
+
+	if HashPhone("+5511999990001") == normalized {
+		t.Error("HashPhone() of another number = the same hash")
+	}
+}
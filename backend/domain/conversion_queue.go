@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConversionQueueItem is a sign-up queued by the database trigger so its shadow balances are
+// converted even when the webhook does not reach the backend
+type ConversionQueueItem struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	UserID        uuid.UUID  `json:"user_id" db:"user_id"`
+	Phone         string     `json:"phone" db:"phone"` // As stored by Supabase Auth, normalized before hashing
+	Processed     bool       `json:"processed" db:"processed"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     *string    `json:"last_error,omitempty" db:"last_error"`
+	FailedAt      *time.Time `json:"failed_at,omitempty" db:"failed_at"` // Set once the retries are exhausted
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	ProcessedAt   *time.Time `json:"processed_at,omitempty" db:"processed_at"`
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// earthRadiusKm is the mean Earth radius used for distances
+const earthRadiusKm = 6371.0
+
+// NearbyQuery selects merchants around a point
+type NearbyQuery struct {
+	Latitude       float64
+	Longitude      float64
+	RadiusKm       float64
+	Category       string     // Category name, case-insensitive; any when empty
+	CategoryID     *uuid.UUID // Any when nil
+	ActiveCampaign bool       // Only merchants running a campaign
+	Limit          int
+}
+
+// BoundingBox returns the latitude/longitude box around the point that contains the whole
+// radius, used as an indexed prefilter before the exact distance. Longitude spans the whole
+// globe near the poles and when the box crosses the antimeridian.
+func (q *NearbyQuery) BoundingBox() (minLat, maxLat, minLng, maxLng float64) {
+	deltaLat := q.RadiusKm / earthRadiusKm * 180 / math.Pi
+	minLat = math.Max(q.Latitude-deltaLat, -90)
+	maxLat = math.Min(q.Latitude+deltaLat, 90)
+
+	minLng, maxLng = -180, 180
+	if cosLat := math.Cos(q.Latitude * math.Pi / 180); maxLat < 90 && minLat > -90 && cosLat > 0 {
+		deltaLng := deltaLat / cosLat
+		if q.Longitude-deltaLng >= -180 && q.Longitude+deltaLng <= 180 {
+			minLng, maxLng = q.Longitude-deltaLng, q.Longitude+deltaLng
+		}
+	}
+	return minLat, maxLat, minLng, maxLng
+}
+
+// NearbyMerchant is a merchant listed in the app's discovery, without private settings
+type NearbyMerchant struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	Name          string     `json:"name" db:"name"`
+	Address       *string    `json:"address,omitempty" db:"address"`
+	LogoURL       *string    `json:"logo_url,omitempty" db:"logo_url"`
+	BannerURL     *string    `json:"banner_url,omitempty" db:"banner_url"`
+	Category      *string    `json:"category,omitempty" db:"category"`
+	CategoryID    *uuid.UUID `json:"category_id,omitempty" db:"category_id"`
+	Latitude      float64    `json:"latitude" db:"latitude"`
+	Longitude     float64    `json:"longitude" db:"longitude"`
+	DistanceKm    float64    `json:"distance_km" db:"distance_km"`
+	CampaignCount int        `json:"campaign_count" db:"campaign_count"` // Running campaigns
+
+	ActiveCampaign *CampaignSummary `json:"active_campaign,omitempty" db:"-"` // Newest running campaign
+
+	// Columns of the newest running campaign, folded into ActiveCampaign
+	ActiveCampaignID     *uuid.UUID    `json:"-" db:"active_campaign_id"`
+	ActiveCampaignName   *string       `json:"-" db:"active_campaign_name"`
+	ActiveCampaignType   *CampaignType `json:"-" db:"active_campaign_type"`
+	ActiveCampaignEndsAt *time.Time    `json:"-" db:"active_campaign_ends_at"`
+}
+
+// CampaignSummary briefly describes a campaign for customers
+type CampaignSummary struct {
+	ID          uuid.UUID    `json:"id"`
+	Name        string       `json:"name"`
+	Type        CampaignType `json:"type"`
+	DisplayName string       `json:"display_name,omitempty"` // Name of the campaign type
+	EndsAt      *time.Time   `json:"ends_at,omitempty"`
+}
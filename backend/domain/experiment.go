@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Experiment splits a campaign's customers into variants to measure incremental impact
+type Experiment struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	CampaignID uuid.UUID  `json:"campaign_id" db:"campaign_id"`
+	Name       string     `json:"name" db:"name"`
+	IsActive   bool       `json:"is_active" db:"is_active"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	EndedAt    *time.Time `json:"ended_at,omitempty" db:"ended_at"`
+
+	Variants []*ExperimentVariant `json:"variants,omitempty" db:"-"`
+}
+
+// ExperimentVariant is one arm of an experiment. The control arm is a holdout that earns nothing;
+// other arms run the campaign with their own config, or the campaign's config when none is set.
+type ExperimentVariant struct {
+	ID           uuid.UUID       `json:"id" db:"id"`
+	ExperimentID uuid.UUID       `json:"experiment_id" db:"experiment_id"`
+	Name         string          `json:"name" db:"name"`
+	Weight       int             `json:"weight" db:"weight"`
+	IsControl    bool            `json:"is_control" db:"is_control"`
+	Config       json.RawMessage `json:"config,omitempty" db:"config"`
+	Position     int             `json:"position" db:"position"`
+}
+
+// ExperimentAssignment pins a customer (by phone hash) to a variant
+type ExperimentAssignment struct {
+	ExperimentID uuid.UUID `json:"experiment_id" db:"experiment_id"`
+	PhoneHash    string    `json:"phone_hash" db:"phone_hash"`
+	VariantID    uuid.UUID `json:"variant_id" db:"variant_id"`
+	AssignedAt   time.Time `json:"assigned_at" db:"assigned_at"`
+}
+
+// ExperimentCustomerMetrics aggregates one customer's activity since assignment
+type ExperimentCustomerMetrics struct {
+	VariantID uuid.UUID `db:"variant_id"`
+	PhoneHash string    `db:"phone_hash"`
+	Visits    int       `db:"visits"`
+	Spend     float64   `db:"spend"`
+	Redeemed  bool      `db:"redeemed"`
+}
+
+// ExperimentResults reports per-variant metrics and their comparison with the control
+type ExperimentResults struct {
+	Experiment *Experiment      `json:"experiment"`
+	Variants   []*VariantResult `json:"variants"`
+}
+
+// VariantResult holds the metrics of one variant
+type VariantResult struct {
+	VariantID         uuid.UUID `json:"variant_id"`
+	Name              string    `json:"name"`
+	IsControl         bool      `json:"is_control"`
+	Customers         int       `json:"customers"`
+	Visits            int       `json:"visits"`
+	VisitsPerCustomer float64   `json:"visits_per_customer"`
+	Spend             float64   `json:"spend"`
+	SpendPerCustomer  float64   `json:"spend_per_customer"`
+	RedemptionRate    float64   `json:"redemption_rate"` // Share of customers that redeemed at least once
+
+	VsControl *VariantComparison `json:"vs_control,omitempty"`
+}
+
+// VariantComparison tests a variant against the control
+type VariantComparison struct {
+	Visits         SignificanceTest `json:"visits_per_customer"`
+	Spend          SignificanceTest `json:"spend_per_customer"`
+	RedemptionRate SignificanceTest `json:"redemption_rate"`
+}
+
+// SignificanceTest is the outcome of a two-sided test at the 5% level
+type SignificanceTest struct {
+	Test        string  `json:"test"` // "welch_t" or "two_proportion_z"
+	Lift        float64 `json:"lift"` // Relative difference to the control (0.1 = +10%)
+	Statistic   float64 `json:"statistic"`
+	PValue      float64 `json:"p_value"`
+	Significant bool    `json:"significant"`
+}
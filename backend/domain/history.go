@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HistoryCursor points at the last entry of a history page. Entries are ordered by
+// (created_at, id), newest first, so pages stay stable while new entries are written.
+type HistoryCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Encode returns the opaque cursor string handed to clients
+func (c HistoryCursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseHistoryCursor decodes a cursor produced by Encode
+func ParseHistoryCursor(value string) (*HistoryCursor, error) {
+	invalid := errors.New("invalid cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, invalid
+	}
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return nil, invalid
+	}
+
+	cursor := &HistoryCursor{}
+	if cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, invalid
+	}
+	if cursor.ID, err = uuid.Parse(id); err != nil {
+		return nil, invalid
+	}
+	return cursor, nil
+}
+
+// HistoryFilter selects a page of ledger entries
+type HistoryFilter struct {
+	Types      []TransactionType // Any type when empty
+	From       *time.Time        // Inclusive
+	To         *time.Time        // Exclusive
+	CampaignID *uuid.UUID
+	After      *HistoryCursor // Entries older than the cursor
+	Limit      int
+}
+
+// HistoryEntry is a ledger entry with a description for customers and merchants
+type HistoryEntry struct {
+	Transaction
+	CampaignName *string `json:"campaign_name,omitempty" db:"campaign_name"`
+	Description  string  `json:"description" db:"-"`
+}
+
+// HistoryPage is a page of ledger entries, newest first
+type HistoryPage struct {
+	Entries    []*HistoryEntry `json:"entries"`
+	NextCursor string          `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// Describe builds the entry's description from its type and stored metadata
+func (e *HistoryEntry) Describe() string {
+	var meta map[string]interface{}
+	_ = json.Unmarshal(e.Metadata, &meta)
+	text := func(key string) string {
+		value, _ := meta[key].(string)
+		return value
+	}
+
+	switch e.Type {
+	case TransactionTypeEarn:
+		description := "Compra registrada"
+		if e.PurchaseAmount != nil {
+			description = fmt.Sprintf("Compra de R$ %.2f", *e.PurchaseAmount)
+		}
+		if e.CampaignName != nil {
+			description += " - " + *e.CampaignName
+		}
+		return description
+
+	case TransactionTypeRedeem:
+		if reward := text("reward"); reward != "" {
+			return "Resgate: " + reward
+		}
+		return fmt.Sprintf("Resgate de R$ %.2f", -e.Amount)
+
+	case TransactionTypeExpire:
+		switch OccasionType(text("occasion")) {
+		case OccasionBirthday:
+			return "Presente de aniversário expirado"
+		case OccasionAnniversary:
+			return "Presente de aniversário de cadastro expirado"
+		}
+		return "Saldo expirado por falta de cadastro"
+
+	case TransactionTypeConvert:
+		return "Saldo transferido para a conta após o cadastro"
+
+	case TransactionTypeOccasion:
+		if OccasionType(text("occasion")) == OccasionAnniversary {
+			return "Presente de aniversário de cadastro"
+		}
+		return "Presente de aniversário"
+
+	case TransactionTypeWelcome:
+		return "Bônus de boas-vindas"
+
+	case TransactionTypeAdjust:
+		// The free-text note is internal to the merchant
+		if label, ok := adjustmentReasonLabels[AdjustmentReason(text("reason_code"))]; ok {
+			return "Ajuste manual: " + label
+		}
+		return "Ajuste manual"
+
+	case TransactionTypeReset:
+		return "Progresso reiniciado na troca de campanha"
+	}
+
+	return string(e.Type)
+}
+
+var adjustmentReasonLabels = map[AdjustmentReason]string{
+	AdjustmentReasonComplaint:      "compensação por reclamação",
+	AdjustmentReasonIngestionError: "correção de compra registrada errada",
+	AdjustmentReasonMissedPurchase: "compra não registrada",
+	AdjustmentReasonFraud:          "estorno por uso indevido",
+	AdjustmentReasonOther:          "outro motivo",
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InstantWinPool holds the secret seed of an instant-win campaign.
+// Only the commitment is public until the campaign ends and the seed is revealed.
+type InstantWinPool struct {
+	CampaignID     uuid.UUID  `json:"campaign_id" db:"campaign_id"`
+	ServerSeed     []byte     `json:"-" db:"server_seed"`
+	SeedCommitment string     `json:"seed_commitment" db:"seed_commitment"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	RevealedAt     *time.Time `json:"revealed_at,omitempty" db:"revealed_at"`
+}
+
+// InstantWinInventory tracks how many units of a prize tier were awarded
+type InstantWinInventory struct {
+	CampaignID uuid.UUID `json:"campaign_id" db:"campaign_id"`
+	TierIndex  int       `json:"tier_index" db:"tier_index"`
+	Name       string    `json:"name" db:"name"`
+	Amount     float64   `json:"amount" db:"amount"`
+	Inventory  int       `json:"inventory" db:"inventory"`
+	Awarded    int       `json:"awarded" db:"awarded"`
+}
+
+// InstantWinDraw is the audit record of a single draw
+type InstantWinDraw struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	CampaignID    uuid.UUID `json:"campaign_id" db:"campaign_id"`
+	TransactionID string    `json:"transaction_id" db:"transaction_id"`
+	PhoneHash     string    `json:"phone_hash" db:"phone_hash"`
+	Roll          float64   `json:"roll" db:"roll"`
+	TierIndex     *int      `json:"tier_index,omitempty" db:"tier_index"`
+	Awarded       bool      `json:"awarded" db:"awarded"` // False when the tier was won but its inventory was exhausted
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// InstantWinReport summarizes the prize pool of a campaign
+type InstantWinReport struct {
+	CampaignID     uuid.UUID                `json:"campaign_id"`
+	SeedCommitment string                   `json:"seed_commitment"`
+	ServerSeed     string                   `json:"server_seed,omitempty"` // Revealed once the campaign has ended
+	Ended          bool                     `json:"ended"`
+	TotalDraws     int64                    `json:"total_draws"`
+	TotalWins      int64                    `json:"total_wins"`
+	Prizes         []InstantWinPrizeSummary `json:"prizes"`
+	UnawardedValue float64                  `json:"unawarded_value"`
+}
+
+// InstantWinPrizeSummary reports awarded and unawarded units of a prize tier
+type InstantWinPrizeSummary struct {
+	TierIndex int     `json:"tier_index"`
+	Name      string  `json:"name"`
+	Amount    float64 `json:"amount"`
+	Inventory int     `json:"inventory"`
+	Awarded   int     `json:"awarded"`
+	Unawarded int     `json:"unawarded"`
+}
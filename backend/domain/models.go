@@ -14,16 +14,22 @@ const (
 	CampaignTypePunchCard   CampaignType = "PUNCH_CARD"
 	CampaignTypeCashback    CampaignType = "CASHBACK"
 	CampaignTypeProgressive CampaignType = "PROGRESSIVE"
+	CampaignTypeStreak      CampaignType = "STREAK"
+	CampaignTypeInstantWin  CampaignType = "INSTANT_WIN"
 )
 
 // TransactionType represents the type of loyalty transaction
 type TransactionType string
 
 const (
-	TransactionTypeEarn    TransactionType = "EARN"
-	TransactionTypeRedeem  TransactionType = "REDEEM"
-	TransactionTypeExpire  TransactionType = "EXPIRE"
-	TransactionTypeConvert TransactionType = "CONVERT"
+	TransactionTypeEarn     TransactionType = "EARN"
+	TransactionTypeRedeem   TransactionType = "REDEEM"
+	TransactionTypeExpire   TransactionType = "EXPIRE"
+	TransactionTypeConvert  TransactionType = "CONVERT"
+	TransactionTypeOccasion TransactionType = "OCCASION"
+	TransactionTypeWelcome  TransactionType = "WELCOME"
+	TransactionTypeAdjust   TransactionType = "ADJUST"
+	TransactionTypeReset    TransactionType = "RESET"
 )
 
 // Merchant represents a business using the loyalty platform
@@ -43,6 +49,31 @@ type Merchant struct {
 	UpdatedAt  time.Time       `json:"updated_at" db:"updated_at"`
 }
 
+// DefaultMerchantTimezone is used when a merchant has not configured one
+const DefaultMerchantTimezone = "America/Sao_Paulo"
+
+// Location returns the merchant's configured timezone (settings.timezone)
+func (m *Merchant) Location() *time.Location {
+	var settings struct {
+		Timezone string `json:"timezone"`
+	}
+	if len(m.Settings) > 0 {
+		_ = json.Unmarshal(m.Settings, &settings)
+	}
+
+	if settings.Timezone != "" {
+		if loc, err := time.LoadLocation(settings.Timezone); err == nil {
+			return loc
+		}
+	}
+
+	loc, err := time.LoadLocation(DefaultMerchantTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
 // Campaign represents a loyalty campaign
 type Campaign struct {
 	ID         uuid.UUID       `json:"id" db:"id"`
@@ -50,11 +81,23 @@ type Campaign struct {
 	Name       string          `json:"name" db:"name"`
 	Type       CampaignType    `json:"type" db:"type"`
 	Config     json.RawMessage `json:"config" db:"config"`
-	IsActive   bool            `json:"is_active" db:"is_active"`
+	Status     CampaignStatus  `json:"status" db:"status"`
+	IsActive   bool            `json:"is_active" db:"is_active"` // Status is ACTIVE, kept for older queries
 	StartsAt   *time.Time      `json:"starts_at" db:"starts_at"`
 	EndsAt     *time.Time      `json:"ends_at" db:"ends_at"`
 	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt  time.Time       `json:"updated_at" db:"updated_at"`
+
+	CurrentVersion int `json:"current_version" db:"current_version"`
+
+	// Budget caps, nil when unlimited
+	MaxRewardValue *float64 `json:"max_reward_value,omitempty" db:"max_reward_value"`
+	MaxRewards     *int     `json:"max_rewards,omitempty" db:"max_rewards"`
+	DailyRewardCap *float64 `json:"daily_reward_cap,omitempty" db:"daily_reward_cap"`
+
+	SegmentID *uuid.UUID `json:"segment_id,omitempty" db:"segment_id"` // Only customers in the segment take part, everyone when nil
+
+	Budget *CampaignBudget `json:"budget,omitempty" db:"-"` // Consumption, filled by the campaign endpoints
 }
 
 // Wallet represents a customer's loyalty balance with a merchant
@@ -65,8 +108,12 @@ type Wallet struct {
 	PhoneHash  string          `json:"phone_hash" db:"phone_hash"`
 	Balance    float64         `json:"balance" db:"balance"`
 	State      json.RawMessage `json:"state" db:"state"`
+	BirthDate  *time.Time      `json:"birth_date,omitempty" db:"birth_date"`
 	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt  time.Time       `json:"updated_at" db:"updated_at"`
+
+	CampaignID        *uuid.UUID `json:"campaign_id,omitempty" db:"campaign_id"`                 // Campaign the state belongs to
+	CampaignVersionID *uuid.UUID `json:"campaign_version_id,omitempty" db:"campaign_version_id"` // Config version the state belongs to
 }
 
 // ShadowBalance represents a temporary loyalty balance for unregistered users
@@ -79,6 +126,15 @@ type ShadowBalance struct {
 	ExpiresAt   time.Time       `json:"expires_at" db:"expires_at"`
 	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
 	ConvertedAt *time.Time      `json:"converted_at" db:"converted_at"`
+
+	LastActivityAt time.Time `json:"last_activity_at" db:"last_activity_at"` // Last purchase, restarts sliding expiration
+
+	CampaignID        *uuid.UUID `json:"campaign_id,omitempty" db:"campaign_id"`                 // Campaign the state belongs to
+	CampaignVersionID *uuid.UUID `json:"campaign_version_id,omitempty" db:"campaign_version_id"` // Config version the state belongs to
+
+	// Set when the customer confirmed the phone with a one-time code
+	ClaimedAt  *time.Time `json:"claimed_at,omitempty" db:"claimed_at"`
+	ClaimEmail *string    `json:"claim_email,omitempty" db:"claim_email"`
 }
 
 // Transaction represents a loyalty transaction in the immutable ledger
@@ -92,6 +148,12 @@ type Transaction struct {
 	Amount          float64         `json:"amount" db:"amount"`
 	Metadata        json.RawMessage `json:"metadata" db:"metadata"`
 	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+
+	CampaignVersionID *uuid.UUID `json:"campaign_version_id,omitempty" db:"campaign_version_id"` // Config version the entry was computed with
+	PurchaseAmount    *float64   `json:"purchase_amount,omitempty" db:"purchase_amount"`         // Purchase that generated an EARN entry
+	ExternalID        *string    `json:"external_id,omitempty" db:"external_id"`                 // Merchant's transaction ID
+
+	ExperimentVariantID *uuid.UUID `json:"experiment_variant_id,omitempty" db:"experiment_variant_id"` // Experiment arm the customer was in
 }
 
 // IngestRequest represents the payload for the ingestion endpoint
@@ -104,19 +166,61 @@ type IngestRequest struct {
 
 // IngestResponse represents the response from the ingestion endpoint
 type IngestResponse struct {
-	Success    bool        `json:"success"`
-	NewBalance float64     `json:"new_balance"`
-	IsShadow   bool        `json:"is_shadow"`
-	ExpiresAt  *time.Time  `json:"expires_at,omitempty"`
-	Reward     *RewardInfo `json:"reward,omitempty"`
-	Message    string      `json:"message,omitempty"`
+	Success      bool        `json:"success"`
+	NewBalance   float64     `json:"new_balance"`
+	IsShadow     bool        `json:"is_shadow"`
+	ExpiresAt    *time.Time  `json:"expires_at,omitempty"`
+	Reward       *RewardInfo `json:"reward,omitempty"`
+	WelcomeBonus *RewardInfo `json:"welcome_bonus,omitempty"`
+	LimitHit     *LimitHit   `json:"limit_hit,omitempty"`    // Customer limit that reduced or blocked the reward
+	NotEligible  bool        `json:"not_eligible,omitempty"` // Customer is outside every active campaign's segment, only the visit was recorded
+	Message      string      `json:"message,omitempty"`
+}
+
+// LimitHit reports which per-customer limit applied to a transaction
+type LimitHit struct {
+	Limit       string  `json:"limit"` // Name of the customer_limits setting, e.g. "max_rewards_per_day"
+	Value       float64 `json:"value"` // Configured limit
+	Description string  `json:"description"`
+}
+
+// CustomerEarnHistory summarizes a customer's recent EARN entries for limit checks
+type CustomerEarnHistory struct {
+	RewardsToday   int     `db:"rewards_today"`
+	ValueToday     float64 `db:"value_today"`
+	ValueThisMonth float64 `db:"value_this_month"`
+	VisitsInWindow int     `db:"visits_in_window"`
 }
 
 // RewardInfo contains information about earned rewards
 type RewardInfo struct {
-	Type        string  `json:"type"`
-	Amount      float64 `json:"amount"`
-	Description string  `json:"description"`
+	Type           string  `json:"type"`
+	Amount         float64 `json:"amount"`
+	Description    string  `json:"description"`
+	CardsCompleted int     `json:"cards_completed,omitempty"`
+}
+
+// WelcomeBonusStatus tracks whether a welcome bonus was paid out
+type WelcomeBonusStatus string
+
+const (
+	WelcomeBonusPending   WelcomeBonusStatus = "PENDING" // Waiting for the customer to sign up
+	WelcomeBonusGranted   WelcomeBonusStatus = "GRANTED"
+	WelcomeBonusForfeited WelcomeBonusStatus = "FORFEITED" // Shadow balance expired before sign-up
+)
+
+// WelcomeBonus records the one-time bonus for a customer's first interaction with a merchant
+type WelcomeBonus struct {
+	ID              uuid.UUID          `json:"id" db:"id"`
+	MerchantID      uuid.UUID          `json:"merchant_id" db:"merchant_id"`
+	CampaignID      *uuid.UUID         `json:"campaign_id" db:"campaign_id"`
+	PhoneHash       string             `json:"phone_hash" db:"phone_hash"`
+	Amount          float64            `json:"amount" db:"amount"`
+	Status          WelcomeBonusStatus `json:"status" db:"status"`
+	WalletID        *uuid.UUID         `json:"wallet_id" db:"wallet_id"`
+	ShadowBalanceID *uuid.UUID         `json:"shadow_balance_id" db:"shadow_balance_id"`
+	CreatedAt       time.Time          `json:"created_at" db:"created_at"`
+	GrantedAt       *time.Time         `json:"granted_at" db:"granted_at"`
 }
 
 // ConversionStats holds statistics about shadow wallet conversions
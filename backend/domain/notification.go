@@ -0,0 +1,17 @@
+package domain
+
+import "github.com/google/uuid"
+
+// Postgres channels the backend listens on (migrations/022_realtime_notifications.sql)
+const (
+	ChannelConversionQueued = "shadow_conversion_queued" // Payload: ID of the queue entry
+	ChannelMerchantChanged  = "merchant_changed"         // Payload: EntityChange
+	ChannelCampaignChanged  = "campaign_changed"         // Payload: EntityChange
+)
+
+// EntityChange is the payload announcing a created, updated or deleted row
+type EntityChange struct {
+	ID         uuid.UUID `json:"id"`
+	MerchantID uuid.UUID `json:"merchant_id"`
+	Operation  string    `json:"op"` // INSERT, UPDATE or DELETE
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OccasionType represents a date-based event that triggers an automatic reward
+type OccasionType string
+
+const (
+	OccasionBirthday    OccasionType = "BIRTHDAY"
+	OccasionAnniversary OccasionType = "ANNIVERSARY"
+)
+
+// IsValid reports whether the occasion is one we know how to schedule
+func (o OccasionType) IsValid() bool {
+	return o == OccasionBirthday || o == OccasionAnniversary
+}
+
+// OccasionReward is a merchant's configuration for an automatic occasion reward
+type OccasionReward struct {
+	ID         uuid.UUID    `json:"id" db:"id"`
+	MerchantID uuid.UUID    `json:"merchant_id" db:"merchant_id"`
+	Occasion   OccasionType `json:"occasion" db:"occasion"`
+	Amount     float64      `json:"amount" db:"amount"`
+	RewardType string       `json:"reward_type" db:"reward_type"`
+	ValidDays  *int         `json:"valid_days,omitempty" db:"valid_days"` // NULL means the reward never expires
+	IsActive   bool         `json:"is_active" db:"is_active"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// OccasionGrant records a reward granted to a wallet for a given occasion and year
+type OccasionGrant struct {
+	ID               uuid.UUID    `json:"id" db:"id"`
+	OccasionRewardID uuid.UUID    `json:"occasion_reward_id" db:"occasion_reward_id"`
+	MerchantID       uuid.UUID    `json:"merchant_id" db:"merchant_id"`
+	WalletID         uuid.UUID    `json:"wallet_id" db:"wallet_id"`
+	Occasion         OccasionType `json:"occasion" db:"occasion"`
+	GrantYear        int          `json:"grant_year" db:"grant_year"`
+	Amount           float64      `json:"amount" db:"amount"`
+	ExpiresAt        *time.Time   `json:"expires_at,omitempty" db:"expires_at"`
+	ExpiredAt        *time.Time   `json:"expired_at,omitempty" db:"expired_at"`
+	CreatedAt        time.Time    `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,51 @@
+package domain
+
+import "encoding/json"
+
+// StateDescriber is implemented by strategies that can explain a customer's state, such as
+// how far they are from the next reward. Strategies without it only expose the raw state.
+type StateDescriber interface {
+	Describe(state, config json.RawMessage) (*RewardProgress, error)
+}
+
+// RewardProgress is a customer's standing in a campaign, read through its strategy.
+// Only the block matching the campaign type is set.
+type RewardProgress struct {
+	Summary string   `json:"summary"`           // Customer-facing sentence
+	Percent *float64 `json:"percent,omitempty"` // Progress toward the next reward or tier, 0-100
+
+	PunchCard   *PunchCardProgress   `json:"punch_card,omitempty"`
+	Progressive *ProgressiveProgress `json:"progressive,omitempty"`
+	Cashback    *CashbackProgress    `json:"cashback,omitempty"`
+}
+
+// PunchCardProgress counts the punches on the current card
+type PunchCardProgress struct {
+	Punches        int `json:"punches"`
+	Required       int `json:"required"`
+	Remaining      int `json:"remaining"`
+	CardsCompleted int `json:"cards_completed"`
+}
+
+// ProgressiveProgress places the customer among the campaign tiers
+type ProgressiveProgress struct {
+	CurrentTier      ProgressTier  `json:"current_tier"`
+	NextTier         *ProgressTier `json:"next_tier,omitempty"` // Nil on the top tier
+	Transactions     int           `json:"transactions"`
+	TransactionsLeft int           `json:"transactions_left"` // Purchases needed for the next tier
+	TotalPoints      float64       `json:"total_points"`
+}
+
+// ProgressTier identifies a progressive campaign tier
+type ProgressTier struct {
+	Index           int    `json:"index"`
+	Name            string `json:"name"`
+	MinTransactions int    `json:"min_transactions"`
+}
+
+// CashbackProgress compares the cashback that can still be used with all that was earned
+type CashbackProgress struct {
+	Redeemable    float64 `json:"redeemable"`
+	TotalEarned   float64 `json:"total_earned"`
+	TotalRedeemed float64 `json:"total_redeemed"`
+}
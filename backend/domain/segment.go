@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SegmentKind tells how a segment's members are determined
+type SegmentKind string
+
+const (
+	SegmentKindRule   SegmentKind = "rule"   // Rules evaluated over wallet and ledger data
+	SegmentKindStatic SegmentKind = "static" // Uploaded list of phone hashes
+)
+
+// Segment is a group of a merchant's customers that campaigns can be restricted to
+type Segment struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	MerchantID  uuid.UUID       `json:"merchant_id" db:"merchant_id"`
+	Name        string          `json:"name" db:"name"`
+	Description string          `json:"description" db:"description"`
+	Kind        SegmentKind     `json:"kind" db:"kind"`
+	Rules       json.RawMessage `json:"rules,omitempty" db:"rules"` // SegmentRules, rule segments only
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+
+	Size *int `json:"size,omitempty" db:"-"` // Current number of members, filled by the segment endpoints
+}
+
+// SegmentRules define a rule segment. A customer is a member when every set rule matches.
+type SegmentRules struct {
+	NewCustomerDays    *int     `json:"new_customer_days,omitempty"`    // First visit within the last N days (or no visit yet)
+	LapsedDays         *int     `json:"lapsed_days,omitempty"`          // Visited before, but not in the last N days
+	TopSpendersPercent *float64 `json:"top_spenders_percent,omitempty"` // Among the top N% of customers by spend
+	SpendWindowDays    *int     `json:"spend_window_days,omitempty"`    // Period ranked by top_spenders_percent, all time when omitted
+	RegisteredOnly     bool     `json:"registered_only,omitempty"`      // Has signed up (real wallet, not a shadow balance)
+	MinTier            *int     `json:"min_tier,omitempty"`             // Progressive campaign tier (current_tier) of at least N
+}
+
+// CustomerStats summarizes a customer's activity with a merchant for segment rules
+type CustomerStats struct {
+	PhoneHash  string     `db:"phone_hash"`
+	Registered bool       `db:"registered"`
+	Tier       *int       `db:"tier"`
+	FirstVisit *time.Time `db:"first_visit"`
+	LastVisit  *time.Time `db:"last_visit"`
+	Spend      float64    `db:"spend"` // Purchases in the spend window
+}
+
+// SpendRanking is the merchant-wide spend distribution a top_spenders_percent rule is checked
+// against, computed once for all customers instead of ranking them on every check
+type SpendRanking struct {
+	Spenders   int       `db:"spenders"` // Customers who spent anything in the window
+	Cutoff     float64   `db:"cutoff"`   // Lowest spend still among the top N%, zero without spenders
+	ComputedAt time.Time `db:"-"`
+}
+
+// ParseSegmentRules decodes the rules of a rule segment
+func ParseSegmentRules(raw json.RawMessage) (*SegmentRules, error) {
+	var rules SegmentRules
+	if len(raw) == 0 {
+		return &rules, nil
+	}
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, fmt.Errorf("invalid segment rules: %w", err)
+	}
+	return &rules, nil
+}
+
+// Validate checks the rule values
+func (r *SegmentRules) Validate() error {
+	var errs ValidationErrors
+	positive := func(field string, value *int) {
+		if value != nil && *value <= 0 {
+			errs = append(errs, NewFieldError("rules."+field, "must be greater than 0"))
+		}
+	}
+	positive("new_customer_days", r.NewCustomerDays)
+	positive("lapsed_days", r.LapsedDays)
+	positive("spend_window_days", r.SpendWindowDays)
+
+	if r.TopSpendersPercent != nil && (*r.TopSpendersPercent <= 0 || *r.TopSpendersPercent > 100) {
+		errs = append(errs, NewFieldError("rules.top_spenders_percent", "must be between 0 and 100"))
+	}
+	if r.SpendWindowDays != nil && r.TopSpendersPercent == nil {
+		errs = append(errs, NewFieldError("rules.spend_window_days", "requires top_spenders_percent"))
+	}
+	if r.MinTier != nil && *r.MinTier < 0 {
+		errs = append(errs, NewFieldError("rules.min_tier", "must be 0 or greater"))
+	}
+	if r.NewCustomerDays != nil && r.LapsedDays != nil {
+		errs = append(errs, NewFieldError("rules.lapsed_days", "cannot be combined with new_customer_days"))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// SpendSince returns the start of the spend ranking window, nil for all time
+func (r *SegmentRules) SpendSince(now time.Time) *time.Time {
+	if r.SpendWindowDays == nil {
+		return nil
+	}
+	since := now.AddDate(0, 0, -*r.SpendWindowDays)
+	return &since
+}
+
+// Matches reports whether a customer with the given stats belongs to the segment. ranking is
+// only read by the top_spenders_percent rule.
+func (r *SegmentRules) Matches(stats *CustomerStats, ranking *SpendRanking, now time.Time) bool {
+	if r.RegisteredOnly && !stats.Registered {
+		return false
+	}
+
+	if r.NewCustomerDays != nil && stats.FirstVisit != nil &&
+		stats.FirstVisit.Before(now.AddDate(0, 0, -*r.NewCustomerDays)) {
+		return false
+	}
+
+	if r.LapsedDays != nil &&
+		(stats.LastVisit == nil || !stats.LastVisit.Before(now.AddDate(0, 0, -*r.LapsedDays))) {
+		return false
+	}
+
+	// Ties with the cutoff are in, like a shared rank
+	if r.TopSpendersPercent != nil &&
+		(ranking == nil || ranking.Spenders == 0 || stats.Spend <= 0 || stats.Spend < ranking.Cutoff) {
+		return false
+	}
+
+	if r.MinTier != nil && (stats.Tier == nil || *stats.Tier < *r.MinTier) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// maxShadowExpirationHours bounds the TTL and the hard maximum (one year)
+const maxShadowExpirationHours = 24 * 365
+
+// ShadowExpiration is a merchant's policy for how long shadow balances last
+// (settings.shadow_expiration)
+type ShadowExpiration struct {
+	TTLHours int  `json:"ttl_hours"`           // From the first purchase, or from the last one when sliding
+	Sliding  bool `json:"sliding"`             // Each purchase restarts the TTL
+	MaxHours int  `json:"max_hours,omitempty"` // Hard limit from the first purchase, sliding only
+}
+
+// ShadowExpiration returns the merchant's policy, or a fixed defaultTTL when none is set
+func (m *Merchant) ShadowExpiration(defaultTTL time.Duration) ShadowExpiration {
+	var settings struct {
+		ShadowExpiration *ShadowExpiration `json:"shadow_expiration"`
+	}
+	if len(m.Settings) > 0 {
+		_ = json.Unmarshal(m.Settings, &settings)
+	}
+
+	if settings.ShadowExpiration != nil && settings.ShadowExpiration.Validate() == nil {
+		return *settings.ShadowExpiration
+	}
+	return ShadowExpiration{TTLHours: int(defaultTTL.Hours())}
+}
+
+// TTL returns the time a balance lasts after the purchase that starts its clock
+func (p ShadowExpiration) TTL() time.Duration {
+	return time.Duration(p.TTLHours) * time.Hour
+}
+
+// Max returns the hard limit of a sliding policy
+func (p ShadowExpiration) Max() time.Duration {
+	return time.Duration(p.MaxHours) * time.Hour
+}
+
+// ExpiresAt returns when a balance created at createdAt, last bought with at lastActivity,
+// expires under the policy
+func (p ShadowExpiration) ExpiresAt(createdAt, lastActivity time.Time) time.Time {
+	if !p.Sliding {
+		return createdAt.Add(p.TTL())
+	}
+
+	expiresAt := lastActivity.Add(p.TTL())
+	if limit := createdAt.Add(p.Max()); expiresAt.After(limit) {
+		return limit
+	}
+	return expiresAt
+}
+
+// Validate checks the policy's bounds
+func (p ShadowExpiration) Validate() error {
+	var errs ValidationErrors
+
+	if p.TTLHours <= 0 || p.TTLHours > maxShadowExpirationHours {
+		errs = append(errs, NewFieldError("ttl_hours", "must be between 1 and %d", maxShadowExpirationHours))
+	}
+	if p.Sliding {
+		if p.MaxHours < p.TTLHours || p.MaxHours > maxShadowExpirationHours {
+			errs = append(errs, NewFieldError("max_hours", "must be between ttl_hours and %d", maxShadowExpirationHours))
+		}
+	} else if p.MaxHours != 0 {
+		errs = append(errs, NewFieldError("max_hours", "only applies to sliding expiration"))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
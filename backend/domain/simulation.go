@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SimulationStatus tracks a backtest job through the worker
+type SimulationStatus string
+
+const (
+	SimulationStatusPending   SimulationStatus = "PENDING"
+	SimulationStatusRunning   SimulationStatus = "RUNNING"
+	SimulationStatusCompleted SimulationStatus = "COMPLETED"
+	SimulationStatusFailed    SimulationStatus = "FAILED"
+)
+
+// SimulationJob replays a merchant's historical purchases through a candidate campaign config
+type SimulationJob struct {
+	ID           uuid.UUID        `json:"id" db:"id"`
+	MerchantID   uuid.UUID        `json:"merchant_id" db:"merchant_id"`
+	CampaignType CampaignType     `json:"campaign_type" db:"campaign_type"`
+	Config       json.RawMessage  `json:"config" db:"config"`
+	PeriodStart  time.Time        `json:"period_start" db:"period_start"`
+	PeriodEnd    time.Time        `json:"period_end" db:"period_end"`
+	Status       SimulationStatus `json:"status" db:"status"`
+	Result       json.RawMessage  `json:"result,omitempty" db:"result"` // SimulationResult once completed
+	Error        *string          `json:"error,omitempty" db:"error"`
+	CreatedAt    time.Time        `json:"created_at" db:"created_at"`
+	StartedAt    *time.Time       `json:"started_at,omitempty" db:"started_at"`
+	CompletedAt  *time.Time       `json:"completed_at,omitempty" db:"completed_at"`
+
+	HeartbeatAt *time.Time `json:"-" db:"heartbeat_at"` // Refreshed by the worker while the job runs
+	Attempts    int        `json:"-" db:"attempts"`     // Times a worker started the job
+}
+
+// HistoricalPurchase is a past purchase taken from the ledger
+type HistoricalPurchase struct {
+	PhoneHash      string          `db:"phone_hash"`
+	PurchaseAmount *float64        `db:"purchase_amount"` // Nil for entries recorded before purchase amounts were stored
+	Metadata       json.RawMessage `db:"metadata"`
+	ExternalID     string          `db:"external_id"`
+	OccurredAt     time.Time       `db:"created_at"`
+}
+
+// SimulationResult summarizes what the candidate config would have issued over the period
+type SimulationResult struct {
+	TransactionsReplayed int     `json:"transactions_replayed"`
+	TransactionsSkipped  int     `json:"transactions_skipped"` // Ledger entries without a purchase amount
+	Customers            int     `json:"customers"`
+	RewardsIssued        int     `json:"rewards_issued"`
+	RewardValue          float64 `json:"reward_value"`
+	ActualRewardValue    float64 `json:"actual_reward_value"` // What the live campaigns issued over the same period
+	RedeemedValue        float64 `json:"redeemed_value"`
+	Liability            float64 `json:"liability"` // Simulated balances still outstanding at the end of the period
+
+	Distribution  RewardDistribution   `json:"distribution"`
+	TierMovements []TierMovement       `json:"tier_movements,omitempty"`
+	PerCustomer   []CustomerSimulation `json:"per_customer"`
+}
+
+// RewardDistribution describes how the simulated reward value spreads across customers
+type RewardDistribution struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// TierMovement counts customers moving between tiers during the simulation
+type TierMovement struct {
+	FromTier int `json:"from_tier"`
+	ToTier   int `json:"to_tier"`
+	Count    int `json:"count"`
+}
+
+// CustomerSimulation is the simulated outcome for one customer
+type CustomerSimulation struct {
+	PhoneHash     string  `json:"phone_hash"`
+	Transactions  int     `json:"transactions"`
+	Spend         float64 `json:"spend"`
+	RewardsIssued int     `json:"rewards_issued"`
+	RewardValue   float64 `json:"reward_value"`
+	RedeemedValue float64 `json:"redeemed_value"`
+	Balance       float64 `json:"balance"`
+	FinalTier     *int    `json:"final_tier,omitempty"`
+}
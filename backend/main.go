@@ -69,29 +69,87 @@ func main() {
 		authClient = services.NewSupabaseAuthClient(cfg.SupabaseURL, cfg.SupabaseServiceKey)
 	}
 
+	// Consumer tokens are verified locally against the project's JWT secret or JWKS
+	if cfg.SupabaseJWTSecret == "" && cfg.SupabaseJWKSURL == "" {
+		log.Println("⚠️  SUPABASE_JWT_SECRET and SUPABASE_JWKS_URL are not set, /v1/me and adjustments will reject every token")
+	}
+	tokenVerifier := services.NewJWTVerifier(services.JWTVerifierConfig{
+		Secret:   cfg.SupabaseJWTSecret,
+		JWKSURL:  cfg.SupabaseJWKSURL,
+		Audience: cfg.SupabaseJWTAudience,
+		Issuer:   cfg.SupabaseJWTIssuer,
+	})
+
 	// Initialize campaign strategies
-	strategyRegistry := map[domain.CampaignType]domain.CampaignStrategy{
+	strategyRegistry := domain.StrategyRegistry{
 		domain.CampaignTypePunchCard:   strategies.NewPunchCardStrategy(),
 		domain.CampaignTypeCashback:    strategies.NewCashbackStrategy(),
 		domain.CampaignTypeProgressive: strategies.NewProgressiveStrategy(),
+		domain.CampaignTypeStreak:      strategies.NewStreakStrategy(),
+		domain.CampaignTypeInstantWin:  strategies.NewInstantWinStrategy(),
+	}
+
+	// One-time codes are sent by SMS
+	var smsSender services.SMSSender
+	if cfg.SMSProvider == "twilio" {
+		smsSender = services.NewTwilioSMSSender(cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.TwilioFromNumber)
+	} else {
+		log.Println("Using Fake SMS Sender, one-time codes are only logged")
+		smsSender = services.NewFakeSMSSender()
 	}
 
 	// Initialize services
-	ingestionEngine := services.NewIngestionEngine(repo, strategyRegistry, authClient, cfg.ShadowWalletTTL)
-	conversionService := services.NewConversionService(repo)
+	notifier := services.NewLogNotifier()
+
+	// Without the listener nothing would invalidate cached merchants and campaigns, so they
+	// are not cached
+	merchantCache := services.NewMerchantCache(repo, 0)
+	campaignCache := services.NewCampaignCache(repo, 0)
+	if cfg.ListenerEnabled {
+		merchantCache = services.NewMerchantCache(repo, cfg.MerchantCacheTTL)
+		campaignCache = services.NewCampaignCache(repo, cfg.CampaignCacheTTL)
+	}
+
+	ingestionEngine := services.NewIngestionEngine(repo, strategyRegistry, authClient, cfg.ShadowWalletTTL, notifier, campaignCache)
+	conversionService := services.NewConversionService(repo, strategyRegistry)
+	simulationService := services.NewSimulationService(repo, strategyRegistry)
+	campaignLifecycle := services.NewCampaignLifecycle(repo, notifier)
+	claimService := services.NewClaimService(repo, smsSender, cfg.ShadowClaimExtension)
 
 	// Initialize workers
 	logger := &workers.SimpleLogger{}
 	expirationWorker := workers.NewExpirationWorker(repo, cfg.ExpirationWorkerInterval, logger)
+	occasionWorker := workers.NewOccasionRewardWorker(repo, cfg.OccasionWorkerInterval, logger)
+	simulationWorker := workers.NewSimulationWorker(repo, simulationService, cfg.SimulationWorkerInterval, logger)
+	campaignWorker := workers.NewCampaignLifecycleWorker(campaignLifecycle, cfg.CampaignWorkerInterval, logger)
+	conversionWorker := workers.NewConversionQueueWorker(repo, conversionService, cfg.ConversionWorkerInterval, logger)
 
-	// Start expiration worker in background
+	// Start workers in background
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	go expirationWorker.Start(ctx)
+	go occasionWorker.Start(ctx)
+	go simulationWorker.Start(ctx)
+	go campaignWorker.Start(ctx)
+	go conversionWorker.Start(ctx)
+
+	if cfg.ListenerEnabled {
+		notificationListener := workers.NewNotificationListener(cfg.ListenerDatabaseURL, logger)
+		notificationListener.Subscribe(domain.ChannelConversionQueued, conversionWorker)
+		notificationListener.Subscribe(domain.ChannelMerchantChanged, merchantCache)
+		notificationListener.Subscribe(domain.ChannelCampaignChanged, campaignCache)
+		go notificationListener.Start(ctx)
+	}
 
 	// Initialize HTTP server
-	router := setupRouter(repo, ingestionEngine, conversionService, cfg.WebhookSecret)
+	router := setupRouter(repo, strategyRegistry, ingestionEngine, conversionService, simulationService, claimService, merchantCache, notifier, tokenVerifier, cfg.WebhookSecret)
+
+	// Without trusted proxies the client IP is the connection's address, so X-Forwarded-For
+	// cannot be forged to dodge the per-IP throttling of claim codes
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	srv := &http.Server{
 		Addr:    ":" + cfg.ServerPort,
@@ -124,7 +182,7 @@ func main() {
 	log.Println("Server exited")
 }
 
-func setupRouter(repo *repository.Repository, engine *services.IngestionEngine, convService *services.ConversionService, webhookSecret string) *gin.Engine {
+func setupRouter(repo *repository.Repository, strategyRegistry domain.StrategyRegistry, engine *services.IngestionEngine, convService *services.ConversionService, simulator *services.SimulationService, claims *services.ClaimService, merchants *services.MerchantCache, notifier services.Notifier, tokenVerifier services.TokenVerifier, webhookSecret string) *gin.Engine {
 	router := gin.Default()
 
 	// CORS middleware
@@ -154,29 +212,135 @@ func setupRouter(repo *repository.Repository, engine *services.IngestionEngine,
 
 		// Protected routes (require API key)
 		protected := v1.Group("")
-		protected.Use(handlers.AuthMiddleware(repo))
+		protected.Use(handlers.AuthMiddleware(merchants))
 		{
 			// Ingestion endpoint
 			ingestHandler := handlers.NewIngestHandler(engine, repo)
 			protected.POST("/ingest", ingestHandler.Handle)
 
+			// Merchant settings
+			settingsHandler := handlers.NewSettingsHandler(repo, engine)
+			protected.GET("/settings/shadow-expiration", settingsHandler.HandleGetShadowExpiration)
+			protected.PUT("/settings/shadow-expiration", settingsHandler.HandleUpdateShadowExpiration)
+
 			// Stats endpoint
 			statsHandler := handlers.NewStatsHandler(repo, convService)
 			protected.GET("/stats", statsHandler.Handle)
 
 			// Campaign endpoints
-			campaignHandler := handlers.NewCampaignHandler(repo)
+			campaignHandler := handlers.NewCampaignHandler(repo, strategyRegistry, notifier)
 			protected.POST("/campaigns", campaignHandler.HandleCreateCampaign)
 			protected.GET("/campaigns", campaignHandler.HandleListCampaigns)
 			protected.GET("/campaigns/:id", campaignHandler.HandleGetCampaign)
 			protected.PUT("/campaigns/:id", campaignHandler.HandleUpdateCampaign)
+			protected.GET("/campaigns/:id/versions", campaignHandler.HandleListCampaignVersions)
 			protected.DELETE("/campaigns/:id", campaignHandler.HandleDeleteCampaign)
 			protected.PATCH("/campaigns/:id/toggle", campaignHandler.HandleToggleCampaign)
+			protected.POST("/campaigns/:id/status", campaignHandler.HandleChangeStatus)
+			protected.POST("/campaigns/:id/clone", campaignHandler.HandleCloneCampaign)
+
+			// Campaign templates (platform and merchant-owned)
+			templateHandler := handlers.NewTemplateHandler(repo, strategyRegistry)
+			protected.GET("/campaign-templates", templateHandler.HandleListTemplates)
+			protected.POST("/campaign-templates", templateHandler.HandleCreateTemplate)
+			protected.GET("/campaign-templates/:id", templateHandler.HandleGetTemplate)
+			protected.DELETE("/campaign-templates/:id", templateHandler.HandleDeleteTemplate)
+			protected.POST("/campaign-templates/:id/campaigns", templateHandler.HandleInstantiateTemplate)
+
+			// Customer segments for campaign targeting
+			segmentHandler := handlers.NewSegmentHandler(repo)
+			protected.GET("/segments", segmentHandler.HandleListSegments)
+			protected.POST("/segments", segmentHandler.HandleCreateSegment)
+			protected.POST("/segments/preview", segmentHandler.HandlePreviewSegment)
+			protected.GET("/segments/:id", segmentHandler.HandleGetSegment)
+			protected.PUT("/segments/:id", segmentHandler.HandleUpdateSegment)
+			protected.DELETE("/segments/:id", segmentHandler.HandleDeleteSegment)
+			protected.POST("/segments/:id/members", segmentHandler.HandleAddMembers)
+			protected.DELETE("/segments/:id/members", segmentHandler.HandleRemoveMembers)
+
+			// Banners in the app's featured carousel
+			promotionHandler := handlers.NewPromotionHandler(repo)
+			protected.GET("/promotions", promotionHandler.HandleListPromotions)
+			protected.POST("/promotions", promotionHandler.HandleCreatePromotion)
+			protected.PUT("/promotions/:id", promotionHandler.HandleUpdatePromotion)
+			protected.DELETE("/promotions/:id", promotionHandler.HandleDeletePromotion)
+
+			// Instant-win prize pools and draw audit
+			instantWinHandler := handlers.NewInstantWinHandler(repo)
+			protected.GET("/campaigns/:id/instant-win", instantWinHandler.HandleGetReport)
+			protected.GET("/campaigns/:id/instant-win/draws/:transactionId", instantWinHandler.HandleVerifyDraw)
+
+			// A/B tests and holdout groups
+			experimentHandler := handlers.NewExperimentHandler(repo, strategyRegistry)
+			protected.POST("/campaigns/:id/experiments", experimentHandler.HandleCreateExperiment)
+			protected.GET("/campaigns/:id/experiments", experimentHandler.HandleListExperiments)
+			protected.GET("/experiments/:id/results", experimentHandler.HandleGetResults)
+			protected.POST("/experiments/:id/stop", experimentHandler.HandleStopExperiment)
+
+			// Campaign backtesting over the historical ledger
+			simulationHandler := handlers.NewSimulationHandler(repo, simulator)
+			protected.POST("/simulations", simulationHandler.HandleCreateSimulation)
+			protected.GET("/simulations", simulationHandler.HandleListSimulations)
+			protected.GET("/simulations/:id", simulationHandler.HandleGetSimulation)
+			protected.GET("/simulations/:id/result", simulationHandler.HandleDownloadResult)
+
+			// Wallet endpoints
+			walletHandler := handlers.NewWalletHandler(repo, strategyRegistry)
+			protected.PUT("/wallets/:id/profile", walletHandler.HandleUpdateProfile)
+			protected.GET("/wallets/:id/transactions", walletHandler.HandleGetHistory)
+			protected.GET("/wallets/:id/progress", walletHandler.HandleGetProgress)
+			protected.GET("/shadow-balances/:id/transactions", walletHandler.HandleGetShadowHistory)
+			protected.GET("/shadow-balances/:id/progress", walletHandler.HandleGetShadowProgress)
+
+			// Manual balance adjustments with approval above the merchant's threshold. Requesting
+			// and reviewing also need the staff member's own access token.
+			adjustmentHandler := handlers.NewAdjustmentHandler(repo)
+			protected.GET("/adjustments", adjustmentHandler.HandleListAdjustments)
+
+			staff := protected.Group("")
+			staff.Use(handlers.MerchantUserMiddleware(repo, tokenVerifier))
+			staff.POST("/wallets/:id/adjustments", adjustmentHandler.HandleCreateAdjustment)
+			staff.POST("/adjustments/:id/approve", adjustmentHandler.HandleApproveAdjustment)
+			staff.POST("/adjustments/:id/reject", adjustmentHandler.HandleRejectAdjustment)
+
+			// Birthday and anniversary rewards
+			occasionHandler := handlers.NewOccasionHandler(repo)
+			protected.GET("/occasion-rewards", occasionHandler.HandleListOccasionRewards)
+			protected.PUT("/occasion-rewards/:occasion", occasionHandler.HandleUpsertOccasionReward)
+		}
+
+		// Consumer routes (require a Supabase access token)
+		me := v1.Group("/me")
+		me.Use(handlers.ConsumerAuthMiddleware(tokenVerifier))
+		{
+			meHandler := handlers.NewMeHandler(repo, strategyRegistry)
+			me.GET("", meHandler.HandleGetMe)
+			me.GET("/wallets", meHandler.HandleListWallets)
+			me.GET("/wallets/:id/transactions", meHandler.HandleGetWalletHistory)
+			me.GET("/shadow-balances", meHandler.HandleListShadowBalances)
+			me.GET("/shadow-balances/:id/transactions", meHandler.HandleGetShadowBalanceHistory)
+			me.GET("/progress", meHandler.HandleListProgress)
 		}
 
 		// Public routes (no authentication required)
 		v1.POST("/demo-request", handlers.HandleDemoRequest)
 
+		// Shadow balance claims by SMS code (public, throttled per phone and IP)
+		claimHandler := handlers.NewClaimHandler(claims)
+		v1.POST("/claims/start", claimHandler.HandleStartClaim)
+		v1.POST("/claims/verify", claimHandler.HandleVerifyClaim)
+
+		// Campaign types with config schema (public)
+		campaignTypesHandler := handlers.NewCampaignHandler(repo, strategyRegistry, notifier)
+		v1.GET("/campaign-types", campaignTypesHandler.HandleListCampaignTypes)
+
+		// App catalog: merchant discovery, categories and promotions (public)
+		discoveryHandler := handlers.NewDiscoveryHandler(repo, strategyRegistry)
+		v1.GET("/merchants/nearby", discoveryHandler.HandleNearbyMerchants)
+		v1.GET("/merchants/:id", discoveryHandler.HandleGetMerchantProfile)
+		v1.GET("/categories", discoveryHandler.HandleListCategories)
+		v1.GET("/promotions/featured", discoveryHandler.HandleListFeaturedPromotions)
+
 		// Plans endpoint (public)
 		plansHandler := handlers.NewPlansHandler(repo)
 		v1.GET("/plans", plansHandler.HandleGetPlans)
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Balance adjustment operations
+
+func (r *Repository) CreateAdjustmentWithTx(ctx context.Context, tx *sqlx.Tx, adjustment *domain.BalanceAdjustment) error {
+	query := `
+		INSERT INTO balance_adjustments (id, merchant_id, wallet_id, direction, amount, reason_code, note,
+		                                 status, requested_by, reviewed_by, reviewed_at, transaction_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+	_, err := tx.ExecContext(ctx, query,
+		adjustment.ID, adjustment.MerchantID, adjustment.WalletID, adjustment.Direction,
+		adjustment.Amount, adjustment.ReasonCode, adjustment.Note, adjustment.Status,
+		adjustment.RequestedBy, adjustment.ReviewedBy, adjustment.ReviewedAt,
+		adjustment.TransactionID, adjustment.CreatedAt,
+	)
+	return err
+}
+
+func (r *Repository) GetAdjustment(ctx context.Context, adjustmentID uuid.UUID) (*domain.BalanceAdjustment, error) {
+	var adjustment domain.BalanceAdjustment
+	query := `SELECT * FROM balance_adjustments WHERE id = $1`
+	if err := r.db.GetContext(ctx, &adjustment, query, adjustmentID); err != nil {
+		return nil, err
+	}
+	return &adjustment, nil
+}
+
+// GetAdjustmentsByMerchant lists the merchant's adjustments, newest first, optionally by status
+func (r *Repository) GetAdjustmentsByMerchant(ctx context.Context, merchantID uuid.UUID, status domain.AdjustmentStatus) ([]*domain.BalanceAdjustment, error) {
+	var adjustments []*domain.BalanceAdjustment
+	query := `
+		SELECT * FROM balance_adjustments
+		WHERE merchant_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+	`
+	err := r.db.SelectContext(ctx, &adjustments, query, merchantID, string(status))
+	return adjustments, err
+}
+
+// ReviewAdjustmentWithTx moves a pending adjustment to its reviewed status.
+// Returns false when the adjustment was no longer pending.
+func (r *Repository) ReviewAdjustmentWithTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	adjustmentID uuid.UUID,
+	status domain.AdjustmentStatus,
+	reviewedBy string,
+	transactionID *uuid.UUID,
+) (bool, error) {
+	query := `
+		UPDATE balance_adjustments
+		SET status = $1, reviewed_by = $2, reviewed_at = $3, transaction_id = $4
+		WHERE id = $5 AND status = $6
+	`
+	result, err := tx.ExecContext(ctx, query,
+		status, reviewedBy, time.Now(), transactionID, adjustmentID, domain.AdjustmentStatusPending,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// AdjustWalletBalanceWithTx adds delta to the wallet balance unless it would become negative.
+// Returns sql.ErrNoRows when the balance does not cover a debit.
+func (r *Repository) AdjustWalletBalanceWithTx(ctx context.Context, tx *sqlx.Tx, walletID uuid.UUID, delta float64) (float64, error) {
+	var balance float64
+	query := `
+		UPDATE wallets SET balance = balance + $1, updated_at = $2
+		WHERE id = $3 AND balance + $1 >= 0
+		RETURNING balance
+	`
+	err := tx.GetContext(ctx, &balance, query, delta, time.Now(), walletID)
+	return balance, err
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Budget operations
+
+// ChargeBudgetWithTx adds the reward to the campaign's budget usage only if every cap still
+// holds afterwards. The row lock taken by the UPDATE serializes concurrent charges and the
+// caps are re-checked against the committed usage, so the budget can never be overspent.
+// Returns sql.ErrNoRows when a cap would be exceeded.
+func (r *Repository) ChargeBudgetWithTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	campaign *domain.Campaign,
+	day string,
+	value float64,
+	rewards int,
+) (*domain.BudgetUsage, error) {
+	initQuery := `
+		INSERT INTO campaign_budget_usage (campaign_id, day)
+		VALUES ($1, $2::date)
+		ON CONFLICT (campaign_id) DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, initQuery, campaign.ID, day); err != nil {
+		return nil, err
+	}
+
+	var usage domain.BudgetUsage
+	query := `
+		UPDATE campaign_budget_usage
+		SET reward_value = reward_value + $2,
+		    rewards_issued = rewards_issued + $3,
+		    daily_reward_value = CASE WHEN day = $4::date THEN daily_reward_value + $2 ELSE $2 END,
+		    day = $4::date,
+		    updated_at = $8
+		WHERE campaign_id = $1
+		AND ($5::numeric IS NULL OR reward_value + $2 <= $5)
+		AND ($6::integer IS NULL OR rewards_issued + $3 <= $6)
+		AND ($7::numeric IS NULL OR (CASE WHEN day = $4::date THEN daily_reward_value ELSE 0 END) + $2 <= $7)
+		RETURNING *
+	`
+	err := tx.GetContext(ctx, &usage, query,
+		campaign.ID, value, rewards, day,
+		campaign.MaxRewardValue, campaign.MaxRewards, campaign.DailyRewardCap, time.Now(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+func (r *Repository) GetBudgetUsageWithTx(ctx context.Context, tx *sqlx.Tx, campaignID uuid.UUID) (*domain.BudgetUsage, error) {
+	var usage domain.BudgetUsage
+	query := `SELECT * FROM campaign_budget_usage WHERE campaign_id = $1`
+	if err := tx.GetContext(ctx, &usage, query, campaignID); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// GetBudgetUsageByMerchant returns the budget usage of the merchant's campaigns by campaign ID
+func (r *Repository) GetBudgetUsageByMerchant(ctx context.Context, merchantID uuid.UUID) (map[uuid.UUID]*domain.BudgetUsage, error) {
+	var usages []*domain.BudgetUsage
+	query := `
+		SELECT u.* FROM campaign_budget_usage u
+		JOIN campaigns c ON c.id = u.campaign_id
+		WHERE c.merchant_id = $1
+	`
+	if err := r.db.SelectContext(ctx, &usages, query, merchantID); err != nil {
+		return nil, err
+	}
+
+	byCampaign := make(map[uuid.UUID]*domain.BudgetUsage, len(usages))
+	for _, usage := range usages {
+		byCampaign[usage.CampaignID] = usage
+	}
+	return byCampaign, nil
+}
+
+// RecordBudgetAlertWithTx stores the alert unless it was already sent.
+// Returns false when the alert for this cap, threshold and period exists.
+func (r *Repository) RecordBudgetAlertWithTx(ctx context.Context, tx *sqlx.Tx, alert *domain.BudgetAlert) (bool, error) {
+	query := `
+		INSERT INTO campaign_budget_alerts (campaign_id, kind, threshold, period, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (campaign_id, kind, threshold, period) DO NOTHING
+	`
+	result, err := tx.ExecContext(ctx, query, alert.CampaignID, alert.Kind, alert.Threshold, alert.Period, alert.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// PauseCampaignWithTx moves an active campaign to PAUSED
+func (r *Repository) PauseCampaignWithTx(ctx context.Context, tx *sqlx.Tx, campaignID uuid.UUID) error {
+	query := `UPDATE campaigns SET status = 'PAUSED', is_active = false, updated_at = $1 WHERE id = $2 AND status = 'ACTIVE'`
+	_, err := tx.ExecContext(ctx, query, time.Now(), campaignID)
+	return err
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Campaign lifecycle operations
+
+// TransitionCampaignStatusWithTx moves the campaign from one status to another.
+// Returns false when the campaign was no longer in the from status.
+func (r *Repository) TransitionCampaignStatusWithTx(ctx context.Context, tx *sqlx.Tx, campaignID uuid.UUID, from, to domain.CampaignStatus) (bool, error) {
+	query := `
+		UPDATE campaigns
+		SET status = $1, is_active = ($1 = 'ACTIVE'), updated_at = $2
+		WHERE id = $3 AND status = $4
+	`
+	result, err := tx.ExecContext(ctx, query, string(to), time.Now(), campaignID, string(from))
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// GetCampaignsDueToStart returns scheduled campaigns whose start time has passed
+func (r *Repository) GetCampaignsDueToStart(ctx context.Context, now time.Time) ([]*domain.Campaign, error) {
+	var campaigns []*domain.Campaign
+	query := `SELECT * FROM campaigns WHERE status = 'SCHEDULED' AND starts_at <= $1 ORDER BY starts_at`
+	err := r.db.SelectContext(ctx, &campaigns, query, now)
+	return campaigns, err
+}
+
+// GetCampaignsDueToEnd returns running or paused campaigns whose end time has passed
+func (r *Repository) GetCampaignsDueToEnd(ctx context.Context, now time.Time) ([]*domain.Campaign, error) {
+	var campaigns []*domain.Campaign
+	query := `SELECT * FROM campaigns WHERE status IN ('ACTIVE', 'PAUSED') AND ends_at <= $1 ORDER BY ends_at`
+	err := r.db.SelectContext(ctx, &campaigns, query, now)
+	return campaigns, err
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// Campaign template operations
+
+func (r *Repository) CreateCampaignTemplate(ctx context.Context, template *domain.CampaignTemplate) error {
+	query := `
+		INSERT INTO campaign_templates (id, merchant_id, name, description, type, config, parameters, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		template.ID, template.MerchantID, template.Name, template.Description, template.Type,
+		template.Config, template.Parameters, template.CreatedAt, template.UpdatedAt,
+	)
+	return err
+}
+
+func (r *Repository) GetCampaignTemplate(ctx context.Context, templateID uuid.UUID) (*domain.CampaignTemplate, error) {
+	var template domain.CampaignTemplate
+	query := `SELECT * FROM campaign_templates WHERE id = $1`
+	if err := r.db.GetContext(ctx, &template, query, templateID); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetCampaignTemplatesForMerchant lists the platform templates followed by the merchant's own
+func (r *Repository) GetCampaignTemplatesForMerchant(ctx context.Context, merchantID uuid.UUID) ([]*domain.CampaignTemplate, error) {
+	var templates []*domain.CampaignTemplate
+	query := `
+		SELECT * FROM campaign_templates
+		WHERE merchant_id IS NULL OR merchant_id = $1
+		ORDER BY merchant_id NULLS FIRST, name
+	`
+	err := r.db.SelectContext(ctx, &templates, query, merchantID)
+	return templates, err
+}
+
+// DeleteCampaignTemplate removes one of the merchant's templates; platform templates cannot be deleted
+func (r *Repository) DeleteCampaignTemplate(ctx context.Context, templateID, merchantID uuid.UUID) (bool, error) {
+	query := `DELETE FROM campaign_templates WHERE id = $1 AND merchant_id = $2`
+	result, err := r.db.ExecContext(ctx, query, templateID, merchantID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
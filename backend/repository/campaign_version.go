@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Campaign version operations
+
+func (r *Repository) CreateCampaignVersionWithTx(ctx context.Context, tx *sqlx.Tx, version *domain.CampaignVersion) error {
+	query := `
+		INSERT INTO campaign_versions (id, campaign_id, version, config, migration_mode, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := tx.ExecContext(ctx, query,
+		version.ID, version.CampaignID, version.Version,
+		version.Config, version.MigrationMode, version.CreatedAt,
+	)
+	return err
+}
+
+func (r *Repository) GetCampaignVersion(ctx context.Context, campaignID uuid.UUID, version int) (*domain.CampaignVersion, error) {
+	var campaignVersion domain.CampaignVersion
+	query := `SELECT * FROM campaign_versions WHERE campaign_id = $1 AND version = $2`
+	if err := r.db.GetContext(ctx, &campaignVersion, query, campaignID, version); err != nil {
+		return nil, err
+	}
+	return &campaignVersion, nil
+}
+
+func (r *Repository) GetCampaignVersionByID(ctx context.Context, versionID uuid.UUID) (*domain.CampaignVersion, error) {
+	var campaignVersion domain.CampaignVersion
+	query := `SELECT * FROM campaign_versions WHERE id = $1`
+	if err := r.db.GetContext(ctx, &campaignVersion, query, versionID); err != nil {
+		return nil, err
+	}
+	return &campaignVersion, nil
+}
+
+func (r *Repository) GetCampaignVersions(ctx context.Context, campaignID uuid.UUID) ([]*domain.CampaignVersion, error) {
+	var versions []*domain.CampaignVersion
+	query := `SELECT * FROM campaign_versions WHERE campaign_id = $1 ORDER BY version DESC`
+	err := r.db.SelectContext(ctx, &versions, query, campaignID)
+	return versions, err
+}
+
+// GetCampaignVersionsWithTx lists the campaign's versions inside a transaction, newest first
+func (r *Repository) GetCampaignVersionsWithTx(ctx context.Context, tx *sqlx.Tx, campaignID uuid.UUID) ([]*domain.CampaignVersion, error) {
+	var versions []*domain.CampaignVersion
+	query := `SELECT * FROM campaign_versions WHERE campaign_id = $1 ORDER BY version DESC`
+	err := tx.SelectContext(ctx, &versions, query, campaignID)
+	return versions, err
+}
+
+// GetCampaignForUpdateWithTx locks the campaign row until the transaction ends
+func (r *Repository) GetCampaignForUpdateWithTx(ctx context.Context, tx *sqlx.Tx, campaignID uuid.UUID) (*domain.Campaign, error) {
+	var campaign domain.Campaign
+	query := `SELECT * FROM campaigns WHERE id = $1 FOR UPDATE`
+	if err := tx.GetContext(ctx, &campaign, query, campaignID); err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// UpdateCampaignWithTx updates the campaign row, including its current config version
+func (r *Repository) UpdateCampaignWithTx(ctx context.Context, tx *sqlx.Tx, campaign *domain.Campaign) error {
+	query := `
+		UPDATE campaigns
+		SET name = $1, config = $2, starts_at = $3, ends_at = $4, updated_at = $5, current_version = $6,
+		    max_reward_value = $7, max_rewards = $8, daily_reward_cap = $9, segment_id = $10
+		WHERE id = $11
+	`
+	_, err := tx.ExecContext(ctx, query,
+		campaign.Name, campaign.Config, campaign.StartsAt,
+		campaign.EndsAt, campaign.UpdatedAt, campaign.CurrentVersion,
+		campaign.MaxRewardValue, campaign.MaxRewards, campaign.DailyRewardCap, campaign.SegmentID, campaign.ID,
+	)
+	return err
+}
+
+// PinUnversionedStateWithTx assigns a config version to the campaign's wallets and open shadow
+// balances whose state was built before versions were tracked
+func (r *Repository) PinUnversionedStateWithTx(ctx context.Context, tx *sqlx.Tx, merchantID, campaignID, versionID uuid.UUID) error {
+	walletQuery := `
+		UPDATE wallets SET campaign_version_id = $1
+		WHERE merchant_id = $2 AND campaign_id = $3 AND campaign_version_id IS NULL
+	`
+	if _, err := tx.ExecContext(ctx, walletQuery, versionID, merchantID, campaignID); err != nil {
+		return err
+	}
+
+	shadowQuery := `
+		UPDATE shadow_balances SET campaign_version_id = $1
+		WHERE merchant_id = $2 AND campaign_id = $3 AND campaign_version_id IS NULL AND converted_at IS NULL
+	`
+	_, err := tx.ExecContext(ctx, shadowQuery, versionID, merchantID, campaignID)
+	return err
+}
+
+// GetCampaignWalletsForUpdateWithTx locks the merchant's wallets whose state belongs to the campaign
+func (r *Repository) GetCampaignWalletsForUpdateWithTx(ctx context.Context, tx *sqlx.Tx, merchantID, campaignID uuid.UUID) ([]*domain.Wallet, error) {
+	var wallets []*domain.Wallet
+	query := `SELECT * FROM wallets WHERE merchant_id = $1 AND campaign_id = $2 FOR UPDATE`
+	err := tx.SelectContext(ctx, &wallets, query, merchantID, campaignID)
+	return wallets, err
+}
+
+// GetCampaignShadowBalancesForUpdateWithTx locks the merchant's open shadow balances whose
+// state belongs to the campaign
+func (r *Repository) GetCampaignShadowBalancesForUpdateWithTx(ctx context.Context, tx *sqlx.Tx, merchantID, campaignID uuid.UUID) ([]*domain.ShadowBalance, error) {
+	var shadows []*domain.ShadowBalance
+	query := `
+		SELECT * FROM shadow_balances
+		WHERE merchant_id = $1 AND campaign_id = $2 AND converted_at IS NULL
+		FOR UPDATE
+	`
+	err := tx.SelectContext(ctx, &shadows, query, merchantID, campaignID)
+	return shadows, err
+}
+
+func (r *Repository) UpdateWalletStateWithTx(ctx context.Context, tx *sqlx.Tx, walletID uuid.UUID, state json.RawMessage, versionID uuid.UUID) error {
+	query := `UPDATE wallets SET state = $1, campaign_version_id = $2, updated_at = $3 WHERE id = $4`
+	_, err := tx.ExecContext(ctx, query, state, versionID, time.Now(), walletID)
+	return err
+}
+
+func (r *Repository) UpdateShadowBalanceStateWithTx(ctx context.Context, tx *sqlx.Tx, shadowID uuid.UUID, state json.RawMessage, versionID uuid.UUID) error {
+	query := `UPDATE shadow_balances SET state = $1, campaign_version_id = $2 WHERE id = $3`
+	_, err := tx.ExecContext(ctx, query, state, versionID, shadowID)
+	return err
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// Catalog operations
+
+// GetCategories returns the app's merchant categories in display order
+func (r *Repository) GetCategories(ctx context.Context) ([]*domain.Category, error) {
+	categories := []*domain.Category{}
+	query := `SELECT id, name, slug, icon_key, color_hex, sort_order FROM categories ORDER BY sort_order, name`
+	err := r.db.SelectContext(ctx, &categories, query)
+	return categories, err
+}
+
+// GetMerchantProfile returns a merchant's public profile with its running campaigns
+func (r *Repository) GetMerchantProfile(ctx context.Context, merchantID uuid.UUID) (*domain.MerchantProfile, error) {
+	var profile domain.MerchantProfile
+	query := `
+		SELECT id, name, address, logo_url, banner_url, category, category_id, latitude, longitude
+		FROM merchants WHERE id = $1
+	`
+	if err := r.db.GetContext(ctx, &profile, query, merchantID); err != nil {
+		return nil, err
+	}
+
+	campaigns, err := r.GetActiveCampaigns(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	profile.Campaigns = make([]*domain.CampaignSummary, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		profile.Campaigns = append(profile.Campaigns, &domain.CampaignSummary{
+			ID:     campaign.ID,
+			Name:   campaign.Name,
+			Type:   campaign.Type,
+			EndsAt: campaign.EndsAt,
+		})
+	}
+
+	return &profile, nil
+}
+
+// Promotion operations
+
+// promotionColumns lists the columns read into domain.Promotion, since featured_promotions may
+// carry extra columns in projects where it predates the migration
+const promotionColumns = `
+	id, merchant_id, campaign_id, title, subtitle, image_url, background_color,
+	priority, is_active, starts_at, ends_at, created_at, updated_at
+`
+
+func (r *Repository) CreatePromotion(ctx context.Context, promotion *domain.Promotion) error {
+	query := `
+		INSERT INTO featured_promotions (id, merchant_id, campaign_id, title, subtitle, image_url,
+			background_color, priority, is_active, starts_at, ends_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		promotion.ID, promotion.MerchantID, promotion.CampaignID, promotion.Title, promotion.Subtitle,
+		promotion.ImageURL, promotion.BackgroundColor, promotion.Priority, promotion.IsActive,
+		promotion.StartsAt, promotion.EndsAt, promotion.CreatedAt, promotion.UpdatedAt,
+	)
+	return err
+}
+
+func (r *Repository) UpdatePromotion(ctx context.Context, promotion *domain.Promotion) error {
+	query := `
+		UPDATE featured_promotions
+		SET campaign_id = $1, title = $2, subtitle = $3, image_url = $4, background_color = $5,
+		    priority = $6, is_active = $7, starts_at = $8, ends_at = $9, updated_at = $10
+		WHERE id = $11
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		promotion.CampaignID, promotion.Title, promotion.Subtitle, promotion.ImageURL,
+		promotion.BackgroundColor, promotion.Priority, promotion.IsActive, promotion.StartsAt,
+		promotion.EndsAt, promotion.UpdatedAt, promotion.ID,
+	)
+	return err
+}
+
+func (r *Repository) GetPromotion(ctx context.Context, promotionID uuid.UUID) (*domain.Promotion, error) {
+	var promotion domain.Promotion
+	query := `SELECT ` + promotionColumns + ` FROM featured_promotions WHERE id = $1`
+	if err := r.db.GetContext(ctx, &promotion, query, promotionID); err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// GetPromotionsByMerchant returns all of a merchant's promotions, including scheduled and
+// finished ones
+func (r *Repository) GetPromotionsByMerchant(ctx context.Context, merchantID uuid.UUID) ([]*domain.Promotion, error) {
+	promotions := []*domain.Promotion{}
+	query := `SELECT ` + promotionColumns + ` FROM featured_promotions WHERE merchant_id = $1 ORDER BY priority DESC, created_at DESC`
+	err := r.db.SelectContext(ctx, &promotions, query, merchantID)
+	return promotions, err
+}
+
+func (r *Repository) DeletePromotion(ctx context.Context, promotionID uuid.UUID) error {
+	query := `DELETE FROM featured_promotions WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, promotionID)
+	return err
+}
+
+// GetFeaturedPromotions returns the promotions on air at now, highest priority first. A
+// merchant ID restricts them to that merchant, a category ID to its merchants and platform
+// promotions.
+func (r *Repository) GetFeaturedPromotions(ctx context.Context, now time.Time, merchantID, categoryID *uuid.UUID, limit int) ([]*domain.Promotion, error) {
+	promotions := []*domain.Promotion{}
+	query := `
+		SELECT ` + promotionColumns + ` FROM featured_promotions p
+		WHERE is_active
+		AND (starts_at IS NULL OR starts_at <= $1)
+		AND (ends_at IS NULL OR ends_at > $1)
+		AND (campaign_id IS NULL OR EXISTS (
+			SELECT 1 FROM campaigns c WHERE c.id = p.campaign_id AND c.status = 'ACTIVE'
+		))
+		AND ($2::uuid IS NULL OR merchant_id = $2)
+		AND ($3::uuid IS NULL OR merchant_id IS NULL OR EXISTS (
+			SELECT 1 FROM merchants m WHERE m.id = p.merchant_id AND m.category_id = $3
+		))
+		ORDER BY priority DESC, starts_at DESC NULLS LAST, created_at DESC
+		LIMIT $4
+	`
+	err := r.db.SelectContext(ctx, &promotions, query, now, merchantID, categoryID, limit)
+	return promotions, err
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// Claim operations
+
+func (r *Repository) CreateOTPCode(ctx context.Context, otp *domain.OTPCode) error {
+	query := `
+		INSERT INTO otp_codes (id, phone_hash, purpose, code_hash, attempts, ip_address, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		otp.ID, otp.PhoneHash, otp.Purpose, otp.CodeHash, otp.Attempts,
+		otp.IPAddress, otp.ExpiresAt, otp.CreatedAt,
+	)
+	return err
+}
+
+// GetLatestOTPCode returns the newest code sent to a phone for the purpose
+func (r *Repository) GetLatestOTPCode(ctx context.Context, phoneHash string, purpose domain.OTPPurpose) (*domain.OTPCode, error) {
+	var otp domain.OTPCode
+	query := `
+		SELECT * FROM otp_codes
+		WHERE phone_hash = $1 AND purpose = $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	if err := r.db.GetContext(ctx, &otp, query, phoneHash, purpose); err != nil {
+		return nil, err
+	}
+	return &otp, nil
+}
+
+// CountOTPCodesSince counts the codes sent to a phone since the given time
+func (r *Repository) CountOTPCodesSince(ctx context.Context, phoneHash string, since time.Time) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM otp_codes WHERE phone_hash = $1 AND created_at >= $2`
+	err := r.db.GetContext(ctx, &count, query, phoneHash, since)
+	return count, err
+}
+
+// CountOTPCodesFromIPSince counts the codes requested from an IP address since the given time
+func (r *Repository) CountOTPCodesFromIPSince(ctx context.Context, ipAddress string, since time.Time) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM otp_codes WHERE ip_address = $1 AND created_at >= $2`
+	err := r.db.GetContext(ctx, &count, query, ipAddress, since)
+	return count, err
+}
+
+// RecordOTPAttempt counts a verification attempt before the code is compared, so concurrent
+// guesses cannot exceed maxAttempts. It returns false when the code is used, expired or out
+// of attempts.
+func (r *Repository) RecordOTPAttempt(ctx context.Context, otpID uuid.UUID, maxAttempts int) (bool, error) {
+	var attempts int
+	query := `
+		UPDATE otp_codes SET attempts = attempts + 1
+		WHERE id = $1 AND attempts < $2 AND consumed_at IS NULL AND expires_at > NOW()
+		RETURNING attempts
+	`
+	err := r.db.GetContext(ctx, &attempts, query, otpID, maxAttempts)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ConsumeOTPCode marks a code as used, returning false when it was already used
+func (r *Repository) ConsumeOTPCode(ctx context.Context, otpID uuid.UUID) (bool, error) {
+	query := `UPDATE otp_codes SET consumed_at = NOW() WHERE id = $1 AND consumed_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, otpID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// ClaimShadowBalances marks the phone's active shadow balances as claimed, extending their
+// expiry to at least expiresAt and attaching the email when one is given. It returns how many
+// balances were claimed.
+func (r *Repository) ClaimShadowBalances(ctx context.Context, phoneHash string, expiresAt time.Time, email *string) (int, error) {
+	query := `
+		UPDATE shadow_balances
+		SET expires_at = GREATEST(expires_at, $2), claimed_at = NOW(), claim_email = COALESCE($3, claim_email)
+		WHERE phone_hash = $1
+		AND converted_at IS NULL
+		AND expires_at > NOW()
+	`
+	result, err := r.db.ExecContext(ctx, query, phoneHash, expiresAt, email)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rows), nil
+}
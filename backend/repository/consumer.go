@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// Consumer operations
+
+// GetConsumerWallets returns the user's wallets across all merchants
+func (r *Repository) GetConsumerWallets(ctx context.Context, userID uuid.UUID) ([]*domain.ConsumerWallet, error) {
+	wallets := []*domain.ConsumerWallet{}
+	query := `
+		SELECT w.id, w.merchant_id, m.name AS merchant_name, m.logo_url AS merchant_logo_url,
+		       w.balance, w.created_at, w.updated_at
+		FROM wallets w
+		JOIN merchants m ON m.id = w.merchant_id
+		WHERE w.user_id = $1
+		ORDER BY m.name
+	`
+	err := r.db.SelectContext(ctx, &wallets, query, userID)
+	return wallets, err
+}
+
+// GetConsumerShadowBalances returns the unclaimed, unexpired shadow balances of a phone
+func (r *Repository) GetConsumerShadowBalances(ctx context.Context, phoneHash string) ([]*domain.ConsumerShadowBalance, error) {
+	shadows := []*domain.ConsumerShadowBalance{}
+	query := `
+		SELECT s.id, s.merchant_id, m.name AS merchant_name, m.logo_url AS merchant_logo_url,
+		       s.amount, s.expires_at, s.created_at
+		FROM shadow_balances s
+		JOIN merchants m ON m.id = s.merchant_id
+		WHERE s.phone_hash = $1
+		AND s.converted_at IS NULL
+		AND s.expires_at > NOW()
+		ORDER BY s.expires_at
+	`
+	err := r.db.SelectContext(ctx, &shadows, query, phoneHash)
+	return shadows, err
+}
+
+// progressQuery attributes the balances of a "balances" CTE to campaigns. State is attributed
+// to the campaign of its config version, or to its owner campaign when it is unversioned, or
+// to the merchant's newest running campaign when it has neither.
+const progressQuery = `
+	SELECT b.merchant_id, m.name AS merchant_name,
+	       c.id AS campaign_id, c.name AS campaign_name, c.type AS campaign_type, c.status AS campaign_status,
+	       b.balance, b.state, b.registered, b.expires_at, COALESCE(cv.config, c.config) AS config
+	FROM balances b
+	JOIN merchants m ON m.id = b.merchant_id
+	LEFT JOIN campaign_versions cv ON cv.id = b.campaign_version_id
+	LEFT JOIN campaigns c ON c.id = COALESCE(cv.campaign_id, b.campaign_id, (
+		SELECT id FROM campaigns
+		WHERE merchant_id = b.merchant_id AND status = 'ACTIVE'
+		ORDER BY created_at DESC
+		LIMIT 1
+	))
+	ORDER BY m.name, b.registered DESC
+`
+
+// GetConsumerProgress returns the campaign state of the user's wallets and, when phoneHash is
+// not empty, of the phone's unclaimed shadow balances
+func (r *Repository) GetConsumerProgress(ctx context.Context, userID uuid.UUID, phoneHash string) ([]*domain.CampaignProgress, error) {
+	progress := []*domain.CampaignProgress{}
+	query := `
+		WITH balances AS (
+			SELECT merchant_id, balance, state, campaign_id, campaign_version_id, TRUE AS registered,
+			       NULL::timestamptz AS expires_at
+			FROM wallets WHERE user_id = $1
+			UNION ALL
+			SELECT merchant_id, amount, state, campaign_id, campaign_version_id, FALSE, expires_at
+			FROM shadow_balances
+			WHERE $2 <> '' AND phone_hash = $2 AND converted_at IS NULL AND expires_at > NOW()
+		)
+	` + progressQuery
+	err := r.db.SelectContext(ctx, &progress, query, userID, phoneHash)
+	return progress, err
+}
+
+// GetWalletProgress returns the campaign state of a wallet
+func (r *Repository) GetWalletProgress(ctx context.Context, walletID uuid.UUID) (*domain.CampaignProgress, error) {
+	var progress domain.CampaignProgress
+	query := `
+		WITH balances AS (
+			SELECT merchant_id, balance, state, campaign_id, campaign_version_id, TRUE AS registered,
+			       NULL::timestamptz AS expires_at
+			FROM wallets WHERE id = $1
+		)
+	` + progressQuery
+	if err := r.db.GetContext(ctx, &progress, query, walletID); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// GetShadowBalanceProgress returns the campaign state of a shadow balance
+func (r *Repository) GetShadowBalanceProgress(ctx context.Context, shadowID uuid.UUID) (*domain.CampaignProgress, error) {
+	var progress domain.CampaignProgress
+	query := `
+		WITH balances AS (
+			SELECT merchant_id, amount AS balance, state, campaign_id, campaign_version_id, FALSE AS registered, expires_at
+			FROM shadow_balances WHERE id = $1
+		)
+	` + progressQuery
+	if err := r.db.GetContext(ctx, &progress, query, shadowID); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// Conversion queue operations
+
+// ClaimConversionQueueBatch takes up to limit due entries, counting the attempt and leasing
+// them until leaseUntil so an entry left behind by a crashed worker is picked up again.
+// SKIP LOCKED lets several API instances drain the queue without taking the same entry.
+func (r *Repository) ClaimConversionQueueBatch(ctx context.Context, limit int, leaseUntil time.Time) ([]*domain.ConversionQueueItem, error) {
+	var items []*domain.ConversionQueueItem
+	query := `
+		UPDATE shadow_conversion_queue
+		SET attempts = attempts + 1, next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM shadow_conversion_queue
+			WHERE processed = false
+			AND failed_at IS NULL
+			AND next_attempt_at <= $2
+			ORDER BY created_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`
+	err := r.db.SelectContext(ctx, &items, query, leaseUntil, time.Now(), limit)
+	return items, err
+}
+
+// ClaimConversionQueueItem takes a single entry when it is due, returning sql.ErrNoRows when
+// it was already processed or another worker holds it
+func (r *Repository) ClaimConversionQueueItem(ctx context.Context, itemID uuid.UUID, leaseUntil time.Time) (*domain.ConversionQueueItem, error) {
+	var item domain.ConversionQueueItem
+	query := `
+		UPDATE shadow_conversion_queue
+		SET attempts = attempts + 1, next_attempt_at = $1
+		WHERE id = (
+			SELECT id FROM shadow_conversion_queue
+			WHERE id = $2
+			AND processed = false
+			AND failed_at IS NULL
+			AND next_attempt_at <= $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`
+	if err := r.db.GetContext(ctx, &item, query, leaseUntil, itemID, time.Now()); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *Repository) CompleteConversionQueueItem(ctx context.Context, itemID uuid.UUID) error {
+	query := `UPDATE shadow_conversion_queue SET processed = true, processed_at = $1 WHERE id = $2`
+	_, err := r.db.ExecContext(ctx, query, time.Now(), itemID)
+	return err
+}
+
+// RetryConversionQueueItem records why the attempt failed and when to try again
+func (r *Repository) RetryConversionQueueItem(ctx context.Context, itemID uuid.UUID, message string, nextAttemptAt time.Time) error {
+	query := `UPDATE shadow_conversion_queue SET last_error = $1, next_attempt_at = $2 WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, message, nextAttemptAt, itemID)
+	return err
+}
+
+// FailConversionQueueItem gives up on the entry, keeping the reason
+func (r *Repository) FailConversionQueueItem(ctx context.Context, itemID uuid.UUID, message string) error {
+	query := `UPDATE shadow_conversion_queue SET last_error = $1, failed_at = $2 WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, message, time.Now(), itemID)
+	return err
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Customer limit operations
+
+// LockCustomerWithTx takes a transaction-scoped advisory lock on the customer's phone hash
+// in the campaign, so concurrent purchases of the same customer are evaluated against the
+// limits one at a time. Keying on the phone rather than a balance row also serializes a
+// purchase on the wallet with one racing on a shadow balance that is being converted.
+func (r *Repository) LockCustomerWithTx(ctx context.Context, tx *sqlx.Tx, campaignID uuid.UUID, phoneHash string) error {
+	query := `SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))`
+	_, err := tx.ExecContext(ctx, query, campaignID.String(), phoneHash)
+	return err
+}
+
+// GetCustomerEarnHistoryWithTx summarizes the customer's EARN entries in the campaign since
+// the given instants, across their wallet and any shadow balance (converted or not)
+func (r *Repository) GetCustomerEarnHistoryWithTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	campaignID uuid.UUID,
+	phoneHash string,
+	dayStart, monthStart, windowStart time.Time,
+) (*domain.CustomerEarnHistory, error) {
+	var history domain.CustomerEarnHistory
+	query := `
+		SELECT COUNT(*) FILTER (WHERE t.amount > 0 AND t.created_at >= $3) AS rewards_today,
+		       COALESCE(SUM(t.amount) FILTER (WHERE t.created_at >= $3), 0) AS value_today,
+		       COALESCE(SUM(t.amount) FILTER (WHERE t.created_at >= $4), 0) AS value_this_month,
+		       COUNT(*) FILTER (WHERE t.created_at >= $5) AS visits_in_window
+		FROM transactions t
+		LEFT JOIN wallets w ON w.id = t.wallet_id
+		LEFT JOIN shadow_balances s ON s.id = t.shadow_balance_id
+		WHERE t.campaign_id = $1
+		AND t.transaction_type = $6
+		AND (w.phone_hash = $2 OR s.phone_hash = $2)
+		AND t.created_at >= LEAST($3::timestamptz, $4::timestamptz, $5::timestamptz)
+	`
+	err := tx.GetContext(ctx, &history, query,
+		campaignID, phoneHash, dayStart, monthStart, windowStart, domain.TransactionTypeEarn,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+)
+
+// Discovery operations
+
+// GetNearbyMerchants returns the merchants within the query radius, nearest first. A
+// bounding box on the indexed coordinates narrows the candidates before the haversine
+// distance is computed.
+func (r *Repository) GetNearbyMerchants(ctx context.Context, q *domain.NearbyQuery) ([]*domain.NearbyMerchant, error) {
+	minLat, maxLat, minLng, maxLng := q.BoundingBox()
+
+	merchants := []*domain.NearbyMerchant{}
+	query := `
+		WITH candidates AS (
+			SELECT m.id, m.name, m.address, m.logo_url, m.banner_url, m.category, m.category_id,
+			       m.latitude, m.longitude,
+			       2 * 6371 * ASIN(SQRT(
+			           POWER(SIN(RADIANS(m.latitude - $1) / 2), 2) +
+			           COS(RADIANS($1)) * COS(RADIANS(m.latitude)) * POWER(SIN(RADIANS(m.longitude - $2) / 2), 2)
+			       )) AS distance_km
+			FROM merchants m
+			WHERE m.latitude BETWEEN $3 AND $4
+			AND m.longitude BETWEEN $5 AND $6
+			AND ($7::text = '' OR LOWER(m.category) = LOWER($7))
+			AND ($8::uuid IS NULL OR m.category_id = $8)
+		)
+		SELECT c.*,
+		       (SELECT COUNT(*) FROM campaigns WHERE merchant_id = c.id AND status = 'ACTIVE') AS campaign_count,
+		       ac.id AS active_campaign_id, ac.name AS active_campaign_name,
+		       ac.type AS active_campaign_type, ac.ends_at AS active_campaign_ends_at
+		FROM candidates c
+		LEFT JOIN LATERAL (
+			SELECT id, name, type, ends_at FROM campaigns
+			WHERE merchant_id = c.id AND status = 'ACTIVE'
+			ORDER BY created_at DESC
+			LIMIT 1
+		) ac ON TRUE
+		WHERE c.distance_km <= $9
+		AND (NOT $10 OR ac.id IS NOT NULL)
+		ORDER BY c.distance_km, c.name
+		LIMIT $11
+	`
+	err := r.db.SelectContext(ctx, &merchants, query,
+		q.Latitude, q.Longitude, minLat, maxLat, minLng, maxLng,
+		q.Category, q.CategoryID, q.RadiusKm, q.ActiveCampaign, q.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, merchant := range merchants {
+		if merchant.ActiveCampaignID != nil {
+			merchant.ActiveCampaign = &domain.CampaignSummary{
+				ID:     *merchant.ActiveCampaignID,
+				Name:   *merchant.ActiveCampaignName,
+				Type:   *merchant.ActiveCampaignType,
+				EndsAt: merchant.ActiveCampaignEndsAt,
+			}
+		}
+	}
+	return merchants, nil
+}
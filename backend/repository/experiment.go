@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// Experiment operations
+
+// CreateExperiment stores the experiment and its variants atomically
+func (r *Repository) CreateExperiment(ctx context.Context, experiment *domain.Experiment) error {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO experiments (id, campaign_id, name, is_active, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := tx.ExecContext(ctx, query,
+		experiment.ID, experiment.CampaignID, experiment.Name, experiment.IsActive, experiment.CreatedAt,
+	); err != nil {
+		return err
+	}
+
+	variantQuery := `
+		INSERT INTO experiment_variants (id, experiment_id, name, weight, is_control, config, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	for _, variant := range experiment.Variants {
+		if _, err := tx.ExecContext(ctx, variantQuery,
+			variant.ID, variant.ExperimentID, variant.Name, variant.Weight,
+			variant.IsControl, variant.Config, variant.Position,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) GetExperiment(ctx context.Context, experimentID uuid.UUID) (*domain.Experiment, error) {
+	var experiment domain.Experiment
+	query := `SELECT * FROM experiments WHERE id = $1`
+	if err := r.db.GetContext(ctx, &experiment, query, experimentID); err != nil {
+		return nil, err
+	}
+
+	variants, err := r.GetExperimentVariants(ctx, experimentID)
+	if err != nil {
+		return nil, err
+	}
+	experiment.Variants = variants
+
+	return &experiment, nil
+}
+
+// GetActiveExperiment returns the running experiment of a campaign with its variants
+func (r *Repository) GetActiveExperiment(ctx context.Context, campaignID uuid.UUID) (*domain.Experiment, error) {
+	var experiment domain.Experiment
+	query := `SELECT * FROM experiments WHERE campaign_id = $1 AND is_active = true`
+	if err := r.db.GetContext(ctx, &experiment, query, campaignID); err != nil {
+		return nil, err
+	}
+
+	variants, err := r.GetExperimentVariants(ctx, experiment.ID)
+	if err != nil {
+		return nil, err
+	}
+	experiment.Variants = variants
+
+	return &experiment, nil
+}
+
+func (r *Repository) GetExperimentsByCampaign(ctx context.Context, campaignID uuid.UUID) ([]*domain.Experiment, error) {
+	var experiments []*domain.Experiment
+	query := `SELECT * FROM experiments WHERE campaign_id = $1 ORDER BY created_at DESC`
+	err := r.db.SelectContext(ctx, &experiments, query, campaignID)
+	return experiments, err
+}
+
+func (r *Repository) GetExperimentVariants(ctx context.Context, experimentID uuid.UUID) ([]*domain.ExperimentVariant, error) {
+	var variants []*domain.ExperimentVariant
+	query := `SELECT * FROM experiment_variants WHERE experiment_id = $1 ORDER BY position`
+	err := r.db.SelectContext(ctx, &variants, query, experimentID)
+	return variants, err
+}
+
+func (r *Repository) StopExperiment(ctx context.Context, experimentID uuid.UUID) error {
+	query := `UPDATE experiments SET is_active = false, ended_at = $1 WHERE id = $2 AND is_active = true`
+	_, err := r.db.ExecContext(ctx, query, time.Now(), experimentID)
+	return err
+}
+
+// AssignExperimentVariant stores the customer's variant unless one was already assigned,
+// and returns the variant the customer is in
+func (r *Repository) AssignExperimentVariant(ctx context.Context, assignment *domain.ExperimentAssignment) (uuid.UUID, error) {
+	insertQuery := `
+		INSERT INTO experiment_assignments (experiment_id, phone_hash, variant_id, assigned_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (experiment_id, phone_hash) DO NOTHING
+	`
+	if _, err := r.db.ExecContext(ctx, insertQuery,
+		assignment.ExperimentID, assignment.PhoneHash, assignment.VariantID, assignment.AssignedAt,
+	); err != nil {
+		return uuid.Nil, err
+	}
+
+	var variantID uuid.UUID
+	query := `SELECT variant_id FROM experiment_assignments WHERE experiment_id = $1 AND phone_hash = $2`
+	err := r.db.GetContext(ctx, &variantID, query, assignment.ExperimentID, assignment.PhoneHash)
+	return variantID, err
+}
+
+// GetExperimentCustomerMetrics returns, for every assigned customer, the visits and spend
+// recorded under their variant and whether they redeemed since being assigned
+func (r *Repository) GetExperimentCustomerMetrics(ctx context.Context, experimentID, merchantID uuid.UUID) ([]*domain.ExperimentCustomerMetrics, error) {
+	var metrics []*domain.ExperimentCustomerMetrics
+	query := `
+		WITH customer_transactions AS (
+			SELECT t.id, t.transaction_type, t.purchase_amount, t.experiment_variant_id, t.created_at,
+			       COALESCE(w.phone_hash, s.phone_hash) AS phone_hash
+			FROM transactions t
+			LEFT JOIN wallets w ON w.id = t.wallet_id
+			LEFT JOIN shadow_balances s ON s.id = t.shadow_balance_id
+			WHERE t.merchant_id = $2 AND t.transaction_type IN ($3, $4)
+		)
+		SELECT a.variant_id, a.phone_hash,
+		       COUNT(ct.id) FILTER (WHERE ct.transaction_type = $3 AND ct.experiment_variant_id = a.variant_id) AS visits,
+		       COALESCE(SUM(ct.purchase_amount) FILTER (WHERE ct.transaction_type = $3 AND ct.experiment_variant_id = a.variant_id), 0) AS spend,
+		       COALESCE(BOOL_OR(ct.transaction_type = $4), false) AS redeemed
+		FROM experiment_assignments a
+		LEFT JOIN customer_transactions ct ON ct.phone_hash = a.phone_hash AND ct.created_at >= a.assigned_at
+		WHERE a.experiment_id = $1
+		GROUP BY a.variant_id, a.phone_hash
+	`
+	err := r.db.SelectContext(ctx, &metrics, query, experimentID, merchantID, domain.TransactionTypeEarn, domain.TransactionTypeRedeem)
+	return metrics, err
+}
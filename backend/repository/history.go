@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// History operations
+
+func (r *Repository) GetShadowBalanceByID(ctx context.Context, shadowID uuid.UUID) (*domain.ShadowBalance, error) {
+	var shadow domain.ShadowBalance
+	query := `SELECT * FROM shadow_balances WHERE id = $1`
+	if err := r.db.GetContext(ctx, &shadow, query, shadowID); err != nil {
+		return nil, err
+	}
+	return &shadow, nil
+}
+
+// GetWalletHistory returns a page of the customer's ledger entries, including the ones
+// recorded on their shadow balances before sign-up
+func (r *Repository) GetWalletHistory(ctx context.Context, wallet *domain.Wallet, filter *domain.HistoryFilter) (*domain.HistoryPage, error) {
+	return r.getHistory(ctx, wallet.MerchantID, &wallet.ID, nil, wallet.PhoneHash, filter)
+}
+
+// GetShadowBalanceHistory returns a page of a shadow balance's ledger entries
+func (r *Repository) GetShadowBalanceHistory(ctx context.Context, shadow *domain.ShadowBalance, filter *domain.HistoryFilter) (*domain.HistoryPage, error) {
+	return r.getHistory(ctx, shadow.MerchantID, nil, &shadow.ID, "", filter)
+}
+
+// getHistory pages through the entries of a wallet, a shadow balance, or every shadow balance
+// of phoneHash (when not empty) at the merchant, newest first by (created_at, id)
+func (r *Repository) getHistory(
+	ctx context.Context,
+	merchantID uuid.UUID,
+	walletID, shadowID *uuid.UUID,
+	phoneHash string,
+	filter *domain.HistoryFilter,
+) (*domain.HistoryPage, error) {
+	var types []string
+	for _, t := range filter.Types {
+		types = append(types, string(t))
+	}
+
+	var afterAt, afterID interface{}
+	if filter.After != nil {
+		afterAt, afterID = filter.After.CreatedAt, filter.After.ID
+	}
+
+	entries := []*domain.HistoryEntry{}
+	query := `
+		SELECT t.*, c.name AS campaign_name
+		FROM transactions t
+		LEFT JOIN campaigns c ON c.id = t.campaign_id
+		WHERE t.merchant_id = $1
+		AND (t.wallet_id = $2
+		     OR t.shadow_balance_id = $3
+		     OR ($4 <> '' AND t.shadow_balance_id IN (SELECT id FROM shadow_balances WHERE merchant_id = $1 AND phone_hash = $4)))
+		AND ($5::text[] IS NULL OR t.transaction_type::text = ANY($5::text[]))
+		AND ($6::timestamptz IS NULL OR t.created_at >= $6)
+		AND ($7::timestamptz IS NULL OR t.created_at < $7)
+		AND ($8::uuid IS NULL OR t.campaign_id = $8)
+		AND ($9::timestamptz IS NULL OR (t.created_at, t.id) < ($9::timestamptz, $10::uuid))
+		ORDER BY t.created_at DESC, t.id DESC
+		LIMIT $11
+	`
+	// One extra row tells whether there is a next page
+	err := r.db.SelectContext(ctx, &entries, query,
+		merchantID, walletID, shadowID, phoneHash,
+		pq.Array(types), filter.From, filter.To, filter.CampaignID,
+		afterAt, afterID, filter.Limit+1,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.HistoryPage{Entries: entries}
+	if len(entries) > filter.Limit {
+		page.Entries = entries[:filter.Limit]
+		last := page.Entries[len(page.Entries)-1]
+		page.NextCursor = domain.HistoryCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	return page, nil
+}
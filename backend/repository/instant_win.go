@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Instant win operations
+
+// CreateInstantWinPool stores the campaign seed and prize inventory.
+// Calling it again for the same campaign keeps the original seed.
+func (r *Repository) CreateInstantWinPool(ctx context.Context, pool *domain.InstantWinPool, prizes []domain.InstantWinPrize) (*domain.InstantWinPool, error) {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	insertPool := `
+		INSERT INTO instant_win_pools (campaign_id, server_seed, seed_commitment, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (campaign_id) DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, insertPool, pool.CampaignID, pool.ServerSeed, pool.SeedCommitment, pool.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	insertPrize := `
+		INSERT INTO instant_win_inventory (campaign_id, tier_index, name, amount, inventory, awarded)
+		VALUES ($1, $2, $3, $4, $5, 0)
+		ON CONFLICT (campaign_id, tier_index) DO NOTHING
+	`
+	for i, prize := range prizes {
+		if _, err := tx.ExecContext(ctx, insertPrize, pool.CampaignID, i, prize.Name, prize.Amount, prize.Inventory); err != nil {
+			return nil, err
+		}
+	}
+
+	var stored domain.InstantWinPool
+	if err := tx.GetContext(ctx, &stored, `SELECT * FROM instant_win_pools WHERE campaign_id = $1`, pool.CampaignID); err != nil {
+		return nil, err
+	}
+
+	return &stored, tx.Commit()
+}
+
+// SyncInstantWinInventoryWithTx brings the prize inventory in line with an edited config.
+// Units already won are kept: a tier cut below them is sold out. Removed tiers that never
+// paid out are deleted; the others stay for the report but cannot be drawn any more.
+func (r *Repository) SyncInstantWinInventoryWithTx(ctx context.Context, tx *sqlx.Tx, campaignID uuid.UUID, prizes []domain.InstantWinPrize) error {
+	upsertPrize := `
+		INSERT INTO instant_win_inventory (campaign_id, tier_index, name, amount, inventory, awarded)
+		VALUES ($1, $2, $3, $4, $5, 0)
+		ON CONFLICT (campaign_id, tier_index) DO UPDATE
+		SET name = EXCLUDED.name,
+		    amount = EXCLUDED.amount,
+		    inventory = GREATEST(EXCLUDED.inventory, instant_win_inventory.awarded)
+	`
+	for i, prize := range prizes {
+		if _, err := tx.ExecContext(ctx, upsertPrize, campaignID, i, prize.Name, prize.Amount, prize.Inventory); err != nil {
+			return err
+		}
+	}
+
+	removeTiers := `DELETE FROM instant_win_inventory WHERE campaign_id = $1 AND tier_index >= $2 AND awarded = 0`
+	if _, err := tx.ExecContext(ctx, removeTiers, campaignID, len(prizes)); err != nil {
+		return err
+	}
+
+	soldOut := `UPDATE instant_win_inventory SET inventory = awarded WHERE campaign_id = $1 AND tier_index >= $2`
+	_, err := tx.ExecContext(ctx, soldOut, campaignID, len(prizes))
+	return err
+}
+
+func (r *Repository) GetInstantWinPool(ctx context.Context, campaignID uuid.UUID) (*domain.InstantWinPool, error) {
+	var pool domain.InstantWinPool
+	query := `SELECT * FROM instant_win_pools WHERE campaign_id = $1`
+	if err := r.db.GetContext(ctx, &pool, query, campaignID); err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// MarkInstantWinSeedRevealedWithTx records that the campaign's seed is public. Campaigns
+// without a prize pool are left untouched.
+func (r *Repository) MarkInstantWinSeedRevealedWithTx(ctx context.Context, tx *sqlx.Tx, campaignID uuid.UUID) error {
+	query := `UPDATE instant_win_pools SET revealed_at = $1 WHERE campaign_id = $2 AND revealed_at IS NULL`
+	_, err := tx.ExecContext(ctx, query, time.Now(), campaignID)
+	return err
+}
+
+func (r *Repository) GetInstantWinInventory(ctx context.Context, campaignID uuid.UUID) ([]*domain.InstantWinInventory, error) {
+	var inventory []*domain.InstantWinInventory
+	query := `SELECT * FROM instant_win_inventory WHERE campaign_id = $1 ORDER BY tier_index`
+	err := r.db.SelectContext(ctx, &inventory, query, campaignID)
+	return inventory, err
+}
+
+// RecordInstantWinDrawWithTx stores the draw for auditing.
+// Returns false when the transaction was already drawn for this campaign.
+func (r *Repository) RecordInstantWinDrawWithTx(ctx context.Context, tx *sqlx.Tx, draw *domain.InstantWinDraw) (bool, error) {
+	query := `
+		INSERT INTO instant_win_draws (id, campaign_id, transaction_id, phone_hash, roll, tier_index, awarded, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (campaign_id, transaction_id) DO NOTHING
+	`
+	result, err := tx.ExecContext(ctx, query,
+		draw.ID, draw.CampaignID, draw.TransactionID, draw.PhoneHash,
+		draw.Roll, draw.TierIndex, draw.Awarded, draw.CreatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted == 1, nil
+}
+
+// ClaimInstantWinPrizeWithTx reserves one unit of the tier drawn as name and amount. The
+// conditional update is atomic, so concurrent winners can never push awarded past the
+// inventory. A tier whose name or amount changed since the draw is not claimed.
+func (r *Repository) ClaimInstantWinPrizeWithTx(ctx context.Context, tx *sqlx.Tx, campaignID uuid.UUID, tierIndex int, name string, amount float64) (bool, error) {
+	query := `
+		UPDATE instant_win_inventory
+		SET awarded = awarded + 1
+		WHERE campaign_id = $1 AND tier_index = $2 AND name = $3 AND amount = ROUND($4::numeric, 2) AND awarded < inventory
+	`
+	result, err := tx.ExecContext(ctx, query, campaignID, tierIndex, name, amount)
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return updated == 1, nil
+}
+
+// ReleaseInstantWinPrizeWithTx returns the prize of an awarded draw to the pool and marks
+// the draw as not awarded. Returns false when the draw had not won a prize.
+func (r *Repository) ReleaseInstantWinPrizeWithTx(ctx context.Context, tx *sqlx.Tx, campaignID uuid.UUID, transactionID string) (bool, error) {
+	var tierIndex int
+	query := `
+		UPDATE instant_win_draws
+		SET awarded = false
+		WHERE campaign_id = $1 AND transaction_id = $2 AND awarded
+		RETURNING tier_index
+	`
+	err := tx.GetContext(ctx, &tierIndex, query, campaignID, transactionID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	inventoryQuery := `
+		UPDATE instant_win_inventory
+		SET awarded = awarded - 1
+		WHERE campaign_id = $1 AND tier_index = $2 AND awarded > 0
+	`
+	if _, err := tx.ExecContext(ctx, inventoryQuery, campaignID, tierIndex); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *Repository) GetInstantWinDraw(ctx context.Context, campaignID uuid.UUID, transactionID string) (*domain.InstantWinDraw, error) {
+	var draw domain.InstantWinDraw
+	query := `SELECT * FROM instant_win_draws WHERE campaign_id = $1 AND transaction_id = $2`
+	if err := r.db.GetContext(ctx, &draw, query, campaignID, transactionID); err != nil {
+		return nil, err
+	}
+	return &draw, nil
+}
+
+func (r *Repository) CountInstantWinDraws(ctx context.Context, campaignID uuid.UUID) (total int64, wins int64, err error) {
+	query := `
+		SELECT COUNT(*), COUNT(CASE WHEN awarded THEN 1 END)
+		FROM instant_win_draws
+		WHERE campaign_id = $1
+	`
+	err = r.db.QueryRowContext(ctx, query, campaignID).Scan(&total, &wins)
+	return total, wins, err
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// Occasion reward operations
+
+func (r *Repository) GetActiveOccasionRewards(ctx context.Context) ([]*domain.OccasionReward, error) {
+	var rewards []*domain.OccasionReward
+	query := `SELECT * FROM occasion_rewards WHERE is_active = TRUE ORDER BY merchant_id`
+	err := r.db.SelectContext(ctx, &rewards, query)
+	return rewards, err
+}
+
+func (r *Repository) GetOccasionRewardsByMerchant(ctx context.Context, merchantID uuid.UUID) ([]*domain.OccasionReward, error) {
+	var rewards []*domain.OccasionReward
+	query := `SELECT * FROM occasion_rewards WHERE merchant_id = $1 ORDER BY occasion`
+	err := r.db.SelectContext(ctx, &rewards, query, merchantID)
+	return rewards, err
+}
+
+func (r *Repository) UpsertOccasionReward(ctx context.Context, reward *domain.OccasionReward) error {
+	query := `
+		INSERT INTO occasion_rewards (id, merchant_id, occasion, amount, reward_type, valid_days, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (merchant_id, occasion) DO UPDATE
+		SET amount = EXCLUDED.amount, reward_type = EXCLUDED.reward_type, valid_days = EXCLUDED.valid_days,
+		    is_active = EXCLUDED.is_active, updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at
+	`
+	return r.db.QueryRowxContext(ctx, query,
+		reward.ID, reward.MerchantID, reward.Occasion, reward.Amount, reward.RewardType,
+		reward.ValidDays, reward.IsActive, reward.CreatedAt, reward.UpdatedAt,
+	).Scan(&reward.ID, &reward.CreatedAt)
+}
+
+// GetWalletsCelebrating returns the merchant's wallets whose occasion falls on the given local date.
+// Customers born on Feb 29 celebrate on Feb 28 in non-leap years.
+func (r *Repository) GetWalletsCelebrating(ctx context.Context, merchantID uuid.UUID, occasion domain.OccasionType, localDate time.Time) ([]*domain.Wallet, error) {
+	var dateExpr string
+	switch occasion {
+	case domain.OccasionBirthday:
+		dateExpr = "birth_date"
+	case domain.OccasionAnniversary:
+		dateExpr = "(created_at AT TIME ZONE $6)::date"
+	default:
+		return nil, fmt.Errorf("unknown occasion: %s", occasion)
+	}
+
+	year, month, day := localDate.Date()
+	isLeapYear := time.Date(year, time.February, 29, 0, 0, 0, 0, time.UTC).Month() == time.February
+	includeLeapDay := month == time.February && day == 28 && !isLeapYear
+
+	query := fmt.Sprintf(`
+		SELECT * FROM wallets
+		WHERE merchant_id = $1
+		AND %[1]s IS NOT NULL
+		AND EXTRACT(YEAR FROM %[1]s) < $5
+		AND (
+			(EXTRACT(MONTH FROM %[1]s) = $2 AND EXTRACT(DAY FROM %[1]s) = $3)
+			OR ($4 AND EXTRACT(MONTH FROM %[1]s) = 2 AND EXTRACT(DAY FROM %[1]s) = 29)
+		)
+	`, dateExpr)
+
+	args := []interface{}{merchantID, int(month), day, includeLeapDay, year}
+	if occasion == domain.OccasionAnniversary {
+		args = append(args, localDate.Location().String())
+	}
+
+	var wallets []*domain.Wallet
+	err := r.db.SelectContext(ctx, &wallets, query, args...)
+	return wallets, err
+}
+
+// GrantOccasionReward credits the wallet once per occasion and year.
+// Returns false when the reward was already granted for that year.
+func (r *Repository) GrantOccasionReward(ctx context.Context, reward *domain.OccasionReward, walletID uuid.UUID, year int, expiresAt *time.Time) (bool, error) {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	grant := &domain.OccasionGrant{
+		ID:               uuid.New(),
+		OccasionRewardID: reward.ID,
+		MerchantID:       reward.MerchantID,
+		WalletID:         walletID,
+		Occasion:         reward.Occasion,
+		GrantYear:        year,
+		Amount:           reward.Amount,
+		ExpiresAt:        expiresAt,
+		CreatedAt:        time.Now(),
+	}
+
+	insertQuery := `
+		INSERT INTO occasion_grants (id, occasion_reward_id, merchant_id, wallet_id, occasion, grant_year, amount, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (wallet_id, occasion, grant_year) DO NOTHING
+	`
+	result, err := tx.ExecContext(ctx, insertQuery,
+		grant.ID, grant.OccasionRewardID, grant.MerchantID, grant.WalletID,
+		grant.Occasion, grant.GrantYear, grant.Amount, grant.ExpiresAt, grant.CreatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if inserted == 0 {
+		return false, nil
+	}
+
+	updateQuery := `UPDATE wallets SET balance = balance + $1, updated_at = $2 WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, updateQuery, grant.Amount, time.Now(), walletID); err != nil {
+		return false, err
+	}
+
+	metadata, err := json.Marshal(map[string]interface{}{
+		"occasion":          grant.Occasion,
+		"occasion_grant_id": grant.ID,
+		"grant_year":        grant.GrantYear,
+		"reward_type":       reward.RewardType,
+		"expires_at":        grant.ExpiresAt,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	ledgerTx := &domain.Transaction{
+		ID:         uuid.New(),
+		MerchantID: grant.MerchantID,
+		WalletID:   &walletID,
+		Type:       domain.TransactionTypeOccasion,
+		Amount:     grant.Amount,
+		Metadata:   metadata,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := r.CreateTransactionWithTx(ctx, tx, ledgerTx); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *Repository) GetExpiredOccasionGrants(ctx context.Context) ([]*domain.OccasionGrant, error) {
+	var grants []*domain.OccasionGrant
+	query := `
+		SELECT * FROM occasion_grants
+		WHERE expires_at IS NOT NULL
+		AND expires_at <= NOW()
+		AND expired_at IS NULL
+	`
+	err := r.db.SelectContext(ctx, &grants, query)
+	return grants, err
+}
+
+// ExpireOccasionGrant removes an unused voucher from the wallet once its validity ends.
+// The debit never takes the wallet below zero.
+func (r *Repository) ExpireOccasionGrant(ctx context.Context, grantID uuid.UUID) (float64, error) {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var grant domain.OccasionGrant
+	query := `SELECT * FROM occasion_grants WHERE id = $1 AND expired_at IS NULL FOR UPDATE`
+	if err := tx.GetContext(ctx, &grant, query, grantID); err != nil {
+		return 0, err
+	}
+
+	var balance float64
+	if err := tx.GetContext(ctx, &balance, `SELECT balance FROM wallets WHERE id = $1 FOR UPDATE`, grant.WalletID); err != nil {
+		return 0, err
+	}
+
+	expiredAmount := grant.Amount
+	if balance < expiredAmount {
+		expiredAmount = balance
+	}
+
+	if expiredAmount > 0 {
+		updateQuery := `UPDATE wallets SET balance = balance - $1, updated_at = $2 WHERE id = $3`
+		if _, err := tx.ExecContext(ctx, updateQuery, expiredAmount, time.Now(), grant.WalletID); err != nil {
+			return 0, err
+		}
+
+		expireTx := &domain.Transaction{
+			ID:         uuid.New(),
+			MerchantID: grant.MerchantID,
+			WalletID:   &grant.WalletID,
+			Type:       domain.TransactionTypeExpire,
+			Amount:     -expiredAmount,
+			Metadata:   json.RawMessage(fmt.Sprintf(`{"occasion_grant_id":"%s","occasion":"%s","expired_at":"%s"}`, grant.ID, grant.Occasion, time.Now().Format(time.RFC3339))),
+			CreatedAt:  time.Now(),
+		}
+
+		if err := r.CreateTransactionWithTx(ctx, tx, expireTx); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE occasion_grants SET expired_at = $1 WHERE id = $2`, time.Now(), grant.ID); err != nil {
+		return 0, err
+	}
+
+	return expiredAmount, tx.Commit()
+}
@@ -39,6 +39,15 @@ func (r *Repository) GetMerchantByAPIKey(ctx context.Context, apiKey string) (*d
 	return &merchant, nil
 }
 
+func (r *Repository) GetMerchantByID(ctx context.Context, merchantID uuid.UUID) (*domain.Merchant, error) {
+	var merchant domain.Merchant
+	query := `SELECT * FROM merchants WHERE id = $1`
+	if err := r.db.GetContext(ctx, &merchant, query, merchantID); err != nil {
+		return nil, err
+	}
+	return &merchant, nil
+}
+
 func (r *Repository) GetMerchantByEmail(ctx context.Context, email string) (*domain.Merchant, error) {
 	var merchant domain.Merchant
 	query := `SELECT * FROM merchants WHERE settings->>'email' = $1`
@@ -56,26 +65,49 @@ func (r *Repository) GetMerchantByEmail(ctx context.Context, email string) (*dom
 	return &merchant, nil
 }
 
+// IsMerchantUser reports whether the Supabase user is a staff member of the merchant
+func (r *Repository) IsMerchantUser(ctx context.Context, merchantID, userID uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM merchant_users WHERE merchant_id = $1 AND user_id = $2)`
+	if err := r.db.GetContext(ctx, &exists, query, merchantID, userID); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
 // Campaign operations
 
-func (r *Repository) GetActiveCampaign(ctx context.Context, merchantID uuid.UUID) (*domain.Campaign, error) {
-	var campaign domain.Campaign
+// GetActiveCampaigns returns the merchant's running campaigns, newest first
+func (r *Repository) GetActiveCampaigns(ctx context.Context, merchantID uuid.UUID) ([]*domain.Campaign, error) {
+	var campaigns []*domain.Campaign
 	query := `
 		SELECT * FROM campaigns 
 		WHERE merchant_id = $1 
-		AND is_active = true 
+		AND status = 'ACTIVE' 
 		AND (starts_at IS NULL OR starts_at <= NOW())
 		AND (ends_at IS NULL OR ends_at >= NOW())
-		ORDER BY created_at DESC 
-		LIMIT 1
+		ORDER BY created_at DESC
 	`
-	if err := r.db.GetContext(ctx, &campaign, query, merchantID); err != nil {
+	err := r.db.SelectContext(ctx, &campaigns, query, merchantID)
+	return campaigns, err
+}
+
+// Wallet operations
+
+func (r *Repository) GetWalletByID(ctx context.Context, walletID uuid.UUID) (*domain.Wallet, error) {
+	var wallet domain.Wallet
+	query := `SELECT * FROM wallets WHERE id = $1`
+	if err := r.db.GetContext(ctx, &wallet, query, walletID); err != nil {
 		return nil, err
 	}
-	return &campaign, nil
+	return &wallet, nil
 }
 
-// Wallet operations
+func (r *Repository) UpdateWalletBirthDate(ctx context.Context, walletID uuid.UUID, birthDate *time.Time) error {
+	query := `UPDATE wallets SET birth_date = $1, updated_at = $2 WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, birthDate, time.Now(), walletID)
+	return err
+}
 
 func (r *Repository) GetOrCreateWallet(ctx context.Context, merchantID, userID uuid.UUID, phoneHash string) (*domain.Wallet, error) {
 	var wallet domain.Wallet
@@ -157,15 +189,22 @@ func (r *Repository) GetOrCreateWalletWithTx(ctx context.Context, tx *sqlx.Tx, m
 	return &wallet, nil
 }
 
-func (r *Repository) UpdateWalletWithTx(ctx context.Context, tx *sqlx.Tx, walletID uuid.UUID, newBalance float64, newState json.RawMessage) error {
-	query := `UPDATE wallets SET balance = $1, state = $2, updated_at = $3 WHERE id = $4`
-	_, err := tx.ExecContext(ctx, query, newBalance, newState, time.Now(), walletID)
+// UpdateWalletWithTx stores the wallet's balance and its state, owned by campaignID and
+// pinned to versionID
+func (r *Repository) UpdateWalletWithTx(ctx context.Context, tx *sqlx.Tx, walletID uuid.UUID, newBalance float64, newState json.RawMessage, campaignID, versionID *uuid.UUID) error {
+	query := `
+		UPDATE wallets SET balance = $1, state = $2, campaign_id = $3, campaign_version_id = $4, updated_at = $5
+		WHERE id = $6
+	`
+	_, err := tx.ExecContext(ctx, query, newBalance, newState, campaignID, versionID, time.Now(), walletID)
 	return err
 }
 
 // Shadow Balance operations
 
-func (r *Repository) GetOrCreateShadowBalance(ctx context.Context, merchantID uuid.UUID, phoneHash string, ttl time.Duration) (*domain.ShadowBalance, error) {
+// GetOrCreateShadowBalance returns the phone's open shadow balance with the merchant, creating
+// one that expires under the merchant's policy
+func (r *Repository) GetOrCreateShadowBalance(ctx context.Context, merchantID uuid.UUID, phoneHash string, policy domain.ShadowExpiration) (*domain.ShadowBalance, error) {
 	var shadow domain.ShadowBalance
 
 	query := `SELECT * FROM shadow_balances WHERE merchant_id = $1 AND phone_hash = $2 AND converted_at IS NULL`
@@ -178,23 +217,25 @@ func (r *Repository) GetOrCreateShadowBalance(ctx context.Context, merchantID uu
 		return nil, err
 	}
 
+	now := time.Now()
 	shadow = domain.ShadowBalance{
-		ID:         uuid.New(),
-		MerchantID: merchantID,
-		PhoneHash:  phoneHash,
-		Amount:     0,
-		State:      json.RawMessage("{}"),
-		ExpiresAt:  time.Now().Add(ttl),
-		CreatedAt:  time.Now(),
+		ID:             uuid.New(),
+		MerchantID:     merchantID,
+		PhoneHash:      phoneHash,
+		Amount:         0,
+		State:          json.RawMessage("{}"),
+		ExpiresAt:      policy.ExpiresAt(now, now),
+		CreatedAt:      now,
+		LastActivityAt: now,
 	}
 
 	insertQuery := `
-		INSERT INTO shadow_balances (id, merchant_id, phone_hash, amount, state, expires_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO shadow_balances (id, merchant_id, phone_hash, amount, state, expires_at, created_at, last_activity_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 	_, err = r.db.ExecContext(ctx, insertQuery,
 		shadow.ID, shadow.MerchantID, shadow.PhoneHash,
-		shadow.Amount, shadow.State, shadow.ExpiresAt, shadow.CreatedAt,
+		shadow.Amount, shadow.State, shadow.ExpiresAt, shadow.CreatedAt, shadow.LastActivityAt,
 	)
 	if err != nil {
 		return nil, err
@@ -203,12 +244,27 @@ func (r *Repository) GetOrCreateShadowBalance(ctx context.Context, merchantID uu
 	return &shadow, nil
 }
 
-func (r *Repository) UpdateShadowBalanceWithTx(ctx context.Context, tx *sqlx.Tx, shadowID uuid.UUID, newAmount float64, newState json.RawMessage) error {
-	query := `UPDATE shadow_balances SET amount = $1, state = $2 WHERE id = $3`
-	_, err := tx.ExecContext(ctx, query, newAmount, newState, shadowID)
+// UpdateShadowBalanceWithTx stores the shadow balance's amount and its state, owned by
+// campaignID and pinned to versionID
+func (r *Repository) UpdateShadowBalanceWithTx(ctx context.Context, tx *sqlx.Tx, shadowID uuid.UUID, newAmount float64, newState json.RawMessage, campaignID, versionID *uuid.UUID) error {
+	query := `UPDATE shadow_balances SET amount = $1, state = $2, campaign_id = $3, campaign_version_id = $4 WHERE id = $5`
+	_, err := tx.ExecContext(ctx, query, newAmount, newState, campaignID, versionID, shadowID)
 	return err
 }
 
+// TouchShadowBalanceWithTx records a purchase on the shadow balance and moves its expiry to
+// expiresAt when that is later. Expiry never moves earlier on a purchase.
+func (r *Repository) TouchShadowBalanceWithTx(ctx context.Context, tx *sqlx.Tx, shadowID uuid.UUID, at, expiresAt time.Time) (time.Time, error) {
+	var newExpiresAt time.Time
+	query := `
+		UPDATE shadow_balances SET last_activity_at = $1, expires_at = GREATEST(expires_at, $2)
+		WHERE id = $3
+		RETURNING expires_at
+	`
+	err := tx.GetContext(ctx, &newExpiresAt, query, at, expiresAt, shadowID)
+	return newExpiresAt, err
+}
+
 func (r *Repository) GetActiveShadowBalancesByPhone(ctx context.Context, phoneHash string) ([]*domain.ShadowBalance, error) {
 	var shadows []*domain.ShadowBalance
 	query := `
@@ -221,6 +277,21 @@ func (r *Repository) GetActiveShadowBalancesByPhone(ctx context.Context, phoneHa
 	return shadows, err
 }
 
+// GetActiveShadowBalancesByPhoneForUpdateWithTx locks the phone's open shadow balances. A
+// concurrent conversion waits for the lock and then no longer sees them as open.
+func (r *Repository) GetActiveShadowBalancesByPhoneForUpdateWithTx(ctx context.Context, tx *sqlx.Tx, phoneHash string) ([]*domain.ShadowBalance, error) {
+	var shadows []*domain.ShadowBalance
+	query := `
+		SELECT * FROM shadow_balances
+		WHERE phone_hash = $1
+		AND converted_at IS NULL
+		AND expires_at > NOW()
+		FOR UPDATE
+	`
+	err := tx.SelectContext(ctx, &shadows, query, phoneHash)
+	return shadows, err
+}
+
 func (r *Repository) MarkShadowAsConvertedWithTx(ctx context.Context, tx *sqlx.Tx, shadowID uuid.UUID) error {
 	query := `UPDATE shadow_balances SET converted_at = $1 WHERE id = $2`
 	_, err := tx.ExecContext(ctx, query, time.Now(), shadowID)
@@ -267,6 +338,11 @@ func (r *Repository) ExpireShadowBalance(ctx context.Context, shadowID uuid.UUID
 		return err
 	}
 
+	// Bonuses waiting for a sign-up that never happened are lost with the shadow balance
+	if err := r.ForfeitWelcomeBonusesWithTx(ctx, tx, shadowID); err != nil {
+		return err
+	}
+
 	// Mark as converted (with null user_id to indicate expiration)
 	if err := r.MarkShadowAsConvertedWithTx(ctx, tx, shadowID); err != nil {
 		return err
@@ -279,13 +355,16 @@ func (r *Repository) ExpireShadowBalance(ctx context.Context, shadowID uuid.UUID
 
 func (r *Repository) CreateTransactionWithTx(ctx context.Context, tx *sqlx.Tx, transaction *domain.Transaction) error {
 	query := `
-		INSERT INTO transactions (id, merchant_id, campaign_id, wallet_id, shadow_balance_id, transaction_type, amount, metadata, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO transactions (id, merchant_id, campaign_id, wallet_id, shadow_balance_id, transaction_type, amount, metadata, created_at,
+		                          campaign_version_id, purchase_amount, external_id, experiment_variant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 	_, err := tx.ExecContext(ctx, query,
 		transaction.ID, transaction.MerchantID, transaction.CampaignID,
 		transaction.WalletID, transaction.ShadowBalanceID, transaction.Type,
 		transaction.Amount, transaction.Metadata, transaction.CreatedAt,
+		transaction.CampaignVersionID, transaction.PurchaseAmount, transaction.ExternalID,
+		transaction.ExperimentVariantID,
 	)
 	return err
 }
@@ -349,23 +428,58 @@ func (r *Repository) GetExpirationMetrics(ctx context.Context) (*domain.Expirati
 
 // Campaign CRUD operations
 
+// CreateCampaign stores the campaign together with its first config version
 func (r *Repository) CreateCampaign(ctx context.Context, campaign *domain.Campaign) error {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if campaign.CurrentVersion == 0 {
+		campaign.CurrentVersion = 1
+	}
+
 	query := `
-		INSERT INTO campaigns (id, merchant_id, name, type, config, is_active, starts_at, ends_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO campaigns (id, merchant_id, name, type, config, status, is_active, starts_at, ends_at, created_at, updated_at,
+		                       current_version, max_reward_value, max_rewards, daily_reward_cap, segment_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		campaign.ID, campaign.MerchantID, campaign.Name, campaign.Type,
-		campaign.Config, campaign.IsActive, campaign.StartsAt, campaign.EndsAt,
-		campaign.CreatedAt, campaign.UpdatedAt,
+		campaign.Config, campaign.Status, campaign.IsActive, campaign.StartsAt, campaign.EndsAt,
+		campaign.CreatedAt, campaign.UpdatedAt, campaign.CurrentVersion,
+		campaign.MaxRewardValue, campaign.MaxRewards, campaign.DailyRewardCap, campaign.SegmentID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	version := &domain.CampaignVersion{
+		ID:            uuid.New(),
+		CampaignID:    campaign.ID,
+		Version:       campaign.CurrentVersion,
+		Config:        campaign.Config,
+		MigrationMode: domain.MigrationModeNewCardsOnly,
+		CreatedAt:     campaign.CreatedAt,
+	}
+	if err := r.CreateCampaignVersionWithTx(ctx, tx, version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *Repository) GetCampaignsByMerchant(ctx context.Context, merchantID uuid.UUID) ([]*domain.Campaign, error) {
+// GetCampaignsByMerchant lists the merchant's campaigns with the given status, or all but
+// the archived ones when status is empty
+func (r *Repository) GetCampaignsByMerchant(ctx context.Context, merchantID uuid.UUID, status domain.CampaignStatus) ([]*domain.Campaign, error) {
 	var campaigns []*domain.Campaign
-	query := `SELECT * FROM campaigns WHERE merchant_id = $1 ORDER BY created_at DESC`
-	err := r.db.SelectContext(ctx, &campaigns, query, merchantID)
+	query := `
+		SELECT * FROM campaigns
+		WHERE merchant_id = $1 AND (($2 = '' AND status <> 'ARCHIVED') OR status = $2)
+		ORDER BY created_at DESC
+	`
+	err := r.db.SelectContext(ctx, &campaigns, query, merchantID, string(status))
 	return campaigns, err
 }
 
@@ -379,25 +493,23 @@ func (r *Repository) GetCampaignByID(ctx context.Context, campaignID uuid.UUID)
 	return &campaign, nil
 }
 
+// UpdateCampaign saves the campaign settings. The status only changes through
+// TransitionCampaignStatusWithTx, so an edit never undoes a concurrent transition.
 func (r *Repository) UpdateCampaign(ctx context.Context, campaign *domain.Campaign) error {
 	query := `
 		UPDATE campaigns 
-		SET name = $1, type = $2, config = $3, is_active = $4, starts_at = $5, ends_at = $6, updated_at = $7
-		WHERE id = $8
+		SET name = $1, type = $2, config = $3, starts_at = $4, ends_at = $5, updated_at = $6,
+		    max_reward_value = $7, max_rewards = $8, daily_reward_cap = $9, segment_id = $10
+		WHERE id = $11
 	`
 	_, err := r.db.ExecContext(ctx, query,
-		campaign.Name, campaign.Type, campaign.Config, campaign.IsActive,
-		campaign.StartsAt, campaign.EndsAt, campaign.UpdatedAt, campaign.ID,
+		campaign.Name, campaign.Type, campaign.Config,
+		campaign.StartsAt, campaign.EndsAt, campaign.UpdatedAt,
+		campaign.MaxRewardValue, campaign.MaxRewards, campaign.DailyRewardCap, campaign.SegmentID, campaign.ID,
 	)
 	return err
 }
 
-func (r *Repository) DeleteCampaign(ctx context.Context, campaignID uuid.UUID) error {
-	query := `DELETE FROM campaigns WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, campaignID)
-	return err
-}
-
 // Plan operations
 
 func (r *Repository) GetPlanBySlug(ctx context.Context, slug string) (*domain.Plan, error) {
@@ -480,7 +592,7 @@ func (r *Repository) UpdateSubscription(ctx context.Context, sub *domain.Subscri
 
 func (r *Repository) CountActiveCampaigns(ctx context.Context, merchantID uuid.UUID) (int, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM campaigns WHERE merchant_id = $1 AND is_active = TRUE`
+	query := `SELECT COUNT(*) FROM campaigns WHERE merchant_id = $1 AND status IN ('ACTIVE', 'SCHEDULED')`
 	err := r.db.GetContext(ctx, &count, query, merchantID)
 	return count, err
 }
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Segment operations
+
+func (r *Repository) CreateSegment(ctx context.Context, segment *domain.Segment) error {
+	query := `
+		INSERT INTO segments (id, merchant_id, name, description, kind, rules, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		segment.ID, segment.MerchantID, segment.Name, segment.Description,
+		segment.Kind, segment.Rules, segment.CreatedAt, segment.UpdatedAt,
+	)
+	return err
+}
+
+func (r *Repository) UpdateSegment(ctx context.Context, segment *domain.Segment) error {
+	query := `UPDATE segments SET name = $1, description = $2, rules = $3, updated_at = $4 WHERE id = $5`
+	_, err := r.db.ExecContext(ctx, query, segment.Name, segment.Description, segment.Rules, segment.UpdatedAt, segment.ID)
+	return err
+}
+
+func (r *Repository) GetSegment(ctx context.Context, segmentID uuid.UUID) (*domain.Segment, error) {
+	var segment domain.Segment
+	query := `SELECT * FROM segments WHERE id = $1`
+	if err := r.db.GetContext(ctx, &segment, query, segmentID); err != nil {
+		return nil, err
+	}
+	return &segment, nil
+}
+
+func (r *Repository) GetSegmentsByMerchant(ctx context.Context, merchantID uuid.UUID) ([]*domain.Segment, error) {
+	var segments []*domain.Segment
+	query := `SELECT * FROM segments WHERE merchant_id = $1 ORDER BY name`
+	err := r.db.SelectContext(ctx, &segments, query, merchantID)
+	return segments, err
+}
+
+func (r *Repository) DeleteSegment(ctx context.Context, segmentID uuid.UUID) error {
+	query := `DELETE FROM segments WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, segmentID)
+	return err
+}
+
+// CountCampaignsUsingSegment counts the campaigns that are not archived and target the segment
+func (r *Repository) CountCampaignsUsingSegment(ctx context.Context, segmentID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM campaigns WHERE segment_id = $1 AND status <> 'ARCHIVED'`
+	err := r.db.GetContext(ctx, &count, query, segmentID)
+	return count, err
+}
+
+// AddSegmentMembers adds phone hashes to a static segment, returning how many were new
+func (r *Repository) AddSegmentMembers(ctx context.Context, segmentID uuid.UUID, phoneHashes []string) (int, error) {
+	query := `
+		INSERT INTO segment_members (segment_id, phone_hash, added_at)
+		SELECT $1, phone_hash, $3 FROM unnest($2::text[]) AS phone_hash
+		ON CONFLICT (segment_id, phone_hash) DO NOTHING
+	`
+	result, err := r.db.ExecContext(ctx, query, segmentID, pq.Array(phoneHashes), time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rows), nil
+}
+
+// ReplaceSegmentMembers swaps the whole member list of a static segment
+func (r *Repository) ReplaceSegmentMembers(ctx context.Context, segmentID uuid.UUID, phoneHashes []string) (int, error) {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM segment_members WHERE segment_id = $1`, segmentID); err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO segment_members (segment_id, phone_hash, added_at)
+		SELECT DISTINCT $1::uuid, phone_hash, $3::timestamptz FROM unnest($2::text[]) AS phone_hash
+	`
+	result, err := tx.ExecContext(ctx, query, segmentID, pq.Array(phoneHashes), time.Now())
+	if err != nil {
+		return 0, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), tx.Commit()
+}
+
+func (r *Repository) RemoveSegmentMembers(ctx context.Context, segmentID uuid.UUID, phoneHashes []string) (int, error) {
+	query := `DELETE FROM segment_members WHERE segment_id = $1 AND phone_hash = ANY($2::text[])`
+	result, err := r.db.ExecContext(ctx, query, segmentID, pq.Array(phoneHashes))
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rows), nil
+}
+
+func (r *Repository) IsSegmentMember(ctx context.Context, segmentID uuid.UUID, phoneHash string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM segment_members WHERE segment_id = $1 AND phone_hash = $2)`
+	err := r.db.GetContext(ctx, &exists, query, segmentID, phoneHash)
+	return exists, err
+}
+
+func (r *Repository) CountSegmentMembers(ctx context.Context, segmentID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM segment_members WHERE segment_id = $1`
+	err := r.db.GetContext(ctx, &count, query, segmentID)
+	return count, err
+}
+
+// GetCustomerStats summarizes every customer the merchant knows (wallets and shadow balances),
+// or only phoneHash when it is not empty. Visits and spend come from EARN entries; spend only
+// counts purchases since spendSince (all time when nil). The tier is read from progressive
+// state only; a tier that is not a number, or state of another strategy, has no tier.
+func (r *Repository) GetCustomerStats(ctx context.Context, merchantID uuid.UUID, phoneHash string, spendSince *time.Time) ([]*domain.CustomerStats, error) {
+	var stats []*domain.CustomerStats
+	query := `
+		WITH balances AS (
+			SELECT w.id, w.phone_hash, TRUE AS registered, TRUE AS known,
+			       CASE WHEN jsonb_typeof(w.state->'current_tier') = 'number' AND (c.id IS NULL OR c.type = 'PROGRESSIVE')
+			            THEN floor((w.state->>'current_tier')::numeric)::int END AS tier
+			FROM wallets w LEFT JOIN campaigns c ON c.id = w.campaign_id
+			WHERE w.merchant_id = $1 AND ($2 = '' OR w.phone_hash = $2)
+			UNION ALL
+			SELECT s.id, s.phone_hash, FALSE, s.converted_at IS NULL,
+			       CASE WHEN jsonb_typeof(s.state->'current_tier') = 'number' AND (c.id IS NULL OR c.type = 'PROGRESSIVE')
+			            THEN floor((s.state->>'current_tier')::numeric)::int END
+			FROM shadow_balances s LEFT JOIN campaigns c ON c.id = s.campaign_id
+			WHERE s.merchant_id = $1 AND ($2 = '' OR s.phone_hash = $2)
+		),
+		customers AS (
+			SELECT phone_hash, bool_or(registered) AS registered, MAX(tier) FILTER (WHERE known) AS tier
+			FROM balances GROUP BY phone_hash
+			HAVING bool_or(known)
+		),
+		entries AS (
+			SELECT b.phone_hash, t.created_at, t.purchase_amount
+			FROM balances b JOIN transactions t ON t.wallet_id = b.id
+			WHERE b.registered AND t.transaction_type = $4
+			UNION ALL
+			SELECT b.phone_hash, t.created_at, t.purchase_amount
+			FROM balances b JOIN transactions t ON t.shadow_balance_id = b.id
+			WHERE NOT b.registered AND t.wallet_id IS NULL AND t.transaction_type = $4
+		),
+		visits AS (
+			SELECT phone_hash,
+			       MIN(created_at) AS first_visit,
+			       MAX(created_at) AS last_visit,
+			       COALESCE(SUM(purchase_amount) FILTER (WHERE $3::timestamptz IS NULL OR created_at >= $3), 0) AS spend
+			FROM entries GROUP BY phone_hash
+		)
+		SELECT c.phone_hash, c.registered, c.tier, v.first_visit, v.last_visit, COALESCE(v.spend, 0) AS spend
+		FROM customers c
+		LEFT JOIN visits v ON v.phone_hash = c.phone_hash
+	`
+	err := r.db.SelectContext(ctx, &stats, query, merchantID, phoneHash, spendSince, domain.TransactionTypeEarn)
+	return stats, err
+}
+
+// GetSpendRanking ranks the merchant's customers by spend since spendSince (all time when nil)
+// and returns the lowest spend among the top percent of those who spent anything
+func (r *Repository) GetSpendRanking(ctx context.Context, merchantID uuid.UUID, spendSince *time.Time, percent float64) (*domain.SpendRanking, error) {
+	var ranking domain.SpendRanking
+	query := `
+		WITH spend AS (
+			SELECT COALESCE(w.phone_hash, s.phone_hash) AS phone_hash, SUM(t.purchase_amount) AS spend
+			FROM transactions t
+			LEFT JOIN wallets w ON w.id = t.wallet_id
+			LEFT JOIN shadow_balances s ON s.id = t.shadow_balance_id
+			WHERE t.merchant_id = $1 AND t.transaction_type = $4
+			AND ($2::timestamptz IS NULL OR t.created_at >= $2)
+			GROUP BY 1
+			HAVING SUM(t.purchase_amount) > 0
+		),
+		ranked AS (
+			SELECT spend,
+			       ROW_NUMBER() OVER (ORDER BY spend DESC) AS position,
+			       COUNT(*) OVER () AS spenders
+			FROM spend
+		)
+		SELECT COUNT(*) AS spenders,
+		       COALESCE(MIN(spend) FILTER (WHERE position <= CEIL(spenders * $3::numeric / 100)), 0) AS cutoff
+		FROM ranked
+	`
+	if err := r.db.GetContext(ctx, &ranking, query, merchantID, spendSince, percent, domain.TransactionTypeEarn); err != nil {
+		return nil, err
+	}
+	ranking.ComputedAt = time.Now()
+	return &ranking, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// Shadow expiration operations
+
+// UpdateShadowExpiration saves the merchant's shadow expiration policy and recomputes the
+// expiry of its open shadow balances under it. Balances already past their expiry are left to
+// the expiration worker, and balances claimed by SMS code keep a later claimed expiry. It
+// returns how many balances were recomputed.
+func (r *Repository) UpdateShadowExpiration(ctx context.Context, merchantID uuid.UUID, policy domain.ShadowExpiration) (int, error) {
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	settingsQuery := `
+		UPDATE merchants
+		SET settings = jsonb_set(COALESCE(settings, '{}'::jsonb), '{shadow_expiration}', $1::jsonb), updated_at = NOW()
+		WHERE id = $2
+	`
+	if _, err := tx.ExecContext(ctx, settingsQuery, string(raw), merchantID); err != nil {
+		return 0, err
+	}
+
+	balancesQuery := `
+		UPDATE shadow_balances s
+		SET expires_at = CASE WHEN s.claimed_at IS NULL THEN e.expires_at ELSE GREATEST(s.expires_at, e.expires_at) END
+		FROM (
+			SELECT id, CASE
+				WHEN $2 THEN LEAST(last_activity_at + $3 * INTERVAL '1 hour', created_at + $4 * INTERVAL '1 hour')
+				ELSE created_at + $3 * INTERVAL '1 hour'
+			END AS expires_at
+			FROM shadow_balances
+			WHERE merchant_id = $1 AND converted_at IS NULL AND expires_at > NOW()
+		) e
+		WHERE s.id = e.id
+	`
+	result, err := tx.ExecContext(ctx, balancesQuery, merchantID, policy.Sliding, policy.TTLHours, policy.MaxHours)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(rows), nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// Simulation operations
+
+func (r *Repository) CreateSimulationJob(ctx context.Context, job *domain.SimulationJob) error {
+	query := `
+		INSERT INTO simulation_jobs (id, merchant_id, campaign_type, config, period_start, period_end, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		job.ID, job.MerchantID, job.CampaignType, job.Config,
+		job.PeriodStart, job.PeriodEnd, job.Status, job.CreatedAt,
+	)
+	return err
+}
+
+func (r *Repository) GetSimulationJob(ctx context.Context, jobID uuid.UUID) (*domain.SimulationJob, error) {
+	var job domain.SimulationJob
+	query := `SELECT * FROM simulation_jobs WHERE id = $1`
+	if err := r.db.GetContext(ctx, &job, query, jobID); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *Repository) GetSimulationJobsByMerchant(ctx context.Context, merchantID uuid.UUID) ([]*domain.SimulationJob, error) {
+	var jobs []*domain.SimulationJob
+	query := `
+		SELECT id, merchant_id, campaign_type, config, period_start, period_end, status,
+		       NULL::jsonb AS result, error, created_at, started_at, completed_at
+		FROM simulation_jobs
+		WHERE merchant_id = $1
+		ORDER BY created_at DESC
+	`
+	err := r.db.SelectContext(ctx, &jobs, query, merchantID)
+	return jobs, err
+}
+
+// ClaimNextSimulationJob marks the oldest pending job as running and returns it. A running
+// job whose heartbeat is older than staleBefore was abandoned by its worker and is taken over.
+// SKIP LOCKED lets several API instances run the worker without picking the same job.
+func (r *Repository) ClaimNextSimulationJob(ctx context.Context, staleBefore time.Time) (*domain.SimulationJob, error) {
+	var job domain.SimulationJob
+	query := `
+		UPDATE simulation_jobs
+		SET status = $1, started_at = $2, heartbeat_at = $2, attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM simulation_jobs
+			WHERE status = $3
+			OR (status = $1 AND (heartbeat_at IS NULL OR heartbeat_at < $4))
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`
+	if err := r.db.GetContext(ctx, &job, query, domain.SimulationStatusRunning, time.Now(), domain.SimulationStatusPending, staleBefore); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// TouchSimulationJob refreshes the heartbeat of a running job
+func (r *Repository) TouchSimulationJob(ctx context.Context, jobID uuid.UUID) error {
+	query := `UPDATE simulation_jobs SET heartbeat_at = $1 WHERE id = $2 AND status = $3`
+	_, err := r.db.ExecContext(ctx, query, time.Now(), jobID, domain.SimulationStatusRunning)
+	return err
+}
+
+func (r *Repository) CompleteSimulationJob(ctx context.Context, jobID uuid.UUID, result []byte) error {
+	query := `UPDATE simulation_jobs SET status = $1, result = $2, completed_at = $3 WHERE id = $4`
+	_, err := r.db.ExecContext(ctx, query, domain.SimulationStatusCompleted, result, time.Now(), jobID)
+	return err
+}
+
+func (r *Repository) FailSimulationJob(ctx context.Context, jobID uuid.UUID, message string) error {
+	query := `UPDATE simulation_jobs SET status = $1, error = $2, completed_at = $3 WHERE id = $4`
+	_, err := r.db.ExecContext(ctx, query, domain.SimulationStatusFailed, message, time.Now(), jobID)
+	return err
+}
+
+// GetHistoricalPurchases returns the merchant's EARN entries in the period, oldest first,
+// with the phone hash of the wallet or shadow balance they were credited to
+func (r *Repository) GetHistoricalPurchases(ctx context.Context, merchantID uuid.UUID, from, to time.Time) ([]*domain.HistoricalPurchase, error) {
+	var purchases []*domain.HistoricalPurchase
+	query := `
+		SELECT COALESCE(w.phone_hash, s.phone_hash) AS phone_hash,
+		       t.purchase_amount,
+		       COALESCE(t.metadata, '{}'::jsonb) AS metadata,
+		       COALESCE(t.external_id, t.id::text) AS external_id,
+		       t.created_at
+		FROM transactions t
+		LEFT JOIN wallets w ON w.id = t.wallet_id
+		LEFT JOIN shadow_balances s ON s.id = t.shadow_balance_id
+		WHERE t.merchant_id = $1
+		AND t.transaction_type = $2
+		AND t.created_at >= $3 AND t.created_at < $4
+		AND COALESCE(w.phone_hash, s.phone_hash) IS NOT NULL
+		ORDER BY t.created_at, t.id
+	`
+	err := r.db.SelectContext(ctx, &purchases, query, merchantID, domain.TransactionTypeEarn, from, to)
+	return purchases, err
+}
+
+// GetRedemptionsByPhone sums what each customer redeemed in the period
+func (r *Repository) GetRedemptionsByPhone(ctx context.Context, merchantID uuid.UUID, from, to time.Time) (map[string]float64, error) {
+	rows := []struct {
+		PhoneHash string  `db:"phone_hash"`
+		Amount    float64 `db:"amount"`
+	}{}
+	query := `
+		SELECT w.phone_hash, COALESCE(SUM(ABS(t.amount)), 0) AS amount
+		FROM transactions t
+		JOIN wallets w ON w.id = t.wallet_id
+		WHERE t.merchant_id = $1
+		AND t.transaction_type = $2
+		AND t.created_at >= $3 AND t.created_at < $4
+		GROUP BY w.phone_hash
+	`
+	if err := r.db.SelectContext(ctx, &rows, query, merchantID, domain.TransactionTypeRedeem, from, to); err != nil {
+		return nil, err
+	}
+
+	redemptions := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		redemptions[row.PhoneHash] = row.Amount
+	}
+	return redemptions, nil
+}
+
+// GetEarnedValue sums the rewards the live campaigns issued in the period
+func (r *Repository) GetEarnedValue(ctx context.Context, merchantID uuid.UUID, from, to time.Time) (float64, error) {
+	var total float64
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE merchant_id = $1 AND transaction_type = $2
+		AND created_at >= $3 AND created_at < $4
+	`
+	err := r.db.GetContext(ctx, &total, query, merchantID, domain.TransactionTypeEarn, from, to)
+	return total, err
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Welcome bonus operations
+
+// HasCustomerInteraction reports whether the phone has ever interacted with the merchant:
+// a ledger entry on its wallet or shadow balances (converted or not), or a previous welcome
+// bonus. Visits outside every campaign's audience (EARN entries without a campaign) do not
+// count, so the first eligible purchase still gets the bonus.
+func (r *Repository) HasCustomerInteraction(ctx context.Context, merchantID uuid.UUID, phoneHash string) (bool, error) {
+	var exists bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM wallets w JOIN transactions t ON t.wallet_id = w.id
+			WHERE w.merchant_id = $1 AND w.phone_hash = $2
+			AND (t.campaign_id IS NOT NULL OR t.transaction_type <> $3)
+		) OR EXISTS (
+			SELECT 1 FROM shadow_balances s JOIN transactions t ON t.shadow_balance_id = s.id
+			WHERE s.merchant_id = $1 AND s.phone_hash = $2
+			AND (t.campaign_id IS NOT NULL OR t.transaction_type <> $3)
+		) OR EXISTS (SELECT 1 FROM welcome_bonuses WHERE merchant_id = $1 AND phone_hash = $2)
+	`
+	err := r.db.GetContext(ctx, &exists, query, merchantID, phoneHash, domain.TransactionTypeEarn)
+	return exists, err
+}
+
+// ClaimWelcomeBonusWithTx records the bonus for the phone hash.
+// Returns false when the customer already received (or is waiting for) a welcome bonus from this merchant.
+func (r *Repository) ClaimWelcomeBonusWithTx(ctx context.Context, tx *sqlx.Tx, bonus *domain.WelcomeBonus) (bool, error) {
+	query := `
+		INSERT INTO welcome_bonuses (id, merchant_id, campaign_id, phone_hash, amount, status, wallet_id, shadow_balance_id, created_at, granted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (merchant_id, phone_hash) DO NOTHING
+	`
+	result, err := tx.ExecContext(ctx, query,
+		bonus.ID, bonus.MerchantID, bonus.CampaignID, bonus.PhoneHash, bonus.Amount,
+		bonus.Status, bonus.WalletID, bonus.ShadowBalanceID, bonus.CreatedAt, bonus.GrantedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted == 1, nil
+}
+
+// DeleteWelcomeBonusWithTx removes a bonus claimed in the same transaction, keeping the
+// customer eligible when the bonus could not be paid
+func (r *Repository) DeleteWelcomeBonusWithTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM welcome_bonuses WHERE id = $1`, id)
+	return err
+}
+
+// GrantPendingWelcomeBonusWithTx pays out a bonus that was waiting for the customer to sign up.
+// Returns nil when there is nothing pending. The caller is responsible for crediting the wallet balance.
+func (r *Repository) GrantPendingWelcomeBonusWithTx(ctx context.Context, tx *sqlx.Tx, merchantID uuid.UUID, phoneHash string, walletID uuid.UUID) (*domain.WelcomeBonus, error) {
+	var bonus domain.WelcomeBonus
+	query := `
+		SELECT * FROM welcome_bonuses
+		WHERE merchant_id = $1 AND phone_hash = $2 AND status = $3
+		FOR UPDATE
+	`
+	err := tx.GetContext(ctx, &bonus, query, merchantID, phoneHash, domain.WelcomeBonusPending)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	updateQuery := `UPDATE welcome_bonuses SET status = $1, wallet_id = $2, granted_at = $3 WHERE id = $4`
+	if _, err := tx.ExecContext(ctx, updateQuery, domain.WelcomeBonusGranted, walletID, now, bonus.ID); err != nil {
+		return nil, err
+	}
+
+	bonus.Status = domain.WelcomeBonusGranted
+	bonus.WalletID = &walletID
+	bonus.GrantedAt = &now
+
+	if err := r.CreateWelcomeTransactionWithTx(ctx, tx, &bonus, &walletID, nil); err != nil {
+		return nil, err
+	}
+
+	return &bonus, nil
+}
+
+// CreateWelcomeTransactionWithTx records a granted welcome bonus in the ledger of the wallet or shadow balance
+func (r *Repository) CreateWelcomeTransactionWithTx(ctx context.Context, tx *sqlx.Tx, bonus *domain.WelcomeBonus, walletID, shadowID *uuid.UUID) error {
+	metadata, err := json.Marshal(map[string]interface{}{
+		"welcome_bonus_id": bonus.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	ledgerTx := &domain.Transaction{
+		ID:              uuid.New(),
+		MerchantID:      bonus.MerchantID,
+		CampaignID:      bonus.CampaignID,
+		WalletID:        walletID,
+		ShadowBalanceID: shadowID,
+		Type:            domain.TransactionTypeWelcome,
+		Amount:          bonus.Amount,
+		Metadata:        metadata,
+		CreatedAt:       time.Now(),
+	}
+	return r.CreateTransactionWithTx(ctx, tx, ledgerTx)
+}
+
+func (r *Repository) ForfeitWelcomeBonusesWithTx(ctx context.Context, tx *sqlx.Tx, shadowID uuid.UUID) error {
+	query := `UPDATE welcome_bonuses SET status = $1 WHERE shadow_balance_id = $2 AND status = $3`
+	_, err := tx.ExecContext(ctx, query, domain.WelcomeBonusForfeited, shadowID, domain.WelcomeBonusPending)
+	return err
+}
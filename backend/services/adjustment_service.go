@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrInsufficientBalance is returned when a debit is larger than the wallet balance
+	ErrInsufficientBalance = errors.New("wallet balance does not cover the debit")
+	// ErrAdjustmentNotPending is returned when reviewing an adjustment that was already reviewed
+	ErrAdjustmentNotPending = errors.New("adjustment is not pending")
+	// ErrSelfApproval is returned when the requester tries to review their own adjustment
+	ErrSelfApproval = errors.New("adjustments must be reviewed by someone other than the requester")
+)
+
+// AdjustmentService posts manual credits and debits to customer wallets. Adjustments above
+// the merchant's approval threshold wait as PENDING until another staff member approves them.
+// Applied adjustments are ADJUST entries in the ledger, so they show in the customer's history.
+type AdjustmentService struct {
+	repo *repository.Repository
+}
+
+// NewAdjustmentService creates a new adjustment service
+func NewAdjustmentService(repo *repository.Repository) *AdjustmentService {
+	return &AdjustmentService{repo: repo}
+}
+
+// Request validates the adjustment and applies it right away, or leaves it pending when the
+// amount is above the approval threshold
+func (s *AdjustmentService) Request(
+	ctx context.Context,
+	merchant *domain.Merchant,
+	wallet *domain.Wallet,
+	adjustment *domain.BalanceAdjustment,
+) (*domain.BalanceAdjustment, error) {
+	var errs domain.ValidationErrors
+	if adjustment.Direction != domain.AdjustmentCredit && adjustment.Direction != domain.AdjustmentDebit {
+		errs = append(errs, domain.NewFieldError("direction", "must be CREDIT or DEBIT"))
+	}
+	if adjustment.Amount <= 0 {
+		errs = append(errs, domain.NewFieldError("amount", "must be greater than 0"))
+	}
+	if !adjustment.ReasonCode.IsValid() {
+		errs = append(errs, domain.NewFieldError("reasonCode", "is invalid: %s", adjustment.ReasonCode))
+	}
+	if strings.TrimSpace(adjustment.Note) == "" {
+		errs = append(errs, domain.NewFieldError("note", "is required"))
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	adjustment.ID = uuid.New()
+	adjustment.MerchantID = merchant.ID
+	adjustment.WalletID = wallet.ID
+	adjustment.CreatedAt = time.Now()
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if adjustment.Amount > merchant.AdjustmentApprovalThreshold() {
+		adjustment.Status = domain.AdjustmentStatusPending
+		if err := s.repo.CreateAdjustmentWithTx(ctx, tx, adjustment); err != nil {
+			return nil, fmt.Errorf("failed to create adjustment: %w", err)
+		}
+	} else {
+		adjustment.Status = domain.AdjustmentStatusApplied
+		transactionID, err := s.postWithTx(ctx, tx, adjustment, adjustment.RequestedBy)
+		if err != nil {
+			return nil, err
+		}
+		adjustment.TransactionID = &transactionID
+		if err := s.repo.CreateAdjustmentWithTx(ctx, tx, adjustment); err != nil {
+			return nil, fmt.Errorf("failed to create adjustment: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return adjustment, nil
+}
+
+// Approve posts a pending adjustment to the ledger
+func (s *AdjustmentService) Approve(ctx context.Context, adjustment *domain.BalanceAdjustment, reviewer string) (*domain.BalanceAdjustment, error) {
+	return s.review(ctx, adjustment, reviewer, domain.AdjustmentStatusApplied)
+}
+
+// Reject discards a pending adjustment
+func (s *AdjustmentService) Reject(ctx context.Context, adjustment *domain.BalanceAdjustment, reviewer string) (*domain.BalanceAdjustment, error) {
+	return s.review(ctx, adjustment, reviewer, domain.AdjustmentStatusRejected)
+}
+
+func (s *AdjustmentService) review(
+	ctx context.Context,
+	adjustment *domain.BalanceAdjustment,
+	reviewer string,
+	status domain.AdjustmentStatus,
+) (*domain.BalanceAdjustment, error) {
+	if adjustment.Status != domain.AdjustmentStatusPending {
+		return nil, ErrAdjustmentNotPending
+	}
+	if strings.EqualFold(strings.TrimSpace(reviewer), strings.TrimSpace(adjustment.RequestedBy)) {
+		return nil, ErrSelfApproval
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var transactionID *uuid.UUID
+	if status == domain.AdjustmentStatusApplied {
+		id, err := s.postWithTx(ctx, tx, adjustment, reviewer)
+		if err != nil {
+			return nil, err
+		}
+		transactionID = &id
+	}
+
+	// Only one reviewer wins when two review the same adjustment at once
+	reviewed, err := s.repo.ReviewAdjustmentWithTx(ctx, tx, adjustment.ID, status, reviewer, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to review adjustment: %w", err)
+	}
+	if !reviewed {
+		return nil, ErrAdjustmentNotPending
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	now := time.Now()
+	adjustment.Status = status
+	adjustment.ReviewedBy = &reviewer
+	adjustment.ReviewedAt = &now
+	adjustment.TransactionID = transactionID
+	return adjustment, nil
+}
+
+// postWithTx moves the wallet balance and records the ADJUST ledger entry
+func (s *AdjustmentService) postWithTx(ctx context.Context, tx *sqlx.Tx, adjustment *domain.BalanceAdjustment, approvedBy string) (uuid.UUID, error) {
+	if _, err := s.repo.AdjustWalletBalanceWithTx(ctx, tx, adjustment.WalletID, adjustment.SignedAmount()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrInsufficientBalance
+		}
+		return uuid.Nil, fmt.Errorf("failed to update wallet balance: %w", err)
+	}
+
+	metadata, err := json.Marshal(map[string]interface{}{
+		"adjustment_id": adjustment.ID,
+		"reason_code":   adjustment.ReasonCode,
+		"note":          adjustment.Note,
+		"requested_by":  adjustment.RequestedBy,
+		"approved_by":   approvedBy,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	ledgerTx := &domain.Transaction{
+		ID:         uuid.New(),
+		MerchantID: adjustment.MerchantID,
+		WalletID:   &adjustment.WalletID,
+		Type:       domain.TransactionTypeAdjust,
+		Amount:     adjustment.SignedAmount(),
+		Metadata:   metadata,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.repo.CreateTransactionWithTx(ctx, tx, ledgerTx); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	return ledgerTx.ID, nil
+}
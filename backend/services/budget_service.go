@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// BudgetService enforces campaign budget caps during ingestion. A reward that would exceed
+// a cap is not granted; when a total cap is used up the campaign is paused. Merchants are
+// alerted once when each cap reaches 80% and 100%.
+type BudgetService struct {
+	repo     *repository.Repository
+	notifier Notifier
+}
+
+// NewBudgetService creates a new budget service
+func NewBudgetService(repo *repository.Repository, notifier Notifier) *BudgetService {
+	return &BudgetService{
+		repo:     repo,
+		notifier: notifier,
+	}
+}
+
+// ChargeWithTx charges the strategy result to the campaign budget inside the settlement
+// transaction. When a cap would be exceeded the result is turned into a no-op that keeps
+// currentState, so the purchase neither pays out nor advances the customer's progress.
+// It returns the alerts to send once the transaction commits.
+func (s *BudgetService) ChargeWithTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	merchant *domain.Merchant,
+	campaign *domain.Campaign,
+	currentState json.RawMessage,
+	result *domain.StrategyResult,
+) ([]*domain.BudgetAlert, error) {
+	if !campaign.HasBudget() || (result.NewBalance <= 0 && result.RewardEarned == nil) {
+		return nil, nil
+	}
+
+	charged, alerts, err := s.chargeWithTx(ctx, tx, merchant, campaign, result.NewBalance, rewardCount(result))
+	if err != nil {
+		return nil, err
+	}
+	if !charged {
+		// Over budget: drop the reward (a prize already drawn is released by the caller)
+		skipReward(result, currentState)
+	}
+	return alerts, nil
+}
+
+// ChargeBonusWithTx charges a welcome bonus to the campaign budget. The bonus counts towards
+// the value caps but not towards the number of rewards. Returns false when it does not fit.
+func (s *BudgetService) ChargeBonusWithTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	merchant *domain.Merchant,
+	campaign *domain.Campaign,
+	amount float64,
+) (bool, []*domain.BudgetAlert, error) {
+	if !campaign.HasBudget() || amount <= 0 {
+		return true, nil, nil
+	}
+	return s.chargeWithTx(ctx, tx, merchant, campaign, amount, 0)
+}
+
+// chargeWithTx adds value and rewards to the budget usage. When a cap would be exceeded
+// nothing is charged and it returns false with the 100% alerts of the caps that were hit.
+func (s *BudgetService) chargeWithTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	merchant *domain.Merchant,
+	campaign *domain.Campaign,
+	value float64,
+	rewards int,
+) (bool, []*domain.BudgetAlert, error) {
+	day := time.Now().In(merchant.Location()).Format("2006-01-02")
+
+	usage, err := s.repo.ChargeBudgetWithTx(ctx, tx, campaign, day, value, rewards)
+	if err == nil {
+		alerts, err := s.crossedThresholdsWithTx(ctx, tx, campaign, usage, day)
+		if err != nil {
+			return false, nil, err
+		}
+		return true, alerts, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, nil, fmt.Errorf("failed to charge campaign budget: %w", err)
+	}
+
+	usage, err = s.repo.GetBudgetUsageWithTx(ctx, tx, campaign.ID)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get campaign budget usage: %w", err)
+	}
+
+	// A reward that does not fit in the remaining budget exhausts it just as well
+	var alerts []*domain.BudgetAlert
+	exhausted := false
+	if campaign.MaxRewardValue != nil && usage.RewardValue+value > *campaign.MaxRewardValue {
+		exhausted = true
+		alerts = append(alerts, s.alert(campaign, domain.BudgetKindRewardValue, 100, "total", usage.RewardValue, *campaign.MaxRewardValue))
+	}
+	if campaign.MaxRewards != nil && usage.RewardsIssued+rewards > *campaign.MaxRewards {
+		exhausted = true
+		alerts = append(alerts, s.alert(campaign, domain.BudgetKindRewards, 100, "total", float64(usage.RewardsIssued), float64(*campaign.MaxRewards)))
+	}
+	if !exhausted && campaign.DailyRewardCap != nil {
+		alerts = append(alerts, s.alert(campaign, domain.BudgetKindDailyValue, 100, day, dailyValue(usage, day), *campaign.DailyRewardCap))
+	}
+
+	if exhausted {
+		if err := s.repo.PauseCampaignWithTx(ctx, tx, campaign.ID); err != nil {
+			return false, nil, fmt.Errorf("failed to pause campaign: %w", err)
+		}
+	}
+
+	alerts, err = s.recordWithTx(ctx, tx, alerts)
+	return false, alerts, err
+}
+
+// Notify sends the alerts returned by ChargeWithTx. Delivery failures are logged only,
+// the purchase has already been settled.
+func (s *BudgetService) Notify(ctx context.Context, alerts []*domain.BudgetAlert) {
+	for _, alert := range alerts {
+		subject, message := budgetAlertMessage(alert)
+		if err := s.notifier.Notify(ctx, alert.MerchantID, subject, message); err != nil {
+			log.Printf("failed to send budget alert for campaign %s: %v", alert.CampaignID, err)
+		}
+	}
+}
+
+// Report computes how much of each cap the campaign has consumed
+func (s *BudgetService) Report(campaign *domain.Campaign, usage *domain.BudgetUsage, loc *time.Location) *domain.CampaignBudget {
+	budget := &domain.CampaignBudget{
+		MaxRewardValue: campaign.MaxRewardValue,
+		MaxRewards:     campaign.MaxRewards,
+		DailyRewardCap: campaign.DailyRewardCap,
+	}
+	if usage != nil {
+		budget.RewardValue = usage.RewardValue
+		budget.RewardsIssued = usage.RewardsIssued
+		budget.TodayRewardValue = dailyValue(usage, time.Now().In(loc).Format("2006-01-02"))
+	}
+
+	if campaign.MaxRewardValue != nil {
+		budget.RewardValueUsed = share(budget.RewardValue, *campaign.MaxRewardValue)
+		budget.Exhausted = budget.Exhausted || budget.RewardValue >= *campaign.MaxRewardValue
+	}
+	if campaign.MaxRewards != nil {
+		budget.RewardsUsed = share(float64(budget.RewardsIssued), float64(*campaign.MaxRewards))
+		budget.Exhausted = budget.Exhausted || budget.RewardsIssued >= *campaign.MaxRewards
+	}
+	if campaign.DailyRewardCap != nil {
+		budget.DailyUsed = share(budget.TodayRewardValue, *campaign.DailyRewardCap)
+	}
+
+	return budget
+}
+
+// crossedThresholdsWithTx returns the alerts for thresholds the charge reached, pausing the
+// campaign when a total cap is fully used
+func (s *BudgetService) crossedThresholdsWithTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	campaign *domain.Campaign,
+	usage *domain.BudgetUsage,
+	day string,
+) ([]*domain.BudgetAlert, error) {
+	type capUsage struct {
+		kind   domain.BudgetKind
+		period string
+		used   float64
+		limit  *float64
+	}
+	caps := []capUsage{
+		{domain.BudgetKindRewardValue, "total", usage.RewardValue, campaign.MaxRewardValue},
+		{domain.BudgetKindDailyValue, day, usage.DailyRewardValue, campaign.DailyRewardCap},
+	}
+	if campaign.MaxRewards != nil {
+		limit := float64(*campaign.MaxRewards)
+		caps = append(caps, capUsage{domain.BudgetKindRewards, "total", float64(usage.RewardsIssued), &limit})
+	}
+
+	var alerts []*domain.BudgetAlert
+	exhausted := false
+	for _, c := range caps {
+		if c.limit == nil || *c.limit <= 0 {
+			continue
+		}
+		for _, threshold := range domain.BudgetAlertThresholds {
+			if c.used >= *c.limit*float64(threshold)/100 {
+				alerts = append(alerts, s.alert(campaign, c.kind, threshold, c.period, c.used, *c.limit))
+			}
+		}
+		if c.period == "total" && c.used >= *c.limit {
+			exhausted = true
+		}
+	}
+
+	if exhausted {
+		if err := s.repo.PauseCampaignWithTx(ctx, tx, campaign.ID); err != nil {
+			return nil, fmt.Errorf("failed to pause campaign: %w", err)
+		}
+	}
+
+	return s.recordWithTx(ctx, tx, alerts)
+}
+
+// recordWithTx keeps only the alerts that were not sent before
+func (s *BudgetService) recordWithTx(ctx context.Context, tx *sqlx.Tx, alerts []*domain.BudgetAlert) ([]*domain.BudgetAlert, error) {
+	pending := make([]*domain.BudgetAlert, 0, len(alerts))
+	for _, alert := range alerts {
+		recorded, err := s.repo.RecordBudgetAlertWithTx(ctx, tx, alert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record budget alert: %w", err)
+		}
+		if recorded {
+			pending = append(pending, alert)
+		}
+	}
+	return pending, nil
+}
+
+func (s *BudgetService) alert(campaign *domain.Campaign, kind domain.BudgetKind, threshold int, period string, used, limit float64) *domain.BudgetAlert {
+	return &domain.BudgetAlert{
+		CampaignID:   campaign.ID,
+		MerchantID:   campaign.MerchantID,
+		CampaignName: campaign.Name,
+		Kind:         kind,
+		Threshold:    threshold,
+		Period:       period,
+		Used:         used,
+		Limit:        limit,
+		CreatedAt:    time.Now(),
+	}
+}
+
+// budgetAlertMessage builds the merchant-facing alert
+func budgetAlertMessage(alert *domain.BudgetAlert) (string, string) {
+	var what string
+	switch alert.Kind {
+	case domain.BudgetKindRewards:
+		what = fmt.Sprintf("%.0f de %.0f recompensas", alert.Used, alert.Limit)
+	case domain.BudgetKindDailyValue:
+		what = fmt.Sprintf("R$ %.2f de R$ %.2f do limite diário de %s", alert.Used, alert.Limit, alert.Period)
+	default:
+		what = fmt.Sprintf("R$ %.2f de R$ %.2f do orçamento", alert.Used, alert.Limit)
+	}
+
+	if alert.Threshold < 100 {
+		return fmt.Sprintf("Campanha %s: %d%% do orçamento usado", alert.CampaignName, alert.Threshold),
+			fmt.Sprintf("A campanha %s já usou %s.", alert.CampaignName, what)
+	}
+
+	if alert.Kind == domain.BudgetKindDailyValue {
+		return fmt.Sprintf("Campanha %s: limite diário atingido", alert.CampaignName),
+			fmt.Sprintf("A campanha %s usou %s. Novas recompensas voltam amanhã.", alert.CampaignName, what)
+	}
+	return fmt.Sprintf("Campanha %s: orçamento esgotado", alert.CampaignName),
+		fmt.Sprintf("A campanha %s usou %s e foi pausada.", alert.CampaignName, what)
+}
+
+// rewardCount returns how many rewards the result grants (a punch card can complete several cards)
+func rewardCount(result *domain.StrategyResult) int {
+	if result.RewardEarned == nil {
+		return 0
+	}
+	if result.RewardEarned.CardsCompleted > 1 {
+		return result.RewardEarned.CardsCompleted
+	}
+	return 1
+}
+
+// dailyValue returns the value granted on day, which is zero when the usage row refers to another day
+func dailyValue(usage *domain.BudgetUsage, day string) float64 {
+	if usage.Day.Format("2006-01-02") != day {
+		return 0
+	}
+	return usage.DailyRewardValue
+}
+
+func share(used, limit float64) *float64 {
+	if limit <= 0 {
+		value := 1.0
+		return &value
+	}
+	value := used / limit
+	return &value
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// CampaignStore is the storage the campaign cache reads through, implemented by *repository.Repository
+type CampaignStore interface {
+	GetActiveCampaigns(ctx context.Context, merchantID uuid.UUID) ([]*domain.Campaign, error)
+	GetCampaignsByMerchant(ctx context.Context, merchantID uuid.UUID, status domain.CampaignStatus) ([]*domain.Campaign, error)
+}
+
+// CampaignCache keeps each merchant's active campaigns in memory, sparing a query on every
+// ingested transaction. A merchant's entry is dropped as soon as a campaign_changed
+// notification names it; the TTL only bounds staleness if one is missed. Start and end
+// dates are checked on every read. A TTL of zero disables caching.
+type CampaignCache struct {
+	repo CampaignStore
+	ttl  time.Duration
+
+	mu         sync.RWMutex
+	generation uint64 // Bumped by every invalidation, so loads that started before one are not stored
+	byMerchant map[uuid.UUID]cachedCampaigns
+}
+
+type cachedCampaigns struct {
+	campaigns []*domain.Campaign // Every ACTIVE campaign, newest first, running or not
+	expiresAt time.Time
+}
+
+// NewCampaignCache creates a new campaign cache
+func NewCampaignCache(repo CampaignStore, ttl time.Duration) *CampaignCache {
+	return &CampaignCache{
+		repo:       repo,
+		ttl:        ttl,
+		byMerchant: make(map[uuid.UUID]cachedCampaigns),
+	}
+}
+
+// GetActiveCampaigns returns the merchant's running campaigns, newest first. Callers get
+// their own copies.
+func (c *CampaignCache) GetActiveCampaigns(ctx context.Context, merchantID uuid.UUID) ([]*domain.Campaign, error) {
+	if c.ttl <= 0 {
+		return c.repo.GetActiveCampaigns(ctx, merchantID)
+	}
+
+	c.mu.RLock()
+	entry, ok := c.byMerchant[merchantID]
+	generation := c.generation
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		campaigns, err := c.repo.GetCampaignsByMerchant(ctx, merchantID, domain.CampaignStatusActive)
+		if err != nil {
+			return nil, err
+		}
+		entry = cachedCampaigns{campaigns: campaigns, expiresAt: time.Now().Add(c.ttl)}
+
+		// Campaigns changed while they were loading may have been read before the change
+		c.mu.Lock()
+		if c.generation == generation {
+			c.byMerchant[merchantID] = entry
+		}
+		c.mu.Unlock()
+	}
+
+	now := time.Now()
+	running := make([]*domain.Campaign, 0, len(entry.campaigns))
+	for _, campaign := range entry.campaigns {
+		if campaign.IsRunning(now) {
+			copied := *campaign
+			running = append(running, &copied)
+		}
+	}
+	return running, nil
+}
+
+// Invalidate drops the cached campaigns of a merchant
+func (c *CampaignCache) Invalidate(merchantID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	delete(c.byMerchant, merchantID)
+}
+
+// Flush drops every cached entry
+func (c *CampaignCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.byMerchant = make(map[uuid.UUID]cachedCampaigns)
+}
+
+// HandleNotification invalidates the merchant named in a campaign_changed notification
+func (c *CampaignCache) HandleNotification(ctx context.Context, payload string) error {
+	var change domain.EntityChange
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		return fmt.Errorf("invalid campaign change: %w", err)
+	}
+	c.Invalidate(change.MerchantID)
+	return nil
+}
+
+// CatchUp flushes the cache, since changes may have been missed while disconnected
+func (c *CampaignCache) CatchUp(ctx context.Context) error {
+	c.Flush()
+	return nil
+}
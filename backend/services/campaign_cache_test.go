@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// stubCampaignStore serves the ACTIVE campaigns of one merchant and counts the loads.
+// loading, when set, runs in the middle of a load, after the campaigns were read.
+type stubCampaignStore struct {
+	campaigns []domain.Campaign
+	loads     int
+	loading   func()
+}
+
+func (s *stubCampaignStore) GetActiveCampaigns(ctx context.Context, merchantID uuid.UUID) ([]*domain.Campaign, error) {
+	s.loads++
+	now := time.Now()
+	var running []*domain.Campaign
+	for _, campaign := range s.read() {
+		if campaign.IsRunning(now) {
+			running = append(running, campaign)
+		}
+	}
+	return running, nil
+}
+
+func (s *stubCampaignStore) GetCampaignsByMerchant(ctx context.Context, merchantID uuid.UUID, status domain.CampaignStatus) ([]*domain.Campaign, error) {
+	s.loads++
+	return s.read(), nil
+}
+
+func (s *stubCampaignStore) read() []*domain.Campaign {
+	campaigns := make([]*domain.Campaign, 0, len(s.campaigns))
+	for i := range s.campaigns {
+		campaign := s.campaigns[i]
+		campaigns = append(campaigns, &campaign)
+	}
+	if s.loading != nil {
+		loading := s.loading
+		s.loading = nil
+		loading()
+	}
+	return campaigns
+}
+
+func TestCampaignCache(t *testing.T) {
+	ctx := context.Background()
+	merchantID := uuid.New()
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	campaignNames := func(t *testing.T, cache *CampaignCache) []string {
+		t.Helper()
+		campaigns, err := cache.GetActiveCampaigns(ctx, merchantID)
+		if err != nil {
+			t.Fatalf("GetActiveCampaigns() error = %v", err)
+		}
+		names := make([]string, 0, len(campaigns))
+		for _, campaign := range campaigns {
+			names = append(names, campaign.Name)
+		}
+		return names
+	}
+
+	t.Run("dates are checked on every read", func(t *testing.T) {
+		store := &stubCampaignStore{campaigns: []domain.Campaign{
+			{ID: uuid.New(), Name: "Ending", Status: domain.CampaignStatusActive, EndsAt: &future},
+			{ID: uuid.New(), Name: "Ended", Status: domain.CampaignStatusActive, EndsAt: &past},
+			{ID: uuid.New(), Name: "Starting later", Status: domain.CampaignStatusActive, StartsAt: &future},
+			{ID: uuid.New(), Name: "Running", Status: domain.CampaignStatusActive, StartsAt: &past},
+		}}
+		cache := NewCampaignCache(store, time.Minute)
+
+		want := fmt.Sprint([]string{"Ending", "Running"})
+		if got := fmt.Sprint(campaignNames(t, cache)); got != want {
+			t.Fatalf("GetActiveCampaigns() = %s, want %s", got, want)
+		}
+
+		// The first campaign ends while cached
+		ended := time.Now().Add(-time.Second)
+		cache.byMerchant[merchantID].campaigns[0].EndsAt = &ended
+		want = fmt.Sprint([]string{"Running"})
+		if got := fmt.Sprint(campaignNames(t, cache)); got != want || store.loads != 1 {
+			t.Errorf("GetActiveCampaigns() = %s after %d loads, want %s after 1", got, store.loads, want)
+		}
+	})
+
+	t.Run("cached until the merchant's campaigns change", func(t *testing.T) {
+		store := &stubCampaignStore{campaigns: []domain.Campaign{
+			{ID: uuid.New(), Name: "Cartão", Status: domain.CampaignStatusActive},
+		}}
+		cache := NewCampaignCache(store, time.Minute)
+
+		campaignNames(t, cache)
+		campaignNames(t, cache)
+		if store.loads != 1 {
+			t.Fatalf("loads = %d, want 1", store.loads)
+		}
+
+		other := fmt.Sprintf(`{"id":%q,"merchant_id":%q,"op":"UPDATE"}`, uuid.New(), uuid.New())
+		if err := cache.HandleNotification(ctx, other); err != nil {
+			t.Fatalf("HandleNotification() error = %v", err)
+		}
+		campaignNames(t, cache)
+		if store.loads != 1 {
+			t.Errorf("loads = %d after another merchant's change, want 1", store.loads)
+		}
+
+		store.campaigns[0].Status = domain.CampaignStatusPaused
+		own := fmt.Sprintf(`{"id":%q,"merchant_id":%q,"op":"UPDATE"}`, store.campaigns[0].ID, merchantID)
+		if err := cache.HandleNotification(ctx, own); err != nil {
+			t.Fatalf("HandleNotification() error = %v", err)
+		}
+		if got := campaignNames(t, cache); len(got) != 0 || store.loads != 2 {
+			t.Errorf("GetActiveCampaigns() = %v after %d loads, want none after 2", got, store.loads)
+		}
+	})
+
+	t.Run("zero TTL disables caching", func(t *testing.T) {
+		store := &stubCampaignStore{campaigns: []domain.Campaign{
+			{ID: uuid.New(), Name: "Cartão", Status: domain.CampaignStatusActive},
+		}}
+		cache := NewCampaignCache(store, 0)
+
+		campaignNames(t, cache)
+		campaignNames(t, cache)
+		if store.loads != 2 {
+			t.Errorf("loads = %d, want 2", store.loads)
+		}
+	})
+
+	t.Run("load overtaken by an invalidation is not cached", func(t *testing.T) {
+		store := &stubCampaignStore{campaigns: []domain.Campaign{
+			{ID: uuid.New(), Name: "Cartão", Status: domain.CampaignStatusActive},
+		}}
+		cache := NewCampaignCache(store, time.Minute)
+
+		// The campaign is paused after the load read it but before the load stored it
+		store.loading = func() {
+			store.campaigns[0].Status = domain.CampaignStatusPaused
+			cache.Invalidate(merchantID)
+		}
+
+		if got := campaignNames(t, cache); len(got) != 1 {
+			t.Fatalf("GetActiveCampaigns() = %v, want the campaign as read", got)
+		}
+		if got := campaignNames(t, cache); len(got) != 0 {
+			t.Errorf("GetActiveCampaigns() after the invalidation = %v, want none", got)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+)
+
+// ErrCampaignStatusChanged is returned when another request or the scheduler changed the
+// campaign status first
+var ErrCampaignStatusChanged = errors.New("campaign status changed concurrently")
+
+// CampaignLifecycle moves campaigns through their statuses. Manual changes come from the
+// merchant; the scheduler starts and ends campaigns at starts_at and ends_at and notifies
+// the merchant of each change.
+type CampaignLifecycle struct {
+	repo     *repository.Repository
+	notifier Notifier
+}
+
+// NewCampaignLifecycle creates a new campaign lifecycle
+func NewCampaignLifecycle(repo *repository.Repository, notifier Notifier) *CampaignLifecycle {
+	return &CampaignLifecycle{
+		repo:     repo,
+		notifier: notifier,
+	}
+}
+
+// Transition moves the campaign to the given status if the lifecycle allows it
+func (l *CampaignLifecycle) Transition(ctx context.Context, campaign *domain.Campaign, to domain.CampaignStatus) error {
+	from := campaign.Status
+	if !from.CanTransitionTo(to) {
+		return &domain.TransitionError{From: from, To: to, Allowed: from.Transitions()}
+	}
+
+	tx, err := l.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	changed, err := l.repo.TransitionCampaignStatusWithTx(ctx, tx, campaign.ID, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to change campaign status: %w", err)
+	}
+	if !changed {
+		return ErrCampaignStatusChanged
+	}
+
+	// An ended campaign draws no more, so its instant-win seed becomes public
+	if to.IsFinal() {
+		if err := l.repo.MarkInstantWinSeedRevealedWithTx(ctx, tx, campaign.ID); err != nil {
+			return fmt.Errorf("failed to reveal draw seed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	campaign.SetStatus(to)
+	campaign.UpdatedAt = time.Now()
+	return nil
+}
+
+// RunSchedule starts scheduled campaigns whose start time passed and ends running ones
+// whose end time passed. A campaign whose whole window is in the past is started and
+// ended in the same run.
+func (l *CampaignLifecycle) RunSchedule(ctx context.Context, now time.Time) (started, ended int, err error) {
+	due, err := l.repo.GetCampaignsDueToStart(ctx, now)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get campaigns due to start: %w", err)
+	}
+	for _, campaign := range due {
+		if l.scheduledTransition(ctx, campaign, domain.CampaignStatusActive) {
+			started++
+		}
+	}
+
+	due, err = l.repo.GetCampaignsDueToEnd(ctx, now)
+	if err != nil {
+		return started, 0, fmt.Errorf("failed to get campaigns due to end: %w", err)
+	}
+	for _, campaign := range due {
+		if l.scheduledTransition(ctx, campaign, domain.CampaignStatusEnded) {
+			ended++
+		}
+	}
+
+	return started, ended, nil
+}
+
+// scheduledTransition applies a scheduler transition and notifies the merchant.
+// Failures are logged so one campaign does not hold back the others.
+func (l *CampaignLifecycle) scheduledTransition(ctx context.Context, campaign *domain.Campaign, to domain.CampaignStatus) bool {
+	if err := l.Transition(ctx, campaign, to); err != nil {
+		if !errors.Is(err, ErrCampaignStatusChanged) {
+			log.Printf("failed to move campaign %s to %s: %v", campaign.ID, to, err)
+		}
+		return false
+	}
+
+	subject, message := lifecycleMessage(campaign)
+	if err := l.notifier.Notify(ctx, campaign.MerchantID, subject, message); err != nil {
+		log.Printf("failed to notify status change of campaign %s: %v", campaign.ID, err)
+	}
+	return true
+}
+
+// lifecycleMessage builds the merchant-facing notification of a scheduled transition
+func lifecycleMessage(campaign *domain.Campaign) (string, string) {
+	if campaign.Status == domain.CampaignStatusActive {
+		return fmt.Sprintf("Campanha %s iniciada", campaign.Name),
+			fmt.Sprintf("A campanha %s começou conforme agendado e já está concedendo recompensas.", campaign.Name)
+	}
+	return fmt.Sprintf("Campanha %s encerrada", campaign.Name),
+		fmt.Sprintf("A campanha %s chegou ao fim. Os saldos dos clientes continuam válidos para resgate.", campaign.Name)
+}
@@ -0,0 +1,460 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+
+	"github.com/google/uuid"
+)
+
+// CampaignService manages campaign config versions and the wallet state pinned to them.
+// Every config change creates an immutable version; wallets remember the version their
+// state was built with so a running card is never reinterpreted under new rules by accident.
+type CampaignService struct {
+	repo       *repository.Repository
+	strategies domain.StrategyRegistry
+}
+
+// NewCampaignService creates a new campaign service
+func NewCampaignService(repo *repository.Repository, strategies domain.StrategyRegistry) *CampaignService {
+	return &CampaignService{
+		repo:       repo,
+		strategies: strategies,
+	}
+}
+
+// VersionRun is the config version a transaction runs under
+type VersionRun struct {
+	Campaign *domain.Campaign        // Campaign carrying the config of Version
+	Version  *domain.CampaignVersion // Version the wallet state belongs to
+	Current  *domain.CampaignVersion // Latest version of the campaign
+}
+
+// UpdateCampaign saves the campaign and, when the config changed, records a new version and
+// applies the migration mode to existing wallet state. The campaign type cannot change.
+func (s *CampaignService) UpdateCampaign(
+	ctx context.Context,
+	campaign *domain.Campaign,
+	config json.RawMessage,
+	mode domain.MigrationMode,
+) error {
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Concurrent updates queue up on the campaign row, so each one sees the version the
+	// previous one created instead of both inserting the same next version
+	locked, err := s.repo.GetCampaignForUpdateWithTx(ctx, tx, campaign.ID)
+	if err != nil {
+		return fmt.Errorf("failed to lock campaign: %w", err)
+	}
+	campaign.CurrentVersion = locked.CurrentVersion
+
+	if sameConfig(locked.Config, config) {
+		campaign.Config = locked.Config
+		if err := s.repo.UpdateCampaignWithTx(ctx, tx, campaign); err != nil {
+			return fmt.Errorf("failed to update campaign: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	}
+
+	strategy, ok := s.strategies[campaign.Type]
+	if !ok {
+		return fmt.Errorf("no strategy found for campaign type: %s", campaign.Type)
+	}
+
+	versions, err := s.repo.GetCampaignVersionsWithTx(ctx, tx, campaign.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get campaign versions: %w", err)
+	}
+	configs := make(map[uuid.UUID]json.RawMessage, len(versions))
+	var previous *domain.CampaignVersion
+	for _, version := range versions {
+		configs[version.ID] = version.Config
+		if version.Version == campaign.CurrentVersion {
+			previous = version
+		}
+	}
+	if previous == nil {
+		return fmt.Errorf("failed to get current campaign version: version %d not found", campaign.CurrentVersion)
+	}
+
+	next := &domain.CampaignVersion{
+		ID:            uuid.New(),
+		CampaignID:    campaign.ID,
+		Version:       campaign.CurrentVersion + 1,
+		Config:        config,
+		MigrationMode: mode,
+		CreatedAt:     time.Now(),
+	}
+	if err := s.repo.CreateCampaignVersionWithTx(ctx, tx, next); err != nil {
+		return fmt.Errorf("failed to create campaign version: %w", err)
+	}
+
+	campaign.Config = config
+	campaign.CurrentVersion = next.Version
+	if err := s.repo.UpdateCampaignWithTx(ctx, tx, campaign); err != nil {
+		return fmt.Errorf("failed to update campaign: %w", err)
+	}
+
+	// Prize inventory follows the edited tiers
+	if campaign.Type == domain.CampaignTypeInstantWin {
+		var instantWin domain.InstantWinConfig
+		if err := json.Unmarshal(config, &instantWin); err != nil {
+			return fmt.Errorf("failed to parse config: %w", err)
+		}
+		if err := s.repo.SyncInstantWinInventoryWithTx(ctx, tx, campaign.ID, instantWin.Prizes); err != nil {
+			return fmt.Errorf("failed to sync prize inventory: %w", err)
+		}
+	}
+
+	switch mode {
+	case domain.MigrationModeMigrateAll:
+		oldConfigFor := func(versionID *uuid.UUID) json.RawMessage {
+			if versionID != nil {
+				if oldConfig, ok := configs[*versionID]; ok {
+					return oldConfig
+				}
+			}
+			return previous.Config
+		}
+
+		wallets, err := s.repo.GetCampaignWalletsForUpdateWithTx(ctx, tx, campaign.MerchantID, campaign.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get wallets: %w", err)
+		}
+		for _, wallet := range wallets {
+			state, err := migrateState(strategy, oldConfigFor(wallet.CampaignVersionID), config, wallet.State)
+			if err != nil {
+				return fmt.Errorf("failed to migrate wallet %s: %w", wallet.ID, err)
+			}
+			if err := s.repo.UpdateWalletStateWithTx(ctx, tx, wallet.ID, state, next.ID); err != nil {
+				return fmt.Errorf("failed to update wallet %s: %w", wallet.ID, err)
+			}
+		}
+
+		shadows, err := s.repo.GetCampaignShadowBalancesForUpdateWithTx(ctx, tx, campaign.MerchantID, campaign.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get shadow balances: %w", err)
+		}
+		for _, shadow := range shadows {
+			state, err := migrateState(strategy, oldConfigFor(shadow.CampaignVersionID), config, shadow.State)
+			if err != nil {
+				return fmt.Errorf("failed to migrate shadow balance %s: %w", shadow.ID, err)
+			}
+			if err := s.repo.UpdateShadowBalanceStateWithTx(ctx, tx, shadow.ID, state, next.ID); err != nil {
+				return fmt.Errorf("failed to update shadow balance %s: %w", shadow.ID, err)
+			}
+		}
+
+	default:
+		// State built before versioning belongs to the config that was live until now
+		if err := s.repo.PinUnversionedStateWithTx(ctx, tx, campaign.MerchantID, campaign.ID, previous.ID); err != nil {
+			return fmt.Errorf("failed to pin existing wallets: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ResolveVersion returns the config version a wallet pinned to versionID runs under.
+// Wallets without a version, or whose version no longer exists, use the current version.
+func (s *CampaignService) ResolveVersion(ctx context.Context, campaign *domain.Campaign, versionID *uuid.UUID) (*VersionRun, error) {
+	current, err := s.repo.GetCampaignVersion(ctx, campaign.ID, campaign.CurrentVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign version: %w", err)
+	}
+
+	run := &VersionRun{Campaign: campaign, Version: current, Current: current}
+	if versionID == nil || *versionID == current.ID {
+		return run, nil
+	}
+
+	pinned, err := s.repo.GetCampaignVersionByID(ctx, *versionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return run, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned campaign version: %w", err)
+	}
+	if pinned.CampaignID != campaign.ID {
+		// Another campaign's state is reset (see StateReset) before it reaches a strategy
+		return nil, fmt.Errorf("state is pinned to version %s of another campaign", pinned.ID)
+	}
+
+	pinnedCampaign := *campaign
+	pinnedCampaign.Config = pinned.Config
+	run.Campaign = &pinnedCampaign
+	run.Version = pinned
+	return run, nil
+}
+
+// StateReset returns the ledger entry recording that state built under another campaign
+// (ownerID) is dropped before campaignID runs, or nil when the state is the campaign's own or
+// holds nothing. A customer moved to another segment's campaign starts over there: one
+// strategy cannot read another's state. State without an owner predates owners and stays.
+func StateReset(merchantID, campaignID uuid.UUID, ownerID, versionID *uuid.UUID, state json.RawMessage) (*domain.Transaction, error) {
+	if ownerID == nil || *ownerID == campaignID || isEmptyState(state) {
+		return nil, nil
+	}
+
+	metadata, err := json.Marshal(map[string]interface{}{
+		"reset_state":  state,
+		"new_campaign": campaignID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal reset metadata: %w", err)
+	}
+
+	return &domain.Transaction{
+		ID:                uuid.New(),
+		MerchantID:        merchantID,
+		CampaignID:        ownerID,
+		Type:              domain.TransactionTypeReset,
+		Amount:            0,
+		Metadata:          metadata,
+		CreatedAt:         time.Now(),
+		CampaignVersionID: versionID,
+	}, nil
+}
+
+// SettleVersion returns the version the wallet is pinned to after the transaction and the
+// state to store. A wallet on an older version moves to the current one when it earns a
+// reward (its card completes), carrying the leftover state over through the strategy's migrator.
+func (s *CampaignService) SettleVersion(strategy domain.CampaignStrategy, run *VersionRun, result *domain.StrategyResult) (uuid.UUID, json.RawMessage, error) {
+	if run.Version.ID == run.Current.ID || result.RewardEarned == nil {
+		return run.Version.ID, result.NewState, nil
+	}
+
+	state, err := migrateState(strategy, run.Version.Config, run.Current.Config, result.NewState)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("failed to migrate state to version %d: %w", run.Current.Version, err)
+	}
+	return run.Current.ID, state, nil
+}
+
+// migrateState converts state between config versions, keeping it as is when the strategy
+// has no migrator or there is nothing to migrate
+func migrateState(strategy domain.CampaignStrategy, oldConfig, newConfig, state json.RawMessage) (json.RawMessage, error) {
+	migrator, ok := strategy.(domain.StateMigrator)
+	if !ok || len(state) == 0 || string(state) == "{}" {
+		return state, nil
+	}
+	return migrator.MigrateState(oldConfig, newConfig, state)
+}
+
+// StateMerge is the outcome of folding a shadow balance's state into a wallet
+type StateMerge struct {
+	Campaign   *domain.Campaign       // Campaign the state belongs to, nil when none was found
+	CampaignID *uuid.UUID             // Owner of the merged state
+	VersionID  *uuid.UUID             // Version the merged state is pinned to
+	Result     *domain.StrategyResult // Merged state and any reward the merge completed
+	Reset      *domain.Transaction    // Shadow state dropped for belonging to another campaign
+}
+
+// MergeStates combines a wallet's state with a shadow balance's through the strategy of their
+// campaign. The merged state keeps the wallet's campaign and config version, or the shadow's
+// when the wallet had none, and the shadow state is migrated to that version first. A shadow
+// state of another campaign than the wallet's is reset instead of merged. Campaigns whose
+// strategy cannot merge, or states without a campaign, fall back to adding numeric fields.
+func (s *CampaignService) MergeStates(ctx context.Context, wallet *domain.Wallet, shadow *domain.ShadowBalance) (*StateMerge, error) {
+	merge := &StateMerge{CampaignID: wallet.CampaignID, VersionID: wallet.CampaignVersionID}
+	shadowState := shadow.State
+	if isEmptyState(wallet.State) || wallet.CampaignID == nil {
+		merge.CampaignID, merge.VersionID = shadow.CampaignID, shadow.CampaignVersionID
+	} else {
+		reset, err := StateReset(shadow.MerchantID, *wallet.CampaignID, shadow.CampaignID, shadow.CampaignVersionID, shadow.State)
+		if err != nil {
+			return nil, err
+		}
+		if reset != nil {
+			reset.ShadowBalanceID = &shadow.ID
+			merge.Reset = reset
+			shadowState = json.RawMessage("{}")
+		} else if merge.VersionID == nil {
+			merge.VersionID = shadow.CampaignVersionID
+		}
+	}
+
+	campaign, err := s.mergeCampaign(ctx, shadow.MerchantID, merge.CampaignID, merge.VersionID)
+	if err != nil {
+		return nil, err
+	}
+	merge.Campaign = campaign
+
+	var strategy domain.CampaignStrategy
+	var config, shadowConfig json.RawMessage
+	if campaign != nil {
+		merge.CampaignID = &campaign.ID
+		strategy = s.strategies[campaign.Type]
+		config = campaign.Config
+
+		if merge.Reset == nil && shadow.CampaignVersionID != nil && merge.VersionID != nil && *shadow.CampaignVersionID != *merge.VersionID {
+			if shadowVersion, err := s.repo.GetCampaignVersionByID(ctx, *shadow.CampaignVersionID); err == nil && shadowVersion.CampaignID == campaign.ID {
+				shadowConfig = shadowVersion.Config
+			}
+		}
+	}
+
+	result, err := mergeStates(strategy, config, shadowConfig, wallet.State, shadowState)
+	if err != nil {
+		return nil, err
+	}
+	merge.Result = result
+	return merge, nil
+}
+
+// mergeStates merges a shadow state into a wallet state under config. A shadow state built
+// under another version's config (shadowConfig) is migrated to config first. Without a
+// strategy that can merge, the numeric fields of both states are added.
+func mergeStates(strategy domain.CampaignStrategy, config, shadowConfig, walletState, shadowState json.RawMessage) (*domain.StrategyResult, error) {
+	merger, ok := strategy.(domain.StateMerger)
+	if !ok {
+		state, err := addStates(walletState, shadowState)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.StrategyResult{NewState: state, StateChanged: true}, nil
+	}
+
+	if shadowConfig != nil {
+		migrated, err := migrateState(strategy, shadowConfig, config, shadowState)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate shadow state: %w", err)
+		}
+		shadowState = migrated
+	}
+
+	result, err := merger.MergeState(walletState, shadowState, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge states: %w", err)
+	}
+	return result, nil
+}
+
+// mergeCampaign returns the campaign a merged state belongs to, carrying the config of the
+// pinned version. State without a version belongs to its owner campaign, or to the merchant's
+// newest running campaign when it has none.
+func (s *CampaignService) mergeCampaign(ctx context.Context, merchantID uuid.UUID, campaignID, versionID *uuid.UUID) (*domain.Campaign, error) {
+	if versionID != nil {
+		version, err := s.repo.GetCampaignVersionByID(ctx, *versionID)
+		if err == nil {
+			campaign, err := s.repo.GetCampaignByID(ctx, version.CampaignID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get campaign: %w", err)
+			}
+			pinned := *campaign
+			pinned.Config = version.Config
+			return &pinned, nil
+		}
+	}
+
+	if campaignID != nil {
+		campaign, err := s.repo.GetCampaignByID(ctx, *campaignID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get campaign: %w", err)
+		}
+		return campaign, nil
+	}
+
+	campaigns, err := s.repo.GetActiveCampaigns(ctx, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active campaigns: %w", err)
+	}
+	if len(campaigns) == 0 {
+		return nil, nil
+	}
+	return campaigns[0], nil
+}
+
+// addStates merges two states of unknown shape by adding their numeric fields. Other fields
+// keep the wallet's value, or the shadow's when the wallet does not have them.
+func addStates(walletState, shadowState json.RawMessage) (json.RawMessage, error) {
+	if isEmptyState(walletState) {
+		return shadowState, nil
+	}
+	if isEmptyState(shadowState) {
+		return walletState, nil
+	}
+
+	var walletData, shadowData map[string]interface{}
+	if err := json.Unmarshal(walletState, &walletData); err != nil {
+		return nil, fmt.Errorf("failed to parse wallet state: %w", err)
+	}
+	if err := json.Unmarshal(shadowState, &shadowData); err != nil {
+		return nil, fmt.Errorf("failed to parse shadow state: %w", err)
+	}
+
+	merged := make(map[string]interface{}, len(walletData)+len(shadowData))
+	for k, v := range walletData {
+		merged[k] = v
+	}
+	for k, v := range shadowData {
+		existing, exists := merged[k]
+		if !exists {
+			merged[k] = v
+			continue
+		}
+		if vNum, ok := v.(float64); ok {
+			if existNum, ok := existing.(float64); ok {
+				merged[k] = existNum + vNum
+			}
+		}
+	}
+
+	result, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal merged state: %w", err)
+	}
+	return result, nil
+}
+
+// isEmptyState reports whether a stored state holds no progress
+func isEmptyState(state json.RawMessage) bool {
+	return len(state) == 0 || string(state) == "{}" || string(state) == "null"
+}
+
+// DescribeProgress reads each entry's state through the strategy of its campaign. Entries
+// without a campaign, or whose strategy cannot describe its state, keep only the raw state.
+func (s *CampaignService) DescribeProgress(entries ...*domain.CampaignProgress) {
+	for _, entry := range entries {
+		if entry.CampaignType == nil {
+			continue
+		}
+		describer, ok := s.strategies[*entry.CampaignType].(domain.StateDescriber)
+		if !ok {
+			continue
+		}
+		// A state the strategy cannot read is still returned raw
+		if progress, err := describer.Describe(entry.State, entry.Config); err == nil {
+			entry.Progress = progress
+		}
+	}
+}
+
+// sameConfig compares two configs ignoring formatting and key order (JSONB reorders keys)
+func sameConfig(a, b json.RawMessage) bool {
+	var parsedA, parsedB interface{}
+	if json.Unmarshal(a, &parsedA) != nil || json.Unmarshal(b, &parsedB) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(parsedA, parsedB)
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/strategies"
+
+	"github.com/google/uuid"
+)
+
+func TestMergeStates(t *testing.T) {
+	punchCard := strategies.NewPunchCardStrategy()
+	tenPunches := json.RawMessage(`{"required_punches":10,"reward_amount":15,"reward_type":"discount"}`)
+	twentyPunches := json.RawMessage(`{"required_punches":20,"reward_amount":15,"reward_type":"discount"}`)
+
+	tests := []struct {
+		name         string
+		strategy     domain.CampaignStrategy
+		config       json.RawMessage
+		shadowConfig json.RawMessage
+		wallet       json.RawMessage
+		shadow       json.RawMessage
+		want         string
+		wantBalance  float64
+	}{
+		{
+			name:     "same version",
+			strategy: punchCard,
+			config:   tenPunches,
+			wallet:   json.RawMessage(`{"current_punches":4,"total_redeemed":0}`),
+			shadow:   json.RawMessage(`{"current_punches":5,"total_redeemed":0}`),
+			want:     `{"current_punches":9,"total_redeemed":0}`,
+		},
+		{
+			name:         "shadow migrated to the wallet's version",
+			strategy:     punchCard,
+			config:       twentyPunches,
+			shadowConfig: tenPunches,
+			wallet:       json.RawMessage(`{"current_punches":4,"total_redeemed":0}`),
+			shadow:       json.RawMessage(`{"current_punches":5,"total_redeemed":0}`),
+			want:         `{"current_punches":14,"total_redeemed":0}`,
+		},
+		{
+			name:         "migrated shadow completes a card",
+			strategy:     punchCard,
+			config:       twentyPunches,
+			shadowConfig: tenPunches,
+			wallet:       json.RawMessage(`{"current_punches":12,"total_redeemed":0}`),
+			shadow:       json.RawMessage(`{"current_punches":5,"total_redeemed":0}`),
+			want:         `{"current_punches":2,"total_redeemed":1}`,
+			wantBalance:  15,
+		},
+		{
+			name:         "wallet without state",
+			strategy:     punchCard,
+			config:       twentyPunches,
+			shadowConfig: tenPunches,
+			wallet:       json.RawMessage("null"),
+			shadow:       json.RawMessage(`{"current_punches":5,"total_redeemed":0}`),
+			want:         `{"current_punches":10,"total_redeemed":0}`,
+		},
+		{
+			name:   "no campaign adds numeric fields",
+			wallet: json.RawMessage(`{"current_punches":4,"label":"wallet"}`),
+			shadow: json.RawMessage(`{"current_punches":5,"label":"shadow","extra":1}`),
+			want:   `{"current_punches":9,"extra":1,"label":"wallet"}`,
+		},
+		{
+			name:   "no campaign and empty wallet",
+			wallet: json.RawMessage("{}"),
+			shadow: json.RawMessage(`{"current_punches":5}`),
+			want:   `{"current_punches":5}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := mergeStates(tt.strategy, tt.config, tt.shadowConfig, tt.wallet, tt.shadow)
+			if err != nil {
+				t.Fatalf("mergeStates() error = %v", err)
+			}
+
+			var got, want interface{}
+			if err := json.Unmarshal(result.NewState, &got); err != nil {
+				t.Fatalf("invalid merged state %s: %v", result.NewState, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("invalid want %s: %v", tt.want, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("merged state = %s, want %s", result.NewState, tt.want)
+			}
+			if result.NewBalance != tt.wantBalance {
+				t.Errorf("NewBalance = %v, want %v", result.NewBalance, tt.wantBalance)
+			}
+		})
+	}
+}
+
+func TestStateReset(t *testing.T) {
+	merchantID := uuid.New()
+	campaignID := uuid.New()
+	otherID := uuid.New()
+	versionID := uuid.New()
+	punches := json.RawMessage(`{"current_punches":4,"total_redeemed":0}`)
+
+	tests := []struct {
+		name      string
+		ownerID   *uuid.UUID
+		state     json.RawMessage
+		wantReset bool
+	}{
+		{name: "state of the campaign", ownerID: &campaignID, state: punches},
+		{name: "state without owner", state: punches},
+		{name: "empty state of another campaign", ownerID: &otherID, state: json.RawMessage("{}")},
+		{name: "state of another campaign", ownerID: &otherID, state: punches, wantReset: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset, err := StateReset(merchantID, campaignID, tt.ownerID, &versionID, tt.state)
+			if err != nil {
+				t.Fatalf("StateReset() error = %v", err)
+			}
+			if (reset != nil) != tt.wantReset {
+				t.Fatalf("StateReset() = %v, want reset %v", reset, tt.wantReset)
+			}
+			if reset == nil {
+				return
+			}
+
+			if reset.Type != domain.TransactionTypeReset || reset.Amount != 0 {
+				t.Errorf("entry = %s of %v, want RESET of 0", reset.Type, reset.Amount)
+			}
+			if reset.CampaignID == nil || *reset.CampaignID != otherID {
+				t.Errorf("CampaignID = %v, want the previous owner %v", reset.CampaignID, otherID)
+			}
+			var metadata struct {
+				ResetState  json.RawMessage `json:"reset_state"`
+				NewCampaign uuid.UUID       `json:"new_campaign"`
+			}
+			if err := json.Unmarshal(reset.Metadata, &metadata); err != nil {
+				t.Fatalf("invalid metadata %s: %v", reset.Metadata, err)
+			}
+			if string(metadata.ResetState) != string(punches) || metadata.NewCampaign != campaignID {
+				t.Errorf("metadata = %s, want the dropped state and the new campaign", reset.Metadata)
+			}
+		})
+	}
+}
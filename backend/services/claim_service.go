@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+const (
+	otpLength          = 6
+	otpTTL             = 10 * time.Minute
+	otpMaxAttempts     = 5
+	otpResendInterval  = time.Minute // Between two codes to the same phone
+	otpMaxPerPhoneHour = 5
+	otpMaxPerIPHour    = 20
+)
+
+var (
+	// ErrNoPendingCode is returned when the phone has no usable code: none was sent, it expired,
+	// it was used or it ran out of attempts
+	ErrNoPendingCode = errors.New("no pending code for this phone")
+
+	// ErrInvalidCode is returned when the code does not match
+	ErrInvalidCode = errors.New("invalid code")
+)
+
+// ThrottleError is returned when too many codes were requested
+type ThrottleError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	return fmt.Sprintf("too many codes requested, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// ClaimStore is the storage used by the claim flow, implemented by *repository.Repository
+type ClaimStore interface {
+	CreateOTPCode(ctx context.Context, otp *domain.OTPCode) error
+	GetLatestOTPCode(ctx context.Context, phoneHash string, purpose domain.OTPPurpose) (*domain.OTPCode, error)
+	CountOTPCodesSince(ctx context.Context, phoneHash string, since time.Time) (int, error)
+	CountOTPCodesFromIPSince(ctx context.Context, ipAddress string, since time.Time) (int, error)
+	RecordOTPAttempt(ctx context.Context, otpID uuid.UUID, maxAttempts int) (bool, error)
+	ConsumeOTPCode(ctx context.Context, otpID uuid.UUID) (bool, error)
+	GetActiveShadowBalancesByPhone(ctx context.Context, phoneHash string) ([]*domain.ShadowBalance, error)
+	ClaimShadowBalances(ctx context.Context, phoneHash string, expiresAt time.Time, email *string) (int, error)
+	GetConsumerShadowBalances(ctx context.Context, phoneHash string) ([]*domain.ConsumerShadowBalance, error)
+}
+
+// ClaimService lets customers without the app claim the shadow balances earned with their
+// phone by confirming it with a one-time code sent by SMS
+type ClaimService struct {
+	repo      ClaimStore
+	sms       SMSSender
+	extension time.Duration
+}
+
+// NewClaimService creates a claim service. Claimed balances are kept for at least extension.
+func NewClaimService(repo ClaimStore, sms SMSSender, extension time.Duration) *ClaimService {
+	return &ClaimService{
+		repo:      repo,
+		sms:       sms,
+		extension: extension,
+	}
+}
+
+// ClaimResult lists the balances kept after a successful claim
+type ClaimResult struct {
+	Claimed  int                             `json:"claimed"`
+	Balances []*domain.ConsumerShadowBalance `json:"balances"`
+}
+
+// StartClaim sends a one-time code to the phone when it has balances to claim. Phones without
+// balances are throttled and answered the same way but get no SMS, so the endpoint does not
+// reveal who has balances.
+func (s *ClaimService) StartClaim(ctx context.Context, phone, ipAddress string) error {
+	phone = domain.NormalizePhone(phone)
+	phoneHash := domain.HashPhone(phone)
+	now := time.Now()
+
+	if err := s.checkThrottle(ctx, phoneHash, ipAddress, now); err != nil {
+		return err
+	}
+
+	code, err := generateOTP()
+	if err != nil {
+		return err
+	}
+
+	otp := &domain.OTPCode{
+		ID:        uuid.New(),
+		PhoneHash: phoneHash,
+		Purpose:   domain.OTPPurposeClaim,
+		IPAddress: ipAddress,
+		ExpiresAt: now.Add(otpTTL),
+		CreatedAt: now,
+	}
+	otp.CodeHash = domain.HashOTP(otp.ID, code)
+
+	if err := s.repo.CreateOTPCode(ctx, otp); err != nil {
+		return fmt.Errorf("failed to store code: %w", err)
+	}
+
+	shadows, err := s.repo.GetActiveShadowBalancesByPhone(ctx, phoneHash)
+	if err != nil {
+		return fmt.Errorf("failed to get shadow balances: %w", err)
+	}
+	if len(shadows) == 0 {
+		return nil
+	}
+
+	message := fmt.Sprintf("Seu código Fidelio é %s. Ele vale por %d minutos.", code, int(otpTTL.Minutes()))
+	if err := s.sms.Send(ctx, phone, message); err != nil {
+		return fmt.Errorf("failed to send code: %w", err)
+	}
+	return nil
+}
+
+// VerifyClaim checks the code and, when it matches, extends the phone's shadow balances and
+// attaches the email, if any
+func (s *ClaimService) VerifyClaim(ctx context.Context, phone, code string, email *string) (*ClaimResult, error) {
+	phoneHash := domain.HashPhone(phone)
+
+	otp, err := s.repo.GetLatestOTPCode(ctx, phoneHash, domain.OTPPurposeClaim)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoPendingCode
+		}
+		return nil, fmt.Errorf("failed to get code: %w", err)
+	}
+
+	allowed, err := s.repo.RecordOTPAttempt(ctx, otp.ID, otpMaxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record attempt: %w", err)
+	}
+	if !allowed {
+		return nil, ErrNoPendingCode
+	}
+
+	if subtle.ConstantTimeCompare([]byte(domain.HashOTP(otp.ID, code)), []byte(otp.CodeHash)) != 1 {
+		return nil, ErrInvalidCode
+	}
+
+	consumed, err := s.repo.ConsumeOTPCode(ctx, otp.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume code: %w", err)
+	}
+	if !consumed {
+		return nil, ErrNoPendingCode
+	}
+
+	claimed, err := s.repo.ClaimShadowBalances(ctx, phoneHash, time.Now().Add(s.extension), email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim shadow balances: %w", err)
+	}
+
+	balances, err := s.repo.GetConsumerShadowBalances(ctx, phoneHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shadow balances: %w", err)
+	}
+
+	return &ClaimResult{Claimed: claimed, Balances: balances}, nil
+}
+
+// checkThrottle limits how often codes are sent to a phone and requested from an IP address
+func (s *ClaimService) checkThrottle(ctx context.Context, phoneHash, ipAddress string, now time.Time) error {
+	latest, err := s.repo.GetLatestOTPCode(ctx, phoneHash, domain.OTPPurposeClaim)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to get code: %w", err)
+	}
+	if latest != nil && now.Sub(latest.CreatedAt) < otpResendInterval {
+		return &ThrottleError{RetryAfter: otpResendInterval - now.Sub(latest.CreatedAt)}
+	}
+
+	hourAgo := now.Add(-time.Hour)
+	sent, err := s.repo.CountOTPCodesSince(ctx, phoneHash, hourAgo)
+	if err != nil {
+		return fmt.Errorf("failed to count codes: %w", err)
+	}
+	if sent >= otpMaxPerPhoneHour {
+		return &ThrottleError{RetryAfter: time.Hour}
+	}
+
+	requested, err := s.repo.CountOTPCodesFromIPSince(ctx, ipAddress, hourAgo)
+	if err != nil {
+		return fmt.Errorf("failed to count codes: %w", err)
+	}
+	if requested >= otpMaxPerIPHour {
+		return &ThrottleError{RetryAfter: time.Hour}
+	}
+	return nil
+}
+
+// generateOTP returns a random numeric code
+func generateOTP() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < otpLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", otpLength, n), nil
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// memoryClaimStore keeps codes and shadow balances in memory, like the otp_codes and
+// shadow_balances tables
+type memoryClaimStore struct {
+	mu      sync.Mutex
+	codes   []*domain.OTPCode
+	shadows map[string][]*domain.ShadowBalance
+}
+
+func newMemoryClaimStore() *memoryClaimStore {
+	return &memoryClaimStore{
+		shadows: make(map[string][]*domain.ShadowBalance),
+	}
+}
+
+func (m *memoryClaimStore) addShadow(phone string, amount float64, expiresAt time.Time) *domain.ShadowBalance {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	shadow := &domain.ShadowBalance{
+		ID:        uuid.New(),
+		PhoneHash: domain.HashPhone(phone),
+		Amount:    amount,
+		ExpiresAt: expiresAt,
+	}
+	m.shadows[shadow.PhoneHash] = append(m.shadows[shadow.PhoneHash], shadow)
+	return shadow
+}
+
+func (m *memoryClaimStore) CreateOTPCode(ctx context.Context, otp *domain.OTPCode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := *otp
+	m.codes = append(m.codes, &stored)
+	return nil
+}
+
+func (m *memoryClaimStore) GetLatestOTPCode(ctx context.Context, phoneHash string, purpose domain.OTPPurpose) (*domain.OTPCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.codes) - 1; i >= 0; i-- {
+		if m.codes[i].PhoneHash == phoneHash && m.codes[i].Purpose == purpose {
+			otp := *m.codes[i]
+			return &otp, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryClaimStore) CountOTPCodesSince(ctx context.Context, phoneHash string, since time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, otp := range m.codes {
+		if otp.PhoneHash == phoneHash && !otp.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memoryClaimStore) CountOTPCodesFromIPSince(ctx context.Context, ipAddress string, since time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, otp := range m.codes {
+		if otp.IPAddress == ipAddress && !otp.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memoryClaimStore) RecordOTPAttempt(ctx context.Context, otpID uuid.UUID, maxAttempts int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, otp := range m.codes {
+		if otp.ID == otpID {
+			if otp.Attempts >= maxAttempts || otp.ConsumedAt != nil || !otp.ExpiresAt.After(time.Now()) {
+				return false, nil
+			}
+			otp.Attempts++
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryClaimStore) ConsumeOTPCode(ctx context.Context, otpID uuid.UUID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, otp := range m.codes {
+		if otp.ID == otpID && otp.ConsumedAt == nil {
+			now := time.Now()
+			otp.ConsumedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryClaimStore) GetActiveShadowBalancesByPhone(ctx context.Context, phoneHash string) ([]*domain.ShadowBalance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var active []*domain.ShadowBalance
+	for _, shadow := range m.shadows[phoneHash] {
+		if shadow.ConvertedAt == nil && shadow.ExpiresAt.After(time.Now()) {
+			active = append(active, shadow)
+		}
+	}
+	return active, nil
+}
+
+func (m *memoryClaimStore) ClaimShadowBalances(ctx context.Context, phoneHash string, expiresAt time.Time, email *string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	claimed := 0
+	now := time.Now()
+	for _, shadow := range m.shadows[phoneHash] {
+		if shadow.ConvertedAt != nil || !shadow.ExpiresAt.After(now) {
+			continue
+		}
+		if expiresAt.After(shadow.ExpiresAt) {
+			shadow.ExpiresAt = expiresAt
+		}
+		shadow.ClaimedAt = &now
+		if email != nil {
+			shadow.ClaimEmail = email
+		}
+		claimed++
+	}
+	return claimed, nil
+}
+
+func (m *memoryClaimStore) GetConsumerShadowBalances(ctx context.Context, phoneHash string) ([]*domain.ConsumerShadowBalance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	balances := []*domain.ConsumerShadowBalance{}
+	for _, shadow := range m.shadows[phoneHash] {
+		if shadow.ConvertedAt == nil && shadow.ExpiresAt.After(time.Now()) {
+			balances = append(balances, &domain.ConsumerShadowBalance{ID: shadow.ID, Amount: shadow.Amount, ExpiresAt: shadow.ExpiresAt})
+		}
+	}
+	return balances, nil
+}
+
+// backdateCodes moves every stored code back in time, as if they were sent earlier
+func (m *memoryClaimStore) backdateCodes(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, otp := range m.codes {
+		otp.CreatedAt = otp.CreatedAt.Add(-d)
+	}
+}
+
+var smsCode = regexp.MustCompile(`\b[0-9]{6}\b`)
+
+// sentCode reads the one-time code from the last SMS sent to the phone
+func sentCode(t *testing.T, sms *FakeSMSSender, phone string) string {
+	t.Helper()
+	message, ok := sms.LastMessage(phone)
+	if !ok {
+		t.Fatalf("no SMS sent to %s", phone)
+	}
+	code := smsCode.FindString(message.Message)
+	if code == "" {
+		t.Fatalf("no code in SMS %q", message.Message)
+	}
+	return code
+}
+
+const claimPhone = "+5511999990000"
+
+func TestClaimFlow(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryClaimStore()
+	sms := NewFakeSMSSender()
+	service := NewClaimService(store, sms, 30*24*time.Hour)
+
+	shadow := store.addShadow(claimPhone, 12.5, time.Now().Add(24*time.Hour))
+
+	if err := service.StartClaim(ctx, claimPhone, "203.0.113.7"); err != nil {
+		t.Fatalf("StartClaim() error = %v", err)
+	}
+	code := sentCode(t, sms, claimPhone)
+
+	email := "cliente@example.com"
+	result, err := service.VerifyClaim(ctx, claimPhone, code, &email)
+	if err != nil {
+		t.Fatalf("VerifyClaim() error = %v", err)
+	}
+	if result.Claimed != 1 || len(result.Balances) != 1 || result.Balances[0].ID != shadow.ID {
+		t.Fatalf("VerifyClaim() = %+v, want the shadow balance claimed", result)
+	}
+	if shadow.ClaimedAt == nil || shadow.ClaimEmail == nil || *shadow.ClaimEmail != email {
+		t.Errorf("shadow balance not claimed: %+v", shadow)
+	}
+	if time.Until(shadow.ExpiresAt) < 29*24*time.Hour {
+		t.Errorf("expires_at = %s, want extended by 30 days", shadow.ExpiresAt)
+	}
+
+	// A code works only once
+	if _, err := service.VerifyClaim(ctx, claimPhone, code, nil); !errors.Is(err, ErrNoPendingCode) {
+		t.Errorf("VerifyClaim() with a used code error = %v, want %v", err, ErrNoPendingCode)
+	}
+}
+
+func TestClaimFlowNormalizesPhone(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryClaimStore()
+	sms := NewFakeSMSSender()
+	service := NewClaimService(store, sms, 30*24*time.Hour)
+
+	// Earned at a POS that formats the number its own way
+	shadow := store.addShadow("+55 (11) 99999-0000", 12.5, time.Now().Add(24*time.Hour))
+
+	if err := service.StartClaim(ctx, "+55 11 99999 0000", "203.0.113.7"); err != nil {
+		t.Fatalf("StartClaim() error = %v", err)
+	}
+	code := sentCode(t, sms, claimPhone)
+
+	result, err := service.VerifyClaim(ctx, "5511999990000", code, nil)
+	if err != nil {
+		t.Fatalf("VerifyClaim() error = %v", err)
+	}
+	if result.Claimed != 1 || result.Balances[0].ID != shadow.ID {
+		t.Errorf("VerifyClaim() = %+v, want the shadow balance claimed", result)
+	}
+}
+
+func TestStartClaimWithoutBalances(t *testing.T) {
+	store := newMemoryClaimStore()
+	sms := NewFakeSMSSender()
+	service := NewClaimService(store, sms, time.Hour)
+
+	// Same answer as a phone with balances, but nothing is sent
+	if err := service.StartClaim(context.Background(), claimPhone, "203.0.113.7"); err != nil {
+		t.Fatalf("StartClaim() error = %v", err)
+	}
+	if messages := sms.Messages(); len(messages) != 0 {
+		t.Errorf("sent %d messages, want none", len(messages))
+	}
+
+	// The request is still throttled
+	var throttled *ThrottleError
+	if err := service.StartClaim(context.Background(), claimPhone, "203.0.113.7"); !errors.As(err, &throttled) {
+		t.Errorf("StartClaim() again error = %v, want a ThrottleError", err)
+	}
+}
+
+func TestVerifyClaimAttempts(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryClaimStore()
+	sms := NewFakeSMSSender()
+	service := NewClaimService(store, sms, time.Hour)
+	store.addShadow(claimPhone, 5, time.Now().Add(time.Hour))
+
+	if _, err := service.VerifyClaim(ctx, claimPhone, "123456", nil); !errors.Is(err, ErrNoPendingCode) {
+		t.Fatalf("VerifyClaim() without a code error = %v, want %v", err, ErrNoPendingCode)
+	}
+
+	if err := service.StartClaim(ctx, claimPhone, "203.0.113.7"); err != nil {
+		t.Fatalf("StartClaim() error = %v", err)
+	}
+	code := sentCode(t, sms, claimPhone)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for i := 0; i < otpMaxAttempts; i++ {
+		if _, err := service.VerifyClaim(ctx, claimPhone, wrong, nil); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("attempt %d error = %v, want %v", i+1, err, ErrInvalidCode)
+		}
+	}
+
+	// Out of attempts, even the right code is refused
+	if _, err := service.VerifyClaim(ctx, claimPhone, code, nil); !errors.Is(err, ErrNoPendingCode) {
+		t.Errorf("VerifyClaim() after %d attempts error = %v, want %v", otpMaxAttempts, err, ErrNoPendingCode)
+	}
+}
+
+func TestStartClaimThrottle(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("resend interval", func(t *testing.T) {
+		store := newMemoryClaimStore()
+		service := NewClaimService(store, NewFakeSMSSender(), time.Hour)
+
+		if err := service.StartClaim(ctx, claimPhone, "203.0.113.7"); err != nil {
+			t.Fatalf("StartClaim() error = %v", err)
+		}
+		var throttled *ThrottleError
+		if err := service.StartClaim(ctx, claimPhone, "203.0.113.7"); !errors.As(err, &throttled) || throttled.RetryAfter > otpResendInterval {
+			t.Fatalf("StartClaim() error = %v, want a ThrottleError within %s", err, otpResendInterval)
+		}
+
+		store.backdateCodes(otpResendInterval)
+		if err := service.StartClaim(ctx, claimPhone, "203.0.113.7"); err != nil {
+			t.Fatalf("StartClaim() after the resend interval error = %v", err)
+		}
+	})
+
+	t.Run("per phone", func(t *testing.T) {
+		store := newMemoryClaimStore()
+		service := NewClaimService(store, NewFakeSMSSender(), time.Hour)
+
+		for i := 0; i < otpMaxPerPhoneHour; i++ {
+			if err := service.StartClaim(ctx, claimPhone, "203.0.113.7"); err != nil {
+				t.Fatalf("code %d error = %v", i+1, err)
+			}
+			store.backdateCodes(otpResendInterval)
+		}
+		var throttled *ThrottleError
+		if err := service.StartClaim(ctx, claimPhone, "203.0.113.7"); !errors.As(err, &throttled) || throttled.RetryAfter != time.Hour {
+			t.Fatalf("StartClaim() error = %v, want a ThrottleError of an hour", err)
+		}
+	})
+
+	t.Run("per IP", func(t *testing.T) {
+		store := newMemoryClaimStore()
+		service := NewClaimService(store, NewFakeSMSSender(), time.Hour)
+
+		for i := 0; i < otpMaxPerIPHour; i++ {
+			phone := fmt.Sprintf("+551199990%04d", i)
+			if err := service.StartClaim(ctx, phone, "203.0.113.7"); err != nil {
+				t.Fatalf("code %d error = %v", i+1, err)
+			}
+		}
+		var throttled *ThrottleError
+		if err := service.StartClaim(ctx, claimPhone, "203.0.113.7"); !errors.As(err, &throttled) {
+			t.Fatalf("StartClaim() error = %v, want a ThrottleError", err)
+		}
+		if err := service.StartClaim(ctx, claimPhone, "198.51.100.4"); err != nil {
+			t.Fatalf("StartClaim() from another IP error = %v", err)
+		}
+	})
+}
@@ -14,18 +14,21 @@ import (
 
 // ConversionService handles the migration of shadow wallets to real wallets
 type ConversionService struct {
-	repo *repository.Repository
+	repo      *repository.Repository
+	campaigns *CampaignService
 }
 
 // NewConversionService creates a new conversion service
-func NewConversionService(repo *repository.Repository) *ConversionService {
+func NewConversionService(repo *repository.Repository, strategies domain.StrategyRegistry) *ConversionService {
 	return &ConversionService{
-		repo: repo,
+		repo:      repo,
+		campaigns: NewCampaignService(repo, strategies),
 	}
 }
 
 // ConvertShadowToRealWallet migrates all shadow balances for a phone to a real wallet
-// This is triggered when a user signs up in Supabase Auth
+// This is triggered when a user signs up in Supabase Auth, by the webhook or the conversion
+// queue. Converting the same sign-up again is a no-op.
 func (c *ConversionService) ConvertShadowToRealWallet(
 	ctx context.Context,
 	userID uuid.UUID,
@@ -38,8 +41,9 @@ func (c *ConversionService) ConvertShadowToRealWallet(
 	}
 	defer tx.Rollback()
 
-	// Get all active shadow balances for this phone hash
-	shadows, err := c.repo.GetActiveShadowBalancesByPhone(ctx, phoneHash)
+	// Lock the open shadow balances for this phone hash. The webhook and the queue worker
+	// may convert the same sign-up at once; the second one finds nothing left to convert.
+	shadows, err := c.repo.GetActiveShadowBalancesByPhoneForUpdateWithTx(ctx, tx, phoneHash)
 	if err != nil {
 		return fmt.Errorf("failed to get shadow balances: %w", err)
 	}
@@ -57,15 +61,32 @@ func (c *ConversionService) ConvertShadowToRealWallet(
 			return fmt.Errorf("failed to get/create wallet for merchant %s: %w", shadow.MerchantID, err)
 		}
 
-		// Merge shadow state with existing wallet state
-		mergedState, err := c.mergeStates(wallet.State, shadow.State)
+		// Merge shadow state with existing wallet state through the campaign's strategy
+		merge, err := c.campaigns.MergeStates(ctx, wallet, shadow)
 		if err != nil {
 			return fmt.Errorf("failed to merge states: %w", err)
 		}
 
-		// Transfer balance and state to real wallet
-		newBalance := wallet.Balance + shadow.Amount
-		if err := c.repo.UpdateWalletWithTx(ctx, tx, wallet.ID, newBalance, mergedState); err != nil {
+		if merge.Reset != nil {
+			merge.Reset.WalletID = &wallet.ID
+			if err := c.repo.CreateTransactionWithTx(ctx, tx, merge.Reset); err != nil {
+				return fmt.Errorf("failed to record state reset: %w", err)
+			}
+		}
+
+		// Pay out a welcome bonus that was waiting for the sign-up
+		bonusAmount := 0.0
+		pendingBonus, err := c.repo.GrantPendingWelcomeBonusWithTx(ctx, tx, shadow.MerchantID, phoneHash, wallet.ID)
+		if err != nil {
+			return fmt.Errorf("failed to grant pending welcome bonus: %w", err)
+		}
+		if pendingBonus != nil {
+			bonusAmount = pendingBonus.Amount
+		}
+
+		// Transfer balance and state to real wallet, with any reward the merge completed
+		newBalance := wallet.Balance + shadow.Amount + bonusAmount + merge.Result.NewBalance
+		if err := c.repo.UpdateWalletWithTx(ctx, tx, wallet.ID, newBalance, merge.Result.NewState, merge.CampaignID, merge.VersionID); err != nil {
 			return fmt.Errorf("failed to update wallet: %w", err)
 		}
 
@@ -89,73 +110,43 @@ func (c *ConversionService) ConvertShadowToRealWallet(
 		if err := c.repo.CreateTransactionWithTx(ctx, tx, convertTx); err != nil {
 			return fmt.Errorf("failed to create conversion transaction: %w", err)
 		}
-	}
-
-	// Commit the transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit conversion: %w", err)
-	}
-
-	return nil
-}
-
-// mergeStates intelligently merges shadow state with existing wallet state
-// This ensures we don't lose progress when converting
-func (c *ConversionService) mergeStates(walletState, shadowState json.RawMessage) (json.RawMessage, error) {
-	// If wallet has no state, use shadow state
-	if len(walletState) == 0 || string(walletState) == "{}" {
-		return shadowState, nil
-	}
 
-	// If shadow has no state, keep wallet state
-	if len(shadowState) == 0 || string(shadowState) == "{}" {
-		return walletState, nil
-	}
-
-	// Parse both states
-	var walletData map[string]interface{}
-	var shadowData map[string]interface{}
-
-	if err := json.Unmarshal(walletState, &walletData); err != nil {
-		return nil, fmt.Errorf("failed to parse wallet state: %w", err)
-	}
-
-	if err := json.Unmarshal(shadowState, &shadowData); err != nil {
-		return nil, fmt.Errorf("failed to parse shadow state: %w", err)
-	}
-
-	// Merge strategy: Add numeric values, keep max for counts
-	merged := make(map[string]interface{})
+		// Record the reward the merge completed, e.g. a punch card filled by both sides
+		if merge.Result.RewardEarned != nil && merge.Result.NewBalance > 0 {
+			metadata, err := json.Marshal(map[string]interface{}{
+				"merged_from_shadow": shadow.ID,
+				"reward":             merge.Result.RewardEarned,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to marshal merge reward metadata: %w", err)
+			}
 
-	// Copy wallet state
-	for k, v := range walletData {
-		merged[k] = v
-	}
+			rewardTx := &domain.Transaction{
+				ID:                uuid.New(),
+				MerchantID:        shadow.MerchantID,
+				WalletID:          &wallet.ID,
+				Type:              domain.TransactionTypeEarn,
+				Amount:            merge.Result.NewBalance,
+				Metadata:          metadata,
+				CreatedAt:         time.Now(),
+				CampaignVersionID: merge.VersionID,
+			}
+			if merge.Campaign != nil {
+				rewardTx.CampaignID = &merge.Campaign.ID
+			}
 
-	// Merge shadow state
-	for k, v := range shadowData {
-		if existing, exists := merged[k]; exists {
-			// If both are numbers, add them
-			if vNum, ok := v.(float64); ok {
-				if existNum, ok := existing.(float64); ok {
-					merged[k] = existNum + vNum
-					continue
-				}
+			if err := c.repo.CreateTransactionWithTx(ctx, tx, rewardTx); err != nil {
+				return fmt.Errorf("failed to create merge reward transaction: %w", err)
 			}
 		}
-		// Otherwise, take shadow value if wallet doesn't have it
-		if _, exists := merged[k]; !exists {
-			merged[k] = v
-		}
 	}
 
-	// Serialize merged state
-	result, err := json.Marshal(merged)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal merged state: %w", err)
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit conversion: %w", err)
 	}
 
-	return result, nil
+	return nil
 }
 
 // GetConversionStats returns statistics about shadow wallet conversions
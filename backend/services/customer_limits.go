@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// enforceCustomerLimitsWithTx applies the campaign's per-customer limits to the strategy
+// result inside the settlement transaction. The customer's phone hash is locked first, so
+// concurrent purchases of the same phone see each other's ledger entries, whichever wallet
+// or shadow balance they land on.
+// A purchase over a limit is turned into a no-op; a cashback that only partly fits the
+// remaining value is reduced instead. It returns the limit that applied, if any.
+func (e *IngestionEngine) enforceCustomerLimitsWithTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	merchant *domain.Merchant,
+	campaign *domain.Campaign,
+	phoneHash string,
+	currentState json.RawMessage,
+	result *domain.StrategyResult,
+) (*domain.LimitHit, error) {
+	limits, err := domain.ParseCustomerLimits(campaign.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse customer limits: %w", err)
+	}
+	if limits == nil || (result.NewBalance <= 0 && !result.StateChanged) {
+		return nil, nil
+	}
+
+	if err := e.repo.LockCustomerWithTx(ctx, tx, campaign.ID, phoneHash); err != nil {
+		return nil, fmt.Errorf("failed to lock customer: %w", err)
+	}
+
+	now := time.Now().In(merchant.Location())
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	windowStart := now.Add(-time.Duration(limits.VisitWindowMinutes) * time.Minute)
+
+	history, err := e.repo.GetCustomerEarnHistoryWithTx(ctx, tx, campaign.ID, phoneHash, dayStart, monthStart, windowStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer history: %w", err)
+	}
+
+	if limits.MaxVisitsPerWindow > 0 && history.VisitsInWindow >= limits.MaxVisitsPerWindow {
+		skipReward(result, currentState)
+		return &domain.LimitHit{
+			Limit:       "max_visits_per_window",
+			Value:       float64(limits.MaxVisitsPerWindow),
+			Description: fmt.Sprintf("Limite de %d compras a cada %d minutos atingido", limits.MaxVisitsPerWindow, limits.VisitWindowMinutes),
+		}, nil
+	}
+
+	if result.NewBalance <= 0 {
+		return nil, nil
+	}
+
+	if limits.MaxRewardsPerDay > 0 && history.RewardsToday >= limits.MaxRewardsPerDay {
+		skipReward(result, currentState)
+		return &domain.LimitHit{
+			Limit:       "max_rewards_per_day",
+			Value:       float64(limits.MaxRewardsPerDay),
+			Description: fmt.Sprintf("Limite de %d recompensas por dia atingido", limits.MaxRewardsPerDay),
+		}, nil
+	}
+
+	// The tightest value limit decides how much of the reward still fits
+	var hit *domain.LimitHit
+	remaining := math.Inf(1)
+	if limits.MaxRewardValuePerDay > 0 && limits.MaxRewardValuePerDay-history.ValueToday < remaining {
+		remaining = limits.MaxRewardValuePerDay - history.ValueToday
+		hit = &domain.LimitHit{
+			Limit:       "max_reward_value_per_day",
+			Value:       limits.MaxRewardValuePerDay,
+			Description: fmt.Sprintf("Limite de R$ %.2f em recompensas por dia atingido", limits.MaxRewardValuePerDay),
+		}
+	}
+	if limits.MaxRewardValuePerMonth > 0 && limits.MaxRewardValuePerMonth-history.ValueThisMonth < remaining {
+		remaining = limits.MaxRewardValuePerMonth - history.ValueThisMonth
+		hit = &domain.LimitHit{
+			Limit:       "max_reward_value_per_month",
+			Value:       limits.MaxRewardValuePerMonth,
+			Description: fmt.Sprintf("Limite de R$ %.2f em recompensas por mês atingido", limits.MaxRewardValuePerMonth),
+		}
+	}
+
+	if result.NewBalance <= remaining {
+		return nil, nil
+	}
+
+	// Cashback is divisible; other rewards (a free item, a tier bonus) are all or nothing
+	if remaining > 0 && result.RewardEarned != nil && result.RewardEarned.Type == "cashback" {
+		result.NewBalance = remaining
+		result.RewardEarned.Amount = remaining
+		return hit, nil
+	}
+
+	skipReward(result, currentState)
+	return hit, nil
+}
+
+// skipReward turns a strategy result into a no-op: no reward, no prize draw and the
+// customer's state left as it was
+func skipReward(result *domain.StrategyResult, currentState json.RawMessage) {
+	result.NewBalance = 0
+	result.RewardEarned = nil
+	result.Draw = nil
+	result.NewState = currentState
+	result.StateChanged = false
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/strategies"
+
+	"github.com/google/uuid"
+)
+
+// ExperimentService runs A/B tests on campaigns. Customers are bucketed deterministically by
+// phone hash and the assignment is stored, so a customer stays in the same variant across
+// visits and after their shadow balance converts into a wallet.
+type ExperimentService struct {
+	repo       *repository.Repository
+	strategies domain.StrategyRegistry
+}
+
+// NewExperimentService creates a new experiment service
+func NewExperimentService(repo *repository.Repository, strategies domain.StrategyRegistry) *ExperimentService {
+	return &ExperimentService{
+		repo:       repo,
+		strategies: strategies,
+	}
+}
+
+// CreateExperiment validates the variants and starts the experiment on the campaign
+func (s *ExperimentService) CreateExperiment(
+	ctx context.Context,
+	campaign *domain.Campaign,
+	name string,
+	variants []*domain.ExperimentVariant,
+) (*domain.Experiment, error) {
+	strategy, ok := s.strategies[campaign.Type]
+	if !ok {
+		return nil, fmt.Errorf("no strategy found for campaign type: %s", campaign.Type)
+	}
+
+	var errs domain.ValidationErrors
+	if strings.TrimSpace(name) == "" {
+		errs = append(errs, domain.NewFieldError("name", "is required"))
+	}
+	if len(variants) < 2 {
+		errs = append(errs, domain.NewFieldError("variants", "must have at least 2 variants"))
+	}
+
+	controls := 0
+	for i, variant := range variants {
+		field := fmt.Sprintf("variants[%d]", i)
+		variant.Config = rawConfig(variant.Config)
+		if strings.TrimSpace(variant.Name) == "" {
+			errs = append(errs, domain.NewFieldError(field+".name", "is required"))
+		}
+		if variant.Weight <= 0 {
+			errs = append(errs, domain.NewFieldError(field+".weight", "must be greater than 0"))
+		}
+		if variant.IsControl {
+			controls++
+			if len(variant.Config) > 0 {
+				errs = append(errs, domain.NewFieldError(field+".config", "must be empty for the control group"))
+			}
+			continue
+		}
+		// Every draw is paid from the campaign's single prize inventory
+		if len(variant.Config) > 0 && campaign.Type == domain.CampaignTypeInstantWin {
+			errs = append(errs, domain.NewFieldError(field+".config", "must be empty for instant-win campaigns, whose prizes are shared"))
+			continue
+		}
+		if len(variant.Config) > 0 {
+			if err := strategies.ValidateConfig(strategy, variant.Config); err != nil {
+				var fieldErrs domain.ValidationErrors
+				if !errors.As(err, &fieldErrs) {
+					return nil, err
+				}
+				for _, fieldErr := range fieldErrs {
+					errs = append(errs, domain.NewFieldError(field+".config."+fieldErr.Field, "%s", fieldErr.Message))
+				}
+			}
+		}
+	}
+	if controls > 1 {
+		errs = append(errs, domain.NewFieldError("variants", "can have only one control group"))
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	if _, err := s.repo.GetActiveExperiment(ctx, campaign.ID); err == nil {
+		return nil, domain.ValidationErrors{domain.NewFieldError("campaign", "already has a running experiment")}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check running experiment: %w", err)
+	}
+
+	experiment := &domain.Experiment{
+		ID:         uuid.New(),
+		CampaignID: campaign.ID,
+		Name:       name,
+		IsActive:   true,
+		CreatedAt:  time.Now(),
+	}
+	for i, variant := range variants {
+		variant.ID = uuid.New()
+		variant.ExperimentID = experiment.ID
+		variant.Position = i
+	}
+	experiment.Variants = variants
+
+	if err := s.repo.CreateExperiment(ctx, experiment); err != nil {
+		return nil, fmt.Errorf("failed to create experiment: %w", err)
+	}
+
+	return experiment, nil
+}
+
+// AssignVariant returns the variant of the campaign's running experiment the customer
+// belongs to, assigning one on first sight. It returns nil when no experiment is running.
+func (s *ExperimentService) AssignVariant(ctx context.Context, campaign *domain.Campaign, phoneHash string) (*domain.ExperimentVariant, error) {
+	experiment, err := s.repo.GetActiveExperiment(ctx, campaign.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get running experiment: %w", err)
+	}
+	if len(experiment.Variants) == 0 {
+		return nil, nil
+	}
+
+	assignment := &domain.ExperimentAssignment{
+		ExperimentID: experiment.ID,
+		PhoneHash:    phoneHash,
+		VariantID:    bucket(experiment, phoneHash).ID,
+		AssignedAt:   time.Now(),
+	}
+
+	variantID, err := s.repo.AssignExperimentVariant(ctx, assignment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign experiment variant: %w", err)
+	}
+
+	for _, variant := range experiment.Variants {
+		if variant.ID == variantID {
+			return variant, nil
+		}
+	}
+	return nil, fmt.Errorf("assigned variant %s not found in experiment %s", variantID, experiment.ID)
+}
+
+// Results aggregates the metrics of each variant and tests them against the control
+func (s *ExperimentService) Results(ctx context.Context, experiment *domain.Experiment, merchantID uuid.UUID) (*domain.ExperimentResults, error) {
+	metrics, err := s.repo.GetExperimentCustomerMetrics(ctx, experiment.ID, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiment metrics: %w", err)
+	}
+
+	type samples struct {
+		visits   []float64
+		spend    []float64
+		redeemed int
+	}
+	byVariant := make(map[uuid.UUID]*samples, len(experiment.Variants))
+	for _, variant := range experiment.Variants {
+		byVariant[variant.ID] = &samples{}
+	}
+	for _, metric := range metrics {
+		sample, ok := byVariant[metric.VariantID]
+		if !ok {
+			continue
+		}
+		sample.visits = append(sample.visits, float64(metric.Visits))
+		sample.spend = append(sample.spend, metric.Spend)
+		if metric.Redeemed {
+			sample.redeemed++
+		}
+	}
+
+	results := &domain.ExperimentResults{Experiment: experiment}
+	var control *domain.VariantResult
+	var controlSample *samples
+
+	for _, variant := range experiment.Variants {
+		sample := byVariant[variant.ID]
+		result := &domain.VariantResult{
+			VariantID: variant.ID,
+			Name:      variant.Name,
+			IsControl: variant.IsControl,
+			Customers: len(sample.visits),
+		}
+		for i := range sample.visits {
+			result.Visits += int(sample.visits[i])
+			result.Spend += sample.spend[i]
+		}
+		if result.Customers > 0 {
+			customers := float64(result.Customers)
+			result.VisitsPerCustomer = float64(result.Visits) / customers
+			result.SpendPerCustomer = result.Spend / customers
+			result.RedemptionRate = float64(sample.redeemed) / customers
+		}
+
+		if variant.IsControl {
+			control, controlSample = result, sample
+		}
+		results.Variants = append(results.Variants, result)
+	}
+
+	if control == nil {
+		return results, nil
+	}
+
+	for i, result := range results.Variants {
+		if result.IsControl {
+			continue
+		}
+		sample := byVariant[experiment.Variants[i].ID]
+
+		visitsT, visitsP := welchTTest(sample.visits, controlSample.visits)
+		spendT, spendP := welchTTest(sample.spend, controlSample.spend)
+		rateZ, rateP := twoProportionZTest(sample.redeemed, result.Customers, controlSample.redeemed, control.Customers)
+
+		result.VsControl = &domain.VariantComparison{
+			Visits:         significance("welch_t", lift(result.VisitsPerCustomer, control.VisitsPerCustomer), visitsT, visitsP),
+			Spend:          significance("welch_t", lift(result.SpendPerCustomer, control.SpendPerCustomer), spendT, spendP),
+			RedemptionRate: significance("two_proportion_z", lift(result.RedemptionRate, control.RedemptionRate), rateZ, rateP),
+		}
+	}
+
+	return results, nil
+}
+
+// VariantCampaign returns the campaign as the variant runs it
+func VariantCampaign(campaign *domain.Campaign, variant *domain.ExperimentVariant) *domain.Campaign {
+	if variant == nil || variant.IsControl || len(variant.Config) == 0 {
+		return campaign
+	}
+	variantCampaign := *campaign
+	variantCampaign.Config = variant.Config
+	return &variantCampaign
+}
+
+// bucket picks the customer's variant from a hash of the experiment and phone hash, so
+// the split follows the weights and does not depend on arrival order
+func bucket(experiment *domain.Experiment, phoneHash string) *domain.ExperimentVariant {
+	total := 0
+	for _, variant := range experiment.Variants {
+		total += variant.Weight
+	}
+
+	sum := sha256.Sum256([]byte(experiment.ID.String() + ":" + phoneHash))
+	point := int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+
+	for _, variant := range experiment.Variants {
+		if point < variant.Weight {
+			return variant
+		}
+		point -= variant.Weight
+	}
+	return experiment.Variants[len(experiment.Variants)-1]
+}
+
+func significance(test string, lift, statistic, pValue float64) domain.SignificanceTest {
+	return domain.SignificanceTest{
+		Test:        test,
+		Lift:        lift,
+		Statistic:   statistic,
+		PValue:      pValue,
+		Significant: pValue < significanceLevel,
+	}
+}
+
+// rawConfig keeps an empty variant config as "no override"
+func rawConfig(config json.RawMessage) json.RawMessage {
+	if len(config) == 0 || string(config) == "null" {
+		return nil
+	}
+	return config
+}
@@ -0,0 +1,157 @@
+package services
+
+import "math"
+
+// significanceLevel is the two-sided alpha used to flag a result as significant
+const significanceLevel = 0.05
+
+// welchTTest compares the means of two samples without assuming equal variances.
+// It returns the t statistic and the two-sided p-value.
+func welchTTest(sample, control []float64) (float64, float64) {
+	n1, n2 := float64(len(sample)), float64(len(control))
+	if n1 < 2 || n2 < 2 {
+		return 0, 1
+	}
+
+	mean1, var1 := meanVariance(sample)
+	mean2, var2 := meanVariance(control)
+
+	se1, se2 := var1/n1, var2/n2
+	// Constant samples carry no information about the spread, so there is nothing to test
+	if se1+se2 == 0 {
+		return 0, 1
+	}
+
+	t := (mean1 - mean2) / math.Sqrt(se1+se2)
+
+	// Welch–Satterthwaite degrees of freedom
+	df := (se1 + se2) * (se1 + se2) / (se1*se1/(n1-1) + se2*se2/(n2-1))
+
+	return t, studentTTwoSided(t, df)
+}
+
+// twoProportionZTest compares two success rates using the pooled proportion.
+// It returns the z statistic and the two-sided p-value.
+func twoProportionZTest(successes1, n1, successes2, n2 int) (float64, float64) {
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+
+	p1 := float64(successes1) / float64(n1)
+	p2 := float64(successes2) / float64(n2)
+	pooled := float64(successes1+successes2) / float64(n1+n2)
+
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 0, 1
+	}
+
+	z := (p1 - p2) / se
+	return z, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// meanVariance returns the mean and the unbiased sample variance
+func meanVariance(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values) - 1)
+
+	return mean, variance
+}
+
+// studentTTwoSided returns P(|T| >= |t|) for a Student's t distribution with df degrees of freedom
+func studentTTwoSided(t, df float64) float64 {
+	x := df / (df + t*t)
+	return regularizedIncompleteBeta(df/2, 0.5, x)
+}
+
+// regularizedIncompleteBeta evaluates I_x(a, b) with the continued fraction expansion
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lgab, _ := math.Lgamma(a + b)
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly only below this point; use the symmetry otherwise
+	if x > (a+1)/(a+b+2) {
+		return 1 - regularizedIncompleteBeta(b, a, 1-x)
+	}
+
+	return front * betaContinuedFraction(a, b, x) / a
+}
+
+// betaContinuedFraction uses the modified Lentz method
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-12
+		tiny          = 1e-300
+	)
+
+	c, d := 1.0, 1.0-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	result := d
+
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+
+		// Even step
+		numerator := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		result *= d * c
+
+		// Odd step
+		numerator = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		result *= delta
+
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+
+	return result
+}
+
+// lift returns the relative difference of a value to the control
+func lift(value, control float64) float64 {
+	if control == 0 {
+		return 0
+	}
+	return (value - control) / control
+}
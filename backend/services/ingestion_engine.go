@@ -2,8 +2,8 @@ package services
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -16,9 +16,15 @@ import (
 // IngestionEngine orchestrates the transaction ingestion process
 type IngestionEngine struct {
 	repo               *repository.Repository
-	strategyRegistry   map[domain.CampaignType]domain.CampaignStrategy
-	shadowWalletTTL    time.Duration
+	strategyRegistry   domain.StrategyRegistry
+	shadowWalletTTL    time.Duration // Fixed TTL for merchants without a shadow expiration policy
 	supabaseAuthClient SupabaseAuthClient
+	instantWin         *InstantWinService
+	campaigns          *CampaignService
+	experiments        *ExperimentService
+	budgets            *BudgetService
+	segments           *SegmentService
+	activeCampaigns    *CampaignCache
 }
 
 // SupabaseAuthClient interface for checking user existence
@@ -29,15 +35,23 @@ type SupabaseAuthClient interface {
 // NewIngestionEngine creates a new ingestion engine
 func NewIngestionEngine(
 	repo *repository.Repository,
-	strategies map[domain.CampaignType]domain.CampaignStrategy,
+	strategies domain.StrategyRegistry,
 	authClient SupabaseAuthClient,
 	shadowTTL time.Duration,
+	notifier Notifier,
+	activeCampaigns *CampaignCache,
 ) *IngestionEngine {
 	return &IngestionEngine{
 		repo:               repo,
 		strategyRegistry:   strategies,
 		shadowWalletTTL:    shadowTTL,
 		supabaseAuthClient: authClient,
+		instantWin:         NewInstantWinService(repo),
+		campaigns:          NewCampaignService(repo, strategies),
+		experiments:        NewExperimentService(repo, strategies),
+		budgets:            NewBudgetService(repo, notifier),
+		segments:           NewSegmentService(repo),
+		activeCampaigns:    activeCampaigns,
 	}
 }
 
@@ -47,11 +61,38 @@ func (e *IngestionEngine) ProcessTransaction(
 	merchant *domain.Merchant,
 	request *domain.IngestRequest,
 ) (*domain.IngestResponse, error) {
-	// Get active campaign for this merchant
-	campaign, err := e.repo.GetActiveCampaign(ctx, merchant.ID)
+	// Hash the phone number for privacy
+	phoneHash := domain.HashPhone(request.Phone)
+
+	// Check if user exists in Supabase Auth
+	userID, userExists, err := e.supabaseAuthClient.UserExistsByPhone(ctx, request.Phone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user existence: %w", err)
+	}
+
+	// Pick the newest active campaign whose segment the customer belongs to
+	campaigns, err := e.activeCampaigns.GetActiveCampaigns(ctx, merchant.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active campaign: %w", err)
 	}
+	campaign, err := e.segments.SelectCampaign(ctx, campaigns, phoneHash, userExists && userID != nil)
+	if errors.Is(err, ErrNoEligibleCampaign) {
+		// The visit still counts for segment rules (e.g. a lapsed customer is no longer lapsed)
+		if !userExists {
+			userID = nil
+		}
+		if err := e.recordVisit(ctx, merchant, userID, phoneHash, request); err != nil {
+			return nil, err
+		}
+		return &domain.IngestResponse{
+			Success:     true,
+			NotEligible: true,
+			Message:     "Cliente fora do público das campanhas ativas",
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	// Get the appropriate strategy
 	strategy, ok := e.strategyRegistry[campaign.Type]
@@ -59,27 +100,35 @@ func (e *IngestionEngine) ProcessTransaction(
 		return nil, fmt.Errorf("no strategy found for campaign type: %s", campaign.Type)
 	}
 
-	// Hash the phone number for privacy
-	phoneHash := e.hashPhone(request.Phone)
-
 	// Ensure metadata is valid JSON
 	if len(request.Metadata) == 0 {
 		request.Metadata = []byte("{}")
 	}
 
-	// Check if user exists in Supabase Auth
-	userID, userExists, err := e.supabaseAuthClient.UserExistsByPhone(ctx, request.Phone)
+	// Customers of a campaign under experiment run the variant they are assigned to
+	variant, err := e.experiments.AssignVariant(ctx, campaign, phoneHash)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check user existence: %w", err)
+		return nil, err
+	}
+
+	// Detect first-time customers before this purchase reaches the ledger
+	welcomeBonus, err := e.welcomeBonusFor(ctx, merchant.ID, VariantCampaign(campaign, variant), phoneHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// The control group is a holdout and earns nothing, not even the welcome bonus
+	if variant != nil && variant.IsControl {
+		welcomeBonus = nil
 	}
 
 	if userExists && userID != nil {
 		// Process with real wallet
-		return e.processRealWallet(ctx, merchant, campaign, strategy, *userID, phoneHash, request)
+		return e.processRealWallet(ctx, merchant, campaign, strategy, *userID, phoneHash, request, welcomeBonus, variant)
 	}
 
 	// Process with shadow wallet
-	return e.processShadowWallet(ctx, merchant, campaign, strategy, phoneHash, request)
+	return e.processShadowWallet(ctx, merchant, campaign, strategy, phoneHash, request, welcomeBonus, variant)
 }
 
 // processRealWallet handles transactions for registered users
@@ -91,6 +140,8 @@ func (e *IngestionEngine) processRealWallet(
 	userID uuid.UUID,
 	phoneHash string,
 	request *domain.IngestRequest,
+	welcomeBonus *domain.WelcomeBonusConfig,
+	variant *domain.ExperimentVariant,
 ) (*domain.IngestResponse, error) {
 	// Get or create wallet
 	wallet, err := e.repo.GetOrCreateWallet(ctx, merchant.ID, userID, phoneHash)
@@ -98,18 +149,43 @@ func (e *IngestionEngine) processRealWallet(
 		return nil, fmt.Errorf("failed to get/create wallet: %w", err)
 	}
 
+	// State built under another campaign starts over rather than being read by this strategy
+	reset, err := StateReset(merchant.ID, campaign.ID, wallet.CampaignID, wallet.CampaignVersionID, wallet.State)
+	if err != nil {
+		return nil, err
+	}
+	if reset != nil {
+		reset.WalletID = &wallet.ID
+		wallet.State = json.RawMessage("{}")
+		wallet.CampaignVersionID = nil
+	}
+
+	// Run under the config version the wallet's state was built with
+	run, err := e.campaigns.ResolveVersion(ctx, campaign, wallet.CampaignVersionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Instant-win campaigns draw from the campaign's secret seed
+	seed, err := e.instantWin.SeedFor(ctx, campaign)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load draw seed: %w", err)
+	}
+
 	// Execute strategy
 	input := &domain.StrategyInput{
-		Campaign:      campaign,
+		Campaign:      strategyCampaign(campaign, run, variant),
 		TransactionID: request.TransactionID,
 		Amount:        request.Amount,
 		CurrentState:  wallet.State,
 		Metadata:      request.Metadata,
+		OccurredAt:    time.Now(),
+		Seed:          seed,
 	}
 
-	result, err := strategy.Execute(ctx, input)
+	result, err := e.execute(ctx, strategy, input, variant)
 	if err != nil {
-		return nil, fmt.Errorf("strategy execution failed: %w", err)
+		return nil, err
 	}
 
 	// Update wallet in transaction
@@ -119,9 +195,54 @@ func (e *IngestionEngine) processRealWallet(
 	}
 	defer tx.Rollback()
 
+	if reset != nil {
+		if err := e.repo.CreateTransactionWithTx(ctx, tx, reset); err != nil {
+			return nil, fmt.Errorf("failed to record state reset: %w", err)
+		}
+	}
+
+	// Apply the per-customer limits before anything is charged or claimed
+	limitHit, err := e.enforceCustomerLimitsWithTx(ctx, tx, merchant, input.Campaign, phoneHash, input.CurrentState, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the prize draw first, dropping the reward if its tier is sold out,
+	// so a prize that cannot be handed out is never charged to the budget
+	draw := result.Draw
+	if err := e.instantWin.SettleDrawWithTx(ctx, tx, campaign, request.TransactionID, phoneHash, result); err != nil {
+		return nil, err
+	}
+
+	// Charge the campaign budget; a reward that does not fit is not granted
+	alerts, err := e.budgets.ChargeWithTx(ctx, tx, merchant, campaign, input.CurrentState, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// A prize the budget could not pay for goes back to the pool
+	if draw != nil && result.Draw == nil {
+		if err := e.instantWin.ReleaseDrawWithTx(ctx, tx, campaign, request.TransactionID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the welcome bonus (or one left pending from the shadow period)
+	bonusAmount, bonusReward, bonusAlerts, err := e.applyWelcomeBonusWithTx(ctx, tx, welcomeBonus, merchant, campaign, phoneHash, &wallet.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	alerts = append(alerts, bonusAlerts...)
+
+	// Wallets on an older config version move to the current one once their card completes
+	versionID, newState, err := e.campaigns.SettleVersion(strategy, run, result)
+	if err != nil {
+		return nil, err
+	}
+
 	// Update wallet balance and state
-	newBalance := wallet.Balance + result.NewBalance
-	if err := e.repo.UpdateWalletWithTx(ctx, tx, wallet.ID, newBalance, result.NewState); err != nil {
+	newBalance := wallet.Balance + result.NewBalance + bonusAmount
+	if err := e.repo.UpdateWalletWithTx(ctx, tx, wallet.ID, newBalance, newState, &campaign.ID, &versionID); err != nil {
 		return nil, fmt.Errorf("failed to update wallet: %w", err)
 	}
 
@@ -135,6 +256,12 @@ func (e *IngestionEngine) processRealWallet(
 		Amount:     result.NewBalance,
 		Metadata:   request.Metadata,
 		CreatedAt:  time.Now(),
+
+		CampaignVersionID: &run.Version.ID,
+		PurchaseAmount:    &request.Amount,
+		ExternalID:        &request.TransactionID,
+
+		ExperimentVariantID: variantID(variant),
 	}
 
 	if err := e.repo.CreateTransactionWithTx(ctx, tx, ledgerTx); err != nil {
@@ -144,16 +271,25 @@ func (e *IngestionEngine) processRealWallet(
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	e.budgets.Notify(ctx, alerts)
 
 	return &domain.IngestResponse{
-		Success:    true,
-		NewBalance: newBalance,
-		IsShadow:   false,
-		Reward:     result.RewardEarned,
-		Message:    "Transação processada com sucesso!",
+		Success:      true,
+		NewBalance:   newBalance,
+		IsShadow:     false,
+		Reward:       result.RewardEarned,
+		WelcomeBonus: bonusReward,
+		LimitHit:     limitHit,
+		Message:      "Transação processada com sucesso!",
 	}, nil
 }
 
+// ShadowExpiration returns the merchant's shadow expiration policy, falling back to the
+// configured TTL
+func (e *IngestionEngine) ShadowExpiration(merchant *domain.Merchant) domain.ShadowExpiration {
+	return merchant.ShadowExpiration(e.shadowWalletTTL)
+}
+
 // processShadowWallet handles transactions for unregistered users
 func (e *IngestionEngine) processShadowWallet(
 	ctx context.Context,
@@ -162,9 +298,12 @@ func (e *IngestionEngine) processShadowWallet(
 	strategy domain.CampaignStrategy,
 	phoneHash string,
 	request *domain.IngestRequest,
+	welcomeBonus *domain.WelcomeBonusConfig,
+	variant *domain.ExperimentVariant,
 ) (*domain.IngestResponse, error) {
 	// Get or create shadow balance
-	shadow, err := e.repo.GetOrCreateShadowBalance(ctx, merchant.ID, phoneHash, e.shadowWalletTTL)
+	policy := e.ShadowExpiration(merchant)
+	shadow, err := e.repo.GetOrCreateShadowBalance(ctx, merchant.ID, phoneHash, policy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get/create shadow balance: %w", err)
 	}
@@ -174,18 +313,43 @@ func (e *IngestionEngine) processShadowWallet(
 		return nil, fmt.Errorf("shadow wallet expired")
 	}
 
+	// State built under another campaign starts over rather than being read by this strategy
+	reset, err := StateReset(merchant.ID, campaign.ID, shadow.CampaignID, shadow.CampaignVersionID, shadow.State)
+	if err != nil {
+		return nil, err
+	}
+	if reset != nil {
+		reset.ShadowBalanceID = &shadow.ID
+		shadow.State = json.RawMessage("{}")
+		shadow.CampaignVersionID = nil
+	}
+
+	// Run under the config version the shadow's state was built with
+	run, err := e.campaigns.ResolveVersion(ctx, campaign, shadow.CampaignVersionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Instant-win campaigns draw from the campaign's secret seed
+	seed, err := e.instantWin.SeedFor(ctx, campaign)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load draw seed: %w", err)
+	}
+
 	// Execute strategy
 	input := &domain.StrategyInput{
-		Campaign:      campaign,
+		Campaign:      strategyCampaign(campaign, run, variant),
 		TransactionID: request.TransactionID,
 		Amount:        request.Amount,
 		CurrentState:  shadow.State,
 		Metadata:      request.Metadata,
+		OccurredAt:    time.Now(),
+		Seed:          seed,
 	}
 
-	result, err := strategy.Execute(ctx, input)
+	result, err := e.execute(ctx, strategy, input, variant)
 	if err != nil {
-		return nil, fmt.Errorf("strategy execution failed: %w", err)
+		return nil, err
 	}
 
 	// Update shadow balance in transaction
@@ -195,12 +359,64 @@ func (e *IngestionEngine) processShadowWallet(
 	}
 	defer tx.Rollback()
 
+	if reset != nil {
+		if err := e.repo.CreateTransactionWithTx(ctx, tx, reset); err != nil {
+			return nil, fmt.Errorf("failed to record state reset: %w", err)
+		}
+	}
+
+	// Apply the per-customer limits before anything is charged or claimed
+	limitHit, err := e.enforceCustomerLimitsWithTx(ctx, tx, merchant, input.Campaign, phoneHash, input.CurrentState, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the prize draw first, dropping the reward if its tier is sold out,
+	// so a prize that cannot be handed out is never charged to the budget
+	draw := result.Draw
+	if err := e.instantWin.SettleDrawWithTx(ctx, tx, campaign, request.TransactionID, phoneHash, result); err != nil {
+		return nil, err
+	}
+
+	// Charge the campaign budget; a reward that does not fit is not granted
+	alerts, err := e.budgets.ChargeWithTx(ctx, tx, merchant, campaign, input.CurrentState, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// A prize the budget could not pay for goes back to the pool
+	if draw != nil && result.Draw == nil {
+		if err := e.instantWin.ReleaseDrawWithTx(ctx, tx, campaign, request.TransactionID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the welcome bonus (held until sign-up when the campaign requires it)
+	bonusAmount, bonusReward, bonusAlerts, err := e.applyWelcomeBonusWithTx(ctx, tx, welcomeBonus, merchant, campaign, phoneHash, nil, &shadow.ID)
+	if err != nil {
+		return nil, err
+	}
+	alerts = append(alerts, bonusAlerts...)
+
+	// Shadow balances on an older config version move to the current one once their card completes
+	versionID, newState, err := e.campaigns.SettleVersion(strategy, run, result)
+	if err != nil {
+		return nil, err
+	}
+
 	// Update shadow balance and state
-	newAmount := shadow.Amount + result.NewBalance
-	if err := e.repo.UpdateShadowBalanceWithTx(ctx, tx, shadow.ID, newAmount, result.NewState); err != nil {
+	newAmount := shadow.Amount + result.NewBalance + bonusAmount
+	if err := e.repo.UpdateShadowBalanceWithTx(ctx, tx, shadow.ID, newAmount, newState, &campaign.ID, &versionID); err != nil {
 		return nil, fmt.Errorf("failed to update shadow balance: %w", err)
 	}
 
+	// A sliding policy moves the expiry forward with each purchase, up to its hard limit
+	now := time.Now()
+	shadow.ExpiresAt, err = e.repo.TouchShadowBalanceWithTx(ctx, tx, shadow.ID, now, policy.ExpiresAt(shadow.CreatedAt, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update shadow balance expiry: %w", err)
+	}
+
 	// Record transaction in ledger
 	ledgerTx := &domain.Transaction{
 		ID:              uuid.New(),
@@ -211,6 +427,12 @@ func (e *IngestionEngine) processShadowWallet(
 		Amount:          result.NewBalance,
 		Metadata:        request.Metadata,
 		CreatedAt:       time.Now(),
+
+		CampaignVersionID: &run.Version.ID,
+		PurchaseAmount:    &request.Amount,
+		ExternalID:        &request.TransactionID,
+
+		ExperimentVariantID: variantID(variant),
 	}
 
 	if err := e.repo.CreateTransactionWithTx(ctx, tx, ledgerTx); err != nil {
@@ -220,19 +442,111 @@ func (e *IngestionEngine) processShadowWallet(
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	e.budgets.Notify(ctx, alerts)
 
 	return &domain.IngestResponse{
-		Success:    true,
-		NewBalance: newAmount,
-		IsShadow:   true,
-		ExpiresAt:  &shadow.ExpiresAt,
-		Reward:     result.RewardEarned,
-		Message:    fmt.Sprintf("Saldo temporário criado! Cadastre-se até %s para não perder seus benefícios.", shadow.ExpiresAt.Format("02/01/2006 15:04")),
+		Success:      true,
+		NewBalance:   newAmount,
+		IsShadow:     true,
+		ExpiresAt:    &shadow.ExpiresAt,
+		Reward:       result.RewardEarned,
+		WelcomeBonus: bonusReward,
+		LimitHit:     limitHit,
+		Message:      fmt.Sprintf("Saldo temporário criado! Cadastre-se até %s para não perder seus benefícios.", shadow.ExpiresAt.Format("02/01/2006 15:04")),
 	}, nil
 }
 
-// hashPhone creates a SHA-256 hash of the phone number
-func (e *IngestionEngine) hashPhone(phone string) string {
-	hash := sha256.Sum256([]byte(phone))
-	return hex.EncodeToString(hash[:])
+// recordVisit records a purchase that earns nothing as a campaign-less EARN entry of zero on
+// the customer's wallet or shadow balance, so their visits and spend stay complete
+func (e *IngestionEngine) recordVisit(
+	ctx context.Context,
+	merchant *domain.Merchant,
+	userID *uuid.UUID,
+	phoneHash string,
+	request *domain.IngestRequest,
+) error {
+	if len(request.Metadata) == 0 {
+		request.Metadata = []byte("{}")
+	}
+
+	ledgerTx := &domain.Transaction{
+		ID:         uuid.New(),
+		MerchantID: merchant.ID,
+		Type:       domain.TransactionTypeEarn,
+		Amount:     0,
+		Metadata:   request.Metadata,
+		CreatedAt:  time.Now(),
+
+		PurchaseAmount: &request.Amount,
+		ExternalID:     &request.TransactionID,
+	}
+
+	if userID != nil {
+		wallet, err := e.repo.GetOrCreateWallet(ctx, merchant.ID, *userID, phoneHash)
+		if err != nil {
+			return fmt.Errorf("failed to get/create wallet: %w", err)
+		}
+		ledgerTx.WalletID = &wallet.ID
+	} else {
+		shadow, err := e.repo.GetOrCreateShadowBalance(ctx, merchant.ID, phoneHash, e.ShadowExpiration(merchant))
+		if err != nil {
+			return fmt.Errorf("failed to get/create shadow balance: %w", err)
+		}
+		ledgerTx.ShadowBalanceID = &shadow.ID
+	}
+
+	tx, err := e.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := e.repo.CreateTransactionWithTx(ctx, tx, ledgerTx); err != nil {
+		return fmt.Errorf("failed to record visit: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// execute runs the strategy, except for customers in an experiment's control group
+// whose state is kept as is and who earn nothing
+func (e *IngestionEngine) execute(
+	ctx context.Context,
+	strategy domain.CampaignStrategy,
+	input *domain.StrategyInput,
+	variant *domain.ExperimentVariant,
+) (*domain.StrategyResult, error) {
+	if variant != nil && variant.IsControl {
+		return &domain.StrategyResult{
+			NewBalance:   0,
+			NewState:     input.CurrentState,
+			StateChanged: false,
+		}, nil
+	}
+
+	result, err := strategy.Execute(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("strategy execution failed: %w", err)
+	}
+	return result, nil
+}
+
+// strategyCampaign returns the campaign as the strategy runs it: under the pinned config version,
+// with the experiment variant's config. Instant-win prizes are always drawn from the current
+// prize table, the one the prize inventory is kept for.
+func strategyCampaign(campaign *domain.Campaign, run *VersionRun, variant *domain.ExperimentVariant) *domain.Campaign {
+	if campaign.Type == domain.CampaignTypeInstantWin {
+		return campaign
+	}
+	return VariantCampaign(run.Campaign, variant)
+}
+
+// variantID returns the ID of the variant, if the customer is in an experiment
+func variantID(variant *domain.ExperimentVariant) *uuid.UUID {
+	if variant == nil {
+		return nil
+	}
+	return &variant.ID
 }
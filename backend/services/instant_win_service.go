@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/strategies"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrSeedRevealed is returned when drawing from a campaign whose seed was already revealed
+var ErrSeedRevealed = errors.New("draw seed already revealed")
+
+// InstantWinService manages the seeded prize pools of instant-win campaigns.
+// Draws use a commit-reveal scheme: the SHA-256 commitment of the secret seed is
+// published when the pool is created and the seed itself is revealed once the
+// campaign ends, so anyone can recompute every draw.
+type InstantWinService struct {
+	repo *repository.Repository
+}
+
+// NewInstantWinService creates a new instant-win service
+func NewInstantWinService(repo *repository.Repository) *InstantWinService {
+	return &InstantWinService{repo: repo}
+}
+
+// DrawVerification is the audit view of a single draw
+type DrawVerification struct {
+	Draw           *domain.InstantWinDraw `json:"draw"`
+	SeedCommitment string                 `json:"seed_commitment"`
+	ServerSeed     string                 `json:"server_seed,omitempty"`
+	RecomputedRoll *float64               `json:"recomputed_roll,omitempty"`
+	Verified       *bool                  `json:"verified,omitempty"` // Only known after the seed is revealed
+}
+
+// EnsurePool creates the seed and prize inventory for an instant-win campaign if missing
+func (s *InstantWinService) EnsurePool(ctx context.Context, campaign *domain.Campaign) (*domain.InstantWinPool, error) {
+	pool, err := s.repo.GetInstantWinPool(ctx, campaign.ID)
+	if err == nil {
+		return pool, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get prize pool: %w", err)
+	}
+
+	var config domain.InstantWinConfig
+	if err := json.Unmarshal(campaign.Config, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("failed to generate seed: %w", err)
+	}
+
+	pool = &domain.InstantWinPool{
+		CampaignID:     campaign.ID,
+		ServerSeed:     seed,
+		SeedCommitment: strategies.SeedCommitment(seed),
+		CreatedAt:      time.Now(),
+	}
+
+	pool, err = s.repo.CreateInstantWinPool(ctx, pool, config.Prizes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prize pool: %w", err)
+	}
+	return pool, nil
+}
+
+// SeedFor returns the draw seed for instant-win campaigns and nil for every other type.
+// A revealed seed draws no more: with the seed public, anyone could pick winning transaction IDs.
+func (s *InstantWinService) SeedFor(ctx context.Context, campaign *domain.Campaign) ([]byte, error) {
+	if campaign.Type != domain.CampaignTypeInstantWin {
+		return nil, nil
+	}
+
+	pool, err := s.EnsurePool(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+	if pool.RevealedAt != nil {
+		return nil, ErrSeedRevealed
+	}
+	return pool.ServerSeed, nil
+}
+
+// SettleDrawWithTx records the draw and reserves the prize inside the settlement transaction.
+// When the prize tier is sold out, or no longer the tier that was drawn (its prize was edited
+// meanwhile), the reward is removed from the result.
+func (s *InstantWinService) SettleDrawWithTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	campaign *domain.Campaign,
+	transactionID string,
+	phoneHash string,
+	result *domain.StrategyResult,
+) error {
+	if result.Draw == nil {
+		return nil
+	}
+
+	draw := &domain.InstantWinDraw{
+		ID:            uuid.New(),
+		CampaignID:    campaign.ID,
+		TransactionID: transactionID,
+		PhoneHash:     phoneHash,
+		Roll:          result.Draw.Roll,
+		TierIndex:     result.Draw.TierIndex,
+		CreatedAt:     time.Now(),
+	}
+
+	if draw.TierIndex != nil {
+		claimed, err := s.repo.ClaimInstantWinPrizeWithTx(ctx, tx, campaign.ID, *draw.TierIndex, result.Draw.TierName, result.Draw.Amount)
+		if err != nil {
+			return fmt.Errorf("failed to claim prize: %w", err)
+		}
+		draw.Awarded = claimed
+
+		if !claimed {
+			result.NewBalance = 0
+			result.RewardEarned = nil
+		}
+	}
+
+	recorded, err := s.repo.RecordInstantWinDrawWithTx(ctx, tx, draw)
+	if err != nil {
+		return fmt.Errorf("failed to record draw: %w", err)
+	}
+	if !recorded {
+		return fmt.Errorf("transaction %s was already drawn for this campaign", transactionID)
+	}
+
+	return nil
+}
+
+// ReleaseDrawWithTx returns the prize of a draw whose reward was later dropped (e.g. by the
+// campaign budget) to the pool, keeping the draw on record as not awarded
+func (s *InstantWinService) ReleaseDrawWithTx(ctx context.Context, tx *sqlx.Tx, campaign *domain.Campaign, transactionID string) error {
+	if _, err := s.repo.ReleaseInstantWinPrizeWithTx(ctx, tx, campaign.ID, transactionID); err != nil {
+		return fmt.Errorf("failed to release prize: %w", err)
+	}
+	return nil
+}
+
+// Report summarizes the prize pool, with the seed once it was revealed (the campaign ended)
+func (s *InstantWinService) Report(ctx context.Context, campaign *domain.Campaign) (*domain.InstantWinReport, error) {
+	pool, err := s.EnsurePool(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory, err := s.repo.GetInstantWinInventory(ctx, campaign.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prize inventory: %w", err)
+	}
+
+	totalDraws, totalWins, err := s.repo.CountInstantWinDraws(ctx, campaign.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count draws: %w", err)
+	}
+
+	report := &domain.InstantWinReport{
+		CampaignID:     campaign.ID,
+		SeedCommitment: pool.SeedCommitment,
+		Ended:          campaign.HasEnded(),
+		TotalDraws:     totalDraws,
+		TotalWins:      totalWins,
+		Prizes:         make([]domain.InstantWinPrizeSummary, 0, len(inventory)),
+	}
+
+	for _, tier := range inventory {
+		unawarded := tier.Inventory - tier.Awarded
+		report.Prizes = append(report.Prizes, domain.InstantWinPrizeSummary{
+			TierIndex: tier.TierIndex,
+			Name:      tier.Name,
+			Amount:    tier.Amount,
+			Inventory: tier.Inventory,
+			Awarded:   tier.Awarded,
+			Unawarded: unawarded,
+		})
+		report.UnawardedValue += float64(unawarded) * tier.Amount
+	}
+
+	if pool.RevealedAt != nil {
+		report.ServerSeed = hex.EncodeToString(pool.ServerSeed)
+	}
+
+	return report, nil
+}
+
+// VerifyDraw returns the stored draw and, once the seed is revealed, recomputes it
+func (s *InstantWinService) VerifyDraw(ctx context.Context, campaign *domain.Campaign, transactionID string) (*DrawVerification, error) {
+	pool, err := s.repo.GetInstantWinPool(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	draw, err := s.repo.GetInstantWinDraw(ctx, campaign.ID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &DrawVerification{
+		Draw:           draw,
+		SeedCommitment: pool.SeedCommitment,
+	}
+
+	if pool.RevealedAt != nil {
+		roll := strategies.DrawRoll(pool.ServerSeed, campaign.ID, transactionID)
+		verified := roll == draw.Roll && strategies.SeedCommitment(pool.ServerSeed) == pool.SeedCommitment
+		verification.ServerSeed = hex.EncodeToString(pool.ServerSeed)
+		verification.RecomputedRoll = &roll
+		verification.Verified = &verified
+	}
+
+	return verification, nil
+}
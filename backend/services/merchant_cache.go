@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// MerchantStore is the storage the merchant cache reads through, implemented by *repository.Repository
+type MerchantStore interface {
+	GetMerchantByAPIKey(ctx context.Context, apiKey string) (*domain.Merchant, error)
+}
+
+// MerchantCache keeps the merchants authenticated by API key in memory, sparing a query on
+// every request. Entries are dropped as soon as a merchant_changed notification arrives;
+// the TTL only bounds staleness if one is missed. A TTL of zero disables caching.
+type MerchantCache struct {
+	repo MerchantStore
+	ttl  time.Duration
+
+	mu         sync.RWMutex
+	generation uint64 // Bumped by every invalidation, so loads that started before one are not stored
+	byAPIKey   map[string]cachedMerchant
+}
+
+type cachedMerchant struct {
+	merchant  *domain.Merchant
+	expiresAt time.Time
+}
+
+// NewMerchantCache creates a new merchant cache
+func NewMerchantCache(repo MerchantStore, ttl time.Duration) *MerchantCache {
+	return &MerchantCache{
+		repo:     repo,
+		ttl:      ttl,
+		byAPIKey: make(map[string]cachedMerchant),
+	}
+}
+
+// GetByAPIKey returns the merchant owning the API key. Callers get their own copy.
+func (c *MerchantCache) GetByAPIKey(ctx context.Context, apiKey string) (*domain.Merchant, error) {
+	if c.ttl <= 0 {
+		return c.repo.GetMerchantByAPIKey(ctx, apiKey)
+	}
+
+	c.mu.RLock()
+	entry, ok := c.byAPIKey[apiKey]
+	generation := c.generation
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		merchant, err := c.repo.GetMerchantByAPIKey(ctx, apiKey)
+		if err != nil {
+			return nil, err
+		}
+		entry = cachedMerchant{merchant: merchant, expiresAt: time.Now().Add(c.ttl)}
+
+		// A merchant invalidated while it was loading may have been read before the change
+		c.mu.Lock()
+		if c.generation == generation {
+			c.byAPIKey[apiKey] = entry
+		}
+		c.mu.Unlock()
+	}
+
+	merchant := *entry.merchant
+	return &merchant, nil
+}
+
+// Invalidate drops the cached entries of a merchant
+func (c *MerchantCache) Invalidate(merchantID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for apiKey, entry := range c.byAPIKey {
+		if entry.merchant.ID == merchantID {
+			delete(c.byAPIKey, apiKey)
+		}
+	}
+}
+
+// Flush drops every cached entry
+func (c *MerchantCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.byAPIKey = make(map[string]cachedMerchant)
+}
+
+// HandleNotification invalidates the merchant named in a merchant_changed notification
+func (c *MerchantCache) HandleNotification(ctx context.Context, payload string) error {
+	var change domain.EntityChange
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		return fmt.Errorf("invalid merchant change: %w", err)
+	}
+	c.Invalidate(change.ID)
+	return nil
+}
+
+// CatchUp flushes the cache, since changes may have been missed while disconnected
+func (c *MerchantCache) CatchUp(ctx context.Context) error {
+	c.Flush()
+	return nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+// stubMerchantStore serves merchants by API key and counts the loads. loading, when set,
+// runs in the middle of a load, after the merchant was read.
+type stubMerchantStore struct {
+	merchants map[string]domain.Merchant
+	loads     int
+	loading   func()
+}
+
+func (s *stubMerchantStore) GetMerchantByAPIKey(ctx context.Context, apiKey string) (*domain.Merchant, error) {
+	s.loads++
+	merchant, ok := s.merchants[apiKey]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	if s.loading != nil {
+		loading := s.loading
+		s.loading = nil
+		loading()
+	}
+	return &merchant, nil
+}
+
+func TestMerchantCache(t *testing.T) {
+	ctx := context.Background()
+	merchant := domain.Merchant{ID: uuid.New(), Name: "Padaria"}
+
+	get := func(t *testing.T, cache *MerchantCache) *domain.Merchant {
+		t.Helper()
+		got, err := cache.GetByAPIKey(ctx, "key")
+		if err != nil {
+			t.Fatalf("GetByAPIKey() error = %v", err)
+		}
+		return got
+	}
+
+	t.Run("cached until invalidated", func(t *testing.T) {
+		store := &stubMerchantStore{merchants: map[string]domain.Merchant{"key": merchant}}
+		cache := NewMerchantCache(store, time.Minute)
+
+		get(t, cache).Name = "changed by the caller"
+		if got := get(t, cache); got.Name != "Padaria" || store.loads != 1 {
+			t.Fatalf("GetByAPIKey() = %q after %d loads, want the cached merchant after 1", got.Name, store.loads)
+		}
+
+		payload := fmt.Sprintf(`{"id":%q,"merchant_id":%q,"op":"UPDATE"}`, merchant.ID, merchant.ID)
+		if err := cache.HandleNotification(ctx, payload); err != nil {
+			t.Fatalf("HandleNotification() error = %v", err)
+		}
+		get(t, cache)
+		if store.loads != 2 {
+			t.Errorf("loads = %d after a merchant_changed notification, want 2", store.loads)
+		}
+	})
+
+	t.Run("zero TTL disables caching", func(t *testing.T) {
+		store := &stubMerchantStore{merchants: map[string]domain.Merchant{"key": merchant}}
+		cache := NewMerchantCache(store, 0)
+
+		get(t, cache)
+		get(t, cache)
+		if store.loads != 2 {
+			t.Errorf("loads = %d, want 2", store.loads)
+		}
+	})
+
+	t.Run("load overtaken by an invalidation is not cached", func(t *testing.T) {
+		store := &stubMerchantStore{merchants: map[string]domain.Merchant{"key": merchant}}
+		cache := NewMerchantCache(store, time.Minute)
+
+		// The merchant changes after the load read it but before the load stored it
+		store.loading = func() {
+			updated := merchant
+			updated.Name = "Padaria Nova"
+			store.merchants["key"] = updated
+			cache.Invalidate(merchant.ID)
+		}
+
+		if got := get(t, cache); got.Name != "Padaria" {
+			t.Fatalf("GetByAPIKey() = %q, want the merchant as read", got.Name)
+		}
+		if got := get(t, cache); got.Name != "Padaria Nova" {
+			t.Errorf("GetByAPIKey() after the invalidation = %q, want %q", got.Name, "Padaria Nova")
+		}
+	})
+}
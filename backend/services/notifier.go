@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// Notifier delivers operational alerts to a merchant
+type Notifier interface {
+	Notify(ctx context.Context, merchantID uuid.UUID, subject, message string) error
+}
+
+// LogNotifier writes alerts to the application log. It is the default until
+// merchants have a delivery channel (e-mail, WhatsApp) configured.
+type LogNotifier struct{}
+
+// NewLogNotifier creates a new log notifier
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Notify logs the alert
+func (n *LogNotifier) Notify(ctx context.Context, merchantID uuid.UUID, subject, message string) error {
+	log.Printf("[NOTIFY] merchant=%s %s: %s", merchantID, subject, message)
+	return nil
+}
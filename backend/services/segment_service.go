@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoEligibleCampaign is returned when the merchant has running campaigns but the customer
+// is outside the segment of every one of them
+var ErrNoEligibleCampaign = errors.New("customer is not eligible for any active campaign")
+
+// spendRankingTTL is how long a merchant's spend ranking is reused by membership checks
+const spendRankingTTL = 10 * time.Minute
+
+// SegmentService evaluates customer segments. Rule segments are computed from the customer's
+// wallets and ledger when checked; static segments are uploaded lists of phone hashes.
+// Top spender rules compare the customer against a spend ranking computed over all customers
+// and cached for spendRankingTTL.
+type SegmentService struct {
+	repo *repository.Repository
+
+	mu       sync.Mutex
+	rankings map[spendRankingKey]*domain.SpendRanking
+}
+
+type spendRankingKey struct {
+	merchantID uuid.UUID
+	windowDays int // Zero for all time
+	percent    float64
+}
+
+// NewSegmentService creates a new segment service
+func NewSegmentService(repo *repository.Repository) *SegmentService {
+	return &SegmentService{
+		repo:     repo,
+		rankings: make(map[spendRankingKey]*domain.SpendRanking),
+	}
+}
+
+// Validate checks the segment definition
+func (s *SegmentService) Validate(segment *domain.Segment) error {
+	switch segment.Kind {
+	case domain.SegmentKindStatic:
+		if len(segment.Rules) > 0 && string(segment.Rules) != "null" {
+			return domain.ValidationErrors{domain.NewFieldError("rules", "is only allowed for rule segments")}
+		}
+		return nil
+	case domain.SegmentKindRule:
+		rules, err := domain.ParseSegmentRules(segment.Rules)
+		if err != nil {
+			return domain.ValidationErrors{domain.NewFieldError("rules", "%s", err.Error())}
+		}
+		return rules.Validate()
+	}
+	return domain.ValidationErrors{domain.NewFieldError("kind", "must be rule or static")}
+}
+
+// SelectCampaign returns the newest of the merchant's running campaigns, given newest first,
+// the customer is eligible for. Campaigns without a segment apply to everyone.
+func (s *SegmentService) SelectCampaign(ctx context.Context, campaigns []*domain.Campaign, phoneHash string, registered bool) (*domain.Campaign, error) {
+	if len(campaigns) == 0 {
+		return nil, fmt.Errorf("failed to get active campaign: %w", sql.ErrNoRows)
+	}
+
+	segments := make(map[uuid.UUID]bool)
+	for _, campaign := range campaigns {
+		if campaign.SegmentID == nil {
+			return campaign, nil
+		}
+
+		member, checked := segments[*campaign.SegmentID]
+		if !checked {
+			segment, err := s.repo.GetSegment(ctx, *campaign.SegmentID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get segment: %w", err)
+			}
+			if member, err = s.IsMember(ctx, segment, phoneHash, registered); err != nil {
+				return nil, err
+			}
+			segments[segment.ID] = member
+		}
+		if member {
+			return campaign, nil
+		}
+	}
+
+	return nil, ErrNoEligibleCampaign
+}
+
+// IsMember reports whether the customer belongs to the segment. registered tells whether the
+// customer has signed up, which is known before their first wallet exists.
+func (s *SegmentService) IsMember(ctx context.Context, segment *domain.Segment, phoneHash string, registered bool) (bool, error) {
+	if segment.Kind == domain.SegmentKindStatic {
+		member, err := s.repo.IsSegmentMember(ctx, segment.ID, phoneHash)
+		if err != nil {
+			return false, fmt.Errorf("failed to check segment membership: %w", err)
+		}
+		return member, nil
+	}
+
+	rules, err := domain.ParseSegmentRules(segment.Rules)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	stats, err := s.repo.GetCustomerStats(ctx, segment.MerchantID, phoneHash, rules.SpendSince(now))
+	if err != nil {
+		return false, fmt.Errorf("failed to get customer stats: %w", err)
+	}
+
+	ranking, err := s.cachedSpendRanking(ctx, segment.MerchantID, rules, now)
+	if err != nil {
+		return false, err
+	}
+
+	// A customer on their first purchase has no history yet
+	customer := &domain.CustomerStats{PhoneHash: phoneHash}
+	if len(stats) > 0 {
+		customer = stats[0]
+	}
+	customer.Registered = customer.Registered || registered
+
+	return rules.Matches(customer, ranking, now), nil
+}
+
+// Size counts the segment's current members
+func (s *SegmentService) Size(ctx context.Context, segment *domain.Segment) (int, error) {
+	if segment.Kind == domain.SegmentKindStatic {
+		return s.repo.CountSegmentMembers(ctx, segment.ID)
+	}
+
+	rules, err := domain.ParseSegmentRules(segment.Rules)
+	if err != nil {
+		return 0, err
+	}
+	return s.PreviewRules(ctx, segment.MerchantID, rules)
+}
+
+// PreviewRules counts the merchant's customers matching the rules right now
+func (s *SegmentService) PreviewRules(ctx context.Context, merchantID uuid.UUID, rules *domain.SegmentRules) (int, error) {
+	now := time.Now()
+	stats, err := s.repo.GetCustomerStats(ctx, merchantID, "", rules.SpendSince(now))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get customer stats: %w", err)
+	}
+
+	// Previews rank the customers afresh
+	var ranking *domain.SpendRanking
+	if rules.TopSpendersPercent != nil {
+		ranking, err = s.repo.GetSpendRanking(ctx, merchantID, rules.SpendSince(now), *rules.TopSpendersPercent)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get spend ranking: %w", err)
+		}
+	}
+
+	size := 0
+	for _, customer := range stats {
+		if rules.Matches(customer, ranking, now) {
+			size++
+		}
+	}
+	return size, nil
+}
+
+// cachedSpendRanking returns the merchant's spend ranking for a top spenders rule, computing
+// it again once it is older than spendRankingTTL. Rules without one need no ranking.
+func (s *SegmentService) cachedSpendRanking(ctx context.Context, merchantID uuid.UUID, rules *domain.SegmentRules, now time.Time) (*domain.SpendRanking, error) {
+	if rules.TopSpendersPercent == nil {
+		return nil, nil
+	}
+
+	key := spendRankingKey{merchantID: merchantID, percent: *rules.TopSpendersPercent}
+	if rules.SpendWindowDays != nil {
+		key.windowDays = *rules.SpendWindowDays
+	}
+
+	s.mu.Lock()
+	ranking, ok := s.rankings[key]
+	s.mu.Unlock()
+	if ok && now.Sub(ranking.ComputedAt) < spendRankingTTL {
+		return ranking, nil
+	}
+
+	ranking, err := s.repo.GetSpendRanking(ctx, merchantID, rules.SpendSince(now), *rules.TopSpendersPercent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spend ranking: %w", err)
+	}
+
+	s.mu.Lock()
+	s.rankings[key] = ranking
+	s.mu.Unlock()
+	return ranking, nil
+}
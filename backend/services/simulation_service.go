@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/strategies"
+
+	"github.com/google/uuid"
+)
+
+// SimulationService backtests candidate campaign configs against the historical ledger.
+// Purchases are replayed in order through the real strategy implementations, in memory,
+// so nothing is written to wallets or the ledger.
+type SimulationService struct {
+	repo       *repository.Repository
+	strategies domain.StrategyRegistry
+}
+
+// NewSimulationService creates a new simulation service
+func NewSimulationService(repo *repository.Repository, strategies domain.StrategyRegistry) *SimulationService {
+	return &SimulationService{
+		repo:       repo,
+		strategies: strategies,
+	}
+}
+
+// CreateJob validates the candidate config and queues a simulation for the worker
+func (s *SimulationService) CreateJob(
+	ctx context.Context,
+	merchantID uuid.UUID,
+	campaignType domain.CampaignType,
+	config json.RawMessage,
+	from, to time.Time,
+) (*domain.SimulationJob, error) {
+	strategy, ok := s.strategies[campaignType]
+	if !ok {
+		return nil, domain.ValidationErrors{domain.NewFieldError("type", "is not a supported campaign type: %s", campaignType)}
+	}
+
+	if err := strategies.ValidateConfig(strategy, config); err != nil {
+		return nil, err
+	}
+
+	if !to.After(from) {
+		return nil, domain.ValidationErrors{domain.NewFieldError("to", "must be after from")}
+	}
+
+	job := &domain.SimulationJob{
+		ID:           uuid.New(),
+		MerchantID:   merchantID,
+		CampaignType: campaignType,
+		Config:       config,
+		PeriodStart:  from,
+		PeriodEnd:    to,
+		Status:       domain.SimulationStatusPending,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := s.repo.CreateSimulationJob(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create simulation job: %w", err)
+	}
+
+	return job, nil
+}
+
+// RunJob loads the job's period from the ledger and replays it
+func (s *SimulationService) RunJob(ctx context.Context, job *domain.SimulationJob) (*domain.SimulationResult, error) {
+	strategy, ok := s.strategies[job.CampaignType]
+	if !ok {
+		return nil, fmt.Errorf("no strategy found for campaign type: %s", job.CampaignType)
+	}
+
+	purchases, err := s.repo.GetHistoricalPurchases(ctx, job.MerchantID, job.PeriodStart, job.PeriodEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load historical purchases: %w", err)
+	}
+
+	redemptions, err := s.repo.GetRedemptionsByPhone(ctx, job.MerchantID, job.PeriodStart, job.PeriodEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load redemptions: %w", err)
+	}
+
+	actual, err := s.repo.GetEarnedValue(ctx, job.MerchantID, job.PeriodStart, job.PeriodEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load earned value: %w", err)
+	}
+
+	campaign := &domain.Campaign{
+		ID:             uuid.New(),
+		MerchantID:     job.MerchantID,
+		Name:           "simulation",
+		Type:           job.CampaignType,
+		Config:         job.Config,
+		IsActive:       true,
+		CurrentVersion: 1,
+	}
+
+	result, err := Simulate(ctx, strategy, campaign, purchases, redemptions)
+	if err != nil {
+		return nil, err
+	}
+	result.ActualRewardValue = actual
+
+	return result, nil
+}
+
+// tierState reads the tier of strategies that track one (e.g. PROGRESSIVE)
+type tierState struct {
+	CurrentTier *int `json:"current_tier"`
+}
+
+// customerRun is the in-memory wallet of a simulated customer
+type customerRun struct {
+	summary *domain.CustomerSimulation
+	state   json.RawMessage
+}
+
+// Simulate replays purchases through the strategy and aggregates the outcome.
+// Redemptions are applied at the end of the period, capped at each simulated balance.
+func Simulate(
+	ctx context.Context,
+	strategy domain.CampaignStrategy,
+	campaign *domain.Campaign,
+	purchases []*domain.HistoricalPurchase,
+	redemptions map[string]float64,
+) (*domain.SimulationResult, error) {
+	// Instant-win draws need a seed; a fresh one gives an unbiased sample of outcomes
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("failed to generate seed: %w", err)
+	}
+
+	result := &domain.SimulationResult{}
+	customers := make(map[string]*customerRun)
+	order := make([]string, 0)
+	movements := make(map[[2]int]int)
+
+	for _, purchase := range purchases {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if purchase.PurchaseAmount == nil {
+			result.TransactionsSkipped++
+			continue
+		}
+
+		customer, ok := customers[purchase.PhoneHash]
+		if !ok {
+			customer = &customerRun{
+				summary: &domain.CustomerSimulation{PhoneHash: purchase.PhoneHash},
+				state:   json.RawMessage("{}"),
+			}
+			customers[purchase.PhoneHash] = customer
+			order = append(order, purchase.PhoneHash)
+		}
+
+		input := &domain.StrategyInput{
+			Campaign:      campaign,
+			TransactionID: purchase.ExternalID,
+			Amount:        *purchase.PurchaseAmount,
+			CurrentState:  customer.state,
+			Metadata:      purchase.Metadata,
+			OccurredAt:    purchase.OccurredAt,
+			Seed:          seed,
+		}
+
+		outcome, err := strategy.Execute(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("strategy execution failed for %s: %w", purchase.ExternalID, err)
+		}
+
+		previousTier := readTier(customer.state)
+		customer.state = outcome.NewState
+		if nextTier := readTier(customer.state); nextTier != nil {
+			from := 0
+			if previousTier != nil {
+				from = *previousTier
+			}
+			if *nextTier != from {
+				movements[[2]int{from, *nextTier}]++
+			}
+			customer.summary.FinalTier = nextTier
+		}
+
+		result.TransactionsReplayed++
+		customer.summary.Transactions++
+		customer.summary.Spend += *purchase.PurchaseAmount
+		customer.summary.RewardValue += outcome.NewBalance
+		if outcome.RewardEarned != nil {
+			customer.summary.RewardsIssued++
+		}
+	}
+
+	result.PerCustomer = make([]domain.CustomerSimulation, 0, len(order))
+	values := make([]float64, 0, len(order))
+
+	for _, phoneHash := range order {
+		summary := customers[phoneHash].summary
+		summary.RedeemedValue = math.Min(redemptions[phoneHash], summary.RewardValue)
+		summary.Balance = summary.RewardValue - summary.RedeemedValue
+
+		result.RewardsIssued += summary.RewardsIssued
+		result.RewardValue += summary.RewardValue
+		result.RedeemedValue += summary.RedeemedValue
+		result.Liability += summary.Balance
+
+		result.PerCustomer = append(result.PerCustomer, *summary)
+		values = append(values, summary.RewardValue)
+	}
+
+	result.Customers = len(order)
+	result.Distribution = distribution(values)
+
+	for key, count := range movements {
+		result.TierMovements = append(result.TierMovements, domain.TierMovement{FromTier: key[0], ToTier: key[1], Count: count})
+	}
+	sort.Slice(result.TierMovements, func(i, j int) bool {
+		if result.TierMovements[i].FromTier != result.TierMovements[j].FromTier {
+			return result.TierMovements[i].FromTier < result.TierMovements[j].FromTier
+		}
+		return result.TierMovements[i].ToTier < result.TierMovements[j].ToTier
+	})
+
+	// Largest beneficiaries first
+	sort.SliceStable(result.PerCustomer, func(i, j int) bool {
+		return result.PerCustomer[i].RewardValue > result.PerCustomer[j].RewardValue
+	})
+
+	return result, nil
+}
+
+// readTier returns the tier stored in the state, if the strategy tracks one
+func readTier(state json.RawMessage) *int {
+	var parsed tierState
+	if len(state) == 0 || json.Unmarshal(state, &parsed) != nil {
+		return nil
+	}
+	return parsed.CurrentTier
+}
+
+// distribution computes summary statistics of the reward value per customer
+func distribution(values []float64) domain.RewardDistribution {
+	if len(values) == 0 {
+		return domain.RewardDistribution{}
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	total := 0.0
+	for _, v := range sorted {
+		total += v
+	}
+
+	percentile := func(p float64) float64 {
+		index := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if index < 0 {
+			index = 0
+		}
+		return sorted[index]
+	}
+
+	return domain.RewardDistribution{
+		Mean: total / float64(len(sorted)),
+		P50:  percentile(0.50),
+		P90:  percentile(0.90),
+		P99:  percentile(0.99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
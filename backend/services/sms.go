@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMSSender delivers text messages to E.164 phone numbers
+type SMSSender interface {
+	Send(ctx context.Context, phone, message string) error
+}
+
+// SMSMessage is a message kept by the fake sender
+type SMSMessage struct {
+	Phone   string
+	Message string
+	SentAt  time.Time
+}
+
+// FakeSMSSender keeps messages in memory and logs them instead of sending them. It is meant
+// for development and tests, where the code can be read back with LastMessage.
+type FakeSMSSender struct {
+	mu       sync.Mutex
+	messages []SMSMessage
+}
+
+// NewFakeSMSSender creates a new fake SMS sender
+func NewFakeSMSSender() *FakeSMSSender {
+	return &FakeSMSSender{}
+}
+
+// Send records and logs the message
+func (s *FakeSMSSender) Send(ctx context.Context, phone, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, SMSMessage{Phone: phone, Message: message, SentAt: time.Now()})
+	log.Printf("[SMS] to=%s %s", phone, message)
+	return nil
+}
+
+// Messages returns every message sent so far
+func (s *FakeSMSSender) Messages() []SMSMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]SMSMessage(nil), s.messages...)
+}
+
+// LastMessage returns the newest message sent to a phone
+func (s *FakeSMSSender) LastMessage(phone string) (SMSMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if s.messages[i].Phone == phone {
+			return s.messages[i], true
+		}
+	}
+	return SMSMessage{}, false
+}
+
+// TwilioSMSSender sends messages through the Twilio Messages API
+type TwilioSMSSender struct {
+	accountSID string
+	authToken  string
+	from       string
+	baseURL    string
+	client     *http.Client
+}
+
+// NewTwilioSMSSender creates a sender for a Twilio account and sender number
+func NewTwilioSMSSender(accountSID, authToken, from string) *TwilioSMSSender {
+	return &TwilioSMSSender{
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		baseURL:    "https://api.twilio.com",
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send posts the message to Twilio
+func (s *TwilioSMSSender) Send(ctx context.Context, phone, message string) error {
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", s.baseURL, s.accountSID)
+	form := url.Values{"To": {phone}, "From": {s.from}, "Body": {message}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(s.accountSID, s.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
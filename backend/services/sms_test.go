@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFakeSMSSender(t *testing.T) {
+	sms := NewFakeSMSSender()
+	ctx := context.Background()
+
+	if _, ok := sms.LastMessage("+5511999990000"); ok {
+		t.Fatal("LastMessage() found a message before any was sent")
+	}
+
+	sms.Send(ctx, "+5511999990000", "first")
+	sms.Send(ctx, "+5521988880000", "other phone")
+	sms.Send(ctx, "+5511999990000", "second")
+
+	if messages := sms.Messages(); len(messages) != 3 {
+		t.Fatalf("Messages() returned %d messages, want 3", len(messages))
+	}
+
+	message, ok := sms.LastMessage("+5511999990000")
+	if !ok || message.Message != "second" || message.SentAt.IsZero() {
+		t.Errorf("LastMessage() = %+v, %v, want the second message", message, ok)
+	}
+
+	// Callers get a copy of the messages
+	messages := sms.Messages()
+	messages[0].Message = "changed"
+	if sms.Messages()[0].Message != "first" {
+		t.Error("Messages() exposed the sender's own slice")
+	}
+}
+
+func TestTwilioSMSSender(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "created", status: http.StatusCreated},
+		{name: "rejected", status: http.StatusBadRequest, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var form map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user, pass, _ := r.BasicAuth()
+				if r.URL.Path != "/2010-04-01/Accounts/AC123/Messages.json" || user != "AC123" || pass != "token" {
+					t.Errorf("unexpected request %s with user %q", r.URL.Path, user)
+				}
+				r.ParseForm()
+				form = map[string]string{"To": r.FormValue("To"), "From": r.FormValue("From"), "Body": r.FormValue("Body")}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			sms := NewTwilioSMSSender("AC123", "token", "+15550000000")
+			sms.baseURL = server.URL
+
+			err := sms.Send(context.Background(), "+5511999990000", "Seu código é 123456")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if form["To"] != "+5511999990000" || form["From"] != "+15550000000" || form["Body"] != "Seu código é 123456" {
+				t.Errorf("form = %v", form)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/strategies"
+
+	"github.com/google/uuid"
+)
+
+// TemplateService validates campaign templates and creates draft campaigns from templates
+// or by cloning existing campaigns
+type TemplateService struct {
+	repo       *repository.Repository
+	strategies domain.StrategyRegistry
+}
+
+// NewTemplateService creates a new template service
+func NewTemplateService(repo *repository.Repository, strategies domain.StrategyRegistry) *TemplateService {
+	return &TemplateService{
+		repo:       repo,
+		strategies: strategies,
+	}
+}
+
+// CampaignOverrides customizes a campaign created from a template
+type CampaignOverrides struct {
+	Name       string
+	Parameters map[string]interface{} // Template parameter values, defaults fill the rest
+	Config     json.RawMessage        // Top-level keys replace the rendered config's (null removes a key)
+	StartsAt   *time.Time
+	EndsAt     *time.Time
+
+	MaxRewardValue *float64
+	MaxRewards     *int
+	DailyRewardCap *float64
+	SegmentID      *uuid.UUID
+}
+
+// Validate checks the template parameters and validates the config, rendered with the
+// parameter defaults, through the strategy registry
+func (s *TemplateService) Validate(template *domain.CampaignTemplate) error {
+	if err := template.ValidateParameters(); err != nil {
+		return err
+	}
+
+	config, err := template.Render(nil)
+	if err != nil {
+		return err
+	}
+	return s.validateConfig(template.Type, config)
+}
+
+// Instantiate creates a draft campaign from the template
+func (s *TemplateService) Instantiate(
+	ctx context.Context,
+	template *domain.CampaignTemplate,
+	merchantID uuid.UUID,
+	overrides CampaignOverrides,
+) (*domain.Campaign, error) {
+	config, err := template.Render(overrides.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(overrides.Config) > 0 {
+		if config, err = mergeConfig(config, overrides.Config); err != nil {
+			return nil, domain.ValidationErrors{domain.NewFieldError("config", "must be a JSON object")}
+		}
+	}
+
+	if err := s.validateConfig(template.Type, config); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	campaign := &domain.Campaign{
+		ID:         uuid.New(),
+		MerchantID: merchantID,
+		Name:       overrides.Name,
+		Type:       template.Type,
+		Config:     config,
+		StartsAt:   overrides.StartsAt,
+		EndsAt:     overrides.EndsAt,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+
+		MaxRewardValue: overrides.MaxRewardValue,
+		MaxRewards:     overrides.MaxRewards,
+		DailyRewardCap: overrides.DailyRewardCap,
+		SegmentID:      overrides.SegmentID,
+	}
+
+	if err := s.createDraft(ctx, campaign); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// Clone copies the campaign's config (including its targeting and limits), schedule and
+// budget caps into a new draft. Customer progress, experiments and history are not copied.
+func (s *TemplateService) Clone(ctx context.Context, source *domain.Campaign, name string) (*domain.Campaign, error) {
+	if name == "" {
+		name = source.Name + " (cópia)"
+	}
+
+	now := time.Now()
+	campaign := &domain.Campaign{
+		ID:         uuid.New(),
+		MerchantID: source.MerchantID,
+		Name:       name,
+		Type:       source.Type,
+		Config:     source.Config,
+		StartsAt:   source.StartsAt,
+		EndsAt:     source.EndsAt,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+
+		MaxRewardValue: source.MaxRewardValue,
+		MaxRewards:     source.MaxRewards,
+		DailyRewardCap: source.DailyRewardCap,
+		SegmentID:      source.SegmentID,
+	}
+
+	if err := s.createDraft(ctx, campaign); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// createDraft stores the campaign as a draft, with its prize pool for instant-win campaigns
+func (s *TemplateService) createDraft(ctx context.Context, campaign *domain.Campaign) error {
+	campaign.SetStatus(domain.CampaignStatusDraft)
+
+	if err := s.repo.CreateCampaign(ctx, campaign); err != nil {
+		return fmt.Errorf("failed to create campaign: %w", err)
+	}
+
+	// Instant-win campaigns commit to their draw seed before the first purchase
+	if campaign.Type == domain.CampaignTypeInstantWin {
+		if _, err := NewInstantWinService(s.repo).EnsurePool(ctx, campaign); err != nil {
+			return fmt.Errorf("failed to create prize pool: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateConfig validates a config through the strategy registry, always failing with
+// domain.ValidationErrors
+func (s *TemplateService) validateConfig(campaignType domain.CampaignType, config json.RawMessage) error {
+	strategy, ok := s.strategies[campaignType]
+	if !ok {
+		return domain.ValidationErrors{domain.NewFieldError("type", "is not a supported campaign type: %s", campaignType)}
+	}
+
+	if err := strategies.ValidateConfig(strategy, config); err != nil {
+		var fields domain.ValidationErrors
+		if !errors.As(err, &fields) {
+			fields = domain.ValidationErrors{domain.NewFieldError("config", "%s", err.Error())}
+		}
+		return fields
+	}
+	return nil
+}
+
+// mergeConfig replaces the top-level keys of config with those of overrides
+func mergeConfig(config, overrides json.RawMessage) (json.RawMessage, error) {
+	var base, patch map[string]json.RawMessage
+	if err := json.Unmarshal(config, &base); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(overrides, &patch); err != nil || patch == nil {
+		return nil, errors.New("config overrides must be a JSON object")
+	}
+
+	for key, value := range patch {
+		if string(value) == "null" {
+			delete(base, key)
+			continue
+		}
+		base[key] = value
+	}
+	return json.Marshal(base)
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidToken is returned for malformed tokens, bad signatures and unexpected claims
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenExpired is returned for tokens past their exp claim
+	ErrTokenExpired = errors.New("token expired")
+)
+
+const (
+	// tokenLeeway tolerates clock skew between Supabase and this server
+	tokenLeeway = 30 * time.Second
+	// jwksRefreshInterval is how long fetched signing keys are trusted
+	jwksRefreshInterval = time.Hour
+	// jwksMinRefreshInterval limits refetches triggered by unknown key IDs
+	jwksMinRefreshInterval = time.Minute
+)
+
+// TokenVerifier authenticates consumers from bearer tokens
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*domain.Consumer, error)
+}
+
+// JWTVerifierConfig selects how Supabase access tokens are verified. Tokens signed with
+// HS256 need Secret (the project's JWT secret); RS256 and ES256 tokens need JWKSURL.
+type JWTVerifierConfig struct {
+	Secret   string
+	JWKSURL  string
+	Audience string // Expected aud claim, not checked when empty
+	Issuer   string // Expected iss claim, not checked when empty
+}
+
+// JWTVerifier verifies Supabase-issued JWTs locally, without calling Supabase per request.
+// JWKS keys are cached and refetched hourly or when a token names an unknown key.
+type JWTVerifier struct {
+	config JWTVerifierConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewJWTVerifier creates a new JWT verifier
+func NewJWTVerifier(config JWTVerifierConfig) *JWTVerifier {
+	return &JWTVerifier{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Phone     string          `json:"phone"`
+	Role      string          `json:"role"`
+	Audience  json.RawMessage `json:"aud"` // A string or a list of strings
+	Issuer    string          `json:"iss"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+}
+
+// Verify checks the token's signature and claims and returns the consumer it identifies
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*domain.Consumer, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	if err := v.verifySignature(ctx, header, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	return v.consumer(&claims)
+}
+
+func (v *JWTVerifier) verifySignature(ctx context.Context, header jwtHeader, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch header.Alg {
+	case "HS256":
+		if v.config.Secret == "" {
+			return fmt.Errorf("%w: HS256 tokens are not accepted", ErrInvalidToken)
+		}
+		mac := hmac.New(sha256.New, []byte(v.config.Secret))
+		mac.Write([]byte(signed))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		return nil
+
+	case "RS256":
+		key, err := v.signingKey(ctx, header.Kid)
+		if err != nil {
+			return err
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		return nil
+
+	case "ES256":
+		key, err := v.signingKey(ctx, header.Kid)
+		if err != nil {
+			return err
+		}
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+}
+
+func (v *JWTVerifier) consumer(claims *jwtClaims) (*domain.Consumer, error) {
+	now := v.now()
+
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	expiresAt := time.Unix(*claims.ExpiresAt, 0)
+	if now.After(expiresAt.Add(tokenLeeway)) {
+		return nil, ErrTokenExpired
+	}
+	if claims.NotBefore != nil && now.Add(tokenLeeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return nil, fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+
+	if v.config.Issuer != "" && claims.Issuer != v.config.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if v.config.Audience != "" && !hasAudience(claims.Audience, v.config.Audience) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+
+	// Anon and service keys are JWTs too, but carry no user
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: subject is not a user", ErrInvalidToken)
+	}
+
+	return &domain.Consumer{
+		UserID:    userID,
+		Phone:     claims.Phone,
+		Role:      claims.Role,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// signingKey returns the JWKS key with the given ID, refetching the set when it is stale
+// or does not have the key
+func (v *JWTVerifier) signingKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if v.config.JWKSURL == "" {
+		return nil, fmt.Errorf("%w: asymmetric tokens are not accepted", ErrInvalidToken)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key, ok := v.keys[kid]
+	age := v.now().Sub(v.fetchedAt)
+	if ok && age < jwksRefreshInterval {
+		return key, nil
+	}
+	if ok || age >= jwksMinRefreshInterval {
+		keys, err := v.fetchKeys(ctx)
+		if err != nil {
+			// Keep serving the cached set while the endpoint is unavailable
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+		v.keys = keys
+		v.fetchedAt = v.now()
+		key, ok = keys[kid]
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (v *JWTVerifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", v.config.JWKSURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		// Keys of unsupported types are skipped, tokens signed with them fail as unknown
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch {
+	case k.Kty == "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s %s", k.Kty, k.Crv)
+}
+
+// LocalTokenSigner issues HS256 tokens shaped like Supabase access tokens. Tests and local
+// setups use it in place of Supabase, with the same secret configured in the verifier.
+type LocalTokenSigner struct {
+	secret   string
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// NewLocalTokenSigner creates a signer for the given JWT secret and claims
+func NewLocalTokenSigner(secret, issuer, audience string) *LocalTokenSigner {
+	return &LocalTokenSigner{
+		secret:   secret,
+		issuer:   issuer,
+		audience: audience,
+		now:      time.Now,
+	}
+}
+
+// Sign returns an access token for the user, valid for ttl
+func (s *LocalTokenSigner) Sign(userID uuid.UUID, phone string, ttl time.Duration) (string, error) {
+	now := s.now()
+	header, err := json.Marshal(jwtHeader{Alg: "HS256"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"sub":   userID.String(),
+		"phone": phone,
+		"role":  "authenticated",
+		"aud":   s.audience,
+		"iss":   s.issuer,
+		"iat":   now.Unix(),
+		"exp":   now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	mac := hmac.New(sha256.New, []byte(s.secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func hasAudience(raw json.RawMessage, audience string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == audience
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return false
+	}
+	for _, aud := range list {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
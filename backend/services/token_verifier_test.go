@@ -0,0 +1,413 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	testSecret   = "test-jwt-secret"
+	testIssuer   = "https://project.supabase.co/auth/v1"
+	testAudience = "authenticated"
+)
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestVerifier returns a verifier whose clock is fixed at testNow
+func newTestVerifier(config JWTVerifierConfig) *JWTVerifier {
+	v := NewJWTVerifier(config)
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+// newTestSigner returns a LocalTokenSigner whose clock is fixed at issuedAt
+func newTestSigner(secret, issuer, audience string, issuedAt time.Time) *LocalTokenSigner {
+	s := NewLocalTokenSigner(secret, issuer, audience)
+	s.now = func() time.Time { return issuedAt }
+	return s
+}
+
+func TestJWTVerifierHS256(t *testing.T) {
+	userID := uuid.New()
+	config := JWTVerifierConfig{Secret: testSecret, Issuer: testIssuer, Audience: testAudience}
+
+	tests := []struct {
+		name    string
+		config  JWTVerifierConfig
+		signer  *LocalTokenSigner
+		ttl     time.Duration
+		wantErr error
+	}{
+		{
+			name:   "valid token",
+			config: config,
+			signer: newTestSigner(testSecret, testIssuer, testAudience, testNow),
+			ttl:    time.Hour,
+		},
+		{
+			name:   "expired within leeway",
+			config: config,
+			signer: newTestSigner(testSecret, testIssuer, testAudience, testNow.Add(-time.Hour)),
+			ttl:    time.Hour - tokenLeeway + time.Second,
+		},
+		{
+			name:    "expired past leeway",
+			config:  config,
+			signer:  newTestSigner(testSecret, testIssuer, testAudience, testNow.Add(-time.Hour)),
+			ttl:     time.Hour - tokenLeeway - time.Second,
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name:    "wrong secret",
+			config:  config,
+			signer:  newTestSigner("another-secret", testIssuer, testAudience, testNow),
+			ttl:     time.Hour,
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "wrong audience",
+			config:  config,
+			signer:  newTestSigner(testSecret, testIssuer, "anon", testNow),
+			ttl:     time.Hour,
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "wrong issuer",
+			config:  config,
+			signer:  newTestSigner(testSecret, "https://other.supabase.co/auth/v1", testAudience, testNow),
+			ttl:     time.Hour,
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "HS256 not configured",
+			config:  JWTVerifierConfig{JWKSURL: "http://127.0.0.1:0/jwks"},
+			signer:  newTestSigner(testSecret, testIssuer, testAudience, testNow),
+			ttl:     time.Hour,
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.signer.Sign(userID, "+5511999990000", tt.ttl)
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+
+			consumer, err := newTestVerifier(tt.config).Verify(context.Background(), token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if consumer.UserID != userID || consumer.Phone != "+5511999990000" || consumer.Role != "authenticated" {
+				t.Errorf("Verify() = %+v, want user %s", consumer, userID)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierClaims(t *testing.T) {
+	userID := uuid.New().String()
+	exp := testNow.Add(time.Hour).Unix()
+
+	tests := []struct {
+		name    string
+		claims  map[string]interface{}
+		wantErr error
+	}{
+		{
+			name:   "audience list",
+			claims: map[string]interface{}{"sub": userID, "aud": []string{"other", testAudience}, "iss": testIssuer, "exp": exp},
+		},
+		{
+			name:    "non-UUID subject",
+			claims:  map[string]interface{}{"sub": "service_role", "aud": testAudience, "iss": testIssuer, "exp": exp},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "missing exp",
+			claims:  map[string]interface{}{"sub": userID, "aud": testAudience, "iss": testIssuer},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "not valid yet",
+			claims:  map[string]interface{}{"sub": userID, "aud": testAudience, "iss": testIssuer, "exp": exp, "nbf": testNow.Add(time.Minute).Unix()},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:   "nbf within leeway",
+			claims: map[string]interface{}{"sub": userID, "aud": testAudience, "iss": testIssuer, "exp": exp, "nbf": testNow.Add(10 * time.Second).Unix()},
+		},
+	}
+
+	verifier := newTestVerifier(JWTVerifierConfig{Secret: testSecret, Issuer: testIssuer, Audience: testAudience})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(context.Background(), signHS256(t, testSecret, tt.claims))
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierMalformed(t *testing.T) {
+	verifier := newTestVerifier(JWTVerifierConfig{Secret: testSecret})
+
+	for _, token := range []string{"", "abc", "a.b", "a.b.c", "e30.e30.!!!"} {
+		if _, err := verifier.Verify(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Verify(%q) error = %v, want %v", token, err, ErrInvalidToken)
+		}
+	}
+}
+
+func TestJWTVerifierJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := newStubJWKS(t, rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-1", &ecKey.PublicKey))
+	verifier := newTestVerifier(JWTVerifierConfig{JWKSURL: jwks.server.URL, Issuer: testIssuer, Audience: testAudience})
+
+	userID := uuid.New()
+	claims := map[string]interface{}{"sub": userID.String(), "aud": testAudience, "iss": testIssuer, "exp": testNow.Add(time.Hour).Unix()}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "RS256", token: signRS256(t, rsaKey, "rsa-1", claims)},
+		{name: "ES256", token: signES256(t, ecKey, "ec-1", claims)},
+		{name: "RS256 signed with the EC key id", token: signRS256(t, rsaKey, "ec-1", claims), wantErr: true},
+		{name: "ES256 with a foreign key", token: signES256(t, mustECKey(t), "ec-1", claims), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer, err := verifier.Verify(context.Background(), tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("Verify() error = %v, want %v", err, ErrInvalidToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if consumer.UserID != userID {
+				t.Errorf("Verify() user = %s, want %s", consumer.UserID, userID)
+			}
+		})
+	}
+
+	if got := jwks.fetches(); got != 1 {
+		t.Errorf("JWKS fetched %d times, want 1 (keys are cached)", got)
+	}
+}
+
+func TestJWTVerifierUnknownKidRefetch(t *testing.T) {
+	oldKey := mustECKey(t)
+	newKey := mustECKey(t)
+
+	jwks := newStubJWKS(t, ecJWK("old", &oldKey.PublicKey))
+	now := testNow
+	verifier := NewJWTVerifier(JWTVerifierConfig{JWKSURL: jwks.server.URL})
+	verifier.now = func() time.Time { return now }
+
+	claims := map[string]interface{}{"sub": uuid.New().String(), "exp": testNow.Add(2 * time.Hour).Unix()}
+	ctx := context.Background()
+
+	if _, err := verifier.Verify(ctx, signES256(t, oldKey, "old", claims)); err != nil {
+		t.Fatalf("Verify() with the published key error = %v", err)
+	}
+
+	// The signing key rotates
+	jwks.setKeys(ecJWK("old", &oldKey.PublicKey), ecJWK("new", &newKey.PublicKey))
+	rotated := signES256(t, newKey, "new", claims)
+
+	// Unknown key IDs do not refetch more than once per jwksMinRefreshInterval
+	now = testNow.Add(jwksMinRefreshInterval / 2)
+	if _, err := verifier.Verify(ctx, rotated); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Verify() before the refetch interval error = %v, want %v", err, ErrInvalidToken)
+	}
+	if got := jwks.fetches(); got != 1 {
+		t.Fatalf("JWKS fetched %d times before the refetch interval, want 1", got)
+	}
+
+	now = testNow.Add(jwksMinRefreshInterval + time.Second)
+	if _, err := verifier.Verify(ctx, rotated); err != nil {
+		t.Fatalf("Verify() with the rotated key error = %v", err)
+	}
+	if got := jwks.fetches(); got != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", got)
+	}
+
+	// A key that is still unknown after the refetch is rejected
+	now = now.Add(jwksMinRefreshInterval + time.Second)
+	if _, err := verifier.Verify(ctx, signES256(t, mustECKey(t), "missing", claims)); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Verify() with an unknown key error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestJWTVerifierJWKSUnavailable(t *testing.T) {
+	key := mustECKey(t)
+	jwks := newStubJWKS(t, ecJWK("ec-1", &key.PublicKey))
+	now := testNow
+	verifier := NewJWTVerifier(JWTVerifierConfig{JWKSURL: jwks.server.URL})
+	verifier.now = func() time.Time { return now }
+
+	token := signES256(t, key, "ec-1", map[string]interface{}{"sub": uuid.New().String(), "exp": testNow.Add(3 * time.Hour).Unix()})
+	if _, err := verifier.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// Cached keys keep working past the refresh interval while the endpoint is down
+	jwks.server.Close()
+	now = testNow.Add(jwksRefreshInterval + time.Minute)
+	if _, err := verifier.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Verify() with a cached key error = %v", err)
+	}
+
+	// Without a cached key the failure is not reported as an invalid token
+	other := signES256(t, mustECKey(t), "ec-2", map[string]interface{}{"sub": uuid.New().String(), "exp": testNow.Add(3 * time.Hour).Unix()})
+	_, err := verifier.Verify(context.Background(), other)
+	if err == nil || errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Verify() error = %v, want a fetch error", err)
+	}
+}
+
+// stubJWKS serves a JWKS document and counts how often it is fetched
+type stubJWKS struct {
+	server *httptest.Server
+
+	mu    sync.Mutex
+	keys  []map[string]string
+	count int
+}
+
+func newStubJWKS(t *testing.T, keys ...map[string]string) *stubJWKS {
+	t.Helper()
+	stub := &stubJWKS{keys: keys}
+	stub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		stub.count++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": stub.keys})
+	}))
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+func (s *stubJWKS) setKeys(keys ...map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func (s *stubJWKS) fetches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func mustECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// signingInput encodes the header and claims of a token
+func signingInput(t *testing.T, header jwtHeader, claims map[string]interface{}) string {
+	t.Helper()
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+}
+
+func signHS256(t *testing.T, secret string, claims map[string]interface{}) string {
+	signed := signingInput(t, jwtHeader{Alg: "HS256"}, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signed := signingInput(t, jwtHeader{Alg: "RS256", Kid: kid}, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signed := signingInput(t, jwtHeader{Alg: "ES256", Kid: kid}, claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// welcomeBonusFor returns the campaign's welcome bonus when this is the customer's
+// first interaction with the merchant, or nil otherwise
+func (e *IngestionEngine) welcomeBonusFor(
+	ctx context.Context,
+	merchantID uuid.UUID,
+	campaign *domain.Campaign,
+	phoneHash string,
+) (*domain.WelcomeBonusConfig, error) {
+	bonus, err := domain.ParseWelcomeBonus(campaign.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse welcome bonus: %w", err)
+	}
+	if bonus == nil {
+		return nil, nil
+	}
+
+	seen, err := e.repo.HasCustomerInteraction(ctx, merchantID, phoneHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check customer history: %w", err)
+	}
+	if seen {
+		return nil, nil
+	}
+
+	return bonus, nil
+}
+
+// applyWelcomeBonusWithTx records the welcome bonus inside the settlement transaction.
+// It returns the amount to credit right away (zero while the bonus waits for sign-up),
+// the reward to report back and the budget alerts to send after commit. A new bonus is
+// charged to the campaign budget when it is claimed, pending or not, and is skipped when
+// it does not fit. Exactly one bonus is kept per phone hash and merchant,
+// so concurrent first purchases or a later shadow conversion never pay it twice.
+func (e *IngestionEngine) applyWelcomeBonusWithTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	config *domain.WelcomeBonusConfig,
+	merchant *domain.Merchant,
+	campaign *domain.Campaign,
+	phoneHash string,
+	walletID *uuid.UUID,
+	shadowID *uuid.UUID,
+) (float64, *domain.RewardInfo, []*domain.BudgetAlert, error) {
+	// Registered customers may still have a bonus waiting from their shadow period
+	if config == nil {
+		if walletID == nil {
+			return 0, nil, nil, nil
+		}
+		pending, err := e.repo.GrantPendingWelcomeBonusWithTx(ctx, tx, campaign.MerchantID, phoneHash, *walletID)
+		if err != nil || pending == nil {
+			return 0, nil, nil, err
+		}
+		return pending.Amount, welcomeBonusReward(pending.Amount, false), nil, nil
+	}
+
+	status := domain.WelcomeBonusGranted
+	if config.RequireSignup && walletID == nil {
+		status = domain.WelcomeBonusPending
+	}
+
+	now := time.Now()
+	bonus := &domain.WelcomeBonus{
+		ID:              uuid.New(),
+		MerchantID:      campaign.MerchantID,
+		CampaignID:      &campaign.ID,
+		PhoneHash:       phoneHash,
+		Amount:          config.Amount,
+		Status:          status,
+		WalletID:        walletID,
+		ShadowBalanceID: shadowID,
+		CreatedAt:       now,
+	}
+	if status == domain.WelcomeBonusGranted {
+		bonus.GrantedAt = &now
+	}
+
+	claimed, err := e.repo.ClaimWelcomeBonusWithTx(ctx, tx, bonus)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to claim welcome bonus: %w", err)
+	}
+	if !claimed {
+		return 0, nil, nil, nil
+	}
+
+	charged, alerts, err := e.budgets.ChargeBonusWithTx(ctx, tx, merchant, campaign, config.Amount)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if !charged {
+		if err := e.repo.DeleteWelcomeBonusWithTx(ctx, tx, bonus.ID); err != nil {
+			return 0, nil, nil, fmt.Errorf("failed to drop welcome bonus: %w", err)
+		}
+		return 0, nil, alerts, nil
+	}
+
+	if status == domain.WelcomeBonusPending {
+		return 0, welcomeBonusReward(config.Amount, true), alerts, nil
+	}
+
+	if err := e.repo.CreateWelcomeTransactionWithTx(ctx, tx, bonus, walletID, shadowID); err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to record welcome bonus: %w", err)
+	}
+
+	return config.Amount, welcomeBonusReward(config.Amount, false), alerts, nil
+}
+
+// welcomeBonusReward builds the customer-facing welcome bonus message
+func welcomeBonusReward(amount float64, pending bool) *domain.RewardInfo {
+	description := fmt.Sprintf("Bônus de boas-vindas de R$ %.2f!", amount)
+	if pending {
+		description = fmt.Sprintf("Cadastre-se para liberar seu bônus de boas-vindas de R$ %.2f!", amount)
+	}
+
+	return &domain.RewardInfo{
+		Type:        "welcome_bonus",
+		Amount:      amount,
+		Description: description,
+	}
+}
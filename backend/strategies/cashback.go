@@ -8,6 +8,21 @@ import (
 	"github.com/Ananiaslitz/fidelio/domain"
 )
 
+var cashbackSchema = withCommonProperties(`{
+	"type": "object",
+	"description": "Cliente ganha % de volta em cada compra",
+	"properties": {
+		"percentage": {"type": "number", "exclusiveMinimum": 0, "maximum": 100, "title": "Percentual de cashback"},
+		"max_cashback": {"type": "number", "minimum": 0, "title": "Cashback máximo por compra"},
+		"min_purchase": {"type": "number", "minimum": 0, "title": "Compra mínima"}
+	},
+	"required": ["percentage"]
+}`)
+
+const cashbackDefaultConfig = `{
+	"percentage": 5
+}`
+
 // CashbackStrategy implements percentage-based cashback rewards
 type CashbackStrategy struct{}
 
@@ -21,6 +36,21 @@ func (s *CashbackStrategy) GetType() domain.CampaignType {
 	return domain.CampaignTypeCashback
 }
 
+// DisplayName returns the name shown to merchants
+func (s *CashbackStrategy) DisplayName() string {
+	return "Cashback"
+}
+
+// ConfigSchema returns the JSON Schema of the campaign configuration
+func (s *CashbackStrategy) ConfigSchema() json.RawMessage {
+	return cashbackSchema
+}
+
+// DefaultConfig returns a valid starting configuration
+func (s *CashbackStrategy) DefaultConfig() json.RawMessage {
+	return json.RawMessage(cashbackDefaultConfig)
+}
+
 // Validate ensures the cashback configuration is valid
 func (s *CashbackStrategy) Validate(config json.RawMessage) error {
 	var cfg domain.CashbackConfig
@@ -29,11 +59,11 @@ func (s *CashbackStrategy) Validate(config json.RawMessage) error {
 	}
 
 	if cfg.Percentage <= 0 || cfg.Percentage > 100 {
-		return fmt.Errorf("percentage must be between 0 and 100")
+		return domain.NewFieldError("percentage", "must be between 0 and 100")
 	}
 
 	if cfg.MaxCashback < 0 {
-		return fmt.Errorf("max_cashback cannot be negative")
+		return domain.NewFieldError("max_cashback", "cannot be negative")
 	}
 
 	return nil
@@ -97,3 +127,51 @@ func (s *CashbackStrategy) Execute(ctx context.Context, input *domain.StrategyIn
 		StateChanged: true,
 	}, nil
 }
+
+// Describe compares the cashback still available with the total earned in the campaign
+func (s *CashbackStrategy) Describe(state, config json.RawMessage) (*domain.RewardProgress, error) {
+	var current domain.CashbackState
+	if len(state) > 0 {
+		if err := json.Unmarshal(state, &current); err != nil {
+			return nil, fmt.Errorf("failed to parse state: %w", err)
+		}
+	}
+
+	redeemable := current.TotalEarned - current.TotalRedeemed
+	if redeemable < 0 {
+		redeemable = 0
+	}
+
+	return &domain.RewardProgress{
+		Summary: fmt.Sprintf("R$ %.2f disponíveis de R$ %.2f acumulados", redeemable, current.TotalEarned),
+		Cashback: &domain.CashbackProgress{
+			Redeemable:    redeemable,
+			TotalEarned:   current.TotalEarned,
+			TotalRedeemed: current.TotalRedeemed,
+		},
+	}, nil
+}
+
+// MergeState adds the cashback earned and redeemed on both sides
+func (s *CashbackStrategy) MergeState(walletState, shadowState, config json.RawMessage) (*domain.StrategyResult, error) {
+	var wallet, shadow domain.CashbackState
+	if err := unmarshalState(walletState, &wallet); err != nil {
+		return nil, fmt.Errorf("failed to parse wallet state: %w", err)
+	}
+	if err := unmarshalState(shadowState, &shadow); err != nil {
+		return nil, fmt.Errorf("failed to parse shadow state: %w", err)
+	}
+
+	newState, err := json.Marshal(domain.CashbackState{
+		TotalEarned:   wallet.TotalEarned + shadow.TotalEarned,
+		TotalRedeemed: wallet.TotalRedeemed + shadow.TotalRedeemed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	return &domain.StrategyResult{
+		NewState:     newState,
+		StateChanged: true,
+	}, nil
+}
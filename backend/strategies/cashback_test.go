@@ -0,0 +1,52 @@
+package strategies
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+)
+
+func TestCashbackMergeState(t *testing.T) {
+	tests := []struct {
+		name   string
+		wallet json.RawMessage
+		shadow json.RawMessage
+		want   domain.CashbackState
+	}{
+		{name: "no state on either side", wallet: nil, shadow: json.RawMessage("null")},
+		{
+			name:   "only the wallet has cashback",
+			wallet: stateJSON(domain.CashbackState{TotalEarned: 12.5, TotalRedeemed: 5}),
+			shadow: nil,
+			want:   domain.CashbackState{TotalEarned: 12.5, TotalRedeemed: 5},
+		},
+		{
+			name:   "both sides are added",
+			wallet: stateJSON(domain.CashbackState{TotalEarned: 12.5, TotalRedeemed: 5}),
+			shadow: stateJSON(domain.CashbackState{TotalEarned: 7.5}),
+			want:   domain.CashbackState{TotalEarned: 20, TotalRedeemed: 5},
+		},
+	}
+
+	strategy := NewCashbackStrategy()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := strategy.MergeState(tt.wallet, tt.shadow, json.RawMessage(`{"percentage":5}`))
+			if err != nil {
+				t.Fatalf("MergeState() error = %v", err)
+			}
+
+			var got domain.CashbackState
+			if err := json.Unmarshal(result.NewState, &got); err != nil {
+				t.Fatalf("invalid merged state %s: %v", result.NewState, err)
+			}
+			if got != tt.want {
+				t.Errorf("merged state = %+v, want %+v", got, tt.want)
+			}
+			if result.NewBalance != 0 || result.RewardEarned != nil {
+				t.Errorf("merge paid %v (%+v), want nothing", result.NewBalance, result.RewardEarned)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package strategies
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+
+	"github.com/google/uuid"
+)
+
+var instantWinSchema = withCommonProperties(`{
+	"type": "object",
+	"description": "Cada compra concorre a prêmios instantâneos",
+	"properties": {
+		"min_purchase": {"type": "number", "minimum": 0, "title": "Compra mínima"},
+		"prizes": {
+			"type": "array",
+			"minItems": 1,
+			"title": "Prêmios",
+			"items": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string", "minLength": 1, "title": "Nome"},
+					"odds": {"type": "integer", "minimum": 1, "title": "Chance (1 em N)"},
+					"amount": {"type": "number", "exclusiveMinimum": 0, "title": "Valor"},
+					"reward_type": {"type": "string", "enum": ["points", "discount", "free_item", "cashback"], "title": "Tipo de recompensa"},
+					"inventory": {"type": "integer", "minimum": 1, "title": "Quantidade"}
+				},
+				"required": ["name", "odds", "amount", "reward_type", "inventory"]
+			}
+		}
+	},
+	"required": ["prizes"]
+}`)
+
+const instantWinDefaultConfig = `{
+	"min_purchase": 30,
+	"prizes": [
+		{"name": "Café grátis", "odds": 20, "amount": 1, "reward_type": "free_item", "inventory": 100}
+	]
+}`
+
+// InstantWinStrategy implements sweepstakes where each qualifying purchase may win a prize
+type InstantWinStrategy struct{}
+
+// NewInstantWinStrategy creates a new instant-win strategy instance
+func NewInstantWinStrategy() *InstantWinStrategy {
+	return &InstantWinStrategy{}
+}
+
+// GetType returns the campaign type
+func (s *InstantWinStrategy) GetType() domain.CampaignType {
+	return domain.CampaignTypeInstantWin
+}
+
+// DisplayName returns the name shown to merchants
+func (s *InstantWinStrategy) DisplayName() string {
+	return "Raspadinha"
+}
+
+// ConfigSchema returns the JSON Schema of the campaign configuration
+func (s *InstantWinStrategy) ConfigSchema() json.RawMessage {
+	return instantWinSchema
+}
+
+// DefaultConfig returns a valid starting configuration
+func (s *InstantWinStrategy) DefaultConfig() json.RawMessage {
+	return json.RawMessage(instantWinDefaultConfig)
+}
+
+// Validate ensures the instant-win configuration is valid
+func (s *InstantWinStrategy) Validate(config json.RawMessage) error {
+	var cfg domain.InstantWinConfig
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return fmt.Errorf("invalid instant win config: %w", err)
+	}
+
+	if len(cfg.Prizes) == 0 {
+		return domain.NewFieldError("prizes", "must have at least one prize")
+	}
+
+	validRewardTypes := map[string]bool{
+		"points":    true,
+		"discount":  true,
+		"free_item": true,
+		"cashback":  true,
+	}
+
+	totalProbability := 0.0
+	for i, prize := range cfg.Prizes {
+		if prize.Odds < 1 {
+			return domain.NewFieldError(fmt.Sprintf("prizes[%d].odds", i), "must be at least 1")
+		}
+		if prize.Amount <= 0 {
+			return domain.NewFieldError(fmt.Sprintf("prizes[%d].amount", i), "must be greater than 0")
+		}
+		if prize.Inventory <= 0 {
+			return domain.NewFieldError(fmt.Sprintf("prizes[%d].inventory", i), "must be greater than 0")
+		}
+		if !validRewardTypes[prize.RewardType] {
+			return domain.NewFieldError(fmt.Sprintf("prizes[%d].reward_type", i), "is invalid: %s", prize.RewardType)
+		}
+		totalProbability += 1.0 / float64(prize.Odds)
+	}
+
+	if totalProbability > 1 {
+		return domain.NewFieldError("prizes", "combined odds cannot exceed 100%%")
+	}
+
+	if cfg.MinPurchase < 0 {
+		return domain.NewFieldError("min_purchase", "cannot be negative")
+	}
+
+	return nil
+}
+
+// Execute draws a prize for the purchase. The roll is derived from the campaign seed
+// and the transaction ID, so the same transaction always gets the same outcome and
+// every draw can be re-computed once the seed is revealed.
+func (s *InstantWinStrategy) Execute(ctx context.Context, input *domain.StrategyInput) (*domain.StrategyResult, error) {
+	// Parse configuration
+	var config domain.InstantWinConfig
+	if err := json.Unmarshal(input.Campaign.Config, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	// Check minimum purchase requirement
+	if config.MinPurchase > 0 && input.Amount < config.MinPurchase {
+		return &domain.StrategyResult{
+			NewBalance:   0,
+			NewState:     input.CurrentState,
+			StateChanged: false,
+		}, nil
+	}
+
+	if len(input.Seed) == 0 {
+		return nil, fmt.Errorf("instant win draw requires a campaign seed")
+	}
+
+	// Parse current state
+	var state domain.InstantWinState
+	if len(input.CurrentState) > 0 {
+		if err := json.Unmarshal(input.CurrentState, &state); err != nil {
+			state = domain.InstantWinState{}
+		}
+	}
+
+	drawAt := input.OccurredAt
+	if drawAt.IsZero() {
+		drawAt = time.Now()
+	}
+
+	roll := DrawRoll(input.Seed, input.Campaign.ID, input.TransactionID)
+	draw := &domain.PrizeDraw{Roll: roll}
+
+	state.TotalDraws++
+	state.LastDrawAt = &drawAt
+	state.LastRoll = roll
+
+	var reward *domain.RewardInfo
+	newBalance := 0.0
+
+	// Walk the tiers in order, each one owning a slice of [0, 1) proportional to its odds
+	threshold := 0.0
+	for i, prize := range config.Prizes {
+		threshold += 1.0 / float64(prize.Odds)
+		if roll < threshold {
+			tierIndex := i
+			draw.TierIndex = &tierIndex
+			draw.TierName = prize.Name
+			draw.Amount = prize.Amount
+
+			newBalance = prize.Amount
+			reward = &domain.RewardInfo{
+				Type:        prize.RewardType,
+				Amount:      prize.Amount,
+				Description: fmt.Sprintf("Você ganhou: %s!", prize.Name),
+			}
+			break
+		}
+	}
+
+	// Serialize new state
+	newState, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	return &domain.StrategyResult{
+		NewBalance:   newBalance,
+		NewState:     newState,
+		RewardEarned: reward,
+		StateChanged: true,
+		Draw:         draw,
+	}, nil
+}
+
+// DrawRoll derives a uniform number in [0, 1) from HMAC-SHA256(seed, campaign_id:transaction_id)
+func DrawRoll(seed []byte, campaignID uuid.UUID, transactionID string) float64 {
+	mac := hmac.New(sha256.New, seed)
+	mac.Write([]byte(campaignID.String() + ":" + transactionID))
+	sum := mac.Sum(nil)
+
+	// Keep 53 bits so the value maps exactly onto a float64 mantissa
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11) / float64(uint64(1)<<53)
+}
+
+// SeedCommitment returns the public commitment (SHA-256) published before any draw
+func SeedCommitment(seed []byte) string {
+	hash := sha256.Sum256(seed)
+	return hex.EncodeToString(hash[:])
+}
+
+// MergeState adds the draws of both sides and keeps the most recent one
+func (s *InstantWinStrategy) MergeState(walletState, shadowState, config json.RawMessage) (*domain.StrategyResult, error) {
+	var wallet, shadow domain.InstantWinState
+	if err := unmarshalState(walletState, &wallet); err != nil {
+		return nil, fmt.Errorf("failed to parse wallet state: %w", err)
+	}
+	if err := unmarshalState(shadowState, &shadow); err != nil {
+		return nil, fmt.Errorf("failed to parse shadow state: %w", err)
+	}
+
+	merged := wallet
+	if shadow.LastDrawAt != nil && (wallet.LastDrawAt == nil || shadow.LastDrawAt.After(*wallet.LastDrawAt)) {
+		merged = shadow
+	}
+	merged.TotalDraws = wallet.TotalDraws + shadow.TotalDraws
+
+	newState, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	return &domain.StrategyResult{
+		NewState:     newState,
+		StateChanged: true,
+	}, nil
+}
@@ -0,0 +1,70 @@
+package strategies
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+)
+
+func TestInstantWinMergeState(t *testing.T) {
+	earlier := time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC)
+	later := earlier.Add(24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		wallet   json.RawMessage
+		shadow   json.RawMessage
+		want     int
+		wantLast *time.Time
+		wantRoll float64
+	}{
+		{name: "no state on either side", wallet: json.RawMessage("null"), shadow: nil},
+		{
+			name:     "wallet drew last",
+			wallet:   stateJSON(domain.InstantWinState{TotalDraws: 3, LastDrawAt: &later, LastRoll: 0.4}),
+			shadow:   stateJSON(domain.InstantWinState{TotalDraws: 2, LastDrawAt: &earlier, LastRoll: 0.9}),
+			want:     5,
+			wantLast: &later,
+			wantRoll: 0.4,
+		},
+		{
+			name:     "shadow drew last",
+			wallet:   stateJSON(domain.InstantWinState{TotalDraws: 3, LastDrawAt: &earlier, LastRoll: 0.4}),
+			shadow:   stateJSON(domain.InstantWinState{TotalDraws: 2, LastDrawAt: &later, LastRoll: 0.9}),
+			want:     5,
+			wantLast: &later,
+			wantRoll: 0.9,
+		},
+		{
+			name:     "wallet never drew",
+			wallet:   nil,
+			shadow:   stateJSON(domain.InstantWinState{TotalDraws: 1, LastDrawAt: &earlier, LastRoll: 0.2}),
+			want:     1,
+			wantLast: &earlier,
+			wantRoll: 0.2,
+		},
+	}
+
+	strategy := NewInstantWinStrategy()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := strategy.MergeState(tt.wallet, tt.shadow, json.RawMessage(`{}`))
+			if err != nil {
+				t.Fatalf("MergeState() error = %v", err)
+			}
+
+			var got domain.InstantWinState
+			if err := json.Unmarshal(result.NewState, &got); err != nil {
+				t.Fatalf("invalid merged state %s: %v", result.NewState, err)
+			}
+			if got.TotalDraws != tt.want || got.LastRoll != tt.wantRoll {
+				t.Errorf("merged state = %+v, want %d draws and roll %v", got, tt.want, tt.wantRoll)
+			}
+			if (got.LastDrawAt == nil) != (tt.wantLast == nil) || (got.LastDrawAt != nil && !got.LastDrawAt.Equal(*tt.wantLast)) {
+				t.Errorf("LastDrawAt = %v, want %v", got.LastDrawAt, tt.wantLast)
+			}
+		})
+	}
+}
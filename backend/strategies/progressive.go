@@ -9,6 +9,39 @@ import (
 	"github.com/Ananiaslitz/fidelio/domain"
 )
 
+var progressiveSchema = withCommonProperties(`{
+	"type": "object",
+	"description": "Quanto mais compra, mais pontos ganha",
+	"properties": {
+		"base_points_ratio": {"type": "number", "exclusiveMinimum": 0, "title": "Pontos por real gasto"},
+		"tiers": {
+			"type": "array",
+			"minItems": 1,
+			"title": "Níveis",
+			"items": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string", "minLength": 1, "title": "Nome"},
+					"min_transactions": {"type": "integer", "minimum": 0, "title": "Compras mínimas"},
+					"reward_multiplier": {"type": "number", "exclusiveMinimum": 0, "title": "Multiplicador"},
+					"bonus_points": {"type": "number", "minimum": 0, "title": "Pontos bônus ao subir de nível"}
+				},
+				"required": ["name", "min_transactions", "reward_multiplier"]
+			}
+		}
+	},
+	"required": ["base_points_ratio", "tiers"]
+}`)
+
+const progressiveDefaultConfig = `{
+	"base_points_ratio": 1,
+	"tiers": [
+		{"name": "Bronze", "min_transactions": 0, "reward_multiplier": 1},
+		{"name": "Prata", "min_transactions": 5, "reward_multiplier": 1.5, "bonus_points": 50},
+		{"name": "Ouro", "min_transactions": 15, "reward_multiplier": 2, "bonus_points": 150}
+	]
+}`
+
 // ProgressiveStrategy implements tier-based progressive rewards
 type ProgressiveStrategy struct{}
 
@@ -22,6 +55,21 @@ func (s *ProgressiveStrategy) GetType() domain.CampaignType {
 	return domain.CampaignTypeProgressive
 }
 
+// DisplayName returns the name shown to merchants
+func (s *ProgressiveStrategy) DisplayName() string {
+	return "Pontos Progressivos"
+}
+
+// ConfigSchema returns the JSON Schema of the campaign configuration
+func (s *ProgressiveStrategy) ConfigSchema() json.RawMessage {
+	return progressiveSchema
+}
+
+// DefaultConfig returns a valid starting configuration
+func (s *ProgressiveStrategy) DefaultConfig() json.RawMessage {
+	return json.RawMessage(progressiveDefaultConfig)
+}
+
 // Validate ensures the progressive configuration is valid
 func (s *ProgressiveStrategy) Validate(config json.RawMessage) error {
 	var cfg domain.ProgressiveConfig
@@ -30,17 +78,17 @@ func (s *ProgressiveStrategy) Validate(config json.RawMessage) error {
 	}
 
 	if len(cfg.Tiers) == 0 {
-		return fmt.Errorf("at least one tier is required")
+		return domain.NewFieldError("tiers", "must have at least one tier")
 	}
 
 	if cfg.BasePointsRatio <= 0 {
-		return fmt.Errorf("base_points_ratio must be greater than 0")
+		return domain.NewFieldError("base_points_ratio", "must be greater than 0")
 	}
 
 	// Validate tiers are sorted by min_transactions
 	for i := 1; i < len(cfg.Tiers); i++ {
 		if cfg.Tiers[i].MinTransactions <= cfg.Tiers[i-1].MinTransactions {
-			return fmt.Errorf("tiers must be sorted by min_transactions in ascending order")
+			return domain.NewFieldError("tiers", "must be sorted by min_transactions in ascending order")
 		}
 	}
 
@@ -139,3 +187,144 @@ func sortTiers(tiers []domain.ProgressiveTier) {
 		return tiers[i].MinTransactions < tiers[j].MinTransactions
 	})
 }
+
+// MigrateState places the customer in the tier that matches their transaction count under
+// the new tiers. Points already earned are kept.
+func (s *ProgressiveStrategy) MigrateState(oldConfig, newConfig, state json.RawMessage) (json.RawMessage, error) {
+	var newCfg domain.ProgressiveConfig
+	if err := json.Unmarshal(newConfig, &newCfg); err != nil {
+		return nil, fmt.Errorf("failed to parse new config: %w", err)
+	}
+
+	var current domain.ProgressiveState
+	if err := json.Unmarshal(state, &current); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+
+	current.CurrentTier = s.calculateTier(newCfg.Tiers, current.TransactionCount)
+
+	return json.Marshal(current)
+}
+
+// Describe reports the customer's tier, the next one and the purchases needed to reach it
+func (s *ProgressiveStrategy) Describe(state, config json.RawMessage) (*domain.RewardProgress, error) {
+	var cfg domain.ProgressiveConfig
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(cfg.Tiers) == 0 {
+		return nil, fmt.Errorf("invalid progressive config: tiers must have at least one tier")
+	}
+
+	var current domain.ProgressiveState
+	if len(state) > 0 {
+		if err := json.Unmarshal(state, &current); err != nil {
+			return nil, fmt.Errorf("failed to parse state: %w", err)
+		}
+	}
+
+	// The tier follows the transaction count under these tiers, even if the stored one is stale
+	tierIndex := s.calculateTier(cfg.Tiers, current.TransactionCount)
+	tier := func(i int) domain.ProgressTier {
+		return domain.ProgressTier{Index: i, Name: cfg.Tiers[i].Name, MinTransactions: cfg.Tiers[i].MinTransactions}
+	}
+
+	progress := &domain.ProgressiveProgress{
+		CurrentTier:  tier(tierIndex),
+		Transactions: current.TransactionCount,
+		TotalPoints:  current.TotalPoints,
+	}
+
+	if tierIndex == len(cfg.Tiers)-1 {
+		percent := 100.0
+		return &domain.RewardProgress{
+			Summary:     fmt.Sprintf("Você está no nível máximo (%s)", progress.CurrentTier.Name),
+			Percent:     &percent,
+			Progressive: progress,
+		}, nil
+	}
+
+	next := tier(tierIndex + 1)
+	progress.NextTier = &next
+	progress.TransactionsLeft = next.MinTransactions - current.TransactionCount
+
+	span := next.MinTransactions - progress.CurrentTier.MinTransactions
+	percent := float64(current.TransactionCount-progress.CurrentTier.MinTransactions) / float64(span) * 100
+	if percent < 0 {
+		percent = 0
+	}
+
+	summary := fmt.Sprintf("Faltam %d compras para o nível %s", progress.TransactionsLeft, next.Name)
+	if progress.TransactionsLeft == 1 {
+		summary = fmt.Sprintf("Falta 1 compra para o nível %s", next.Name)
+	}
+
+	return &domain.RewardProgress{
+		Summary:     summary,
+		Percent:     &percent,
+		Progressive: progress,
+	}, nil
+}
+
+// MergeState adds the transactions and points of both sides and recomputes the tier from the
+// combined count. Each tier neither side had reached grants its bonus points, as a purchase would.
+func (s *ProgressiveStrategy) MergeState(walletState, shadowState, config json.RawMessage) (*domain.StrategyResult, error) {
+	var cfg domain.ProgressiveConfig
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(cfg.Tiers) == 0 {
+		return nil, fmt.Errorf("invalid progressive config: tiers must have at least one tier")
+	}
+
+	var wallet, shadow domain.ProgressiveState
+	if err := unmarshalState(walletState, &wallet); err != nil {
+		return nil, fmt.Errorf("failed to parse wallet state: %w", err)
+	}
+	if err := unmarshalState(shadowState, &shadow); err != nil {
+		return nil, fmt.Errorf("failed to parse shadow state: %w", err)
+	}
+
+	merged := domain.ProgressiveState{
+		TransactionCount: wallet.TransactionCount + shadow.TransactionCount,
+		TotalPoints:      wallet.TotalPoints + shadow.TotalPoints,
+	}
+	merged.CurrentTier = s.calculateTier(cfg.Tiers, merged.TransactionCount)
+
+	// Tiers are compared by transaction count, since stored tiers may predate a config change
+	previousTier := s.calculateTier(cfg.Tiers, wallet.TransactionCount)
+	if shadowTier := s.calculateTier(cfg.Tiers, shadow.TransactionCount); shadowTier > previousTier {
+		previousTier = shadowTier
+	}
+
+	var reward *domain.RewardInfo
+	newBalance := 0.0
+
+	// Every tier crossed by the merge pays its bonus, as the purchases would have one by one
+	for i := previousTier + 1; i <= merged.CurrentTier; i++ {
+		newBalance += cfg.Tiers[i].BonusPoints
+	}
+
+	if newBalance > 0 {
+		tier := cfg.Tiers[merged.CurrentTier]
+		merged.TotalPoints += newBalance
+
+		reward = &domain.RewardInfo{
+			Type:        "points",
+			Amount:      newBalance,
+			Description: fmt.Sprintf("Bônus de %.0f pontos por atingir o tier %s!", newBalance, tier.Name),
+		}
+	}
+
+	newState, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	return &domain.StrategyResult{
+		NewBalance:   newBalance,
+		NewState:     newState,
+		RewardEarned: reward,
+		StateChanged: true,
+	}, nil
+}
@@ -0,0 +1,130 @@
+package strategies
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+)
+
+func TestProgressiveMergeState(t *testing.T) {
+	config := stateJSON(domain.ProgressiveConfig{
+		BasePointsRatio: 1,
+		Tiers: []domain.ProgressiveTier{
+			{Name: "Bronze", MinTransactions: 0, RewardMultiplier: 1},
+			{Name: "Prata", MinTransactions: 5, RewardMultiplier: 1.5, BonusPoints: 50},
+			{Name: "Ouro", MinTransactions: 15, RewardMultiplier: 2, BonusPoints: 150},
+			{Name: "Diamante", MinTransactions: 30, RewardMultiplier: 3},
+		},
+	})
+	// Two sides short of the first tier can only cross several tiers when they are close together
+	closeTiers := stateJSON(domain.ProgressiveConfig{
+		BasePointsRatio: 1,
+		Tiers: []domain.ProgressiveTier{
+			{Name: "Bronze", MinTransactions: 0, RewardMultiplier: 1},
+			{Name: "Prata", MinTransactions: 5, RewardMultiplier: 1.5, BonusPoints: 50},
+			{Name: "Ouro", MinTransactions: 8, RewardMultiplier: 2, BonusPoints: 150},
+		},
+	})
+
+	tests := []struct {
+		name        string
+		config      json.RawMessage
+		wallet      json.RawMessage
+		shadow      json.RawMessage
+		want        domain.ProgressiveState
+		wantBalance float64
+		wantErr     bool
+	}{
+		{
+			name:   "no state on either side",
+			config: config,
+			wallet: json.RawMessage("null"),
+			shadow: nil,
+		},
+		{
+			name:   "tier unchanged",
+			config: config,
+			wallet: stateJSON(domain.ProgressiveState{TransactionCount: 2, TotalPoints: 40}),
+			shadow: stateJSON(domain.ProgressiveState{TransactionCount: 2, TotalPoints: 30}),
+			want:   domain.ProgressiveState{TransactionCount: 4, TotalPoints: 70},
+		},
+		{
+			name:        "one tier up",
+			config:      config,
+			wallet:      stateJSON(domain.ProgressiveState{TransactionCount: 3, TotalPoints: 60}),
+			shadow:      stateJSON(domain.ProgressiveState{TransactionCount: 3, TotalPoints: 45}),
+			want:        domain.ProgressiveState{TransactionCount: 6, CurrentTier: 1, TotalPoints: 155},
+			wantBalance: 50,
+		},
+		{
+			name:        "bonuses of every tier crossed",
+			config:      closeTiers,
+			wallet:      stateJSON(domain.ProgressiveState{TransactionCount: 4, TotalPoints: 80}),
+			shadow:      stateJSON(domain.ProgressiveState{TransactionCount: 4, TotalPoints: 70}),
+			want:        domain.ProgressiveState{TransactionCount: 8, CurrentTier: 2, TotalPoints: 350},
+			wantBalance: 200,
+		},
+		{
+			name:        "tiers already reached by one side pay nothing",
+			config:      config,
+			wallet:      stateJSON(domain.ProgressiveState{TransactionCount: 8, CurrentTier: 1, TotalPoints: 200}),
+			shadow:      stateJSON(domain.ProgressiveState{TransactionCount: 8, CurrentTier: 1, TotalPoints: 200}),
+			want:        domain.ProgressiveState{TransactionCount: 16, CurrentTier: 2, TotalPoints: 550},
+			wantBalance: 150,
+		},
+		{
+			name:   "tier without bonus",
+			config: config,
+			wallet: stateJSON(domain.ProgressiveState{TransactionCount: 20, CurrentTier: 2, TotalPoints: 500}),
+			shadow: stateJSON(domain.ProgressiveState{TransactionCount: 10, CurrentTier: 1, TotalPoints: 250}),
+			want:   domain.ProgressiveState{TransactionCount: 30, CurrentTier: 3, TotalPoints: 750},
+		},
+		{
+			name:   "stored tier is recomputed from the transactions",
+			config: config,
+			wallet: stateJSON(domain.ProgressiveState{TransactionCount: 16, CurrentTier: 0, TotalPoints: 400}),
+			shadow: stateJSON(domain.ProgressiveState{TransactionCount: 1, CurrentTier: 3, TotalPoints: 20}),
+			want:   domain.ProgressiveState{TransactionCount: 17, CurrentTier: 2, TotalPoints: 420},
+		},
+		{
+			name:    "config without tiers",
+			config:  stateJSON(domain.ProgressiveConfig{BasePointsRatio: 1}),
+			wantErr: true,
+		},
+	}
+
+	strategy := NewProgressiveStrategy()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := strategy.MergeState(tt.wallet, tt.shadow, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MergeState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var got domain.ProgressiveState
+			if err := json.Unmarshal(result.NewState, &got); err != nil {
+				t.Fatalf("invalid merged state %s: %v", result.NewState, err)
+			}
+			if got != tt.want {
+				t.Errorf("merged state = %+v, want %+v", got, tt.want)
+			}
+			if result.NewBalance != tt.wantBalance {
+				t.Errorf("NewBalance = %v, want %v", result.NewBalance, tt.wantBalance)
+			}
+
+			if tt.wantBalance == 0 {
+				if result.RewardEarned != nil {
+					t.Errorf("RewardEarned = %+v, want none", result.RewardEarned)
+				}
+				return
+			}
+			if result.RewardEarned == nil || result.RewardEarned.Amount != tt.wantBalance {
+				t.Errorf("RewardEarned = %+v, want %v points", result.RewardEarned, tt.wantBalance)
+			}
+		})
+	}
+}
@@ -4,10 +4,35 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/Ananiaslitz/fidelio/domain"
 )
 
+var punchCardSchema = withCommonProperties(`{
+	"type": "object",
+	"description": "A cada X compras, ganha recompensa",
+	"properties": {
+		"required_punches": {"type": "integer", "minimum": 1, "title": "Carimbos para a recompensa"},
+		"reward_amount": {"type": "number", "exclusiveMinimum": 0, "title": "Valor da recompensa"},
+		"reward_type": {"type": "string", "enum": ["points", "discount", "free_item"], "title": "Tipo de recompensa"},
+		"min_purchase": {"type": "number", "minimum": 0, "title": "Compra mínima"},
+		"stamp_mode": {"type": "string", "enum": ["per_purchase", "per_amount", "per_item"], "title": "Modo de carimbo"},
+		"amount_per_stamp": {"type": "number", "exclusiveMinimum": 0, "title": "Valor por carimbo"},
+		"item_category": {"type": "string", "title": "Categoria dos itens"},
+		"max_stamps_per_purchase": {"type": "integer", "minimum": 0, "title": "Máximo de carimbos por compra"}
+	},
+	"required": ["required_punches", "reward_amount", "reward_type"]
+}`)
+
+const punchCardDefaultConfig = `{
+	"required_punches": 10,
+	"reward_amount": 1,
+	"reward_type": "free_item",
+	"stamp_mode": "per_purchase"
+}`
+
 // PunchCardStrategy implements the "Buy X, Get Y" punch card logic
 type PunchCardStrategy struct{}
 
@@ -21,6 +46,21 @@ func (s *PunchCardStrategy) GetType() domain.CampaignType {
 	return domain.CampaignTypePunchCard
 }
 
+// DisplayName returns the name shown to merchants
+func (s *PunchCardStrategy) DisplayName() string {
+	return "Cartão Fidelidade"
+}
+
+// ConfigSchema returns the JSON Schema of the campaign configuration
+func (s *PunchCardStrategy) ConfigSchema() json.RawMessage {
+	return punchCardSchema
+}
+
+// DefaultConfig returns a valid starting configuration
+func (s *PunchCardStrategy) DefaultConfig() json.RawMessage {
+	return json.RawMessage(punchCardDefaultConfig)
+}
+
 // Validate ensures the punch card configuration is valid
 func (s *PunchCardStrategy) Validate(config json.RawMessage) error {
 	var cfg domain.PunchCardConfig
@@ -29,11 +69,11 @@ func (s *PunchCardStrategy) Validate(config json.RawMessage) error {
 	}
 
 	if cfg.RequiredPunches <= 0 {
-		return fmt.Errorf("required_punches must be greater than 0")
+		return domain.NewFieldError("required_punches", "must be greater than 0")
 	}
 
 	if cfg.RewardAmount <= 0 {
-		return fmt.Errorf("reward_amount must be greater than 0")
+		return domain.NewFieldError("reward_amount", "must be greater than 0")
 	}
 
 	validRewardTypes := map[string]bool{
@@ -43,7 +83,21 @@ func (s *PunchCardStrategy) Validate(config json.RawMessage) error {
 	}
 
 	if !validRewardTypes[cfg.RewardType] {
-		return fmt.Errorf("invalid reward_type: %s", cfg.RewardType)
+		return domain.NewFieldError("reward_type", "is invalid: %s", cfg.RewardType)
+	}
+
+	switch cfg.StampMode {
+	case "", domain.StampModePerPurchase, domain.StampModePerItem:
+	case domain.StampModePerAmount:
+		if cfg.AmountPerStamp <= 0 {
+			return domain.NewFieldError("amount_per_stamp", "must be greater than 0")
+		}
+	default:
+		return domain.NewFieldError("stamp_mode", "is invalid: %s", cfg.StampMode)
+	}
+
+	if cfg.MaxStampsPerPurchase < 0 {
+		return domain.NewFieldError("max_stamps_per_purchase", "cannot be negative")
 	}
 
 	return nil
@@ -56,6 +110,9 @@ func (s *PunchCardStrategy) Execute(ctx context.Context, input *domain.StrategyI
 	if err := json.Unmarshal(input.Campaign.Config, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
+	if config.RequiredPunches <= 0 {
+		return nil, fmt.Errorf("invalid punch card config: required_punches must be greater than 0")
+	}
 
 	// Check minimum purchase requirement
 	if config.MinPurchase > 0 && input.Amount < config.MinPurchase {
@@ -78,23 +135,35 @@ func (s *PunchCardStrategy) Execute(ctx context.Context, input *domain.StrategyI
 		}
 	}
 
-	// Increment punch count
-	state.CurrentPunches++
+	// Count the punches earned by this purchase
+	stamps := s.stampsFor(config, input)
+	if stamps == 0 {
+		return &domain.StrategyResult{
+			NewBalance:   0,
+			NewState:     input.CurrentState,
+			StateChanged: false,
+		}, nil
+	}
+
+	state.CurrentPunches += stamps
+	state.TotalPunches += stamps
 
 	var reward *domain.RewardInfo
 	newBalance := 0.0
 
-	// Check if reward earned
-	if state.CurrentPunches >= config.RequiredPunches {
-		// Award the reward
-		newBalance = config.RewardAmount
-		state.CurrentPunches = 0 // Reset punches
-		state.TotalRedeemed++
+	// Check if reward earned, carrying extra punches over to the next card
+	cardsCompleted := state.CurrentPunches / config.RequiredPunches
+	if cardsCompleted > 0 {
+		// Award one reward per completed card
+		newBalance = config.RewardAmount * float64(cardsCompleted)
+		state.CurrentPunches = state.CurrentPunches % config.RequiredPunches
+		state.TotalRedeemed += cardsCompleted
 
 		reward = &domain.RewardInfo{
-			Type:        config.RewardType,
-			Amount:      config.RewardAmount,
-			Description: fmt.Sprintf("Completou %d compras! Parabéns!", config.RequiredPunches),
+			Type:           config.RewardType,
+			Amount:         newBalance,
+			Description:    s.rewardDescription(config, cardsCompleted),
+			CardsCompleted: cardsCompleted,
 		}
 	}
 
@@ -111,3 +180,176 @@ func (s *PunchCardStrategy) Execute(ctx context.Context, input *domain.StrategyI
 		StateChanged: true,
 	}, nil
 }
+
+// stampsFor returns how many punches the purchase earns under the configured stamp mode
+func (s *PunchCardStrategy) stampsFor(config domain.PunchCardConfig, input *domain.StrategyInput) int {
+	stamps := 0
+
+	switch config.StampMode {
+	case domain.StampModePerAmount:
+		// Small epsilon so R$ 40.00 with R$ 20.00 per stamp is not rounded down to 1
+		stamps = int(math.Floor(input.Amount/config.AmountPerStamp + 1e-9))
+	case domain.StampModePerItem:
+		for _, item := range domain.ParseTransactionItems(input.Metadata) {
+			if config.ItemCategory != "" && !strings.EqualFold(item.Category, config.ItemCategory) {
+				continue
+			}
+			if item.Quantity > 0 {
+				stamps += item.Quantity
+			}
+		}
+	default:
+		stamps = 1
+	}
+
+	if config.MaxStampsPerPurchase > 0 && stamps > config.MaxStampsPerPurchase {
+		stamps = config.MaxStampsPerPurchase
+	}
+
+	return stamps
+}
+
+// rewardDescription builds the customer-facing reward message
+func (s *PunchCardStrategy) rewardDescription(config domain.PunchCardConfig, cardsCompleted int) string {
+	if cardsCompleted > 1 {
+		return fmt.Sprintf("Completou %d cartões de %d carimbos! Parabéns!", cardsCompleted, config.RequiredPunches)
+	}
+
+	switch config.StampMode {
+	case domain.StampModePerAmount, domain.StampModePerItem:
+		return fmt.Sprintf("Completou um cartão de %d carimbos! Parabéns!", config.RequiredPunches)
+	default:
+		return fmt.Sprintf("Completou %d compras! Parabéns!", config.RequiredPunches)
+	}
+}
+
+// MigrateState keeps the customer's progress on the current card when required_punches
+// changes: the same fraction of the card stays filled, rounded in the customer's favor but
+// never completing a card that was not completed under the old rules.
+func (s *PunchCardStrategy) MigrateState(oldConfig, newConfig, state json.RawMessage) (json.RawMessage, error) {
+	var oldCfg, newCfg domain.PunchCardConfig
+	if err := json.Unmarshal(oldConfig, &oldCfg); err != nil {
+		return nil, fmt.Errorf("failed to parse old config: %w", err)
+	}
+	if err := json.Unmarshal(newConfig, &newCfg); err != nil {
+		return nil, fmt.Errorf("failed to parse new config: %w", err)
+	}
+
+	var current domain.PunchCardState
+	if err := json.Unmarshal(state, &current); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+
+	if oldCfg.RequiredPunches <= 0 || oldCfg.RequiredPunches == newCfg.RequiredPunches {
+		return state, nil
+	}
+
+	progress := float64(current.CurrentPunches) / float64(oldCfg.RequiredPunches)
+	punches := int(math.Ceil(progress*float64(newCfg.RequiredPunches) - 1e-9))
+	if punches > newCfg.RequiredPunches-1 {
+		punches = newCfg.RequiredPunches - 1
+	}
+	if punches < 0 {
+		punches = 0
+	}
+	current.CurrentPunches = punches
+
+	return json.Marshal(current)
+}
+
+// Describe reports the punches on the current card and how many are left for the reward
+func (s *PunchCardStrategy) Describe(state, config json.RawMessage) (*domain.RewardProgress, error) {
+	var cfg domain.PunchCardConfig
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if cfg.RequiredPunches <= 0 {
+		return nil, fmt.Errorf("invalid punch card config: required_punches must be greater than 0")
+	}
+
+	var current domain.PunchCardState
+	if len(state) > 0 {
+		if err := json.Unmarshal(state, &current); err != nil {
+			return nil, fmt.Errorf("failed to parse state: %w", err)
+		}
+	}
+
+	// Never show a full card: a completed card is always paid out, so more punches can only
+	// be left from a longer card before a config change
+	punches := current.CurrentPunches
+	if punches > cfg.RequiredPunches-1 {
+		punches = cfg.RequiredPunches - 1
+	}
+	remaining := cfg.RequiredPunches - punches
+	percent := float64(punches) / float64(cfg.RequiredPunches) * 100
+
+	summary := fmt.Sprintf("Faltam %d carimbos para a próxima recompensa (%d de %d)", remaining, punches, cfg.RequiredPunches)
+	if remaining == 1 {
+		summary = fmt.Sprintf("Falta 1 carimbo para a próxima recompensa (%d de %d)", punches, cfg.RequiredPunches)
+	}
+
+	return &domain.RewardProgress{
+		Summary: summary,
+		Percent: &percent,
+		PunchCard: &domain.PunchCardProgress{
+			Punches:        punches,
+			Required:       cfg.RequiredPunches,
+			Remaining:      remaining,
+			CardsCompleted: current.TotalRedeemed,
+		},
+	}, nil
+}
+
+// MergeState adds the punches of both cards. Punches that complete cards are paid out as
+// rewards and the rest stays on the current card.
+func (s *PunchCardStrategy) MergeState(walletState, shadowState, config json.RawMessage) (*domain.StrategyResult, error) {
+	var cfg domain.PunchCardConfig
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if cfg.RequiredPunches <= 0 {
+		return nil, fmt.Errorf("invalid punch card config: required_punches must be greater than 0")
+	}
+
+	var wallet, shadow domain.PunchCardState
+	if err := unmarshalState(walletState, &wallet); err != nil {
+		return nil, fmt.Errorf("failed to parse wallet state: %w", err)
+	}
+	if err := unmarshalState(shadowState, &shadow); err != nil {
+		return nil, fmt.Errorf("failed to parse shadow state: %w", err)
+	}
+
+	merged := domain.PunchCardState{
+		CurrentPunches: wallet.CurrentPunches + shadow.CurrentPunches,
+		TotalRedeemed:  wallet.TotalRedeemed + shadow.TotalRedeemed,
+		TotalPunches:   wallet.TotalPunches + shadow.TotalPunches,
+	}
+
+	var reward *domain.RewardInfo
+	newBalance := 0.0
+
+	if cardsCompleted := merged.CurrentPunches / cfg.RequiredPunches; cardsCompleted > 0 {
+		newBalance = cfg.RewardAmount * float64(cardsCompleted)
+		merged.CurrentPunches = merged.CurrentPunches % cfg.RequiredPunches
+		merged.TotalRedeemed += cardsCompleted
+
+		reward = &domain.RewardInfo{
+			Type:           cfg.RewardType,
+			Amount:         newBalance,
+			Description:    s.rewardDescription(cfg, cardsCompleted),
+			CardsCompleted: cardsCompleted,
+		}
+	}
+
+	newState, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	return &domain.StrategyResult{
+		NewBalance:   newBalance,
+		NewState:     newState,
+		RewardEarned: reward,
+		StateChanged: true,
+	}, nil
+}
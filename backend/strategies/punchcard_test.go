@@ -0,0 +1,142 @@
+package strategies
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+)
+
+func TestPunchCardMergeState(t *testing.T) {
+	config := stateJSON(domain.PunchCardConfig{RequiredPunches: 10, RewardAmount: 15, RewardType: "discount"})
+
+	tests := []struct {
+		name        string
+		config      json.RawMessage
+		wallet      json.RawMessage
+		shadow      json.RawMessage
+		want        domain.PunchCardState
+		wantBalance float64
+		wantCards   int
+		wantErr     bool
+	}{
+		{
+			name:   "no state on either side",
+			config: config,
+			wallet: nil,
+			shadow: json.RawMessage("null"),
+		},
+		{
+			name:   "only the shadow has punches",
+			config: config,
+			wallet: nil,
+			shadow: stateJSON(domain.PunchCardState{CurrentPunches: 4, TotalPunches: 4}),
+			want:   domain.PunchCardState{CurrentPunches: 4, TotalPunches: 4},
+		},
+		{
+			name:   "punches stay on the current card",
+			config: config,
+			wallet: stateJSON(domain.PunchCardState{CurrentPunches: 3, TotalRedeemed: 1, TotalPunches: 13}),
+			shadow: stateJSON(domain.PunchCardState{CurrentPunches: 4, TotalPunches: 4}),
+			want:   domain.PunchCardState{CurrentPunches: 7, TotalRedeemed: 1, TotalPunches: 17},
+		},
+		{
+			name:        "exactly one card completed",
+			config:      config,
+			wallet:      stateJSON(domain.PunchCardState{CurrentPunches: 6, TotalPunches: 6}),
+			shadow:      stateJSON(domain.PunchCardState{CurrentPunches: 4, TotalPunches: 4}),
+			want:        domain.PunchCardState{CurrentPunches: 0, TotalRedeemed: 1, TotalPunches: 10},
+			wantBalance: 15,
+			wantCards:   1,
+		},
+		{
+			name:        "rollover across several cards",
+			config:      config,
+			wallet:      stateJSON(domain.PunchCardState{CurrentPunches: 9, TotalRedeemed: 2, TotalPunches: 29}),
+			shadow:      stateJSON(domain.PunchCardState{CurrentPunches: 24, TotalPunches: 24}),
+			want:        domain.PunchCardState{CurrentPunches: 3, TotalRedeemed: 5, TotalPunches: 53},
+			wantBalance: 45,
+			wantCards:   3,
+		},
+		{
+			name:    "invalid config",
+			config:  stateJSON(domain.PunchCardConfig{RequiredPunches: 0}),
+			wallet:  nil,
+			shadow:  nil,
+			wantErr: true,
+		},
+		{
+			name:    "malformed shadow state",
+			config:  config,
+			wallet:  nil,
+			shadow:  json.RawMessage(`{"current_punches":`),
+			wantErr: true,
+		},
+	}
+
+	strategy := NewPunchCardStrategy()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := strategy.MergeState(tt.wallet, tt.shadow, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MergeState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var got domain.PunchCardState
+			if err := json.Unmarshal(result.NewState, &got); err != nil {
+				t.Fatalf("invalid merged state %s: %v", result.NewState, err)
+			}
+			if got != tt.want {
+				t.Errorf("merged state = %+v, want %+v", got, tt.want)
+			}
+			if result.NewBalance != tt.wantBalance {
+				t.Errorf("NewBalance = %v, want %v", result.NewBalance, tt.wantBalance)
+			}
+
+			if tt.wantCards == 0 {
+				if result.RewardEarned != nil {
+					t.Errorf("RewardEarned = %+v, want none", result.RewardEarned)
+				}
+				return
+			}
+			if result.RewardEarned == nil {
+				t.Fatalf("RewardEarned = nil, want %d cards", tt.wantCards)
+			}
+			if result.RewardEarned.CardsCompleted != tt.wantCards || result.RewardEarned.Amount != tt.wantBalance {
+				t.Errorf("RewardEarned = %+v, want %d cards worth %v", result.RewardEarned, tt.wantCards, tt.wantBalance)
+			}
+		})
+	}
+}
+
+func TestPunchCardExecuteInvalidConfig(t *testing.T) {
+	strategy := NewPunchCardStrategy()
+
+	tests := []struct {
+		name   string
+		config json.RawMessage
+	}{
+		{name: "zero required punches", config: json.RawMessage(`{"required_punches":0,"reward_amount":15}`)},
+		{name: "required punches missing", config: json.RawMessage(`{"reward_amount":15}`)},
+		{name: "negative required punches", config: json.RawMessage(`{"required_punches":-1,"reward_amount":15}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &domain.StrategyInput{
+				Campaign:     &domain.Campaign{Type: domain.CampaignTypePunchCard, Config: tt.config},
+				Amount:       20,
+				CurrentState: stateJSON(domain.PunchCardState{CurrentPunches: 3, TotalPunches: 3}),
+			}
+
+			result, err := strategy.Execute(context.Background(), input)
+			if err == nil {
+				t.Fatalf("Execute() = %+v, want error", result)
+			}
+		})
+	}
+}
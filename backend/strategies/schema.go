@@ -0,0 +1,259 @@
+package strategies
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+)
+
+// commonProperties are config settings accepted by every campaign type
+var commonProperties = map[string]json.RawMessage{
+	"welcome_bonus": json.RawMessage(`{
+		"type": "object",
+		"title": "Bônus de boas-vindas",
+		"description": "Pago uma única vez na primeira compra do cliente",
+		"properties": {
+			"amount": {"type": "number", "exclusiveMinimum": 0, "title": "Valor"},
+			"require_signup": {"type": "boolean", "title": "Exigir cadastro"}
+		},
+		"required": ["amount"]
+	}`),
+	"customer_limits": json.RawMessage(`{
+		"type": "object",
+		"title": "Limites por cliente",
+		"description": "Evita abusos limitando o que cada cliente pode ganhar",
+		"properties": {
+			"max_rewards_per_day": {"type": "integer", "minimum": 0, "title": "Recompensas por dia"},
+			"max_reward_value_per_day": {"type": "number", "minimum": 0, "title": "Valor de recompensas por dia"},
+			"max_reward_value_per_month": {"type": "number", "minimum": 0, "title": "Valor de recompensas por mês"},
+			"max_visits_per_window": {"type": "integer", "minimum": 0, "title": "Compras por janela"},
+			"visit_window_minutes": {"type": "integer", "minimum": 1, "title": "Janela (minutos)"}
+		}
+	}`),
+}
+
+// withCommonProperties adds the settings shared by all campaign types to a config schema
+func withCommonProperties(schema string) json.RawMessage {
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+		panic(fmt.Sprintf("invalid config schema: %v", err))
+	}
+
+	properties, _ := parsed["properties"].(map[string]interface{})
+	if properties == nil {
+		properties = make(map[string]interface{})
+		parsed["properties"] = properties
+	}
+	for name, property := range commonProperties {
+		properties[name] = property
+	}
+
+	out, err := json.Marshal(parsed)
+	if err != nil {
+		panic(fmt.Sprintf("invalid config schema: %v", err))
+	}
+	return out
+}
+
+// ValidateConfig checks a campaign config against the strategy's JSON Schema and then its
+// own Validate rules. Failures are returned as domain.ValidationErrors.
+func ValidateConfig(strategy domain.CampaignStrategy, config json.RawMessage) error {
+	if errs := ValidateSchema(strategy.ConfigSchema(), config); len(errs) > 0 {
+		return errs
+	}
+
+	limits, err := domain.ParseCustomerLimits(config)
+	if err != nil {
+		return domain.ValidationErrors{{Field: "customer_limits", Message: err.Error()}}
+	}
+	if limits != nil {
+		if err := limits.Validate(); err != nil {
+			var fieldErr *domain.FieldError
+			if errors.As(err, &fieldErr) {
+				return domain.ValidationErrors{fieldErr}
+			}
+			return domain.ValidationErrors{{Field: "customer_limits", Message: err.Error()}}
+		}
+	}
+
+	if err := strategy.Validate(config); err != nil {
+		var fieldErr *domain.FieldError
+		if errors.As(err, &fieldErr) {
+			return domain.ValidationErrors{fieldErr}
+		}
+		return domain.ValidationErrors{{Field: "config", Message: err.Error()}}
+	}
+
+	return nil
+}
+
+// ValidateSchema validates a JSON document against a schema. It supports the subset of
+// JSON Schema used by the campaign configs: type, properties, required, enum, items,
+// minItems, minLength, minimum, maximum, exclusiveMinimum and exclusiveMaximum.
+func ValidateSchema(schema, document json.RawMessage) domain.ValidationErrors {
+	var parsedSchema map[string]interface{}
+	if err := json.Unmarshal(schema, &parsedSchema); err != nil {
+		return domain.ValidationErrors{domain.NewFieldError("", "invalid schema: %v", err)}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return domain.ValidationErrors{domain.NewFieldError("config", "is not valid JSON")}
+	}
+
+	var errs domain.ValidationErrors
+	validateValue(parsedSchema, value, "", &errs)
+	return errs
+}
+
+// validateValue checks a single value and recurses into objects and arrays
+func validateValue(schema map[string]interface{}, value interface{}, path string, errs *domain.ValidationErrors) {
+	field := path
+	if field == "" {
+		field = "config"
+	}
+
+	if expected, ok := schema["type"].(string); ok && !matchesType(expected, value) {
+		*errs = append(*errs, domain.NewFieldError(field, "must be of type %s", expected))
+		return
+	}
+
+	if options, ok := schema["enum"].([]interface{}); ok && !inEnum(options, value) {
+		*errs = append(*errs, domain.NewFieldError(field, "must be one of %s", formatEnum(options)))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		validateObject(schema, v, path, errs)
+	case []interface{}:
+		if minItems, ok := schemaNumber(schema, "minItems"); ok && float64(len(v)) < minItems {
+			*errs = append(*errs, domain.NewFieldError(field, "must have at least %d item(s)", int(minItems)))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", field, i), errs)
+			}
+		}
+	case json.Number:
+		validateNumber(schema, v, field, errs)
+	case string:
+		if minLength, ok := schemaNumber(schema, "minLength"); ok && float64(len([]rune(v))) < minLength {
+			*errs = append(*errs, domain.NewFieldError(field, "must have at least %d character(s)", int(minLength)))
+		}
+	}
+}
+
+// validateObject checks required and declared properties in a stable order
+func validateObject(schema map[string]interface{}, object map[string]interface{}, path string, errs *domain.ValidationErrors) {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			key, _ := name.(string)
+			if _, present := object[key]; !present {
+				*errs = append(*errs, domain.NewFieldError(joinPath(path, key), "is required"))
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propertyValue, present := object[name]
+		if !present {
+			continue
+		}
+		if propertySchema, ok := properties[name].(map[string]interface{}); ok {
+			validateValue(propertySchema, propertyValue, joinPath(path, name), errs)
+		}
+	}
+}
+
+// validateNumber applies the numeric bounds of the schema
+func validateNumber(schema map[string]interface{}, number json.Number, field string, errs *domain.ValidationErrors) {
+	n, err := number.Float64()
+	if err != nil {
+		*errs = append(*errs, domain.NewFieldError(field, "must be a number"))
+		return
+	}
+
+	if minimum, ok := schemaNumber(schema, "minimum"); ok && n < minimum {
+		*errs = append(*errs, domain.NewFieldError(field, "must be at least %v", minimum))
+	}
+	if maximum, ok := schemaNumber(schema, "maximum"); ok && n > maximum {
+		*errs = append(*errs, domain.NewFieldError(field, "must be at most %v", maximum))
+	}
+	if minimum, ok := schemaNumber(schema, "exclusiveMinimum"); ok && n <= minimum {
+		*errs = append(*errs, domain.NewFieldError(field, "must be greater than %v", minimum))
+	}
+	if maximum, ok := schemaNumber(schema, "exclusiveMaximum"); ok && n >= maximum {
+		*errs = append(*errs, domain.NewFieldError(field, "must be less than %v", maximum))
+	}
+}
+
+func matchesType(expected string, value interface{}) bool {
+	switch expected {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		n, err := number.Float64()
+		return err == nil && n == math.Trunc(n)
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func inEnum(options []interface{}, value interface{}) bool {
+	for _, option := range options {
+		if fmt.Sprint(option) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(options []interface{}) string {
+	out, _ := json.Marshal(options)
+	return string(out)
+}
+
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	n, ok := schema[key].(float64)
+	return n, ok
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,11 @@
+package strategies
+
+import "encoding/json"
+
+// unmarshalState decodes a stored state, leaving v at its zero value when there is none
+func unmarshalState(state json.RawMessage, v interface{}) error {
+	if len(state) == 0 || string(state) == "null" {
+		return nil
+	}
+	return json.Unmarshal(state, v)
+}
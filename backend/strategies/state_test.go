@@ -0,0 +1,49 @@
+package strategies
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+)
+
+// stateJSON encodes a state for the merge tables
+func stateJSON(state interface{}) json.RawMessage {
+	data, err := json.Marshal(state)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func TestUnmarshalState(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   json.RawMessage
+		want    domain.PunchCardState
+		wantErr bool
+	}{
+		{name: "no state", state: nil},
+		{name: "null state", state: json.RawMessage("null")},
+		{name: "empty object", state: json.RawMessage("{}")},
+		{
+			name:  "stored state",
+			state: json.RawMessage(`{"current_punches":3,"total_redeemed":1}`),
+			want:  domain.PunchCardState{CurrentPunches: 3, TotalRedeemed: 1},
+		},
+		{name: "malformed state", state: json.RawMessage(`{"current_punches":`), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got domain.PunchCardState
+			err := unmarshalState(tt.state, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unmarshalState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("unmarshalState() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
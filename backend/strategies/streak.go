@@ -0,0 +1,410 @@
+package strategies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+)
+
+var streakSchema = withCommonProperties(`{
+	"type": "object",
+	"description": "Recompensa clientes que voltam com frequência",
+	"properties": {
+		"mode": {"type": "string", "enum": ["frequency", "consecutive"], "title": "Modo"},
+		"milestone": {"type": "integer", "minimum": 2, "title": "Visitas ou períodos para a recompensa"},
+		"window_hours": {"type": "integer", "minimum": 1, "title": "Janela (horas)"},
+		"min_gap_minutes": {"type": "integer", "minimum": 0, "title": "Intervalo mínimo entre visitas (minutos)"},
+		"reward_amount": {"type": "number", "exclusiveMinimum": 0, "title": "Valor da recompensa"},
+		"reward_type": {"type": "string", "enum": ["points", "discount", "free_item"], "title": "Tipo de recompensa"},
+		"min_purchase": {"type": "number", "minimum": 0, "title": "Compra mínima"}
+	},
+	"required": ["mode", "milestone", "window_hours", "reward_amount", "reward_type"]
+}`)
+
+const streakDefaultConfig = `{
+	"mode": "frequency",
+	"milestone": 5,
+	"window_hours": 168,
+	"min_gap_minutes": 120,
+	"reward_amount": 10,
+	"reward_type": "points"
+}`
+
+// StreakStrategy implements visit streak and frequency rewards
+type StreakStrategy struct{}
+
+// NewStreakStrategy creates a new streak strategy instance
+func NewStreakStrategy() *StreakStrategy {
+	return &StreakStrategy{}
+}
+
+// GetType returns the campaign type
+func (s *StreakStrategy) GetType() domain.CampaignType {
+	return domain.CampaignTypeStreak
+}
+
+// DisplayName returns the name shown to merchants
+func (s *StreakStrategy) DisplayName() string {
+	return "Sequência de Visitas"
+}
+
+// ConfigSchema returns the JSON Schema of the campaign configuration
+func (s *StreakStrategy) ConfigSchema() json.RawMessage {
+	return streakSchema
+}
+
+// DefaultConfig returns a valid starting configuration
+func (s *StreakStrategy) DefaultConfig() json.RawMessage {
+	return json.RawMessage(streakDefaultConfig)
+}
+
+// Validate ensures the streak configuration is valid
+func (s *StreakStrategy) Validate(config json.RawMessage) error {
+	var cfg domain.StreakConfig
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return fmt.Errorf("invalid streak config: %w", err)
+	}
+
+	if cfg.Mode != domain.StreakModeFrequency && cfg.Mode != domain.StreakModeConsecutive {
+		return domain.NewFieldError("mode", "is invalid: %s", cfg.Mode)
+	}
+
+	if cfg.Milestone <= 1 {
+		return domain.NewFieldError("milestone", "must be greater than 1")
+	}
+
+	if cfg.WindowHours <= 0 {
+		return domain.NewFieldError("window_hours", "must be greater than 0")
+	}
+
+	if cfg.MinGapMinutes < 0 {
+		return domain.NewFieldError("min_gap_minutes", "cannot be negative")
+	}
+
+	// A gap as long as the window would make the milestone unreachable
+	if time.Duration(cfg.MinGapMinutes)*time.Minute >= time.Duration(cfg.WindowHours)*time.Hour {
+		return domain.NewFieldError("min_gap_minutes", "must be shorter than window_hours")
+	}
+
+	if cfg.RewardAmount <= 0 {
+		return domain.NewFieldError("reward_amount", "must be greater than 0")
+	}
+
+	validRewardTypes := map[string]bool{
+		"points":    true,
+		"discount":  true,
+		"free_item": true,
+	}
+
+	if !validRewardTypes[cfg.RewardType] {
+		return domain.NewFieldError("reward_type", "is invalid: %s", cfg.RewardType)
+	}
+
+	return nil
+}
+
+// Execute registers a visit and rewards the customer when a streak milestone is reached
+func (s *StreakStrategy) Execute(ctx context.Context, input *domain.StrategyInput) (*domain.StrategyResult, error) {
+	// Parse configuration
+	var config domain.StreakConfig
+	if err := json.Unmarshal(input.Campaign.Config, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	unchanged := &domain.StrategyResult{
+		NewBalance:   0,
+		NewState:     input.CurrentState,
+		StateChanged: false,
+	}
+
+	// Check minimum purchase requirement
+	if config.MinPurchase > 0 && input.Amount < config.MinPurchase {
+		return unchanged, nil
+	}
+
+	// Parse current state
+	var state domain.StreakState
+	if len(input.CurrentState) > 0 {
+		if err := json.Unmarshal(input.CurrentState, &state); err != nil {
+			state = domain.StreakState{}
+		}
+	}
+
+	visitAt := input.OccurredAt
+	if visitAt.IsZero() {
+		visitAt = time.Now()
+	}
+
+	// Ignore visits too close to the previous one (several punches in one sitting)
+	if state.LastVisitAt != nil {
+		gap := visitAt.Sub(*state.LastVisitAt)
+		if gap < 0 || gap < time.Duration(config.MinGapMinutes)*time.Minute {
+			return unchanged, nil
+		}
+	}
+
+	window := time.Duration(config.WindowHours) * time.Hour
+
+	var milestoneReached bool
+	switch config.Mode {
+	case domain.StreakModeConsecutive:
+		milestoneReached = s.registerConsecutiveVisit(&state, config, window, visitAt)
+	default:
+		milestoneReached = s.registerFrequencyVisit(&state, config, window, visitAt)
+	}
+
+	state.LastVisitAt = &visitAt
+
+	var reward *domain.RewardInfo
+	newBalance := 0.0
+
+	if milestoneReached {
+		newBalance = config.RewardAmount
+		state.TotalRewards++
+
+		reward = &domain.RewardInfo{
+			Type:        config.RewardType,
+			Amount:      config.RewardAmount,
+			Description: s.rewardDescription(config),
+		}
+
+		// Frequency streaks start over once the reward is granted
+		if config.Mode == domain.StreakModeFrequency {
+			state.VisitTimestamps = []time.Time{}
+			state.CurrentStreak = 0
+			state.StreakStartedAt = nil
+		}
+	}
+
+	// Serialize new state
+	newState, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	return &domain.StrategyResult{
+		NewBalance:   newBalance,
+		NewState:     newState,
+		RewardEarned: reward,
+		StateChanged: true,
+	}, nil
+}
+
+// registerFrequencyVisit counts the visit inside the rolling window
+func (s *StreakStrategy) registerFrequencyVisit(state *domain.StreakState, config domain.StreakConfig, window time.Duration, visitAt time.Time) bool {
+	// Drop visits that fell out of the window
+	cutoff := visitAt.Add(-window)
+	visits := make([]time.Time, 0, len(state.VisitTimestamps)+1)
+	for _, ts := range state.VisitTimestamps {
+		if ts.After(cutoff) {
+			visits = append(visits, ts)
+		}
+	}
+	visits = append(visits, visitAt)
+
+	state.VisitTimestamps = visits
+	state.CurrentStreak = len(visits)
+	state.StreakStartedAt = &visits[0]
+
+	if state.CurrentStreak > state.BestStreak {
+		state.BestStreak = state.CurrentStreak
+	}
+
+	return state.CurrentStreak >= config.Milestone
+}
+
+// registerConsecutiveVisit advances the streak when the visit lands in the period after the last one
+func (s *StreakStrategy) registerConsecutiveVisit(state *domain.StreakState, config domain.StreakConfig, window time.Duration, visitAt time.Time) bool {
+	advanced := false
+
+	if state.StreakStartedAt == nil || state.LastVisitAt == nil {
+		s.restartStreak(state, visitAt)
+		advanced = true
+	} else {
+		lastPeriod := int(state.LastVisitAt.Sub(*state.StreakStartedAt) / window)
+		currentPeriod := int(visitAt.Sub(*state.StreakStartedAt) / window)
+
+		switch currentPeriod {
+		case lastPeriod:
+			// Already visited in this period, the streak does not move
+		case lastPeriod + 1:
+			state.CurrentStreak++
+			advanced = true
+		default:
+			// Missed at least one period, the streak is broken
+			s.restartStreak(state, visitAt)
+			advanced = true
+		}
+	}
+
+	state.VisitTimestamps = append(state.VisitTimestamps, visitAt)
+	if len(state.VisitTimestamps) > config.Milestone {
+		state.VisitTimestamps = state.VisitTimestamps[len(state.VisitTimestamps)-config.Milestone:]
+	}
+
+	if state.CurrentStreak > state.BestStreak {
+		state.BestStreak = state.CurrentStreak
+	}
+
+	// Reward every time the streak hits a multiple of the milestone
+	return advanced && state.CurrentStreak%config.Milestone == 0
+}
+
+// restartStreak begins a new streak anchored at the given visit
+func (s *StreakStrategy) restartStreak(state *domain.StreakState, visitAt time.Time) {
+	state.StreakStartedAt = &visitAt
+	state.CurrentStreak = 1
+	state.VisitTimestamps = []time.Time{}
+}
+
+// rewardDescription builds the customer-facing reward message
+func (s *StreakStrategy) rewardDescription(config domain.StreakConfig) string {
+	if config.Mode == domain.StreakModeConsecutive {
+		return fmt.Sprintf("Visitou por %d períodos seguidos! Parabéns!", config.Milestone)
+	}
+	return fmt.Sprintf("Completou %d visitas em %s! Parabéns!", config.Milestone, formatWindow(config.WindowHours))
+}
+
+// formatWindow renders a window length in days when it is a whole number of days
+func formatWindow(hours int) string {
+	if hours%24 == 0 {
+		days := hours / 24
+		if days == 1 {
+			return "1 dia"
+		}
+		return fmt.Sprintf("%d dias", days)
+	}
+	return fmt.Sprintf("%d horas", hours)
+}
+
+// MigrateState keeps the visit history while the streak rules stay comparable. Changing the
+// mode or the window makes the running streak meaningless, so it restarts from zero.
+func (s *StreakStrategy) MigrateState(oldConfig, newConfig, state json.RawMessage) (json.RawMessage, error) {
+	var oldCfg, newCfg domain.StreakConfig
+	if err := json.Unmarshal(oldConfig, &oldCfg); err != nil {
+		return nil, fmt.Errorf("failed to parse old config: %w", err)
+	}
+	if err := json.Unmarshal(newConfig, &newCfg); err != nil {
+		return nil, fmt.Errorf("failed to parse new config: %w", err)
+	}
+
+	if oldCfg.Mode == newCfg.Mode && oldCfg.WindowHours == newCfg.WindowHours {
+		return state, nil
+	}
+
+	var current domain.StreakState
+	if err := json.Unmarshal(state, &current); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+
+	current.VisitTimestamps = nil
+	current.CurrentStreak = 0
+	current.StreakStartedAt = nil
+
+	return json.Marshal(current)
+}
+
+// MergeState combines the visit history of both sides. Frequency streaks count the visits of
+// either side still inside the window and pay the reward when together they reach the
+// milestone; consecutive streaks cannot be joined, so the one visited most recently is kept.
+func (s *StreakStrategy) MergeState(walletState, shadowState, config json.RawMessage) (*domain.StrategyResult, error) {
+	var cfg domain.StreakConfig
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	var wallet, shadow domain.StreakState
+	if err := unmarshalState(walletState, &wallet); err != nil {
+		return nil, fmt.Errorf("failed to parse wallet state: %w", err)
+	}
+	if err := unmarshalState(shadowState, &shadow); err != nil {
+		return nil, fmt.Errorf("failed to parse shadow state: %w", err)
+	}
+
+	merged := wallet
+	if shadow.LastVisitAt != nil && (wallet.LastVisitAt == nil || shadow.LastVisitAt.After(*wallet.LastVisitAt)) {
+		merged = shadow
+	}
+	merged.TotalRewards = wallet.TotalRewards + shadow.TotalRewards
+	merged.BestStreak = wallet.BestStreak
+	if shadow.BestStreak > merged.BestStreak {
+		merged.BestStreak = shadow.BestStreak
+	}
+
+	var reward *domain.RewardInfo
+	newBalance := 0.0
+
+	if cfg.Mode != domain.StreakModeConsecutive && merged.LastVisitAt != nil {
+		visits := s.mergeVisits(cfg, *merged.LastVisitAt, wallet.VisitTimestamps, shadow.VisitTimestamps)
+
+		merged.VisitTimestamps = visits
+		merged.CurrentStreak = len(visits)
+		merged.StreakStartedAt = nil
+		if len(visits) > 0 {
+			merged.StreakStartedAt = &visits[0]
+		}
+		if merged.CurrentStreak > merged.BestStreak {
+			merged.BestStreak = merged.CurrentStreak
+		}
+
+		if cfg.Milestone > 0 && merged.CurrentStreak >= cfg.Milestone {
+			newBalance = cfg.RewardAmount
+			merged.TotalRewards++
+
+			reward = &domain.RewardInfo{
+				Type:        cfg.RewardType,
+				Amount:      cfg.RewardAmount,
+				Description: s.rewardDescription(cfg),
+			}
+
+			merged.VisitTimestamps = []time.Time{}
+			merged.CurrentStreak = 0
+			merged.StreakStartedAt = nil
+		}
+	}
+
+	newState, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	return &domain.StrategyResult{
+		NewBalance:   newBalance,
+		NewState:     newState,
+		RewardEarned: reward,
+		StateChanged: true,
+	}, nil
+}
+
+// mergeVisits joins two visit histories in order, keeping the visits inside the window that
+// ends at the latest one and dropping those closer than the minimum gap to the previous visit
+func (s *StreakStrategy) mergeVisits(cfg domain.StreakConfig, latest time.Time, histories ...[]time.Time) []time.Time {
+	var all []time.Time
+	for _, history := range histories {
+		all = append(all, history...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Before(all[j]) })
+
+	cutoff := latest.Add(-time.Duration(cfg.WindowHours) * time.Hour)
+	minGap := time.Duration(cfg.MinGapMinutes) * time.Minute
+
+	visits := make([]time.Time, 0, len(all))
+	for _, ts := range all {
+		if !ts.After(cutoff) {
+			continue
+		}
+		if n := len(visits); n > 0 && ts.Sub(visits[n-1]) < minGap {
+			continue
+		}
+		// The same visit may appear on both sides when minGap is zero
+		if n := len(visits); n > 0 && ts.Equal(visits[n-1]) {
+			continue
+		}
+		visits = append(visits, ts)
+	}
+	return visits
+}
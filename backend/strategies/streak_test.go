@@ -0,0 +1,204 @@
+package strategies
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+)
+
+func TestStreakMergeState(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration) *time.Time {
+		ts := now.Add(offset)
+		return &ts
+	}
+	day := 24 * time.Hour
+
+	frequency := stateJSON(domain.StreakConfig{
+		Mode:          domain.StreakModeFrequency,
+		Milestone:     5,
+		WindowHours:   7 * 24,
+		MinGapMinutes: 60,
+		RewardAmount:  20,
+		RewardType:    "points",
+	})
+	noGap := stateJSON(domain.StreakConfig{
+		Mode:         domain.StreakModeFrequency,
+		Milestone:    5,
+		WindowHours:  7 * 24,
+		RewardAmount: 20,
+		RewardType:   "points",
+	})
+	consecutive := stateJSON(domain.StreakConfig{
+		Mode:         domain.StreakModeConsecutive,
+		Milestone:    4,
+		WindowHours:  7 * 24,
+		RewardAmount: 50,
+		RewardType:   "discount",
+	})
+
+	tests := []struct {
+		name        string
+		config      json.RawMessage
+		wallet      json.RawMessage
+		shadow      json.RawMessage
+		want        domain.StreakState
+		wantBalance float64
+	}{
+		{
+			name:   "no state on either side",
+			config: frequency,
+			wallet: nil,
+			shadow: json.RawMessage("null"),
+		},
+		{
+			name:   "visits outside the window are dropped",
+			config: frequency,
+			wallet: stateJSON(domain.StreakState{
+				VisitTimestamps: []time.Time{*at(-10 * day), *at(-2 * day)},
+				CurrentStreak:   2,
+				BestStreak:      2,
+				LastVisitAt:     at(-2 * day),
+			}),
+			shadow: stateJSON(domain.StreakState{
+				VisitTimestamps: []time.Time{*at(-day), *at(0)},
+				CurrentStreak:   2,
+				BestStreak:      2,
+				LastVisitAt:     at(0),
+			}),
+			want: domain.StreakState{
+				VisitTimestamps: []time.Time{*at(-2 * day), *at(-day), *at(0)},
+				CurrentStreak:   3,
+				BestStreak:      3,
+				StreakStartedAt: at(-2 * day),
+				LastVisitAt:     at(0),
+			},
+		},
+		{
+			name:   "visits closer than the minimum gap count once",
+			config: frequency,
+			wallet: stateJSON(domain.StreakState{
+				VisitTimestamps: []time.Time{*at(-3 * time.Hour), *at(-time.Hour)},
+				CurrentStreak:   2,
+				BestStreak:      2,
+				LastVisitAt:     at(-time.Hour),
+			}),
+			shadow: stateJSON(domain.StreakState{
+				VisitTimestamps: []time.Time{*at(-150 * time.Minute), *at(-30 * time.Minute)},
+				CurrentStreak:   2,
+				BestStreak:      2,
+				LastVisitAt:     at(-30 * time.Minute),
+			}),
+			want: domain.StreakState{
+				VisitTimestamps: []time.Time{*at(-3 * time.Hour), *at(-time.Hour)},
+				CurrentStreak:   2,
+				BestStreak:      2,
+				StreakStartedAt: at(-3 * time.Hour),
+				LastVisitAt:     at(-30 * time.Minute),
+			},
+		},
+		{
+			name:   "the same visit on both sides counts once",
+			config: noGap,
+			wallet: stateJSON(domain.StreakState{
+				VisitTimestamps: []time.Time{*at(-day), *at(0)},
+				CurrentStreak:   2,
+				BestStreak:      2,
+				LastVisitAt:     at(0),
+			}),
+			shadow: stateJSON(domain.StreakState{
+				VisitTimestamps: []time.Time{*at(0)},
+				CurrentStreak:   1,
+				BestStreak:      1,
+				LastVisitAt:     at(0),
+			}),
+			want: domain.StreakState{
+				VisitTimestamps: []time.Time{*at(-day), *at(0)},
+				CurrentStreak:   2,
+				BestStreak:      2,
+				StreakStartedAt: at(-day),
+				LastVisitAt:     at(0),
+			},
+		},
+		{
+			name:   "reaching the milestone pays the reward",
+			config: frequency,
+			wallet: stateJSON(domain.StreakState{
+				VisitTimestamps: []time.Time{*at(-6 * day), *at(-4 * day), *at(-2 * day)},
+				CurrentStreak:   3,
+				BestStreak:      3,
+				LastVisitAt:     at(-2 * day),
+				TotalRewards:    1,
+			}),
+			shadow: stateJSON(domain.StreakState{
+				VisitTimestamps: []time.Time{*at(-day), *at(0)},
+				CurrentStreak:   2,
+				BestStreak:      2,
+				LastVisitAt:     at(0),
+			}),
+			want: domain.StreakState{
+				VisitTimestamps: []time.Time{},
+				BestStreak:      5,
+				LastVisitAt:     at(0),
+				TotalRewards:    2,
+			},
+			wantBalance: 20,
+		},
+		{
+			name:   "consecutive streaks keep the most recent side",
+			config: consecutive,
+			wallet: stateJSON(domain.StreakState{
+				VisitTimestamps: []time.Time{*at(-14 * day), *at(-7 * day)},
+				CurrentStreak:   2,
+				BestStreak:      3,
+				StreakStartedAt: at(-14 * day),
+				LastVisitAt:     at(-7 * day),
+				TotalRewards:    1,
+			}),
+			shadow: stateJSON(domain.StreakState{
+				VisitTimestamps: []time.Time{*at(0)},
+				CurrentStreak:   1,
+				BestStreak:      2,
+				StreakStartedAt: at(0),
+				LastVisitAt:     at(0),
+				TotalRewards:    1,
+			}),
+			want: domain.StreakState{
+				VisitTimestamps: []time.Time{*at(0)},
+				CurrentStreak:   1,
+				BestStreak:      3,
+				StreakStartedAt: at(0),
+				LastVisitAt:     at(0),
+				TotalRewards:    2,
+			},
+		},
+	}
+
+	strategy := NewStreakStrategy()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := strategy.MergeState(tt.wallet, tt.shadow, tt.config)
+			if err != nil {
+				t.Fatalf("MergeState() error = %v", err)
+			}
+
+			var got domain.StreakState
+			if err := json.Unmarshal(result.NewState, &got); err != nil {
+				t.Fatalf("invalid merged state %s: %v", result.NewState, err)
+			}
+			// Compare through JSON so times from both sides share a location
+			if want := stateJSON(tt.want); !reflect.DeepEqual(stateJSON(got), want) {
+				t.Errorf("merged state = %s, want %s", stateJSON(got), want)
+			}
+			if result.NewBalance != tt.wantBalance {
+				t.Errorf("NewBalance = %v, want %v", result.NewBalance, tt.wantBalance)
+			}
+			if (result.RewardEarned != nil) != (tt.wantBalance > 0) {
+				t.Errorf("RewardEarned = %+v, want reward %v", result.RewardEarned, tt.wantBalance)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/services"
+)
+
+// CampaignLifecycleWorker starts and ends campaigns at their scheduled times
+type CampaignLifecycleWorker struct {
+	lifecycle *services.CampaignLifecycle
+	interval  time.Duration
+	logger    Logger
+}
+
+// NewCampaignLifecycleWorker creates a new campaign lifecycle worker
+func NewCampaignLifecycleWorker(lifecycle *services.CampaignLifecycle, interval time.Duration, logger Logger) *CampaignLifecycleWorker {
+	return &CampaignLifecycleWorker{
+		lifecycle: lifecycle,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Start begins the campaign lifecycle worker loop
+func (w *CampaignLifecycleWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.logger.Info("Campaign lifecycle worker started", "interval", w.interval)
+
+	// Run immediately on start
+	w.run(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Campaign lifecycle worker stopped")
+			return
+		case <-ticker.C:
+			w.run(ctx)
+		}
+	}
+}
+
+// run applies the transitions that are due. Start and end times are stored as instants,
+// already resolved in the merchant's timezone when the campaign was saved.
+func (w *CampaignLifecycleWorker) run(ctx context.Context) {
+	started, ended, err := w.lifecycle.RunSchedule(ctx, time.Now())
+	if err != nil {
+		w.logger.Error("Failed to run campaign schedule", err)
+	}
+	if started > 0 || ended > 0 {
+		w.logger.Info("Campaign schedule applied", "started", started, "ended", ended)
+	}
+}
@@ -0,0 +1,168 @@
+package workers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/services"
+
+	"github.com/google/uuid"
+)
+
+const (
+	conversionBatchSize   = 50
+	conversionLease       = 5 * time.Minute // Time a claimed entry is hidden from other workers
+	conversionMaxAttempts = 8
+	conversionRetryBase   = time.Minute
+	conversionRetryMax    = 6 * time.Hour
+)
+
+// ConversionQueueWorker converts the shadow balances of sign-ups queued in
+// shadow_conversion_queue, covering the cases where the webhook never arrived
+type ConversionQueueWorker struct {
+	repo        *repository.Repository
+	conversions *services.ConversionService
+	interval    time.Duration
+	logger      Logger
+}
+
+// NewConversionQueueWorker creates a new conversion queue worker
+func NewConversionQueueWorker(repo *repository.Repository, conversions *services.ConversionService, interval time.Duration, logger Logger) *ConversionQueueWorker {
+	return &ConversionQueueWorker{
+		repo:        repo,
+		conversions: conversions,
+		interval:    interval,
+		logger:      logger,
+	}
+}
+
+// Start begins the conversion queue worker loop
+func (w *ConversionQueueWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.logger.Info("Conversion queue worker started", "interval", w.interval)
+
+	// Run immediately on start
+	w.drainQueue(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Conversion queue worker stopped")
+			return
+		case <-ticker.C:
+			w.drainQueue(ctx)
+		}
+	}
+}
+
+// drainQueue processes due entries batch by batch until the queue has none left
+func (w *ConversionQueueWorker) drainQueue(ctx context.Context) {
+	for ctx.Err() == nil {
+		items, err := w.repo.ClaimConversionQueueBatch(ctx, conversionBatchSize, time.Now().Add(conversionLease))
+		if err != nil {
+			w.logger.Error("Failed to claim conversion queue entries", err)
+			return
+		}
+
+		converted := 0
+		for _, item := range items {
+			if w.convert(ctx, item) {
+				converted++
+			}
+		}
+		if len(items) > 0 {
+			w.logger.Info("Conversion queue batch processed", "entries", len(items), "converted", converted)
+		}
+
+		if len(items) < conversionBatchSize {
+			return
+		}
+	}
+}
+
+// HandleNotification converts the queue entry named in a shadow_conversion_queued
+// notification right away. Every instance is notified; only one gets to claim the entry.
+func (w *ConversionQueueWorker) HandleNotification(ctx context.Context, payload string) error {
+	itemID, err := uuid.Parse(payload)
+	if err != nil {
+		return fmt.Errorf("invalid queue entry ID: %w", err)
+	}
+
+	item, err := w.repo.ClaimConversionQueueItem(ctx, itemID, time.Now().Add(conversionLease))
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim queue entry: %w", err)
+	}
+
+	w.convert(ctx, item)
+	return nil
+}
+
+// CatchUp drains the entries queued while the listener was disconnected
+func (w *ConversionQueueWorker) CatchUp(ctx context.Context) error {
+	w.drainQueue(ctx)
+	return nil
+}
+
+// convert runs the conversion of one entry and records the outcome
+func (w *ConversionQueueWorker) convert(ctx context.Context, item *domain.ConversionQueueItem) bool {
+	phone := domain.NormalizePhone(item.Phone)
+	if !domain.ValidPhone(phone) {
+		// Retrying cannot fix the phone
+		w.fail(ctx, item, fmt.Errorf("invalid phone number: %q", item.Phone))
+		return false
+	}
+
+	if err := w.conversions.ConvertShadowToRealWallet(ctx, item.UserID, domain.HashPhone(phone)); err != nil {
+		if item.Attempts >= conversionMaxAttempts {
+			w.fail(ctx, item, err)
+			return false
+		}
+
+		nextAttemptAt := time.Now().Add(conversionRetryDelay(item.Attempts))
+		w.logger.Warn("Conversion failed, will retry",
+			"queue_id", item.ID,
+			"attempt", item.Attempts,
+			"next_attempt_at", nextAttemptAt,
+			"error", err.Error(),
+		)
+		if err := w.repo.RetryConversionQueueItem(ctx, item.ID, err.Error(), nextAttemptAt); err != nil {
+			w.logger.Error("Failed to schedule conversion retry", err, "queue_id", item.ID)
+		}
+		return false
+	}
+
+	if err := w.repo.CompleteConversionQueueItem(ctx, item.ID); err != nil {
+		// The entry comes back after the lease and converts to nothing, conversions are idempotent
+		w.logger.Error("Failed to mark conversion as processed", err, "queue_id", item.ID)
+	}
+	return true
+}
+
+// fail gives up on the entry and keeps the reason for inspection
+func (w *ConversionQueueWorker) fail(ctx context.Context, item *domain.ConversionQueueItem, cause error) {
+	w.logger.Error("Conversion failed for good", cause, "queue_id", item.ID, "attempts", item.Attempts)
+	if err := w.repo.FailConversionQueueItem(ctx, item.ID, cause.Error()); err != nil {
+		w.logger.Error("Failed to mark conversion as failed", err, "queue_id", item.ID)
+	}
+}
+
+// conversionRetryDelay doubles the wait after each failed attempt: 1m, 2m, 4m... up to 6h
+func conversionRetryDelay(attempts int) time.Duration {
+	delay := conversionRetryBase
+	for i := 1; i < attempts && delay < conversionRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > conversionRetryMax {
+		delay = conversionRetryMax
+	}
+	return delay
+}
@@ -0,0 +1,138 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	listenerMinReconnect = time.Second
+	listenerMaxReconnect = time.Minute
+	listenerPingInterval = 90 * time.Second // Detects connections that died without an error
+)
+
+// NotificationHandler reacts to the notifications of one Postgres channel
+type NotificationHandler interface {
+	// HandleNotification processes a single notification
+	HandleNotification(ctx context.Context, payload string) error
+	// CatchUp makes up for notifications that may have been missed while the listener was
+	// not connected. It runs once connected and after every reconnection.
+	CatchUp(ctx context.Context) error
+}
+
+// NotificationListener subscribes to Postgres LISTEN/NOTIFY channels and dispatches each
+// notification to the handler of its channel. It needs a direct database connection:
+// poolers in transaction mode do not deliver notifications.
+type NotificationListener struct {
+	dsn      string
+	handlers map[string]NotificationHandler
+	logger   Logger
+}
+
+// NewNotificationListener creates a listener for the database at dsn
+func NewNotificationListener(dsn string, logger Logger) *NotificationListener {
+	return &NotificationListener{
+		dsn:      dsn,
+		handlers: make(map[string]NotificationHandler),
+		logger:   logger,
+	}
+}
+
+// Subscribe registers the handler of a channel. Must be called before Start.
+func (l *NotificationListener) Subscribe(channel string, handler NotificationHandler) {
+	l.handlers[channel] = handler
+}
+
+// Start listens until the context is cancelled, reconnecting whenever the connection drops
+func (l *NotificationListener) Start(ctx context.Context) {
+	listener := pq.NewListener(l.dsn, listenerMinReconnect, listenerMaxReconnect, l.logEvent)
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for channel := range l.handlers {
+		// Blocks until connected; channels are listened on again after each reconnection
+		if err := listener.Listen(channel); err != nil {
+			if ctx.Err() == nil {
+				l.logger.Error("Failed to listen on channel", err, "channel", channel)
+			}
+		}
+	}
+
+	l.logger.Info("Notification listener started", "channels", len(l.handlers))
+
+	// Entries created before the listener was up
+	l.catchUp(ctx)
+
+	ping := time.NewTicker(listenerPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			l.logger.Info("Notification listener stopped")
+			return
+		case notification, ok := <-listener.Notify:
+			if !ok {
+				return
+			}
+			// A nil notification follows a reconnection, anything sent meanwhile is lost
+			if notification == nil {
+				l.catchUp(ctx)
+				continue
+			}
+			l.dispatch(ctx, notification)
+		case <-ping.C:
+			go func() {
+				if err := listener.Ping(); err != nil && ctx.Err() == nil {
+					l.logger.Warn("Notification listener ping failed", "error", err.Error())
+				}
+			}()
+		}
+	}
+}
+
+// dispatch hands a notification to the handler of its channel
+func (l *NotificationListener) dispatch(ctx context.Context, notification *pq.Notification) {
+	handler, ok := l.handlers[notification.Channel]
+	if !ok {
+		return
+	}
+	if err := handler.HandleNotification(ctx, notification.Extra); err != nil {
+		l.logger.Error("Failed to handle notification", err,
+			"channel", notification.Channel,
+			"payload", notification.Extra,
+		)
+	}
+}
+
+// catchUp runs the catch-up of every handler
+func (l *NotificationListener) catchUp(ctx context.Context) {
+	for channel, handler := range l.handlers {
+		if err := handler.CatchUp(ctx); err != nil {
+			l.logger.Error("Failed to catch up on channel", err, "channel", channel)
+		}
+	}
+}
+
+// logEvent reports connection changes
+func (l *NotificationListener) logEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		l.logger.Warn("Notification listener disconnected", "error", errString(err))
+	case pq.ListenerEventConnectionAttemptFailed:
+		l.logger.Warn("Notification listener failed to connect", "error", errString(err))
+	case pq.ListenerEventReconnected:
+		l.logger.Info("Notification listener reconnected")
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
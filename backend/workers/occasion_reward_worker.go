@@ -0,0 +1,133 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+
+	"github.com/google/uuid"
+)
+
+// OccasionRewardWorker grants birthday and anniversary rewards on the customer's local date
+type OccasionRewardWorker struct {
+	repo     *repository.Repository
+	interval time.Duration
+	logger   Logger
+}
+
+// NewOccasionRewardWorker creates a new occasion reward worker
+func NewOccasionRewardWorker(repo *repository.Repository, interval time.Duration, logger Logger) *OccasionRewardWorker {
+	return &OccasionRewardWorker{
+		repo:     repo,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start begins the occasion reward worker loop
+func (w *OccasionRewardWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.logger.Info("Occasion reward worker started", "interval", w.interval)
+
+	// Run immediately on start
+	w.run(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Occasion reward worker stopped")
+			return
+		case <-ticker.C:
+			w.run(ctx)
+		}
+	}
+}
+
+// run grants today's rewards and expires vouchers past their validity.
+// Grants are idempotent per year, so running several times a day is safe.
+func (w *OccasionRewardWorker) run(ctx context.Context) {
+	w.grantOccasionRewards(ctx)
+	w.expireVouchers(ctx)
+}
+
+// grantOccasionRewards evaluates every active occasion reward against the merchant's local date
+func (w *OccasionRewardWorker) grantOccasionRewards(ctx context.Context) {
+	rewards, err := w.repo.GetActiveOccasionRewards(ctx)
+	if err != nil {
+		w.logger.Error("Failed to get occasion rewards", err)
+		return
+	}
+
+	merchants := make(map[uuid.UUID]*domain.Merchant)
+	grantedCount := 0
+
+	for _, reward := range rewards {
+		merchant, ok := merchants[reward.MerchantID]
+		if !ok {
+			merchant, err = w.repo.GetMerchantByID(ctx, reward.MerchantID)
+			if err != nil {
+				w.logger.Error("Failed to load merchant", err, "merchant_id", reward.MerchantID)
+				continue
+			}
+			merchants[reward.MerchantID] = merchant
+		}
+
+		localNow := time.Now().In(merchant.Location())
+
+		wallets, err := w.repo.GetWalletsCelebrating(ctx, reward.MerchantID, reward.Occasion, localNow)
+		if err != nil {
+			w.logger.Error("Failed to find celebrating wallets", err, "merchant_id", reward.MerchantID, "occasion", reward.Occasion)
+			continue
+		}
+
+		expiresAt := voucherExpiry(reward, localNow)
+
+		for _, wallet := range wallets {
+			granted, err := w.repo.GrantOccasionReward(ctx, reward, wallet.ID, localNow.Year(), expiresAt)
+			if err != nil {
+				w.logger.Error("Failed to grant occasion reward", err, "wallet_id", wallet.ID, "occasion", reward.Occasion)
+				continue
+			}
+			if granted {
+				grantedCount++
+			}
+		}
+	}
+
+	if grantedCount > 0 {
+		w.logger.Info("Occasion rewards granted", "count", grantedCount)
+	}
+}
+
+// expireVouchers removes occasion rewards that were not used within their validity
+func (w *OccasionRewardWorker) expireVouchers(ctx context.Context) {
+	grants, err := w.repo.GetExpiredOccasionGrants(ctx)
+	if err != nil {
+		w.logger.Error("Failed to get expired occasion vouchers", err)
+		return
+	}
+
+	for _, grant := range grants {
+		amount, err := w.repo.ExpireOccasionGrant(ctx, grant.ID)
+		if err != nil {
+			w.logger.Error("Failed to expire occasion voucher", err, "grant_id", grant.ID)
+			continue
+		}
+		w.logger.Info("Occasion voucher expired", "grant_id", grant.ID, "wallet_id", grant.WalletID, "amount", amount)
+	}
+}
+
+// voucherExpiry returns the end of the last valid local day, or nil for rewards that never expire
+func voucherExpiry(reward *domain.OccasionReward, localNow time.Time) *time.Time {
+	if reward.ValidDays == nil || *reward.ValidDays <= 0 {
+		return nil
+	}
+
+	year, month, day := localNow.Date()
+	expiresAt := time.Date(year, month, day+*reward.ValidDays, 0, 0, 0, 0, localNow.Location())
+	return &expiresAt
+}
@@ -0,0 +1,138 @@
+package workers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Ananiaslitz/fidelio/domain"
+	"github.com/Ananiaslitz/fidelio/repository"
+	"github.com/Ananiaslitz/fidelio/services"
+)
+
+const (
+	simulationHeartbeat   = 30 * time.Second
+	simulationStaleAfter  = 2 * time.Minute // Running jobs without a heartbeat for this long are taken over
+	simulationMaxAttempts = 3
+)
+
+// SimulationWorker runs queued campaign backtests
+type SimulationWorker struct {
+	repo      *repository.Repository
+	simulator *services.SimulationService
+	interval  time.Duration
+	logger    Logger
+}
+
+// NewSimulationWorker creates a new simulation worker
+func NewSimulationWorker(repo *repository.Repository, simulator *services.SimulationService, interval time.Duration, logger Logger) *SimulationWorker {
+	return &SimulationWorker{
+		repo:      repo,
+		simulator: simulator,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Start begins the simulation worker loop
+func (w *SimulationWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.logger.Info("Simulation worker started", "interval", w.interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Simulation worker stopped")
+			return
+		case <-ticker.C:
+			w.drainQueue(ctx)
+		}
+	}
+}
+
+// drainQueue runs pending jobs one at a time until the queue is empty
+func (w *SimulationWorker) drainQueue(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := w.repo.ClaimNextSimulationJob(ctx, time.Now().Add(-simulationStaleAfter))
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			w.logger.Error("Failed to claim simulation job", err)
+			return
+		}
+
+		w.runJob(ctx, job)
+	}
+}
+
+// runJob replays the job and stores its result or failure
+func (w *SimulationWorker) runJob(ctx context.Context, job *domain.SimulationJob) {
+	started := time.Now()
+
+	// A job that keeps killing its worker is not tried forever
+	if job.Attempts > simulationMaxAttempts {
+		w.fail(ctx, job, fmt.Errorf("simulation abandoned after %d attempts", simulationMaxAttempts))
+		return
+	}
+
+	stop := w.keepAlive(ctx, job)
+	defer stop()
+
+	result, err := w.simulator.RunJob(ctx, job)
+	if err != nil {
+		w.fail(ctx, job, err)
+		return
+	}
+
+	payload, err := json.Marshal(result)
+	if err != nil {
+		w.fail(ctx, job, err)
+		return
+	}
+
+	if err := w.repo.CompleteSimulationJob(ctx, job.ID, payload); err != nil {
+		w.logger.Error("Failed to store simulation result", err, "job_id", job.ID)
+		return
+	}
+
+	w.logger.Info("Simulation completed",
+		"job_id", job.ID,
+		"transactions", result.TransactionsReplayed,
+		"duration", time.Since(started),
+	)
+}
+
+// keepAlive refreshes the job's heartbeat until the returned function is called
+func (w *SimulationWorker) keepAlive(ctx context.Context, job *domain.SimulationJob) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(simulationHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := w.repo.TouchSimulationJob(ctx, job.ID); err != nil {
+					w.logger.Error("Failed to refresh simulation heartbeat", err, "job_id", job.ID)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// fail records the error on the job so the merchant can see why it did not complete
+func (w *SimulationWorker) fail(ctx context.Context, job *domain.SimulationJob, cause error) {
+	w.logger.Error("Simulation failed", cause, "job_id", job.ID)
+	if err := w.repo.FailSimulationJob(ctx, job.ID, cause.Error()); err != nil {
+		w.logger.Error("Failed to mark simulation as failed", err, "job_id", job.ID)
+	}
+}
@@ -0,0 +1,10 @@
+-- Fidelio Loyalty Platform - Visit Streak Campaigns
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- CAMPAIGN TYPE: STREAK
+-- =====================================================
+
+-- Streak campaigns reward visit habits (e.g. 5 visits in 7 days).
+-- Visit timestamps and streak lengths live in the wallet/shadow state JSON.
+ALTER TYPE campaign_type ADD VALUE IF NOT EXISTS 'STREAK';
@@ -0,0 +1,66 @@
+-- Fidelio Loyalty Platform - Birthday and Anniversary Rewards
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- CUSTOMER PROFILE ATTRIBUTES
+-- =====================================================
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS birth_date DATE;
+
+CREATE INDEX IF NOT EXISTS idx_wallets_birthday
+    ON wallets(merchant_id, EXTRACT(MONTH FROM birth_date), EXTRACT(DAY FROM birth_date))
+    WHERE birth_date IS NOT NULL;
+
+-- =====================================================
+-- LEDGER TYPE: OCCASION
+-- =====================================================
+ALTER TYPE transaction_type ADD VALUE IF NOT EXISTS 'OCCASION';
+
+-- =====================================================
+-- OCCASION REWARDS TABLE (merchant configuration)
+-- =====================================================
+CREATE TABLE occasion_rewards (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    occasion TEXT NOT NULL CHECK (occasion IN ('BIRTHDAY', 'ANNIVERSARY')),
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    reward_type TEXT NOT NULL DEFAULT 'points',
+    valid_days INT CHECK (valid_days > 0), -- NULL = never expires
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(merchant_id, occasion)
+);
+
+CREATE TRIGGER update_occasion_rewards_updated_at BEFORE UPDATE ON occasion_rewards
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- =====================================================
+-- OCCASION GRANTS TABLE (one grant per wallet, occasion and year)
+-- =====================================================
+CREATE TABLE occasion_grants (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    occasion_reward_id UUID NOT NULL REFERENCES occasion_rewards(id) ON DELETE CASCADE,
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    occasion TEXT NOT NULL,
+    grant_year INT NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    expired_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(wallet_id, occasion, grant_year)
+);
+
+CREATE INDEX idx_occasion_grants_expiring ON occasion_grants(expires_at)
+    WHERE expires_at IS NOT NULL AND expired_at IS NULL;
+
+ALTER TABLE occasion_rewards ENABLE ROW LEVEL SECURITY;
+ALTER TABLE occasion_grants ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service role can manage occasion rewards"
+    ON occasion_rewards FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
+
+CREATE POLICY "Service role can manage occasion grants"
+    ON occasion_grants FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
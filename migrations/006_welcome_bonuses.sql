@@ -0,0 +1,38 @@
+-- Fidelio Loyalty Platform - Welcome Bonus for First-Time Customers
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- LEDGER TYPE: WELCOME
+-- =====================================================
+ALTER TYPE transaction_type ADD VALUE IF NOT EXISTS 'WELCOME';
+
+-- =====================================================
+-- WELCOME BONUSES TABLE
+-- =====================================================
+
+-- One row per customer (phone hash) and merchant, whatever wallet or
+-- shadow balance the bonus ends up in. The unique constraint is what
+-- guarantees the bonus is paid exactly once, including across
+-- shadow-to-real conversion.
+CREATE TABLE welcome_bonuses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    campaign_id UUID REFERENCES campaigns(id) ON DELETE SET NULL,
+    phone_hash TEXT NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('PENDING', 'GRANTED', 'FORFEITED')),
+    wallet_id UUID REFERENCES wallets(id) ON DELETE SET NULL,
+    shadow_balance_id UUID REFERENCES shadow_balances(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    granted_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE(merchant_id, phone_hash)
+);
+
+CREATE INDEX idx_welcome_bonuses_pending ON welcome_bonuses(merchant_id, phone_hash) WHERE status = 'PENDING';
+CREATE INDEX idx_welcome_bonuses_shadow ON welcome_bonuses(shadow_balance_id);
+
+ALTER TABLE welcome_bonuses ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service role can manage welcome bonuses"
+    ON welcome_bonuses FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
@@ -0,0 +1,77 @@
+-- Fidelio Loyalty Platform - Instant Win Campaigns
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- CAMPAIGN TYPE: INSTANT_WIN
+-- =====================================================
+ALTER TYPE campaign_type ADD VALUE IF NOT EXISTS 'INSTANT_WIN';
+
+-- =====================================================
+-- PRIZE POOLS
+-- =====================================================
+
+-- Secret seed of each instant-win campaign. Only the SHA-256 commitment
+-- is exposed while the campaign runs; the seed is revealed once it ends
+-- so every draw can be recomputed.
+CREATE TABLE instant_win_pools (
+    campaign_id UUID PRIMARY KEY REFERENCES campaigns(id) ON DELETE CASCADE,
+    server_seed BYTEA NOT NULL,
+    seed_commitment TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    revealed_at TIMESTAMP WITH TIME ZONE
+);
+
+ALTER TABLE instant_win_pools ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service role can manage instant win pools"
+    ON instant_win_pools FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
+
+-- =====================================================
+-- PRIZE INVENTORY
+-- =====================================================
+
+-- The check constraint backs up the conditional update used to claim a
+-- prize, so a tier can never be awarded more times than its inventory.
+CREATE TABLE instant_win_inventory (
+    campaign_id UUID NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    tier_index INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    inventory INTEGER NOT NULL CHECK (inventory > 0),
+    awarded INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (campaign_id, tier_index),
+    CHECK (awarded >= 0 AND awarded <= inventory)
+);
+
+ALTER TABLE instant_win_inventory ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service role can manage instant win inventory"
+    ON instant_win_inventory FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
+
+-- =====================================================
+-- DRAWS
+-- =====================================================
+
+-- One draw per transaction and campaign: replaying a transaction ID is
+-- rejected instead of drawing again.
+CREATE TABLE instant_win_draws (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    campaign_id UUID NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    transaction_id TEXT NOT NULL,
+    phone_hash TEXT NOT NULL,
+    roll DOUBLE PRECISION NOT NULL,
+    tier_index INTEGER,
+    awarded BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(campaign_id, transaction_id)
+);
+
+CREATE INDEX idx_instant_win_draws_campaign ON instant_win_draws(campaign_id, created_at);
+
+ALTER TABLE instant_win_draws ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service role can manage instant win draws"
+    ON instant_win_draws FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
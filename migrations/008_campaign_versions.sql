@@ -0,0 +1,43 @@
+-- Fidelio Loyalty Platform - Campaign Config Versioning
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- CAMPAIGN VERSIONS
+-- =====================================================
+
+-- Every config change creates an immutable version. Wallets and shadow
+-- balances point at the version their state was built with, and ledger
+-- entries at the version used to compute them.
+CREATE TABLE campaign_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    campaign_id UUID NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    config JSONB NOT NULL,
+    migration_mode TEXT NOT NULL DEFAULT 'new_cards_only' CHECK (migration_mode IN ('new_cards_only', 'migrate_all')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(campaign_id, version)
+);
+
+ALTER TABLE campaign_versions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service role can manage campaign versions"
+    ON campaign_versions FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
+
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS current_version INTEGER NOT NULL DEFAULT 1;
+
+-- Existing campaigns start at version 1 with their current config
+INSERT INTO campaign_versions (campaign_id, version, config, created_at)
+SELECT id, 1, config, created_at FROM campaigns
+ON CONFLICT (campaign_id, version) DO NOTHING;
+
+-- =====================================================
+-- VERSION REFERENCES
+-- =====================================================
+
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS campaign_version_id UUID REFERENCES campaign_versions(id) ON DELETE SET NULL;
+ALTER TABLE shadow_balances ADD COLUMN IF NOT EXISTS campaign_version_id UUID REFERENCES campaign_versions(id) ON DELETE SET NULL;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS campaign_version_id UUID REFERENCES campaign_versions(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_wallets_campaign_version ON wallets(campaign_version_id);
+CREATE INDEX idx_shadow_balances_campaign_version ON shadow_balances(campaign_version_id);
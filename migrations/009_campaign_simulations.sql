@@ -0,0 +1,43 @@
+-- Fidelio Loyalty Platform - Campaign Backtesting Simulations
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- LEDGER: PURCHASE DETAILS
+-- =====================================================
+
+-- EARN entries only stored the reward. Keeping the purchase amount and the
+-- merchant's transaction ID lets past purchases be replayed through other
+-- campaign configs. Entries recorded before this migration have no amount
+-- and are skipped by simulations.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS purchase_amount DECIMAL(12, 2);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS external_id TEXT;
+
+CREATE INDEX idx_transactions_merchant_type_created ON transactions(merchant_id, transaction_type, created_at);
+
+-- =====================================================
+-- SIMULATION JOBS
+-- =====================================================
+
+CREATE TABLE simulation_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    campaign_type campaign_type NOT NULL,
+    config JSONB NOT NULL,
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'RUNNING', 'COMPLETED', 'FAILED')),
+    result JSONB,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_simulation_jobs_merchant ON simulation_jobs(merchant_id, created_at DESC);
+CREATE INDEX idx_simulation_jobs_pending ON simulation_jobs(created_at) WHERE status = 'PENDING';
+
+ALTER TABLE simulation_jobs ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service role can manage simulation jobs"
+    ON simulation_jobs FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
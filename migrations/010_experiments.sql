@@ -0,0 +1,80 @@
+-- Fidelio Loyalty Platform - Campaign Experiments (A/B Tests and Holdouts)
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- EXPERIMENTS
+-- =====================================================
+
+CREATE TABLE experiments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    campaign_id UUID NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    ended_at TIMESTAMP WITH TIME ZONE
+);
+
+-- A campaign runs at most one experiment at a time
+CREATE UNIQUE INDEX idx_experiments_active_campaign ON experiments(campaign_id) WHERE is_active;
+CREATE INDEX idx_experiments_campaign ON experiments(campaign_id, created_at DESC);
+
+-- =====================================================
+-- VARIANTS
+-- =====================================================
+
+-- The control variant is a holdout that earns nothing. Other variants run
+-- the campaign with their own config, or the campaign's config when NULL.
+CREATE TABLE experiment_variants (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    experiment_id UUID NOT NULL REFERENCES experiments(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    weight INTEGER NOT NULL CHECK (weight > 0),
+    is_control BOOLEAN NOT NULL DEFAULT false,
+    config JSONB,
+    position INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_experiment_variants_experiment ON experiment_variants(experiment_id, position);
+
+-- =====================================================
+-- ASSIGNMENTS
+-- =====================================================
+
+-- Keyed by phone hash so the variant survives shadow balance conversion
+CREATE TABLE experiment_assignments (
+    experiment_id UUID NOT NULL REFERENCES experiments(id) ON DELETE CASCADE,
+    phone_hash TEXT NOT NULL,
+    variant_id UUID NOT NULL REFERENCES experiment_variants(id) ON DELETE CASCADE,
+    assigned_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (experiment_id, phone_hash)
+);
+
+CREATE INDEX idx_experiment_assignments_variant ON experiment_assignments(variant_id);
+
+-- =====================================================
+-- LEDGER: EXPERIMENT VARIANT
+-- =====================================================
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS experiment_variant_id UUID REFERENCES experiment_variants(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_transactions_experiment_variant ON transactions(experiment_variant_id) WHERE experiment_variant_id IS NOT NULL;
+
+-- =====================================================
+-- ROW LEVEL SECURITY
+-- =====================================================
+
+ALTER TABLE experiments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE experiment_variants ENABLE ROW LEVEL SECURITY;
+ALTER TABLE experiment_assignments ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service role can manage experiments"
+    ON experiments FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
+
+CREATE POLICY "Service role can manage experiment variants"
+    ON experiment_variants FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
+
+CREATE POLICY "Service role can manage experiment assignments"
+    ON experiment_assignments FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
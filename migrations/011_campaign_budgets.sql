@@ -0,0 +1,56 @@
+-- Fidelio Loyalty Platform - Campaign Budget Caps
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- CAMPAIGN BUDGET CAPS
+-- =====================================================
+
+-- NULL means no limit
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS max_reward_value DECIMAL(12, 2) CHECK (max_reward_value > 0);
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS max_rewards INTEGER CHECK (max_rewards > 0);
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS daily_reward_cap DECIMAL(12, 2) CHECK (daily_reward_cap > 0);
+
+-- =====================================================
+-- BUDGET USAGE
+-- =====================================================
+
+-- One row per campaign, charged with a conditional UPDATE during ingestion.
+-- day/daily_reward_value track the current merchant day only.
+CREATE TABLE campaign_budget_usage (
+    campaign_id UUID PRIMARY KEY REFERENCES campaigns(id) ON DELETE CASCADE,
+    reward_value DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    rewards_issued INTEGER NOT NULL DEFAULT 0,
+    day DATE NOT NULL,
+    daily_reward_value DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- =====================================================
+-- BUDGET ALERTS
+-- =====================================================
+
+-- Alerts sent at 80% and 100% of each cap, once per period
+-- ('total' for the campaign caps, the merchant day for the daily cap)
+CREATE TABLE campaign_budget_alerts (
+    campaign_id UUID NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('reward_value', 'rewards', 'daily_reward_value')),
+    threshold INTEGER NOT NULL,
+    period TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (campaign_id, kind, threshold, period)
+);
+
+-- =====================================================
+-- ROW LEVEL SECURITY
+-- =====================================================
+
+ALTER TABLE campaign_budget_usage ENABLE ROW LEVEL SECURITY;
+ALTER TABLE campaign_budget_alerts ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service role can manage campaign budget usage"
+    ON campaign_budget_usage FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
+
+CREATE POLICY "Service role can manage campaign budget alerts"
+    ON campaign_budget_alerts FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
@@ -0,0 +1,44 @@
+-- Fidelio Loyalty Platform - Manual Balance Adjustments
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- LEDGER TYPE
+-- =====================================================
+
+ALTER TYPE transaction_type ADD VALUE IF NOT EXISTS 'ADJUST';
+
+-- =====================================================
+-- BALANCE ADJUSTMENTS
+-- =====================================================
+
+-- Manual credits and debits by merchant staff. Amounts above the merchant's
+-- approval threshold stay PENDING until another user approves them; applied
+-- adjustments point to their ADJUST ledger entry.
+CREATE TABLE balance_adjustments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    direction TEXT NOT NULL CHECK (direction IN ('CREDIT', 'DEBIT')),
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    reason_code TEXT NOT NULL CHECK (reason_code IN ('COMPLAINT', 'INGESTION_ERROR', 'MISSED_PURCHASE', 'FRAUD', 'OTHER')),
+    note TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('PENDING', 'APPLIED', 'REJECTED')),
+    requested_by TEXT NOT NULL,
+    reviewed_by TEXT,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    transaction_id UUID REFERENCES transactions(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_balance_adjustments_merchant ON balance_adjustments(merchant_id, created_at DESC);
+CREATE INDEX idx_balance_adjustments_pending ON balance_adjustments(merchant_id) WHERE status = 'PENDING';
+
+-- =====================================================
+-- ROW LEVEL SECURITY
+-- =====================================================
+
+ALTER TABLE balance_adjustments ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service role can manage balance adjustments"
+    ON balance_adjustments FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
@@ -0,0 +1,27 @@
+-- Fidelio Loyalty Platform - Campaign Lifecycle
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- CAMPAIGN STATUS
+-- =====================================================
+
+-- DRAFT -> SCHEDULED -> ACTIVE <-> PAUSED -> ENDED -> ARCHIVED.
+-- is_active stays in sync (status = 'ACTIVE') for the queries and policies using it.
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'ACTIVE'
+    CHECK (status IN ('DRAFT', 'SCHEDULED', 'ACTIVE', 'PAUSED', 'ENDED', 'ARCHIVED'));
+
+-- Existing campaigns: past the end date -> ENDED, not yet started -> SCHEDULED,
+-- otherwise ACTIVE or PAUSED from is_active
+UPDATE campaigns SET status = CASE
+    WHEN ends_at IS NOT NULL AND ends_at <= CURRENT_TIMESTAMP THEN 'ENDED'
+    WHEN is_active AND starts_at IS NOT NULL AND starts_at > CURRENT_TIMESTAMP THEN 'SCHEDULED'
+    WHEN is_active THEN 'ACTIVE'
+    ELSE 'PAUSED'
+END;
+UPDATE campaigns SET is_active = (status = 'ACTIVE');
+
+CREATE INDEX idx_campaigns_status ON campaigns(merchant_id, status);
+
+-- Scheduler lookups
+CREATE INDEX idx_campaigns_due_to_start ON campaigns(starts_at) WHERE status = 'SCHEDULED';
+CREATE INDEX idx_campaigns_due_to_end ON campaigns(ends_at) WHERE status IN ('ACTIVE', 'PAUSED');
@@ -0,0 +1,62 @@
+-- Fidelio Loyalty Platform - Campaign Templates
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- CAMPAIGN TEMPLATES
+-- =====================================================
+
+-- Reusable campaign setups. merchant_id NULL marks platform templates, visible to
+-- every merchant. config holds {{param}} placeholders declared in parameters.
+CREATE TABLE campaign_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID REFERENCES merchants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    type campaign_type NOT NULL,
+    config JSONB NOT NULL,
+    parameters JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_campaign_templates_merchant ON campaign_templates(merchant_id);
+
+-- =====================================================
+-- PLATFORM TEMPLATES
+-- =====================================================
+
+INSERT INTO campaign_templates (merchant_id, name, description, type, config, parameters) VALUES
+(
+    NULL,
+    'Cafeteria - cartão de 10 selos',
+    'Um selo por compra; ao completar o cartão o cliente ganha um item grátis.',
+    'PUNCH_CARD',
+    '{"required_punches": "{{stamps}}", "reward_amount": "{{reward_amount}}", "reward_type": "free_item", "stamp_mode": "per_purchase", "min_purchase": "{{min_purchase}}"}',
+    '[
+        {"name": "stamps", "type": "integer", "title": "Selos para a recompensa", "default": 10},
+        {"name": "reward_amount", "type": "number", "title": "Valor do item grátis", "default": 8},
+        {"name": "min_purchase", "type": "number", "title": "Compra mínima", "default": 0}
+    ]'
+),
+(
+    NULL,
+    'Restaurante - 5% de cashback',
+    'Devolve um percentual de cada conta como saldo para a próxima visita.',
+    'CASHBACK',
+    '{"percentage": "{{percentage}}", "max_cashback": "{{max_cashback}}", "min_purchase": "{{min_purchase}}"}',
+    '[
+        {"name": "percentage", "type": "number", "title": "Percentual de cashback", "default": 5},
+        {"name": "max_cashback", "type": "number", "title": "Cashback máximo por conta", "default": 50},
+        {"name": "min_purchase", "type": "number", "title": "Conta mínima", "default": 30}
+    ]'
+);
+
+-- =====================================================
+-- ROW LEVEL SECURITY
+-- =====================================================
+
+ALTER TABLE campaign_templates ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service role can manage campaign templates"
+    ON campaign_templates FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
@@ -0,0 +1,54 @@
+-- Fidelio Loyalty Platform - Customer Segments
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- SEGMENTS
+-- =====================================================
+
+-- Groups of a merchant's customers that campaigns can target. Rule segments are
+-- evaluated from wallets and the ledger on every check (rules JSONB); static
+-- segments are uploaded lists of phone hashes in segment_members.
+CREATE TABLE segments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('rule', 'static')),
+    rules JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (kind = 'rule' OR rules IS NULL)
+);
+
+CREATE INDEX idx_segments_merchant ON segments(merchant_id);
+
+CREATE TABLE segment_members (
+    segment_id UUID NOT NULL REFERENCES segments(id) ON DELETE CASCADE,
+    phone_hash VARCHAR(64) NOT NULL,
+    added_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (segment_id, phone_hash)
+);
+
+-- =====================================================
+-- CAMPAIGN TARGETING
+-- =====================================================
+
+-- NULL means the campaign applies to every customer
+ALTER TABLE campaigns ADD COLUMN segment_id UUID REFERENCES segments(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_campaigns_segment ON campaigns(segment_id) WHERE segment_id IS NOT NULL;
+
+-- =====================================================
+-- ROW LEVEL SECURITY
+-- =====================================================
+
+ALTER TABLE segments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE segment_members ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service role can manage segments"
+    ON segments FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
+
+CREATE POLICY "Service role can manage segment members"
+    ON segment_members FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
@@ -0,0 +1,16 @@
+-- Fidelio Loyalty Platform - Transaction History
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- HISTORY PAGINATION
+-- =====================================================
+
+-- History pages are read newest first with a (created_at, id) cursor per wallet or
+-- shadow balance.
+CREATE INDEX idx_transactions_wallet_history
+    ON transactions(wallet_id, created_at DESC, id DESC)
+    WHERE wallet_id IS NOT NULL;
+
+CREATE INDEX idx_transactions_shadow_history
+    ON transactions(shadow_balance_id, created_at DESC, id DESC)
+    WHERE shadow_balance_id IS NOT NULL;
@@ -0,0 +1,39 @@
+-- Fidelio Loyalty Platform - Merchant Geosearch
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- MERCHANT PROFILE
+-- =====================================================
+
+-- Public profile shown in the app. Columns may already exist in Supabase projects where
+-- they were added by hand.
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS address TEXT;
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS logo_url TEXT;
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS banner_url TEXT;
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS category VARCHAR(100);
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS category_id UUID;
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+
+ALTER TABLE merchants ADD CONSTRAINT chk_merchants_coordinates CHECK (
+    (latitude IS NULL AND longitude IS NULL) OR
+    (latitude BETWEEN -90 AND 90 AND longitude BETWEEN -180 AND 180)
+);
+
+-- =====================================================
+-- GEOSEARCH
+-- =====================================================
+
+-- Nearby searches prefilter on a latitude/longitude bounding box before computing the
+-- haversine distance, so only merchants with coordinates are indexed.
+CREATE INDEX idx_merchants_location
+    ON merchants(latitude, longitude)
+    WHERE latitude IS NOT NULL AND longitude IS NOT NULL;
+
+CREATE INDEX idx_merchants_category ON merchants(LOWER(category));
+CREATE INDEX idx_merchants_category_id ON merchants(category_id);
+
+-- Running campaigns are looked up per merchant for the listing summary
+CREATE INDEX idx_campaigns_merchant_active
+    ON campaigns(merchant_id, created_at DESC)
+    WHERE status = 'ACTIVE';
@@ -0,0 +1,82 @@
+-- Fidelio Loyalty Platform - App Catalog
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- CATEGORIES
+-- =====================================================
+
+-- Created by hand in some Supabase projects, hence IF NOT EXISTS
+CREATE TABLE IF NOT EXISTS categories (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    icon_key VARCHAR(50) NOT NULL,
+    color_hex VARCHAR(20) NOT NULL,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_categories_sort ON categories(sort_order, name);
+
+ALTER TABLE merchants ADD CONSTRAINT fk_merchants_category
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL;
+
+-- =====================================================
+-- FEATURED PROMOTIONS
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS featured_promotions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    title VARCHAR(255) NOT NULL,
+    subtitle TEXT,
+    image_url TEXT,
+    background_color VARCHAR(20),
+    priority INTEGER NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Ownership and scheduling. Promotions without a merchant belong to the platform.
+ALTER TABLE featured_promotions ADD COLUMN IF NOT EXISTS merchant_id UUID REFERENCES merchants(id) ON DELETE CASCADE;
+ALTER TABLE featured_promotions ADD COLUMN IF NOT EXISTS campaign_id UUID REFERENCES campaigns(id) ON DELETE SET NULL;
+ALTER TABLE featured_promotions ADD COLUMN IF NOT EXISTS starts_at TIMESTAMPTZ;
+ALTER TABLE featured_promotions ADD COLUMN IF NOT EXISTS ends_at TIMESTAMPTZ;
+ALTER TABLE featured_promotions ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+ALTER TABLE featured_promotions ADD CONSTRAINT chk_featured_promotions_schedule
+    CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at);
+
+-- The carousel reads active promotions by priority
+CREATE INDEX idx_featured_promotions_on_air
+    ON featured_promotions(priority DESC, starts_at DESC)
+    WHERE is_active;
+
+CREATE INDEX idx_featured_promotions_merchant ON featured_promotions(merchant_id);
+
+-- =====================================================
+-- ROW LEVEL SECURITY
+-- =====================================================
+
+ALTER TABLE categories ENABLE ROW LEVEL SECURITY;
+ALTER TABLE featured_promotions ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS "Service role can manage categories" ON categories;
+CREATE POLICY "Service role can manage categories"
+    ON categories FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
+
+DROP POLICY IF EXISTS "Service role can manage featured promotions" ON featured_promotions;
+CREATE POLICY "Service role can manage featured promotions"
+    ON featured_promotions FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
+
+-- App versions that still read these tables directly from Supabase
+DROP POLICY IF EXISTS "Anyone can read categories" ON categories;
+CREATE POLICY "Anyone can read categories"
+    ON categories FOR SELECT
+    USING (true);
+
+DROP POLICY IF EXISTS "Anyone can read promotions on air" ON featured_promotions;
+CREATE POLICY "Anyone can read promotions on air"
+    ON featured_promotions FOR SELECT
+    USING (is_active AND (starts_at IS NULL OR starts_at <= NOW()) AND (ends_at IS NULL OR ends_at > NOW()));
@@ -0,0 +1,40 @@
+-- Fidelio Loyalty Platform - Shadow Balance Claims
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- ONE-TIME CODES
+-- =====================================================
+
+-- Codes sent by SMS. Only a hash of the code is stored.
+CREATE TABLE otp_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    phone_hash VARCHAR(64) NOT NULL,
+    purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('claim')),
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    ip_address VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Latest code per phone and throttling windows per phone and IP
+CREATE INDEX idx_otp_codes_phone ON otp_codes(phone_hash, purpose, created_at DESC);
+CREATE INDEX idx_otp_codes_ip ON otp_codes(ip_address, created_at DESC);
+
+-- =====================================================
+-- CLAIMED SHADOW BALANCES
+-- =====================================================
+
+ALTER TABLE shadow_balances ADD COLUMN claimed_at TIMESTAMPTZ;
+ALTER TABLE shadow_balances ADD COLUMN claim_email VARCHAR(255);
+
+-- =====================================================
+-- ROW LEVEL SECURITY
+-- =====================================================
+
+ALTER TABLE otp_codes ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service role can manage otp codes"
+    ON otp_codes FOR ALL
+    USING (auth.jwt()->>'role' = 'service_role');
@@ -0,0 +1,22 @@
+-- Fidelio Loyalty Platform - Shadow Expiration Policies
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- LAST ACTIVITY
+-- =====================================================
+
+-- Sliding expiration restarts the TTL at each purchase. Merchants set the policy in
+-- settings.shadow_expiration; without one, balances keep the global TTL from their creation.
+ALTER TABLE shadow_balances ADD COLUMN last_activity_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+UPDATE shadow_balances s
+SET last_activity_at = COALESCE(
+    (SELECT MAX(t.created_at) FROM transactions t
+     WHERE t.shadow_balance_id = s.id AND t.transaction_type = 'EARN'),
+    s.created_at
+);
+
+-- Policy changes recompute the open balances of a merchant
+CREATE INDEX idx_shadow_balances_open
+    ON shadow_balances(merchant_id, expires_at)
+    WHERE converted_at IS NULL;
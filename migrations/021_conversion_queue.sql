@@ -0,0 +1,52 @@
+-- Fidelio Loyalty Platform - Conversion Queue Worker
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- RETRIES
+-- =====================================================
+
+-- The backend drains shadow_conversion_queue as a fallback for the sign-up webhook.
+-- Failed conversions are retried with backoff until max attempts, then kept with
+-- failed_at and the last error for inspection.
+UPDATE shadow_conversion_queue SET processed = false WHERE processed IS NULL;
+
+ALTER TABLE shadow_conversion_queue
+    ALTER COLUMN processed SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL,
+    ADD COLUMN attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN last_error TEXT,
+    ADD COLUMN failed_at TIMESTAMPTZ;
+
+DROP INDEX IF EXISTS idx_conversion_queue_pending;
+CREATE INDEX idx_conversion_queue_pending
+    ON shadow_conversion_queue(next_attempt_at)
+    WHERE processed = false AND failed_at IS NULL;
+
+-- =====================================================
+-- FUNCTION: Process Conversion Queue (for polling)
+-- =====================================================
+
+-- Only entries that are due and have not failed for good
+CREATE OR REPLACE FUNCTION get_pending_conversions(batch_size INT DEFAULT 10)
+RETURNS TABLE (
+    queue_id UUID,
+    user_id UUID,
+    phone TEXT,
+    created_at TIMESTAMP WITH TIME ZONE
+) AS $$
+BEGIN
+    RETURN QUERY
+    SELECT
+        q.id,
+        q.user_id,
+        q.phone,
+        q.created_at
+    FROM shadow_conversion_queue q
+    WHERE q.processed = false
+    AND q.failed_at IS NULL
+    AND q.next_attempt_at <= NOW()
+    ORDER BY q.created_at ASC
+    LIMIT batch_size;
+END;
+$$ LANGUAGE plpgsql;
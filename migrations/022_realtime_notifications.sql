@@ -0,0 +1,59 @@
+-- Fidelio Loyalty Platform - Real-time Notifications
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- CONVERSION QUEUE
+-- =====================================================
+
+-- Sign-ups reach the queue through notify_shadow_conversion; the backend listens on
+-- shadow_conversion_queued and converts the entry right away instead of waiting for
+-- the next poll. The payload is the queue entry ID. NOTIFY is delivered on commit,
+-- so the entry is always visible when the backend receives it.
+CREATE OR REPLACE FUNCTION notify_conversion_queued()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('shadow_conversion_queued', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER on_conversion_queued
+    AFTER INSERT ON shadow_conversion_queue
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_conversion_queued();
+
+-- =====================================================
+-- CACHE INVALIDATION
+-- =====================================================
+
+-- Changes to merchants and campaigns are announced on <table>_changed channels so
+-- in-memory caches can drop stale entries. Payload: {"id", "merchant_id", "op"}.
+CREATE OR REPLACE FUNCTION notify_entity_changed()
+RETURNS TRIGGER AS $$
+DECLARE
+    rec RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+    ELSE
+        rec := NEW;
+    END IF;
+
+    PERFORM pg_notify(TG_ARGV[0], json_build_object(
+        'id', rec.id,
+        'merchant_id', COALESCE(to_jsonb(rec)->>'merchant_id', rec.id::text),
+        'op', TG_OP
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER on_merchant_changed
+    AFTER INSERT OR UPDATE OR DELETE ON merchants
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_entity_changed('merchant_changed');
+
+CREATE TRIGGER on_campaign_changed
+    AFTER INSERT OR UPDATE OR DELETE ON campaigns
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_entity_changed('campaign_changed');
@@ -0,0 +1,17 @@
+-- Fidelio Loyalty Platform - Simulation Job Leases
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- HEARTBEAT
+-- =====================================================
+
+-- A running job is kept alive by its worker's heartbeat. Jobs whose heartbeat went
+-- stale (the worker crashed or the instance was stopped) are picked up again, up to
+-- a few attempts before they are marked FAILED.
+ALTER TABLE simulation_jobs
+    ADD COLUMN heartbeat_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+
+UPDATE simulation_jobs SET heartbeat_at = started_at, attempts = 1 WHERE status = 'RUNNING';
+
+CREATE INDEX idx_simulation_jobs_running ON simulation_jobs(heartbeat_at) WHERE status = 'RUNNING';
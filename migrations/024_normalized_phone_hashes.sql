@@ -0,0 +1,90 @@
+-- Fidelio Loyalty Platform - Normalized Phone Hashes
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- REKEYING
+-- =====================================================
+
+-- Phone hashes are now taken from the E.164 form of the number (+5511999999999).
+-- Rows hashed from another way of writing the number with its country code (e.g.
+-- "+55 (11) 99999-9999" as sent by a POS, or the "5511999999999" Supabase Auth
+-- stores) move to the normalized hash. Rows whose normalized counterpart already
+-- exists are left under the old hash. National numbers without the country code
+-- ("11 99999-9999") cannot be normalized: they are no longer accepted, and rows
+-- hashed from them are not moved.
+CREATE OR REPLACE FUNCTION rekey_phone_hash(legacy_hash TEXT, normalized_hash TEXT)
+RETURNS INT AS $$
+DECLARE
+    moved INT := 0;
+    n INT;
+BEGIN
+    IF legacy_hash = normalized_hash THEN
+        RETURN 0;
+    END IF;
+
+    UPDATE wallets SET phone_hash = normalized_hash WHERE phone_hash = legacy_hash;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    moved := moved + n;
+
+    UPDATE shadow_balances s SET phone_hash = normalized_hash
+    WHERE s.phone_hash = legacy_hash
+    AND NOT EXISTS (
+        SELECT 1 FROM shadow_balances o
+        WHERE o.merchant_id = s.merchant_id AND o.phone_hash = normalized_hash
+    );
+    GET DIAGNOSTICS n = ROW_COUNT;
+    moved := moved + n;
+
+    UPDATE welcome_bonuses w SET phone_hash = normalized_hash
+    WHERE w.phone_hash = legacy_hash
+    AND NOT EXISTS (
+        SELECT 1 FROM welcome_bonuses o
+        WHERE o.merchant_id = w.merchant_id AND o.phone_hash = normalized_hash
+    );
+    GET DIAGNOSTICS n = ROW_COUNT;
+    moved := moved + n;
+
+    UPDATE instant_win_draws SET phone_hash = normalized_hash WHERE phone_hash = legacy_hash;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    moved := moved + n;
+
+    UPDATE experiment_assignments a SET phone_hash = normalized_hash
+    WHERE a.phone_hash = legacy_hash
+    AND NOT EXISTS (
+        SELECT 1 FROM experiment_assignments o
+        WHERE o.experiment_id = a.experiment_id AND o.phone_hash = normalized_hash
+    );
+    GET DIAGNOSTICS n = ROW_COUNT;
+    moved := moved + n;
+
+    UPDATE segment_members m SET phone_hash = normalized_hash
+    WHERE m.phone_hash = legacy_hash
+    AND NOT EXISTS (
+        SELECT 1 FROM segment_members o
+        WHERE o.segment_id = m.segment_id AND o.phone_hash = normalized_hash
+    );
+    GET DIAGNOSTICS n = ROW_COUNT;
+    moved := moved + n;
+
+    RETURN moved;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =====================================================
+-- BACKFILL
+-- =====================================================
+
+-- Registered users are rekeyed once, here, from the phone Supabase Auth keeps. Shadow
+-- balances of customers who never signed up have no stored phone to rekey from; they
+-- stay under their old hash until they expire.
+DO $$
+BEGIN
+    IF to_regclass('auth.users') IS NULL THEN
+        RETURN;
+    END IF;
+
+    PERFORM rekey_phone_hash(hash_phone(u.phone), hash_phone('+' || regexp_replace(u.phone, '[^0-9]', '', 'g')))
+    FROM auth.users u
+    WHERE u.phone IS NOT NULL AND u.phone <> '';
+END;
+$$;
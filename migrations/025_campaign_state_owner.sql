@@ -0,0 +1,49 @@
+-- Fidelio Loyalty Platform - Campaign State Owner
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- LEDGER TYPE
+-- =====================================================
+
+-- Records state dropped because it was built under another campaign
+ALTER TYPE transaction_type ADD VALUE IF NOT EXISTS 'RESET';
+
+-- =====================================================
+-- STATE OWNER
+-- =====================================================
+
+-- Several campaigns can run at once for different segments, so wallets and
+-- shadow balances record which campaign their state belongs to. A customer
+-- picked by another campaign starts over there instead of feeding one
+-- strategy's state to another.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS campaign_id UUID REFERENCES campaigns(id) ON DELETE SET NULL;
+ALTER TABLE shadow_balances ADD COLUMN IF NOT EXISTS campaign_id UUID REFERENCES campaigns(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_wallets_campaign ON wallets(merchant_id, campaign_id);
+CREATE INDEX idx_shadow_balances_campaign ON shadow_balances(merchant_id, campaign_id);
+
+-- Versioned state belongs to the campaign of its version
+UPDATE wallets w SET campaign_id = v.campaign_id
+FROM campaign_versions v
+WHERE w.campaign_version_id = v.id AND w.campaign_id IS NULL;
+
+UPDATE shadow_balances s SET campaign_id = v.campaign_id
+FROM campaign_versions v
+WHERE s.campaign_version_id = v.id AND s.campaign_id IS NULL;
+
+-- Unversioned state belongs to the campaign the customer last earned under
+UPDATE wallets w SET campaign_id = (
+    SELECT t.campaign_id FROM transactions t
+    WHERE t.wallet_id = w.id AND t.transaction_type = 'EARN' AND t.campaign_id IS NOT NULL
+    ORDER BY t.created_at DESC
+    LIMIT 1
+)
+WHERE w.campaign_id IS NULL;
+
+UPDATE shadow_balances s SET campaign_id = (
+    SELECT t.campaign_id FROM transactions t
+    WHERE t.shadow_balance_id = s.id AND t.transaction_type = 'EARN' AND t.campaign_id IS NOT NULL
+    ORDER BY t.created_at DESC
+    LIMIT 1
+)
+WHERE s.campaign_id IS NULL;
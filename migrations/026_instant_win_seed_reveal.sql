@@ -0,0 +1,15 @@
+-- Fidelio Loyalty Platform - Instant Win Seed Reveal
+-- PostgreSQL/Supabase
+
+-- =====================================================
+-- REVEAL ON END
+-- =====================================================
+
+-- Seeds are now revealed when the campaign moves to ENDED or ARCHIVED instead
+-- of when the report is first read after ends_at. Campaigns that already
+-- ended are revealed here; a revealed seed no longer draws.
+UPDATE instant_win_pools p SET revealed_at = NOW()
+FROM campaigns c
+WHERE c.id = p.campaign_id
+AND c.status IN ('ENDED', 'ARCHIVED')
+AND p.revealed_at IS NULL;
@@ -6,6 +6,7 @@ import DashboardLayout from './layouts/DashboardLayout';
 import LandingPage from './pages/LandingPage';
 import Login from './pages/Login';
 import Checkout from './pages/Checkout';
+import Claim from './pages/Claim';
 import Dashboard from './pages/Dashboard';
 import Campaigns from './pages/Campaigns';
 import Settings from './pages/Settings';
@@ -19,6 +20,7 @@ function App() {
             <Route path="/" element={<LandingPage />} />
             <Route path="/login" element={<Login />} />
             <Route path="/checkout" element={<Checkout />} />
+            <Route path="/resgatar" element={<Claim />} />
             <Route
               path="/dashboard"
               element={
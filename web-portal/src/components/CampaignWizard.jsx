@@ -1,29 +1,16 @@
-import React, { useState } from 'react';
-import { X, ArrowRight, ArrowLeft, Check, Percent, Gift, CreditCard, Calendar, AlertCircle } from 'lucide-react';
+import React, { useEffect, useState } from 'react';
+import { X, ArrowRight, ArrowLeft, Check, Percent, Gift, CreditCard, Calendar, Sparkles } from 'lucide-react';
+import SchemaForm from './SchemaForm';
 
-const CAMPAIGN_TYPES = [
-    {
-        id: 'CASHBACK',
-        name: 'Cashback',
-        description: 'Cliente ganha % de volta em cada compra',
-        icon: Percent,
-        color: 'bg-green-500'
-    },
-    {
-        id: 'PROGRESSIVE',
-        name: 'Pontos Progressivos',
-        description: 'Quanto mais compra, mais pontos ganha',
-        icon: Gift,
-        color: 'bg-purple-600'
-    },
-    {
-        id: 'PUNCH_CARD',
-        name: 'Cartão Fidelidade',
-        description: 'A cada X compras, ganha recompensa',
-        icon: CreditCard,
-        color: 'bg-blue-600'
-    }
-];
+// Icons and colors per campaign type; types the portal does not know yet use the fallback
+const TYPE_STYLES = {
+    CASHBACK: { icon: Percent, color: 'bg-green-500' },
+    PROGRESSIVE: { icon: Gift, color: 'bg-purple-600' },
+    PUNCH_CARD: { icon: CreditCard, color: 'bg-blue-600' },
+    STREAK: { icon: Calendar, color: 'bg-orange-500' },
+    INSTANT_WIN: { icon: Sparkles, color: 'bg-pink-500' }
+};
+const DEFAULT_TYPE_STYLE = { icon: Gift, color: 'bg-gray-600' };
 
 export default function CampaignWizard({ isOpen, onClose, onSuccess }) {
     const [step, setStep] = useState(1);
@@ -36,11 +23,27 @@ export default function CampaignWizard({ isOpen, onClose, onSuccess }) {
         endsAt: '',
         config: {}
     });
+    // Types, their config schemas and defaults, as published by the backend
+    const [campaignTypes, setCampaignTypes] = useState([]);
+
+    useEffect(() => {
+        if (!isOpen) return;
+        fetch('/api/v1/campaign-types')
+            .then((response) => (response.ok ? response.json() : []))
+            .then((types) => {
+                setCampaignTypes(types);
+            })
+            .catch((error) => console.error('Error loading campaign types:', error));
+    }, [isOpen]);
 
     if (!isOpen) return null;
 
+    const selectedType = campaignTypes.find((t) => t.type === formData.type);
+
     const handleTypeSelect = (type) => {
-        setFormData({ ...formData, type });
+        // Start from the backend defaults so fields without a form input (e.g. tiers) stay valid
+        const defaults = campaignTypes.find((t) => t.type === type)?.defaultConfig || {};
+        setFormData({ ...formData, type, config: { ...defaults } });
         setStep(2);
     };
 
@@ -62,7 +65,7 @@ export default function CampaignWizard({ isOpen, onClose, onSuccess }) {
                 },
                 body: JSON.stringify({
                     ...formData,
-                    config: JSON.stringify(formData.config)
+                    config: formData.config
                 }),
             });
 
@@ -77,6 +80,13 @@ export default function CampaignWizard({ isOpen, onClose, onSuccess }) {
                 return;
             }
 
+            if (response.status === 422) {
+                const errorData = await response.json();
+                const fields = (errorData.fields || []).map((f) => `${f.field} ${f.message}`);
+                alert(`Configuração inválida:\n${fields.join('\n')}`);
+                return;
+            }
+
             if (!response.ok) {
                 throw new Error('Failed to create campaign');
             }
@@ -136,21 +146,26 @@ export default function CampaignWizard({ isOpen, onClose, onSuccess }) {
                             </div>
 
                             <div className="grid gap-4">
-                                {CAMPAIGN_TYPES.map((type) => {
-                                    const Icon = type.icon;
+                                {campaignTypes.length === 0 && (
+                                    <p className="text-center text-sm text-gray-500">Carregando tipos de campanha...</p>
+                                )}
+                                {campaignTypes.map((type) => {
+                                    const { icon: Icon, color } = TYPE_STYLES[type.type] || DEFAULT_TYPE_STYLE;
                                     return (
                                         <button
-                                            key={type.id}
-                                            onClick={() => handleTypeSelect(type.id)}
+                                            key={type.type}
+                                            onClick={() => handleTypeSelect(type.type)}
                                             className="p-6 border-2 border-gray-200 rounded-2xl hover:border-blue-500 hover:bg-blue-50/50 transition-all text-left group"
                                         >
                                             <div className="flex items-start gap-4">
-                                                <div className={`${type.color} text-white p-3 rounded-xl group-hover:scale-110 transition-transform`}>
+                                                <div className={`${color} text-white p-3 rounded-xl group-hover:scale-110 transition-transform`}>
                                                     <Icon className="w-6 h-6" />
                                                 </div>
                                                 <div className="flex-1">
-                                                    <h4 className="font-bold text-gray-900 mb-1">{type.name}</h4>
-                                                    <p className="text-sm text-gray-500">{type.description}</p>
+                                                    <h4 className="font-bold text-gray-900 mb-1">{type.displayName}</h4>
+                                                    {type.configSchema?.description && (
+                                                        <p className="text-sm text-gray-500">{type.configSchema.description}</p>
+                                                    )}
                                                 </div>
                                                 <ArrowRight className="w-5 h-5 text-gray-400 group-hover:text-blue-600 group-hover:translate-x-1 transition-all" />
                                             </div>
@@ -241,16 +256,12 @@ export default function CampaignWizard({ isOpen, onClose, onSuccess }) {
                                 <p className="text-gray-500">Defina as regras e recompensas</p>
                             </div>
 
-                            {formData.type === 'CASHBACK' && (
-                                <CashbackConfig formData={formData} setFormData={setFormData} />
-                            )}
-
-                            {formData.type === 'PUNCH_CARD' && (
-                                <PunchCardConfig formData={formData} setFormData={setFormData} />
-                            )}
-
-                            {formData.type === 'PROGRESSIVE' && (
-                                <ProgressiveConfig formData={formData} setFormData={setFormData} />
+                            {selectedType && (
+                                <SchemaForm
+                                    schema={selectedType.configSchema}
+                                    value={formData.config}
+                                    onChange={(config) => setFormData({ ...formData, config })}
+                                />
                             )}
 
                             <div className="flex gap-3 pt-4">
@@ -282,147 +293,3 @@ export default function CampaignWizard({ isOpen, onClose, onSuccess }) {
         </div>
     );
 }
-
-// Cashback Configuration Component
-function CashbackConfig({ formData, setFormData }) {
-    const updateConfig = (field, value) => {
-        setFormData({
-            ...formData,
-            config: { ...formData.config, [field]: parseFloat(value) || 0 }
-        });
-    };
-
-    return (
-        <div className="space-y-4">
-            <div>
-                <label className="block text-sm font-medium text-gray-700 mb-2">Percentual de Cashback (%)</label>
-                <input
-                    required
-                    type="number"
-                    step="0.1"
-                    min="0"
-                    max="100"
-                    value={formData.config.percentage || ''}
-                    onChange={(e) => updateConfig('percentage', e.target.value)}
-                    placeholder="Ex: 5"
-                    className="w-full px-4 py-3 rounded-xl border border-gray-200 focus:border-blue-500 focus:ring-2 focus:ring-blue-500/20 outline-none transition-all"
-                />
-            </div>
-            <div>
-                <label className="block text-sm font-medium text-gray-700 mb-2">Cashback Máximo por Compra (R$) - Opcional</label>
-                <input
-                    type="number"
-                    step="0.01"
-                    min="0"
-                    value={formData.config.max_cashback || ''}
-                    onChange={(e) => updateConfig('max_cashback', e.target.value)}
-                    placeholder="Ex: 50"
-                    className="w-full px-4 py-3 rounded-xl border border-gray-200 focus:border-blue-500 focus:ring-2 focus:ring-blue-500/20 outline-none transition-all"
-                />
-            </div>
-            <div>
-                <label className="block text-sm font-medium text-gray-700 mb-2">Compra Mínima (R$) - Opcional</label>
-                <input
-                    type="number"
-                    step="0.01"
-                    min="0"
-                    value={formData.config.min_purchase || ''}
-                    onChange={(e) => updateConfig('min_purchase', e.target.value)}
-                    placeholder="Ex: 20"
-                    className="w-full px-4 py-3 rounded-xl border border-gray-200 focus:border-blue-500 focus:ring-2 focus:ring-blue-500/20 outline-none transition-all"
-                />
-            </div>
-        </div>
-    );
-}
-
-// Punch Card Configuration Component
-function PunchCardConfig({ formData, setFormData }) {
-    const updateConfig = (field, value) => {
-        setFormData({
-            ...formData,
-            config: { ...formData.config, [field]: field === 'reward_type' ? value : (parseFloat(value) || 0) }
-        });
-    };
-
-    return (
-        <div className="space-y-4">
-            <div>
-                <label className="block text-sm font-medium text-gray-700 mb-2">Número de Carimbos Necessários</label>
-                <input
-                    required
-                    type="number"
-                    min="1"
-                    value={formData.config.required_punches || ''}
-                    onChange={(e) => updateConfig('required_punches', e.target.value)}
-                    placeholder="Ex: 10"
-                    className="w-full px-4 py-3 rounded-xl border border-gray-200 focus:border-blue-500 focus:ring-2 focus:ring-blue-500/20 outline-none transition-all"
-                />
-            </div>
-            <div>
-                <label className="block text-sm font-medium text-gray-700 mb-2">Tipo de Recompensa</label>
-                <select
-                    required
-                    value={formData.config.reward_type || ''}
-                    onChange={(e) => updateConfig('reward_type', e.target.value)}
-                    className="w-full px-4 py-3 rounded-xl border border-gray-200 focus:border-blue-500 focus:ring-2 focus:ring-blue-500/20 outline-none transition-all bg-white"
-                >
-                    <option value="">Selecione...</option>
-                    <option value="points">Pontos</option>
-                    <option value="discount">Desconto</option>
-                    <option value="free_item">Produto Grátis</option>
-                </select>
-            </div>
-            <div>
-                <label className="block text-sm font-medium text-gray-700 mb-2">Valor da Recompensa (R$ ou Pontos)</label>
-                <input
-                    required
-                    type="number"
-                    step="0.01"
-                    min="0"
-                    value={formData.config.reward_amount || ''}
-                    onChange={(e) => updateConfig('reward_amount', e.target.value)}
-                    placeholder="Ex: 50"
-                    className="w-full px-4 py-3 rounded-xl border border-gray-200 focus:border-blue-500 focus:ring-2 focus:ring-blue-500/20 outline-none transition-all"
-                />
-            </div>
-        </div>
-    );
-}
-
-// Progressive Configuration Component
-function ProgressiveConfig({ formData, setFormData }) {
-    const updateConfig = (field, value) => {
-        setFormData({
-            ...formData,
-            config: { ...formData.config, [field]: parseFloat(value) || 0 }
-        });
-    };
-
-    return (
-        <div className="space-y-4">
-            <div>
-                <label className="block text-sm font-medium text-gray-700 mb-2">Pontos Base por R$ 1 Gasto</label>
-                <input
-                    required
-                    type="number"
-                    step="0.1"
-                    min="0"
-                    value={formData.config.base_points_ratio || ''}
-                    onChange={(e) => updateConfig('base_points_ratio', e.target.value)}
-                    placeholder="Ex: 1"
-                    className="w-full px-4 py-3 rounded-xl border border-gray-200 focus:border-blue-500 focus:ring-2 focus:ring-blue-500/20 outline-none transition-all"
-                />
-            </div>
-            <div className="bg-blue-50 border border-blue-200 rounded-xl p-4">
-                <div className="flex gap-2 items-start">
-                    <AlertCircle className="w-5 h-5 text-blue-600 flex-shrink-0 mt-0.5" />
-                    <div className="text-sm text-blue-800">
-                        <p className="font-medium mb-1">Sistema Progressivo Simplificado</p>
-                        <p>Os níveis e multiplicadores serão configurados automaticamente baseados no histórico de compras do cliente.</p>
-                    </div>
-                </div>
-            </div>
-        </div>
-    );
-}
@@ -0,0 +1,199 @@
+import React from 'react';
+import { Plus, Trash2 } from 'lucide-react';
+
+// Friendly labels for enum values shared by several campaign types
+const ENUM_LABELS = {
+    points: 'Pontos',
+    discount: 'Desconto',
+    free_item: 'Produto Grátis',
+    cashback: 'Cashback',
+    per_purchase: 'Um carimbo por compra',
+    per_amount: 'Um carimbo a cada valor gasto',
+    per_item: 'Um carimbo por item da categoria',
+    frequency: 'Visitas dentro de uma janela',
+    consecutive: 'Períodos consecutivos'
+};
+
+const inputClass =
+    'w-full px-4 py-3 rounded-xl border border-gray-200 focus:border-blue-500 focus:ring-2 focus:ring-blue-500/20 outline-none transition-all bg-white';
+
+// Required fields first, then the rest in schema order
+function orderedProperties(schema) {
+    const required = schema.required || [];
+    const names = Object.keys(schema.properties || {});
+    return [...names.filter((n) => required.includes(n)), ...names.filter((n) => !required.includes(n))];
+}
+
+// Drops empty values so optional settings stay out of the config
+function setField(object, name, value) {
+    const next = { ...(object || {}) };
+    if (value === undefined || value === '' || (typeof value === 'object' && value !== null && !Array.isArray(value) && Object.keys(value).length === 0)) {
+        delete next[name];
+    } else {
+        next[name] = value;
+    }
+    return next;
+}
+
+function emptyValue(schema) {
+    if (schema.type === 'object') return {};
+    if (schema.type === 'array') return [];
+    return undefined;
+}
+
+/**
+ * Renders a form for a JSON Schema as published by GET /v1/campaign-types.
+ * Supports objects, arrays of objects or scalars, enums, numbers, strings and booleans.
+ */
+export default function SchemaForm({ schema, value, onChange }) {
+    return <ObjectFields schema={schema} value={value || {}} onChange={onChange} />;
+}
+
+function ObjectFields({ schema, value, onChange }) {
+    const required = schema.required || [];
+    return (
+        <div className="space-y-4">
+            {orderedProperties(schema).map((name) => (
+                <Field
+                    key={name}
+                    name={name}
+                    schema={schema.properties[name]}
+                    required={required.includes(name)}
+                    value={value[name]}
+                    onChange={(fieldValue) => onChange(setField(value, name, fieldValue))}
+                />
+            ))}
+        </div>
+    );
+}
+
+function Field({ name, schema, required, value, onChange }) {
+    const label = schema.title || name;
+
+    if (schema.type === 'object') {
+        // Optional groups (e.g. welcome bonus, customer limits) start collapsed
+        return (
+            <details open={required || value !== undefined} className="border border-gray-200 rounded-xl p-4">
+                <summary className="text-sm font-medium text-gray-700 cursor-pointer">
+                    {label}
+                    {!required && <span className="text-gray-400"> (opcional)</span>}
+                </summary>
+                {schema.description && <p className="text-xs text-gray-500 mt-2">{schema.description}</p>}
+                <div className="mt-4">
+                    <ObjectFields schema={schema} value={value || {}} onChange={onChange} />
+                </div>
+            </details>
+        );
+    }
+
+    if (schema.type === 'array') {
+        return <ArrayField label={label} schema={schema} value={value || []} onChange={onChange} />;
+    }
+
+    return (
+        <div>
+            <label className="block text-sm font-medium text-gray-700 mb-2">
+                {label}
+                {!required && <span className="text-gray-400"> (opcional)</span>}
+            </label>
+            <ScalarInput schema={schema} required={required} value={value} onChange={onChange} />
+            {schema.description && <p className="text-xs text-gray-500 mt-1">{schema.description}</p>}
+        </div>
+    );
+}
+
+function ScalarInput({ schema, required, value, onChange }) {
+    if (schema.enum) {
+        return (
+            <select required={required} value={value ?? ''} onChange={(e) => onChange(e.target.value || undefined)} className={inputClass}>
+                <option value="">Selecione...</option>
+                {schema.enum.map((option) => (
+                    <option key={option} value={option}>
+                        {ENUM_LABELS[option] || option}
+                    </option>
+                ))}
+            </select>
+        );
+    }
+
+    if (schema.type === 'boolean') {
+        return (
+            <input
+                type="checkbox"
+                checked={Boolean(value)}
+                onChange={(e) => onChange(e.target.checked || undefined)}
+                className="w-5 h-5 rounded border-gray-300"
+            />
+        );
+    }
+
+    if (schema.type === 'number' || schema.type === 'integer') {
+        const min = schema.minimum ?? schema.exclusiveMinimum;
+        return (
+            <input
+                required={required}
+                type="number"
+                step={schema.type === 'integer' ? 1 : 'any'}
+                min={min}
+                max={schema.maximum}
+                value={value ?? ''}
+                onChange={(e) => {
+                    if (e.target.value === '') return onChange(undefined);
+                    const parsed = schema.type === 'integer' ? parseInt(e.target.value, 10) : parseFloat(e.target.value);
+                    onChange(Number.isNaN(parsed) ? undefined : parsed);
+                }}
+                className={inputClass}
+            />
+        );
+    }
+
+    return (
+        <input
+            required={required}
+            type="text"
+            minLength={schema.minLength}
+            value={value ?? ''}
+            onChange={(e) => onChange(e.target.value)}
+            className={inputClass}
+        />
+    );
+}
+
+function ArrayField({ label, schema, value, onChange }) {
+    const items = schema.items || {};
+    const update = (index, itemValue) => onChange(value.map((item, i) => (i === index ? itemValue : item)));
+    const remove = (index) => onChange(value.filter((_, i) => i !== index));
+    const add = () => onChange([...value, emptyValue(items)]);
+
+    return (
+        <div className="space-y-3">
+            <label className="block text-sm font-medium text-gray-700">{label}</label>
+            {value.map((item, index) => (
+                <div key={index} className="border border-gray-200 rounded-xl p-4 relative">
+                    {(schema.minItems || 0) < value.length && (
+                        <button
+                            type="button"
+                            onClick={() => remove(index)}
+                            className="absolute top-3 right-3 p-1 text-gray-400 hover:text-red-600"
+                        >
+                            <Trash2 className="w-4 h-4" />
+                        </button>
+                    )}
+                    {items.type === 'object' ? (
+                        <ObjectFields schema={items} value={item || {}} onChange={(itemValue) => update(index, itemValue)} />
+                    ) : (
+                        <ScalarInput schema={items} required value={item} onChange={(itemValue) => update(index, itemValue)} />
+                    )}
+                </div>
+            ))}
+            <button
+                type="button"
+                onClick={add}
+                className="w-full py-2 rounded-xl border-2 border-dashed border-gray-200 text-sm font-medium text-gray-500 hover:border-blue-500 hover:text-blue-600 transition-all flex items-center justify-center gap-2"
+            >
+                <Plus className="w-4 h-4" />
+                Adicionar
+            </button>
+        </div>
+    );
+}
@@ -0,0 +1,209 @@
+import React, { useState } from 'react';
+import { Phone, KeyRound, Mail, ArrowRight, Loader2, CheckCircle2 } from 'lucide-react';
+import Logo from '../components/Logo';
+
+const inputClass = 'w-full pl-12 pr-4 py-3 rounded-xl border border-gray-200 focus:border-fidelio-primary focus:ring-2 focus:ring-fidelio-primary/20 outline-none transition-all placeholder:text-gray-300';
+
+// Formats a Brazilian phone typed by the customer as E.164 (+5511999999999)
+function toE164(value) {
+    const digits = value.replace(/\D/g, '');
+    if (value.trim().startsWith('+')) {
+        return `+${digits}`;
+    }
+    return digits.startsWith('55') && digits.length > 11 ? `+${digits}` : `+55${digits}`;
+}
+
+export default function Claim() {
+    const [step, setStep] = useState('phone');
+    const [phone, setPhone] = useState('');
+    const [code, setCode] = useState('');
+    const [email, setEmail] = useState('');
+    const [result, setResult] = useState(null);
+    const [error, setError] = useState('');
+    const [loading, setLoading] = useState(false);
+
+    const post = async (path, body) => {
+        const response = await fetch(path, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify(body),
+        });
+        const data = await response.json().catch(() => ({}));
+        return { response, data };
+    };
+
+    const handleStart = async (e) => {
+        e.preventDefault();
+        setLoading(true);
+        setError('');
+
+        try {
+            const { response } = await post('/api/v1/claims/start', { phone: toE164(phone) });
+
+            if (response.status === 429) {
+                setError('Muitas tentativas. Aguarde alguns minutos e tente novamente.');
+                return;
+            }
+            if (!response.ok) {
+                setError('Confira o número de telefone, com DDD.');
+                return;
+            }
+
+            setStep('code');
+        } catch (err) {
+            console.error('Claim error:', err);
+            setError('Erro ao enviar o código. Tente novamente.');
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    const handleVerify = async (e) => {
+        e.preventDefault();
+        setLoading(true);
+        setError('');
+
+        try {
+            const { response, data } = await post('/api/v1/claims/verify', {
+                phone: toE164(phone),
+                code,
+                email: email || undefined,
+            });
+
+            if (response.status === 410) {
+                setError('O código expirou. Peça um novo código.');
+                setStep('phone');
+                setCode('');
+                return;
+            }
+            if (!response.ok) {
+                const emailError = data.fields?.some((field) => field.field === 'email');
+                setError(emailError ? 'Confira o email informado.' : 'Código inválido.');
+                return;
+            }
+
+            setResult(data);
+            setStep('done');
+        } catch (err) {
+            console.error('Claim error:', err);
+            setError('Erro ao confirmar o código. Tente novamente.');
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    const submitButton = (label) => (
+        <button
+            type="submit"
+            disabled={loading}
+            className="w-full bg-blue-600 text-white py-4 rounded-xl font-bold hover:bg-blue-700 transition-all shadow-lg hover:shadow-xl flex items-center justify-center gap-2 group disabled:opacity-70 disabled:cursor-not-allowed"
+        >
+            {loading ? (
+                <Loader2 className="w-5 h-5 animate-spin" />
+            ) : (
+                <>
+                    {label}
+                    <ArrowRight className="w-5 h-5 group-hover:translate-x-1 transition-transform" />
+                </>
+            )}
+        </button>
+    );
+
+    return (
+        <div className="min-h-screen flex items-center justify-center bg-gray-50 px-6">
+            <div className="w-full max-w-md bg-white rounded-3xl shadow-xl overflow-hidden border border-gray-100">
+                <div className="p-10">
+                    <div className="text-center mb-8">
+                        <div className="flex justify-center mb-2">
+                            <Logo className="text-5xl" />
+                        </div>
+                        <p className="text-gray-500">Resgate os pontos ganhos com seu telefone</p>
+                    </div>
+
+                    {error && (
+                        <p className="mb-6 text-sm text-red-600 bg-red-50 rounded-xl px-4 py-3">{error}</p>
+                    )}
+
+                    {step === 'phone' && (
+                        <form onSubmit={handleStart} className="space-y-6">
+                            <div>
+                                <label className="block text-sm font-medium text-gray-700 mb-2">Telefone</label>
+                                <div className="relative">
+                                    <Phone className="absolute left-4 top-1/2 -translate-y-1/2 w-5 h-5 text-gray-400" />
+                                    <input
+                                        type="tel"
+                                        required
+                                        value={phone}
+                                        onChange={(e) => setPhone(e.target.value)}
+                                        className={inputClass}
+                                        placeholder="(11) 99999-9999"
+                                    />
+                                </div>
+                            </div>
+                            {submitButton('Enviar código')}
+                        </form>
+                    )}
+
+                    {step === 'code' && (
+                        <form onSubmit={handleVerify} className="space-y-6">
+                            <p className="text-sm text-gray-500">
+                                Se houver pontos para este telefone, você vai receber um código por SMS.
+                            </p>
+                            <div>
+                                <label className="block text-sm font-medium text-gray-700 mb-2">Código</label>
+                                <div className="relative">
+                                    <KeyRound className="absolute left-4 top-1/2 -translate-y-1/2 w-5 h-5 text-gray-400" />
+                                    <input
+                                        type="text"
+                                        inputMode="numeric"
+                                        autoComplete="one-time-code"
+                                        required
+                                        value={code}
+                                        onChange={(e) => setCode(e.target.value)}
+                                        className={inputClass}
+                                        placeholder="000000"
+                                    />
+                                </div>
+                            </div>
+                            <div>
+                                <label className="block text-sm font-medium text-gray-700 mb-2">Email (opcional)</label>
+                                <div className="relative">
+                                    <Mail className="absolute left-4 top-1/2 -translate-y-1/2 w-5 h-5 text-gray-400" />
+                                    <input
+                                        type="email"
+                                        value={email}
+                                        onChange={(e) => setEmail(e.target.value)}
+                                        className={inputClass}
+                                        placeholder="seu@email.com"
+                                    />
+                                </div>
+                            </div>
+                            {submitButton('Resgatar')}
+                        </form>
+                    )}
+
+                    {step === 'done' && result && (
+                        <div className="space-y-4 text-center">
+                            <CheckCircle2 className="w-12 h-12 text-green-500 mx-auto" />
+                            <p className="text-gray-700 font-medium">
+                                {result.claimed > 0 ? 'Seus pontos estão garantidos!' : 'Não encontramos pontos para este telefone.'}
+                            </p>
+                            <ul className="space-y-2 text-left">
+                                {result.balances.map((balance) => (
+                                    <li key={balance.id} className="flex justify-between rounded-xl border border-gray-100 px-4 py-3 text-sm">
+                                        <span className="font-medium text-gray-800">{balance.merchant_name}</span>
+                                        <span className="text-gray-500">
+                                            até {new Date(balance.expires_at).toLocaleDateString('pt-BR')}
+                                        </span>
+                                    </li>
+                                ))}
+                            </ul>
+                        </div>
+                    )}
+                </div>
+            </div>
+        </div>
+    );
+}